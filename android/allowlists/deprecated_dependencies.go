@@ -0,0 +1,44 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package allowlists
+
+// DeprecatedDependency describes a module that is being deprecated. Modules that are not in
+// Allowlist may not depend on it directly.
+type DeprecatedDependency struct {
+	// The name of the deprecated module.
+	Name string
+
+	// A link to the documentation that explains how to migrate away from the module, which is
+	// included in the error message.
+	MigrationDoc string
+
+	// The names of the modules that depended on the module before it was deprecated. Remove
+	// modules from this list as they are migrated, never add to it.
+	Allowlist []string
+}
+
+var (
+	// DeprecatedDependencies is enforced by a neverallow rule for each entry.
+	DeprecatedDependencies = []DeprecatedDependency{
+		// Example:
+		// {
+		//	Name:         "libdeprecated",
+		//	MigrationDoc: "https://source.android.com/...",
+		//	Allowlist: []string{
+		//		"libexisting_user",
+		//	},
+		// },
+	}
+)
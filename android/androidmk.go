@@ -585,12 +585,14 @@ func (a *AndroidMkEntries) fillInEntries(ctx fillInEntriesContext, mod blueprint
 			a.AddPaths("LOCAL_FULL_VINTF_FRAGMENTS", base.vintfFragmentsPaths)
 		}
 		a.SetBoolIfTrue("LOCAL_PROPRIETARY_MODULE", Bool(base.commonProperties.Proprietary))
-		if Bool(base.commonProperties.Vendor) || Bool(base.commonProperties.Soc_specific) {
+		partitionKind, hasInstallPartition := base.installPartitionKind()
+		if Bool(base.commonProperties.Vendor) || Bool(base.commonProperties.Soc_specific) ||
+			(hasInstallPartition && partitionKind == socSpecificModule) {
 			a.SetString("LOCAL_VENDOR_MODULE", "true")
 		}
-		a.SetBoolIfTrue("LOCAL_ODM_MODULE", Bool(base.commonProperties.Device_specific))
-		a.SetBoolIfTrue("LOCAL_PRODUCT_MODULE", Bool(base.commonProperties.Product_specific))
-		a.SetBoolIfTrue("LOCAL_SYSTEM_EXT_MODULE", Bool(base.commonProperties.System_ext_specific))
+		a.SetBoolIfTrue("LOCAL_ODM_MODULE", base.DeviceSpecific())
+		a.SetBoolIfTrue("LOCAL_PRODUCT_MODULE", base.ProductSpecific())
+		a.SetBoolIfTrue("LOCAL_SYSTEM_EXT_MODULE", base.SystemExtSpecific())
 		if base.commonProperties.Owner != nil {
 			a.SetString("LOCAL_MODULE_OWNER", *base.commonProperties.Owner)
 		}
@@ -376,9 +376,10 @@ func (m *ApexModuleBase) BuildForApex(apex ApexInfo) {
 			// `com.android.foo` (from the `apex` module type) and
 			// `com.mycompany.android.foo` (from the `override_apex` module type), both
 			// of which has the same ApexVariantName `com.android.foo`. Add the apex
-			// name to the list so that it's not lost.
+			// name to the list so that it's not lost. The apexes call this from a parallel
+			// mutator, so the list is kept sorted to be independent of the order of the calls.
 			if !InList(apex.InApexModules[0], v.InApexModules) {
-				m.apexInfos[i].InApexModules = append(m.apexInfos[i].InApexModules, apex.InApexModules[0])
+				m.apexInfos[i].InApexModules = SortedUniqueStrings(append(CopyOf(v.InApexModules), apex.InApexModules[0]))
 			}
 			return
 		}
@@ -535,7 +536,7 @@ func mergeApexVariations(ctx PathContext, apexInfos []ApexInfo) (merged []ApexIn
 		if index, exists := seen[mergedName]; exists {
 			// Variants having the same mergedName are deduped
 			merged[index].InApexVariants = append(merged[index].InApexVariants, variantName)
-			merged[index].InApexModules = append(merged[index].InApexModules, apexInfo.InApexModules...)
+			merged[index].InApexModules = SortedUniqueStrings(append(merged[index].InApexModules, apexInfo.InApexModules...))
 			merged[index].ApexContents = append(merged[index].ApexContents, apexInfo.ApexContents...)
 			merged[index].Updatable = merged[index].Updatable || apexInfo.Updatable
 			// Platform APIs is allowed for this module only when all APEXes containing
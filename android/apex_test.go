@@ -146,3 +146,30 @@ func Test_mergeApexVariations(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildForApexOrder(t *testing.T) {
+	apexInfo := func(apex string) ApexInfo {
+		return ApexInfo{
+			ApexVariationName: "com.android.foo",
+			MinSdkVersion:     FutureApiLevel,
+			InApexVariants:    []string{"com.android.foo"},
+			InApexModules:     []string{apex},
+		}
+	}
+
+	// The apexes that override com.android.foo call BuildForApex from a parallel mutator, so the
+	// order of the calls varies between builds.
+	var first, second ApexModuleBase
+	first.BuildForApex(apexInfo("com.android.foo"))
+	first.BuildForApex(apexInfo("com.mycompany.android.foo"))
+	second.BuildForApex(apexInfo("com.mycompany.android.foo"))
+	second.BuildForApex(apexInfo("com.android.foo"))
+
+	want := []string{"com.android.foo", "com.mycompany.android.foo"}
+	if !reflect.DeepEqual(first.apexInfos[0].InApexModules, want) {
+		t.Errorf("want InApexModules %q, got %q", want, first.apexInfos[0].InApexModules)
+	}
+	if !reflect.DeepEqual(second.apexInfos[0].InApexModules, want) {
+		t.Errorf("want InApexModules %q, got %q", want, second.apexInfos[0].InApexModules)
+	}
+}
@@ -479,8 +479,16 @@ func osMutator(bpctx blueprint.BottomUpMutatorContext) {
 		return
 	}
 
+	if Bool(base.commonProperties.Host_cross_supported) &&
+		base.commonProperties.HostOrDeviceSupported&hostCrossSupported == 0 {
+		mctx.PropertyErrorf("host_cross_supported", "module type %q does not support host cross targets",
+			mctx.ModuleType())
+		return
+	}
+
 	// Collect a list of OSTypes supported by this module based on the HostOrDevice value
-	// passed to InitAndroidArchModule and the device_supported and host_supported properties.
+	// passed to InitAndroidArchModule and the device_supported, host_supported and
+	// host_cross_supported properties.
 	var moduleOSList []OsType
 	for _, os := range osTypeList {
 		for _, t := range mctx.Config().Targets[os] {
@@ -15,8 +15,11 @@
 package android
 
 import (
+	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/blueprint/proptools"
@@ -385,6 +388,155 @@ func TestArchMutator(t *testing.T) {
 	}
 }
 
+type variantOutTestModule struct {
+	ModuleBase
+	out WritablePath
+}
+
+func (m *variantOutTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.out = PathForModuleOut(ctx, "out")
+}
+
+func variantOutTestModuleFactory() Module {
+	m := &variantOutTestModule{}
+	InitAndroidArchModule(m, HostAndDeviceSupported, MultilibBoth)
+	return m
+}
+
+// TestModuleOutPathOfVariants checks that the intermediates directory of a variant joins the
+// variation names in the order of the mutators that created them, whatever the order in which the
+// variations were listed.
+func TestModuleOutPathOfVariants(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the host variants are only checked on linux")
+	}
+
+	bp := `
+		module {
+			name: "foo",
+			host_supported: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("module", variantOutTestModuleFactory)
+			ctx.PostDepsMutators(func(ctx RegisterMutatorsContext) {
+				ctx.BottomUp("flavor", func(ctx BottomUpMutatorContext) {
+					ctx.CreateVariations("vanilla", "chocolate")
+				})
+				ctx.BottomUp("topping", func(ctx BottomUpMutatorContext) {
+					ctx.CreateLocalVariations("", "sprinkles")
+				})
+			})
+		}),
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	for _, variant := range []string{
+		"android_arm64_armv8-a_chocolate",
+		"android_arm64_armv8-a_vanilla_sprinkles",
+		"android_arm_armv7-a-neon_vanilla",
+		"linux_glibc_x86_chocolate_sprinkles",
+		"linux_glibc_x86_64_vanilla",
+	} {
+		m := result.ModuleForTests("foo", variant).Module().(*variantOutTestModule)
+		AssertPathRelativeToTopEquals(t, "out path of "+variant,
+			"out/soong/.intermediates/foo/"+variant+"/out", m.out)
+	}
+}
+
+func TestHostCrossSupported(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("windows is only a host cross target on linux")
+	}
+
+	bp := `
+		module {
+			name: "foo",
+			host_supported: true,
+		}
+
+		module {
+			name: "bar",
+			host_supported: true,
+			host_cross_supported: true,
+		}
+
+		module {
+			name: "baz",
+			host_cross_supported: true,
+		}
+
+		module {
+			name: "qux",
+			host_supported: true,
+			host_cross_supported: false,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForArchTest,
+		FixtureModifyConfig(func(config Config) {
+			config.Targets[Windows] = []Target{
+				{Windows, Arch{ArchType: X86_64}, NativeBridgeDisabled, "", "", true},
+				{Windows, Arch{ArchType: X86}, NativeBridgeDisabled, "", "", true},
+			}
+		}),
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	// hostVariants returns the sorted host variants of the module, each suffixed with whether it is
+	// enabled.
+	hostVariants := func(name string) []string {
+		var ret []string
+		for _, variant := range result.ModuleVariantsForTests(name) {
+			if strings.HasPrefix(variant, "android_") {
+				continue
+			}
+			m := result.ModuleForTests(name, variant).Module()
+			ret = append(ret, fmt.Sprintf("%s:%t", variant, m.Enabled()))
+		}
+		sort.Strings(ret)
+		return ret
+	}
+
+	AssertDeepEquals(t, "foo host variants", []string{
+		"linux_glibc_x86:true", "linux_glibc_x86_64:true", "windows_x86:false", "windows_x86_64:false",
+	}, hostVariants("foo"))
+	AssertDeepEquals(t, "bar host variants", []string{
+		"linux_glibc_x86:true", "linux_glibc_x86_64:true", "windows_x86:true", "windows_x86_64:true",
+	}, hostVariants("bar"))
+	AssertDeepEquals(t, "baz host variants", []string{
+		"windows_x86:true", "windows_x86_64:true",
+	}, hostVariants("baz"))
+	AssertDeepEquals(t, "qux host variants", []string{
+		"linux_glibc_x86:true", "linux_glibc_x86_64:true",
+	}, hostVariants("qux"))
+}
+
+func TestHostCrossSupportedUnsupportedModuleType(t *testing.T) {
+	GroupFixturePreparers(
+		prepareForArchTest,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("no_cross_module", func() Module {
+				m := &archTestModule{}
+				m.AddProperties(&m.props)
+				InitAndroidArchModule(m, HostSupportedNoCross, MultilibFirst)
+				return m
+			})
+		}),
+	).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`host_cross_supported: module type "no_cross_module" does not support host cross targets`)).
+		RunTestWithBp(t, `
+		no_cross_module {
+			name: "foo",
+			host_cross_supported: true,
+		}
+	`)
+}
+
 func TestArchMutatorNativeBridge(t *testing.T) {
 	bp := `
 		// This module is only enabled for x86.
@@ -89,6 +89,31 @@ type BazelModuleBase struct {
 	baseModuleType string
 }
 
+// UnconvertedReasonType is the category of the reason that bp2build didn't convert a module.
+type UnconvertedReasonType string
+
+const (
+	// The module type doesn't have a bp2build converter.
+	UnconvertedReasonTypeUnsupportedModuleType UnconvertedReasonType = "unsupported_module_type"
+
+	// The module isn't enabled for conversion by the bp2build allowlist or its bazel_module property.
+	UnconvertedReasonTypeNotAllowlisted UnconvertedReasonType = "not_allowlisted"
+
+	// The module sets a property, or a value of a property, that the converter doesn't support.
+	UnconvertedReasonTypeUnsupportedProperty UnconvertedReasonType = "unsupported_property"
+
+	// The converter ran but didn't generate any targets for the module.
+	UnconvertedReasonTypeNoTargets UnconvertedReasonType = "no_targets"
+)
+
+// UnconvertedReason is the reason that bp2build didn't convert a module.
+type UnconvertedReason struct {
+	ReasonType UnconvertedReasonType `json:"type"`
+
+	// A description of what prevented the conversion, may be empty.
+	Detail string `json:"detail,omitempty"`
+}
+
 // Bazelable is specifies the interface for modules that can be converted to Bazel.
 type Bazelable interface {
 	bazelProps() *properties
@@ -509,11 +534,20 @@ func registerBp2buildConversionMutator(ctx RegisterMutatorsContext) {
 
 func convertWithBp2build(ctx TopDownMutatorContext) {
 	bModule, ok := ctx.Module().(Bazelable)
-	if !ok || !bModule.shouldConvertWithBp2build(ctx, ctx.Module()) {
+	if !ok || !bModule.bazelProps().Bazel_module.CanConvertToBazel {
+		ctx.MarkBp2buildUnconvertible(UnconvertedReasonTypeUnsupportedModuleType, "")
+		return
+	}
+	if !bModule.shouldConvertWithBp2build(ctx, ctx.Module()) {
+		ctx.MarkBp2buildUnconvertible(UnconvertedReasonTypeNotAllowlisted, "")
 		return
 	}
 
 	bModule.ConvertWithBp2build(ctx)
+
+	if !ctx.Module().IsConvertedByBp2build() {
+		ctx.MarkBp2buildUnconvertible(UnconvertedReasonTypeNoTargets, "")
+	}
 }
 
 // GetMainClassInManifest scans the manifest file specified in filepath and returns
@@ -94,6 +94,7 @@ type BazelConversionPathContext interface {
 	ModuleFromName(name string) (blueprint.Module, bool)
 	AddUnconvertedBp2buildDep(string)
 	AddMissingBp2buildDep(dep string)
+	MarkBp2buildUnconvertible(reasonType UnconvertedReasonType, detail string)
 }
 
 // BazelLabelForModuleDeps expects a list of reference to other modules, ("<module>"
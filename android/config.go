@@ -169,6 +169,22 @@ type config struct {
 	// regenerate build.ninja.
 	ninjaFileDepsSet sync.Map
 
+	// If set, the time spent in GenerateAndroidBuildActions is recorded by module type
+	// and reported in the soong_build metrics.
+	moduleTypeTimings *moduleTypeTimings
+
+	// If set, the dependencies of every module are recorded for WriteDependencyGraph.
+	dependencyGraph *dependencyGraph
+
+	// If set, the time spent in every mutator is recorded for WriteMutatorProfile.
+	mutatorProfile *mutatorProfile
+
+	// If set, the mutators that are registered as parallel run on one module at a time.
+	singleThreadedMutators bool
+
+	// If set, the variants of the modules with a given name are recorded for WriteModuleVariants.
+	moduleVariants *moduleVariants
+
 	OncePer
 }
 
@@ -449,6 +465,21 @@ func ConfigForAdditionalRun(c Config) (Config, error) {
 	}
 	newConfig.BazelContext = c.BazelContext
 	newConfig.envDeps = c.envDeps
+	if c.dependencyGraph != nil {
+		newConfig.EnableDependencyGraph()
+	}
+	if c.mutatorProfile != nil {
+		newConfig.EnableMutatorProfile()
+	}
+	if c.singleThreadedMutators {
+		newConfig.EnableSingleThreadedMutators()
+	}
+	if c.moduleVariants != nil {
+		newConfig.EnableModuleVariants(c.moduleVariants.name)
+		if c.moduleVariants.resolvedProperties {
+			newConfig.EnableModuleVariantProperties()
+		}
+	}
 	return newConfig, nil
 }
 
@@ -503,6 +534,10 @@ func NewConfig(moduleListFile string, runGoTests bool, outDir, soongOutDir strin
 
 	determineBuildOS(config)
 
+	if config.IsEnvTrue("SOONG_MODULE_TYPE_TIMINGS") {
+		config.moduleTypeTimings = newModuleTypeTimings()
+	}
+
 	// Sets up the map of target OSes to the finer grained compilation targets
 	// that are configured from the product variables.
 	targets, err := decodeTargetProductVariables(config)
@@ -699,6 +734,92 @@ func (c *config) EnvDeps() map[string]string {
 	return c.envDeps
 }
 
+// parseSoongBool parses value using the truthy and falsy spellings accepted
+// by IsEnvTrue and IsEnvFalse.
+func parseSoongBool(value string) (bool, error) {
+	switch value {
+	case "1", "y", "yes", "on", "true":
+		return true, nil
+	case "0", "n", "no", "off", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a valid boolean value", value)
+}
+
+// productVariable returns the field of productVariables called name with any
+// pointer dereferenced. ok is false if the variable is an unset pointer.
+func (c *config) productVariable(name string) (value reflect.Value, ok bool, err error) {
+	field := reflect.ValueOf(&c.productVariables).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return reflect.Value{}, false, fmt.Errorf("unknown product variable %q", name)
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return reflect.Value{}, false, nil
+		}
+		field = field.Elem()
+	}
+	return field, true, nil
+}
+
+// BoolVar returns the value of the product variable called name as a bool.
+// String variables are parsed with the values accepted by IsEnvTrue and
+// IsEnvFalse. ok is false if the variable is not set, and an error is
+// returned if the variable does not exist or cannot be parsed as a bool.
+func (c *config) BoolVar(name string) (value bool, ok bool, err error) {
+	field, ok, err := c.productVariable(name)
+	if !ok || err != nil {
+		return false, false, err
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		return field.Bool(), true, nil
+	case reflect.String:
+		value, err := parseSoongBool(field.String())
+		if err != nil {
+			return false, false, fmt.Errorf("product variable %q: %s", name, err)
+		}
+		return value, true, nil
+	}
+	return false, false, fmt.Errorf("product variable %q has type %s, not bool", name, field.Type())
+}
+
+// StringVar returns the value of the product variable called name as a
+// string. ok is false if the variable is not set, and an error is returned
+// if the variable does not exist or is not a string.
+func (c *config) StringVar(name string) (value string, ok bool, err error) {
+	field, ok, err := c.productVariable(name)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	if field.Kind() != reflect.String {
+		return "", false, fmt.Errorf("product variable %q has type %s, not string", name, field.Type())
+	}
+	return field.String(), true, nil
+}
+
+// IntVar returns the value of the product variable called name as an int.
+// String variables are parsed as decimal integers. ok is false if the
+// variable is not set, and an error is returned if the variable does not
+// exist or cannot be parsed as an int.
+func (c *config) IntVar(name string) (value int, ok bool, err error) {
+	field, ok, err := c.productVariable(name)
+	if !ok || err != nil {
+		return 0, false, err
+	}
+	switch field.Kind() {
+	case reflect.Int:
+		return int(field.Int()), true, nil
+	case reflect.String:
+		value, err := strconv.Atoi(field.String())
+		if err != nil {
+			return 0, false, fmt.Errorf("product variable %q: %q is not a valid integer", name, field.String())
+		}
+		return value, true, nil
+	}
+	return 0, false, fmt.Errorf("product variable %q has type %s, not int", name, field.Type())
+}
+
 func (c *config) KatiEnabled() bool {
 	return c.katiEnabled
 }
@@ -718,6 +839,37 @@ func (c *config) BuildNumberFile(ctx PathContext) Path {
 	return PathForOutput(ctx, String(c.productVariables.BuildNumberFile))
 }
 
+// HasBuildNumberFile returns true if the product sets a BuildNumberFile.
+func (c *config) HasBuildNumberFile() bool {
+	return String(c.productVariables.BuildNumberFile) != ""
+}
+
+var volatileInputsKey = NewOnceKey("volatileInputs")
+
+// VolatileInputs returns the generated files that change in every build, like the
+// BuildNumberFile.  Rules should read them without depending on them, with
+// RuleBuilderCommand.VolatileInput or an order-only dependency, so that only the rules that run
+// for other reasons see the new contents.  RuleBuilder.Build reports an error when one of them is
+// an input or an implicit input, as it would rerun the rule and everything that depends on it in
+// every build.  Rules built directly with ctx.Build are not checked, some of them, like the sdk
+// snapshot copy of the BuildNumberFile, depend on it on purpose.
+func (c *config) VolatileInputs(ctx PathContext) Paths {
+	return c.Once(volatileInputsKey, func() interface{} {
+		var paths Paths
+		if c.HasBuildNumberFile() {
+			paths = append(paths, c.BuildNumberFile(ctx))
+		}
+		return paths
+	}).(Paths)
+}
+
+// ReadOnlyOutputs returns true if SOONG_READ_ONLY_OUTPUTS is set, which makes the rules built
+// with RuleBuilder remove the write permissions of their outputs, so that tools that modify the
+// generated files by accident fail instead of confusing incremental builds.
+func (c *config) ReadOnlyOutputs() bool {
+	return c.IsEnvTrue("SOONG_READ_ONLY_OUTPUTS")
+}
+
 // DeviceName returns the name of the current device target.
 // TODO: take an AndroidModuleContext to select the device name for multi-device builds
 func (c *config) DeviceName() string {
@@ -1006,6 +1158,24 @@ func (c *config) RunErrorProne() bool {
 	return c.IsEnvTrue("RUN_ERROR_PRONE")
 }
 
+// GenruleSandboxInputs returns true if genrules that don't set sandbox_inputs should run with only
+// their declared inputs and tools visible.
+func (c *config) GenruleSandboxInputs() bool {
+	return c.IsEnvTrue("GENRULE_SANDBOX_INPUTS")
+}
+
+// HostToolCacheDir returns the cache directory shared with the builds in other output directories
+// that stores the objects compiled for host modules, or "" if SOONG_HOST_TOOL_CACHE is not set.
+func (c *config) HostToolCacheDir() string {
+	return c.Getenv("SOONG_HOST_TOOL_CACHE")
+}
+
+// HostToolCacheMaxSize returns the size in bytes of the objects in the SOONG_HOST_TOOL_CACHE
+// directory above which the least recently used ones are deleted, or "" to use the default.
+func (c *config) HostToolCacheMaxSize() string {
+	return c.Getenv("SOONG_HOST_TOOL_CACHE_MAX_SIZE")
+}
+
 // XrefCorpusName returns the Kythe cross-reference corpus name.
 func (c *config) XrefCorpusName() string {
 	return c.Getenv("XREF_CORPUS")
@@ -1039,6 +1209,18 @@ func (c Config) XrefCuJavaSourceMax() string {
 
 }
 
+// PreferPrebuiltModules returns the names of the modules for which the product uses the prebuilt
+// even if a source module exists and the prebuilt does not set prefer.
+func (c *config) PreferPrebuiltModules() []string {
+	return c.productVariables.PreferPrebuiltModules
+}
+
+// PreferSourceModules returns the names of the modules for which the product uses the source
+// module even if the prebuilt sets prefer.
+func (c *config) PreferSourceModules() []string {
+	return c.productVariables.PreferSourceModules
+}
+
 func (c *config) EmitXrefRules() bool {
 	return c.XrefCorpusName() != ""
 }
@@ -1231,6 +1413,31 @@ func (c *deviceConfig) SystemExtPath() string {
 	return "system_ext"
 }
 
+// InstallPartitions returns the partitions that modules can select with install_partition, the
+// ones that the product builds an image for.  The system partition is always built, the other ones
+// are installed in a directory of another partition, like system/product, when the product
+// doesn't build their image.
+func (c *deviceConfig) InstallPartitions() []string {
+	partitions := []string{"system"}
+	for _, p := range []struct{ name, path string }{
+		{"system_ext", c.SystemExtPath()},
+		{"product", c.ProductPath()},
+		{"vendor", c.VendorPath()},
+		{"odm", c.OdmPath()},
+	} {
+		if p.path == p.name {
+			partitions = append(partitions, p.name)
+		}
+	}
+	return partitions
+}
+
+// InstallPartitionEnabled returns true if modules can select the partition with
+// install_partition.
+func (c *deviceConfig) InstallPartitionEnabled(partition string) bool {
+	return InList(partition, c.InstallPartitions())
+}
+
 func (c *deviceConfig) BtConfigIncludeDir() string {
 	return String(c.config.productVariables.BtConfigIncludeDir)
 }
@@ -2034,3 +2241,15 @@ func (c *config) RBEWrapper() string {
 func (c *config) UseHostMusl() bool {
 	return Bool(c.productVariables.HostMusl)
 }
+
+// NinjaPool returns the ninja pool declared with the given name by the NinjaPools product variable,
+// or false if there is none.
+func (c *config) NinjaPool(name string) (blueprint.Pool, bool) {
+	for _, pool := range c.productVariables.NinjaPools {
+		if strings.SplitN(pool, ":", 2)[0] == name {
+			// The pool is declared by soong_ui in the combined ninja file, like highmem_pool.
+			return blueprint.NewBuiltinPool(name), true
+		}
+	}
+	return nil, false
+}
@@ -180,3 +180,116 @@ func TestConfiguredJarList(t *testing.T) {
 		assertStringEquals(t, "apex1:jarA", list5.String())
 	})
 }
+
+func TestProductVariableAccessors(t *testing.T) {
+	c := &config{
+		productVariables: productVariables{
+			Platform_sdk_final:   boolPtr(true),
+			Platform_sdk_version: intPtr(30),
+			ShippingApiLevel:     stringPtr("29"),
+			DeviceName:           stringPtr("test_device"),
+			DeviceProduct:        stringPtr("on"),
+			BuildId:              stringPtr("not_an_int"),
+
+			Platform_version_active_codenames: []string{"S"},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		get     func(string) (interface{}, bool, error)
+		varName string
+		value   interface{}
+		ok      bool
+		err     string
+	}{
+		{
+			name:    "bool",
+			get:     func(n string) (interface{}, bool, error) { return c.BoolVar(n) },
+			varName: "Platform_sdk_final",
+			value:   true,
+			ok:      true,
+		},
+		{
+			name:    "bool from string",
+			get:     func(n string) (interface{}, bool, error) { return c.BoolVar(n) },
+			varName: "DeviceProduct",
+			value:   true,
+			ok:      true,
+		},
+		{
+			name:    "bool unset",
+			get:     func(n string) (interface{}, bool, error) { return c.BoolVar(n) },
+			varName: "Eng",
+			value:   false,
+		},
+		{
+			name:    "bool invalid string",
+			get:     func(n string) (interface{}, bool, error) { return c.BoolVar(n) },
+			varName: "DeviceName",
+			value:   false,
+			err:     `product variable "DeviceName": "test_device" is not a valid boolean value`,
+		},
+		{
+			name:    "string",
+			get:     func(n string) (interface{}, bool, error) { return c.StringVar(n) },
+			varName: "DeviceName",
+			value:   "test_device",
+			ok:      true,
+		},
+		{
+			name:    "string wrong type",
+			get:     func(n string) (interface{}, bool, error) { return c.StringVar(n) },
+			varName: "Platform_version_active_codenames",
+			value:   "",
+			err:     `product variable "Platform_version_active_codenames" has type []string, not string`,
+		},
+		{
+			name:    "int",
+			get:     func(n string) (interface{}, bool, error) { return c.IntVar(n) },
+			varName: "Platform_sdk_version",
+			value:   30,
+			ok:      true,
+		},
+		{
+			name:    "int from string",
+			get:     func(n string) (interface{}, bool, error) { return c.IntVar(n) },
+			varName: "ShippingApiLevel",
+			value:   29,
+			ok:      true,
+		},
+		{
+			name:    "int invalid string",
+			get:     func(n string) (interface{}, bool, error) { return c.IntVar(n) },
+			varName: "BuildId",
+			value:   0,
+			err:     `product variable "BuildId": "not_an_int" is not a valid integer`,
+		},
+		{
+			name:    "unknown",
+			get:     func(n string) (interface{}, bool, error) { return c.IntVar(n) },
+			varName: "Not_a_product_variable",
+			value:   0,
+			err:     `unknown product variable "Not_a_product_variable"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, ok, err := tc.get(tc.varName)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("expected error %q, got %v", tc.err, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if ok != tc.ok {
+				t.Errorf("expected ok %t, got %t", tc.ok, ok)
+			}
+			if value != tc.value {
+				t.Errorf("expected value %#v, got %#v", tc.value, value)
+			}
+		})
+	}
+}
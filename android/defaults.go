@@ -16,6 +16,7 @@ package android
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -27,15 +28,36 @@ type defaultsDependencyTag struct {
 
 var DefaultsDepTag defaultsDependencyTag
 
+// variantDefaultsDependencyTag is the tag of the dependencies on the defaults modules listed in
+// the arch, multilib and target specific defaults properties.
+type variantDefaultsDependencyTag struct {
+	blueprint.BaseDependencyTag
+
+	// The path of the arch-specific property struct that the defaults are applied to, e.g.
+	// "Target.Host".
+	condition string
+}
+
 type defaultsProperties struct {
 	Defaults []string
 }
 
+// variantDefaultsProperties is the property struct used to create the arch-specific
+// property structs for the defaults property, e.g. target: { host: { defaults: [...] } }.
+type variantDefaultsProperties struct {
+	// defaults modules whose properties are only applied to the matching variants. Only properties
+	// that support arch variants can be applied this way.
+	Defaults []string `android:"arch_variant"`
+}
+
 type DefaultableModuleBase struct {
 	defaultsProperties            defaultsProperties
 	defaultableProperties         []interface{}
 	defaultableVariableProperties interface{}
 
+	// The arch-specific property structs containing the defaults property.
+	variantDefaultsProperties []interface{}
+
 	// The optional hook to call after any defaults have been applied.
 	hook DefaultableHook
 }
@@ -44,6 +66,78 @@ func (d *DefaultableModuleBase) defaults() *defaultsProperties {
 	return &d.defaultsProperties
 }
 
+// variantDefaults is a list of defaults modules that is only applied to some variants.
+type variantDefaults struct {
+	// The path of the arch-specific property struct, e.g. "Target.Host".
+	condition string
+
+	// The name of the property for use in error messages, e.g. "target.host.defaults".
+	property string
+
+	defaults []string
+}
+
+// initVariantDefaultsProperties creates the arch-specific property structs for the defaults
+// property, reusing the types that initArchModule creates for arch-specific properties.
+func (d *DefaultableModuleBase) initVariantDefaultsProperties() []interface{} {
+	t := reflect.TypeOf(&variantDefaultsProperties{})
+	archPropTypes := archPropTypeMap.Once(NewCustomOnceKey(t), func() interface{} {
+		return createArchPropTypeDesc(t)
+	}).([]archPropTypeDesc)
+
+	for _, t := range archPropTypes {
+		d.variantDefaultsProperties = append(d.variantDefaultsProperties, &archPropRoot{
+			Arch:     reflect.Zero(t.arch).Interface(),
+			Multilib: reflect.Zero(t.multilib).Interface(),
+			Target:   reflect.Zero(t.target).Interface(),
+		})
+	}
+	return d.variantDefaultsProperties
+}
+
+func (d *DefaultableModuleBase) variantDefaults() []variantDefaults {
+	var ret []variantDefaults
+
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			fieldPath := append(append([]string(nil), path...), field.Name)
+			if field.Type.Kind() != reflect.Struct {
+				continue
+			}
+			// The property structs containing only the defaults property are the leaves.
+			if field.Type.NumField() == 1 && field.Type.Field(0).Name == "Defaults" {
+				if list := v.Field(i).Field(0).Interface().([]string); len(list) > 0 {
+					var names []string
+					for _, name := range fieldPath {
+						if name != "BlueprintEmbed" {
+							names = append(names, proptools.PropertyNameForField(name))
+						}
+					}
+					ret = append(ret, variantDefaults{
+						condition: strings.Join(fieldPath, "."),
+						property:  strings.Join(append(names, "defaults"), "."),
+						defaults:  list,
+					})
+				}
+				continue
+			}
+			walk(v.Field(i), fieldPath)
+		}
+	}
+
+	for _, props := range d.variantDefaultsProperties {
+		root := reflect.ValueOf(props).Elem()
+		for _, field := range []string{"Arch", "Multilib", "Target"} {
+			if ptr := root.FieldByName(field).Elem(); !ptr.IsNil() {
+				walk(ptr.Elem(), []string{field})
+			}
+		}
+	}
+	return ret
+}
+
 func (d *DefaultableModuleBase) setProperties(props []interface{}, variableProperties interface{}) {
 	d.defaultableProperties = props
 	d.defaultableVariableProperties = variableProperties
@@ -64,6 +158,13 @@ type Defaultable interface {
 	// Get a pointer to the struct containing the Defaults property.
 	defaults() *defaultsProperties
 
+	// Create the property structs containing the arch, multilib and target specific defaults
+	// properties.
+	initVariantDefaultsProperties() []interface{}
+
+	// Get the defaults modules that are only applied to some variants.
+	variantDefaults() []variantDefaults
+
 	// Set the property structures into which defaults will be added.
 	setProperties(props []interface{}, variableProperties interface{})
 
@@ -71,6 +172,10 @@ type Defaultable interface {
 	// setProperties(...).
 	applyDefaults(TopDownMutatorContext, []Defaults)
 
+	// Apply defaults from the supplied Defaults to the arch-specific property structs selected by
+	// the variantDefaults.
+	applyVariantDefaults(TopDownMutatorContext, variantDefaults, []Defaults)
+
 	// Set the hook to be called after any defaults have been applied.
 	//
 	// Should be used in preference to a AddLoadHook when the behavior of the load
@@ -96,6 +201,8 @@ func InitDefaultableModule(module DefaultableModule) {
 	module.setProperties(module.GetProperties(), module.base().variableProperties)
 
 	module.AddProperties(module.defaults())
+
+	module.AddProperties(module.initVariantDefaultsProperties()...)
 }
 
 // A restricted subset of context methods, similar to LoadHookContext.
@@ -334,6 +441,119 @@ func (defaultable *DefaultableModuleBase) applyDefaultProperties(ctx TopDownMuta
 	}
 }
 
+// applyVariantDefaults applies the properties of the defaults modules to the arch-specific
+// property structs of the module selected by condition, e.g. "Target.Host", so that the arch
+// mutator only applies them to the matching variants in the same way as properties set directly
+// in the arch-specific property structs.
+func (defaultable *DefaultableModuleBase) applyVariantDefaults(ctx TopDownMutatorContext,
+	condition variantDefaults, defaultsList []Defaults) {
+
+	m := ctx.Module().base()
+	path := strings.Split(condition.condition, ".")
+
+	for _, defaults := range defaultsList {
+		for _, def := range defaults.properties() {
+			i := -1
+			for j, prop := range defaultable.defaultableProperties {
+				if j < len(m.archProperties) && m.archProperties[j] != nil && proptools.TypeEqual(prop, def) {
+					i = j
+					break
+				}
+			}
+
+			// Only the properties that are also in the arch-specific property structs can be
+			// applied.
+			archVariantAllowed := i != -1 && def != defaults.productVariableProperties()
+			for _, property := range propertiesNotArchVariant(reflect.ValueOf(def), "", archVariantAllowed) {
+				ctx.PropertyErrorf(condition.property, "%q sets %q, which does not support arch variants",
+					ctx.OtherModuleName(defaults.(Module)), property)
+			}
+			if !archVariantAllowed {
+				continue
+			}
+
+			var dst []interface{}
+			for _, archProps := range m.archProperties[i] {
+				dst = append(dst, variantPropertyStruct(archProps, path).Addr().Interface())
+			}
+			// Put an empty copy of the defaults properties into dst so that properties that don't
+			// support arch variants, and which were reported above, don't cause a "failed to find
+			// property to extend" error.
+			dst = append(dst, proptools.CloneEmptyProperties(reflect.ValueOf(def)).Interface())
+
+			err := proptools.PrependMatchingProperties(dst, def, nil)
+			if err != nil {
+				if propertyErr, ok := err.(*proptools.ExtendPropertyError); ok {
+					ctx.PropertyErrorf(propertyErr.Property, "%s", propertyErr.Err.Error())
+				} else {
+					panic(err)
+				}
+			}
+		}
+	}
+}
+
+// variantPropertyStruct returns the property struct at the given path, e.g. ["Target", "Host"],
+// in the archPropRoot archProps, allocating the arch, multilib or target struct if necessary.
+func variantPropertyStruct(archProps interface{}, path []string) reflect.Value {
+	field := reflect.ValueOf(archProps).Elem().FieldByName(path[0])
+	ptr := field.Elem()
+	if ptr.IsNil() {
+		ptr = reflect.New(ptr.Type().Elem())
+		field.Set(ptr)
+	}
+	v := ptr.Elem()
+	for _, name := range path[1:] {
+		v = v.FieldByName(name)
+	}
+	return v
+}
+
+// propertiesNotArchVariant returns the names of the properties that are set in the property
+// struct v and can't be applied to some variants only. When archVariantAllowed is false that is
+// all of them, otherwise it is the ones that aren't tagged with `android:"arch_variant"`.
+func propertiesNotArchVariant(v reflect.Value, prefix string, archVariantAllowed bool) []string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return propertiesNotArchVariant(v.Elem(), prefix, archVariantAllowed)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	var ret []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || proptools.HasTag(field, "blueprint", "mutated") {
+			continue
+		}
+		if archVariantAllowed && proptools.HasTag(field, "android", "arch_variant") {
+			continue
+		}
+		name := prefix
+		if !field.Anonymous && field.Name != "BlueprintEmbed" {
+			name += proptools.PropertyNameForField(field.Name)
+		}
+
+		fieldValue := v.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.Struct, reflect.Ptr, reflect.Interface:
+			if name != prefix {
+				name += "."
+			}
+			ret = append(ret, propertiesNotArchVariant(fieldValue, name, archVariantAllowed)...)
+		default:
+			if !fieldValue.IsZero() {
+				ret = append(ret, name)
+			}
+		}
+	}
+	return ret
+}
+
 func RegisterDefaultsPreArchMutators(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("defaults_deps", defaultsDepsMutator).Parallel()
 	ctx.TopDown("defaults", defaultsMutator).Parallel()
@@ -342,31 +562,84 @@ func RegisterDefaultsPreArchMutators(ctx RegisterMutatorsContext) {
 func defaultsDepsMutator(ctx BottomUpMutatorContext) {
 	if defaultable, ok := ctx.Module().(Defaultable); ok {
 		ctx.AddDependency(ctx.Module(), DefaultsDepTag, defaultable.defaults().Defaults...)
+		for _, variant := range defaultable.variantDefaults() {
+			ctx.AddDependency(ctx.Module(), variantDefaultsDependencyTag{condition: variant.condition},
+				variant.defaults...)
+		}
 	}
 }
 
 func defaultsMutator(ctx TopDownMutatorContext) {
 	if defaultable, ok := ctx.Module().(Defaultable); ok {
-		if len(defaultable.defaults().Defaults) > 0 {
+		variants := defaultable.variantDefaults()
+		if len(defaultable.defaults().Defaults) > 0 || len(variants) > 0 {
 			var defaultsList []Defaults
 			seen := make(map[Defaults]bool)
 
+			// The defaults modules listed in the arch-specific defaults properties, and their
+			// defaults, are collected separately for each arch-specific property struct.
+			variantDefaultsLists := make(map[string][]Defaults)
+			variantSeen := make(map[string]map[Defaults]bool)
+			conditions := make(map[Module]string)
+
 			ctx.WalkDeps(func(module, parent Module) bool {
-				if ctx.OtherModuleDependencyTag(module) == DefaultsDepTag {
-					if defaults, ok := module.(Defaults); ok {
-						if !seen[defaults] {
-							seen[defaults] = true
-							defaultsList = append(defaultsList, defaults)
-							return len(defaults.defaults().Defaults) > 0
-						}
-					} else {
-						ctx.PropertyErrorf("defaults", "module %s is not an defaults module",
-							ctx.OtherModuleName(module))
+				condition := conditions[parent]
+				property := "defaults"
+				switch tag := ctx.OtherModuleDependencyTag(module).(type) {
+				case defaultsDependencyTag:
+				case variantDefaultsDependencyTag:
+					if condition != "" {
+						ctx.PropertyErrorf("defaults", "module %s is listed in arch-specific defaults of %s, which is itself an arch-specific default",
+							ctx.OtherModuleName(module), ctx.OtherModuleName(parent))
+						return false
 					}
+					condition = tag.condition
+				default:
+					return false
+				}
+				if condition != "" {
+					property = "arch-specific defaults"
+				}
+
+				defaults, ok := module.(Defaults)
+				if !ok {
+					ctx.PropertyErrorf(property, "module %s is not an defaults module",
+						ctx.OtherModuleName(module))
+					return false
 				}
-				return false
+
+				if condition == "" {
+					if seen[defaults] {
+						return false
+					}
+					seen[defaults] = true
+					defaultsList = append(defaultsList, defaults)
+				} else {
+					if variantSeen[condition] == nil {
+						variantSeen[condition] = make(map[Defaults]bool)
+					}
+					if variantSeen[condition][defaults] {
+						return false
+					}
+					variantSeen[condition][defaults] = true
+					variantDefaultsLists[condition] = append(variantDefaultsLists[condition], defaults)
+				}
+				conditions[module] = condition
+				return len(defaults.defaults().Defaults) > 0 || len(defaults.variantDefaults()) > 0
 			})
 			defaultable.applyDefaults(ctx, defaultsList)
+
+			// Conditions that are only inherited from defaults modules are reported against the
+			// property of the module that lists the defaults modules.
+			for _, condition := range SortedStringKeys(variantDefaultsLists) {
+				variant := variantDefaults{condition: condition, property: "defaults"}
+				for _, v := range variants {
+					if v.condition == condition {
+						variant = v
+					}
+				}
+				defaultable.applyVariantDefaults(ctx, variant, variantDefaultsLists[condition])
+			}
 		}
 
 		defaultable.callHookIfAvailable(ctx)
@@ -15,6 +15,7 @@
 package android
 
 import (
+	"regexp"
 	"testing"
 )
 
@@ -130,3 +131,204 @@ func TestDefaultsAllowMissingDependencies(t *testing.T) {
 	// TODO: missing transitive defaults is currently not handled
 	_ = missingTransitiveDefaults
 }
+
+type variantDefaultsTestProperties struct {
+	Foo []string `android:"arch_variant"`
+	Bar []string
+}
+
+type variantDefaultsTestModule struct {
+	ModuleBase
+	DefaultableModuleBase
+	properties variantDefaultsTestProperties
+}
+
+func (d *variantDefaultsTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+func variantDefaultsTestModuleFactory() Module {
+	module := &variantDefaultsTestModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, HostAndDeviceSupported, MultilibFirst)
+	InitDefaultableModule(module)
+	return module
+}
+
+type variantDefaultsTestDefaults struct {
+	ModuleBase
+	DefaultsModuleBase
+}
+
+func variantDefaultsTestDefaultsFactory() Module {
+	defaults := &variantDefaultsTestDefaults{}
+	defaults.AddProperties(&variantDefaultsTestProperties{})
+	InitDefaultsModule(defaults)
+	return defaults
+}
+
+var prepareForVariantDefaultsTest = GroupFixturePreparers(
+	PrepareForTestWithArchMutator,
+	PrepareForTestWithDefaults,
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("test", variantDefaultsTestModuleFactory)
+		ctx.RegisterModuleType("defaults", variantDefaultsTestDefaultsFactory)
+	}),
+)
+
+func TestVariantDefaults(t *testing.T) {
+	bp := `
+		defaults {
+			name: "common",
+			foo: ["common"],
+		}
+
+		defaults {
+			name: "host_transitive",
+			foo: ["host_transitive"],
+		}
+
+		defaults {
+			name: "host",
+			defaults: ["host_transitive"],
+			foo: ["host"],
+		}
+
+		defaults {
+			name: "device",
+			foo: ["device"],
+		}
+
+		defaults {
+			name: "inherited",
+			target: {
+				android: {
+					defaults: ["device_inherited"],
+				},
+			},
+		}
+
+		defaults {
+			name: "device_inherited",
+			foo: ["device_inherited"],
+		}
+
+		test {
+			name: "foo",
+			host_supported: true,
+			defaults: ["common", "inherited"],
+			foo: ["module"],
+			target: {
+				host: {
+					defaults: ["host"],
+					foo: ["module_host"],
+				},
+				android: {
+					defaults: ["device"],
+				},
+			},
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForVariantDefaultsTest,
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	host := result.ModuleForTests("foo", result.Config.BuildOSTarget.String()).Module().(*variantDefaultsTestModule)
+	AssertDeepEquals(t, "host foo", []string{"common", "module", "host_transitive", "host", "module_host"},
+		host.properties.Foo)
+
+	device := result.ModuleForTests("foo", "android_arm64_armv8-a").Module().(*variantDefaultsTestModule)
+	AssertDeepEquals(t, "device foo", []string{"common", "module", "device", "device_inherited"},
+		device.properties.Foo)
+}
+
+func TestVariantDefaultsErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name: "not arch variant",
+			bp: `
+				defaults {
+					name: "host",
+					bar: ["host"],
+				}
+
+				test {
+					name: "foo",
+					host_supported: true,
+					target: {
+						host: {
+							defaults: ["host"],
+						},
+					},
+				}
+			`,
+			error: `target.host.defaults: "host" sets "bar", which does not support arch variants`,
+		},
+		{
+			name: "arch-specific properties",
+			bp: `
+				defaults {
+					name: "host",
+					target: {
+						linux_glibc: {
+							foo: ["linux_glibc"],
+						},
+					},
+				}
+
+				test {
+					name: "foo",
+					host_supported: true,
+					target: {
+						host: {
+							defaults: ["host"],
+						},
+					},
+				}
+			`,
+			error: `target.host.defaults: "host" sets "target.linux_glibc.foo", which does not support arch variants`,
+		},
+		{
+			name: "nested",
+			bp: `
+				defaults {
+					name: "nested",
+				}
+
+				defaults {
+					name: "host",
+					target: {
+						android: {
+							defaults: ["nested"],
+						},
+					},
+				}
+
+				test {
+					name: "foo",
+					host_supported: true,
+					target: {
+						host: {
+							defaults: ["host"],
+						},
+					},
+				}
+			`,
+			error: `module nested is listed in arch-specific defaults of host, which is itself an arch-specific default`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForVariantDefaultsTest,
+				FixtureWithRootAndroidBp(tc.bp),
+			).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.error))).
+				RunTest(t)
+		})
+	}
+}
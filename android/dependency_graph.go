@@ -0,0 +1,151 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// dependencyGraph records the direct dependencies of every module variant while build actions
+// are generated, so that they can be written out with WriteDependencyGraph.
+type dependencyGraph struct {
+	lock  sync.Mutex
+	nodes map[string]DependencyGraphNode
+	edges map[DependencyGraphEdge]bool
+}
+
+// DependencyGraph is the format of the file written by WriteDependencyGraph. Nodes and edges are
+// sorted so that the files from two builds can be diffed.
+type DependencyGraph struct {
+	Nodes []DependencyGraphNode `json:"nodes"`
+	Edges []DependencyGraphEdge `json:"edges"`
+}
+
+// DependencyGraphNode is a single variant of a module.
+type DependencyGraphNode struct {
+	// Id uniquely identifies the module variant, in the form "//dir:name@variant". The "@variant"
+	// suffix is omitted for modules with a single variant.
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+	Dir     string `json:"dir"`
+	Type    string `json:"type"`
+}
+
+// DependencyGraphEdge is a direct dependency from one module variant on another.
+type DependencyGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Tag is the type of the dependency tag, for example "cc.libraryDependencyTag".
+	Tag string `json:"tag"`
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{
+		nodes: make(map[string]DependencyGraphNode),
+		edges: make(map[DependencyGraphEdge]bool),
+	}
+}
+
+// EnableDependencyGraph makes the build record the dependencies of every module so that they can
+// be written with WriteDependencyGraph.
+func (c *config) EnableDependencyGraph() {
+	c.dependencyGraph = newDependencyGraph()
+}
+
+func dependencyGraphNodeId(dir, name, variant string) string {
+	id := "//" + dir + ":" + name
+	if variant != "" {
+		id += "@" + variant
+	}
+	return id
+}
+
+// record adds the module being visited by ctx and its direct dependencies to the graph.
+func (g *dependencyGraph) record(ctx blueprint.ModuleContext) {
+	node := DependencyGraphNode{
+		Id:      dependencyGraphNodeId(ctx.ModuleDir(), ctx.ModuleName(), ctx.ModuleSubDir()),
+		Name:    ctx.ModuleName(),
+		Variant: ctx.ModuleSubDir(),
+		Dir:     ctx.ModuleDir(),
+		Type:    ctx.ModuleType(),
+	}
+
+	var edges []DependencyGraphEdge
+	ctx.VisitDirectDeps(func(dep blueprint.Module) {
+		edges = append(edges, DependencyGraphEdge{
+			From: node.Id,
+			To:   dependencyGraphNodeId(ctx.OtherModuleDir(dep), ctx.OtherModuleName(dep), ctx.OtherModuleSubDir(dep)),
+			Tag:  fmt.Sprintf("%T", ctx.OtherModuleDependencyTag(dep)),
+		})
+	})
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.nodes[node.Id] = node
+	for _, edge := range edges {
+		g.edges[edge] = true
+	}
+}
+
+// graph returns the recorded graph in a deterministic order.
+func (g *dependencyGraph) graph() DependencyGraph {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	ret := DependencyGraph{
+		Nodes: make([]DependencyGraphNode, 0, len(g.nodes)),
+		Edges: make([]DependencyGraphEdge, 0, len(g.edges)),
+	}
+	for _, node := range g.nodes {
+		ret.Nodes = append(ret.Nodes, node)
+	}
+	for edge := range g.edges {
+		ret.Edges = append(ret.Edges, edge)
+	}
+	sort.Slice(ret.Nodes, func(i, j int) bool {
+		return ret.Nodes[i].Id < ret.Nodes[j].Id
+	})
+	sort.Slice(ret.Edges, func(i, j int) bool {
+		a, b := ret.Edges[i], ret.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Tag < b.Tag
+	})
+	return ret
+}
+
+// WriteDependencyGraph writes the module dependency graph recorded since EnableDependencyGraph was
+// called to path as JSON.
+func WriteDependencyGraph(config Config, path string) error {
+	if config.dependencyGraph == nil {
+		return fmt.Errorf("EnableDependencyGraph was not called")
+	}
+	data, err := json.MarshalIndent(config.dependencyGraph.graph(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(absolutePath(path), append(data, '\n'), 0666)
+}
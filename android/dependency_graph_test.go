@@ -0,0 +1,84 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		FixtureModifyConfig(func(config Config) {
+			config.EnableDependencyGraph()
+		}),
+	).RunTestWithBp(t, `
+		deps {
+			name: "foo",
+			deps: ["bar"],
+		}
+		deps {
+			name: "bar",
+		}
+	`)
+
+	path := filepath.Join(t.TempDir(), "dependency-graph.json")
+	if err := WriteDependencyGraph(result.Config, path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var graph DependencyGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		t.Fatalf("failed to parse %s: %s", path, err)
+	}
+
+	expectedNodes := []DependencyGraphNode{
+		{Id: "//:bar@android_common", Name: "bar", Variant: "android_common", Type: "deps"},
+		{Id: "//:foo@android_common", Name: "foo", Variant: "android_common", Type: "deps"},
+	}
+	if !reflect.DeepEqual(graph.Nodes, expectedNodes) {
+		t.Errorf("expected nodes %v, got %v", expectedNodes, graph.Nodes)
+	}
+
+	expectedEdge := DependencyGraphEdge{
+		From: "//:foo@android_common",
+		To:   "//:bar@android_common",
+		Tag:  "android.installDepTag",
+	}
+	found := false
+	for _, edge := range graph.Edges {
+		if edge == expectedEdge {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected edge %v in %v", expectedEdge, graph.Edges)
+	}
+}
+
+func TestWriteDependencyGraphNotEnabled(t *testing.T) {
+	config := TestConfig(t.TempDir(), nil, "", nil)
+	if err := WriteDependencyGraph(config, filepath.Join(t.TempDir(), "graph.json")); err == nil {
+		t.Errorf("expected an error when the dependency graph was not enabled")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	RegisterDeprecatedModulesBuildComponents(InitRegistrationContext)
+}
+
+func RegisterDeprecatedModulesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("deprecated_modules", deprecatedModulesSingletonFactory)
+}
+
+var PrepareForTestWithDeprecatedModules = FixtureRegisterWithContext(RegisterDeprecatedModulesBuildComponents)
+
+// Dependencies on modules that are deprecated at least as strongly as the level in
+// DEPRECATED_MODULE_ERROR_LEVEL are errors instead of warnings.
+const deprecatedModuleErrorLevelEnvVar = "DEPRECATED_MODULE_ERROR_LEVEL"
+
+const (
+	softDeprecation = "soft"
+	hardDeprecation = "hard"
+)
+
+// deprecationStrength orders the deprecation levels, from the weakest to the strongest.
+var deprecationStrength = map[string]int{
+	softDeprecation: 1,
+	hardDeprecation: 2,
+}
+
+// deprecatedDependency is a dependency of a module on a deprecated module.
+type deprecatedDependency struct {
+	pos     string
+	module  string
+	dep     string
+	level   string
+	message string
+}
+
+func (d deprecatedDependency) String() string {
+	s := fmt.Sprintf("%s: %q depends on %s deprecated module %q", d.pos, d.module, d.level, d.dep)
+	if d.message != "" {
+		s += ": " + d.message
+	}
+	return s
+}
+
+// deprecatedModulesSingleton reports every dependency on a module that sets the deprecation
+// property. They are collected across all modules so that a single error lists all of them.
+type deprecatedModulesSingleton struct{}
+
+func deprecatedModulesSingletonFactory() Singleton {
+	return &deprecatedModulesSingleton{}
+}
+
+// deprecationLevel returns the level that m is deprecated at, or "" if it isn't deprecated.
+func deprecationLevel(m Module) string {
+	deprecation := m.base().commonProperties.Deprecation
+	if deprecation.Level != nil {
+		return *deprecation.Level
+	} else if deprecation.Message != nil {
+		return softDeprecation
+	}
+	return ""
+}
+
+func (s *deprecatedModulesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	errorLevel := ctx.Config().Getenv(deprecatedModuleErrorLevelEnvVar)
+	if errorLevel != "" && deprecationStrength[errorLevel] == 0 {
+		ctx.Errorf("invalid %s %q, expected %q or %q", deprecatedModuleErrorLevelEnvVar, errorLevel,
+			softDeprecation, hardDeprecation)
+		return
+	}
+
+	invalid := make(map[string]bool)
+	found := make(map[deprecatedDependency]bool)
+	ctx.VisitAllModules(func(m Module) {
+		if level := deprecationLevel(m); level != "" && deprecationStrength[level] == 0 {
+			if !invalid[ctx.ModuleName(m)] {
+				ctx.ModuleErrorf(m, "invalid deprecation.level %q, expected %q or %q", level,
+					softDeprecation, hardDeprecation)
+				invalid[ctx.ModuleName(m)] = true
+			}
+			return
+		}
+
+		ctx.VisitDirectDeps(m, func(dep Module) {
+			level := deprecationLevel(dep)
+			// Dependencies between the variants of a deprecated module are not reported.
+			if deprecationStrength[level] == 0 || ctx.ModuleName(dep) == ctx.ModuleName(m) {
+				return
+			}
+			found[deprecatedDependency{
+				pos:     modulePosition(ctx, m),
+				module:  ctx.ModuleName(m),
+				dep:     ctx.ModuleName(dep),
+				level:   level,
+				message: proptools.String(dep.base().commonProperties.Deprecation.Message),
+			}] = true
+		})
+	})
+
+	var errs, warnings []string
+	for d := range found {
+		if errorLevel != "" && deprecationStrength[d.level] >= deprecationStrength[errorLevel] {
+			errs = append(errs, d.String())
+		} else {
+			warnings = append(warnings, d.String())
+		}
+	}
+	sort.Strings(errs)
+	sort.Strings(warnings)
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if len(errs) > 0 {
+		ctx.Errorf("%d dependencies on deprecated modules are errors with %s=%s:\n    %s",
+			len(errs), deprecatedModuleErrorLevelEnvVar, errorLevel, strings.Join(errs, "\n    "))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestDeprecatedModules(t *testing.T) {
+	bp := `
+		deps {
+			name: "soft",
+			deprecation: {
+				message: "use new instead",
+			},
+		}
+		deps {
+			name: "hard",
+			deprecation: {
+				level: "hard",
+			},
+		}
+		deps {
+			name: "foo",
+			deps: ["soft", "hard"],
+		}
+		deps {
+			name: "bar",
+			deps: ["hard"],
+		}
+	`
+
+	testCases := []struct {
+		name       string
+		errorLevel string
+		errors     []string
+	}{
+		{
+			name: "warnings only",
+		},
+		{
+			name:       "hard",
+			errorLevel: "hard",
+			errors: []string{
+				`2 dependencies on deprecated modules are errors with DEPRECATED_MODULE_ERROR_LEVEL=hard:\n` +
+					`    Android.bp:18:3: "bar" depends on hard deprecated module "hard"\n` +
+					`    Android.bp:14:3: "foo" depends on hard deprecated module "hard"$`,
+			},
+		},
+		{
+			name:       "soft",
+			errorLevel: "soft",
+			errors: []string{
+				`3 dependencies on deprecated modules are errors with DEPRECATED_MODULE_ERROR_LEVEL=soft:\n` +
+					`    Android.bp:18:3: "bar" depends on hard deprecated module "hard"\n` +
+					`    Android.bp:14:3: "foo" depends on hard deprecated module "hard"\n` +
+					`    Android.bp:14:3: "foo" depends on soft deprecated module "soft": use new instead$`,
+			},
+		},
+		{
+			name:       "invalid error level",
+			errorLevel: "all",
+			errors:     []string{`invalid DEPRECATED_MODULE_ERROR_LEVEL "all", expected "soft" or "hard"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForModuleTests,
+				PrepareForTestWithDeprecatedModules,
+				FixtureMergeEnv(map[string]string{deprecatedModuleErrorLevelEnvVar: tc.errorLevel}),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(tc.errors)).
+				RunTestWithBp(t, bp)
+		})
+	}
+}
+
+func TestDeprecatedModulesInvalidLevel(t *testing.T) {
+	GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithDeprecatedModules,
+	).
+		ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`module "foo": invalid deprecation.level "medium", expected "soft" or "hard"`)).
+		RunTestWithBp(t, `
+			deps {
+				name: "foo",
+				deprecation: {
+					level: "medium",
+				},
+			}
+		`)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"os"
+)
+
+func init() {
+	RegisterEmptyGlobsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterEmptyGlobsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("empty_globs", emptyGlobsSingletonFactory)
+}
+
+var PrepareForTestWithEmptyGlobs = FixtureRegisterWithContext(RegisterEmptyGlobsBuildComponents)
+
+// When SOONG_LINT_EMPTY_GLOBS is true, globs in the path properties of modules that match no
+// files are reported as warnings at the end of analysis.
+const lintEmptyGlobsEnvVar = "SOONG_LINT_EMPTY_GLOBS"
+
+func lintEmptyGlobs(config Config) bool {
+	return config.IsEnvTrue(lintEmptyGlobsEnvVar)
+}
+
+// recordEmptyGlob records that glob, as written in a property of the module being built,
+// matched no files, unless the module lists it in allow_empty_globs.
+func recordEmptyGlob(ctx EarlyModulePathContext, glob string) {
+	mctx, ok := ctx.(interface{ Module() Module })
+	if !ok {
+		return
+	}
+	m := mctx.Module().base()
+	if InList(glob, m.commonProperties.Allow_empty_globs) || InList(glob, m.emptyGlobs) {
+		return
+	}
+	m.emptyGlobs = append(m.emptyGlobs, glob)
+}
+
+// emptyGlobsSingleton reports the globs that matched no files in any module, collected by the
+// path expansion functions while the modules were built.
+type emptyGlobsSingleton struct {
+	// The reported warnings, for tests.
+	warnings []string
+}
+
+func emptyGlobsSingletonFactory() Singleton {
+	return &emptyGlobsSingleton{}
+}
+
+func (s *emptyGlobsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !lintEmptyGlobs(ctx.Config()) {
+		return
+	}
+
+	// Modules with multiple variants usually report the same globs in each of them.
+	found := make(map[string]bool)
+	ctx.VisitAllModules(func(m Module) {
+		for _, glob := range m.base().emptyGlobs {
+			found[fmt.Sprintf("%s: %q: glob %q matches no files, remove it or add it to allow_empty_globs",
+				modulePosition(ctx, m), ctx.ModuleName(m), glob)] = true
+		}
+	})
+
+	s.warnings = SortedStringKeys(found)
+	for _, warning := range s.warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
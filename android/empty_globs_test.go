@@ -0,0 +1,75 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestEmptyGlobs(t *testing.T) {
+	bp := `
+		filegroup {
+			name: "used",
+			srcs: ["*.c"],
+			exclude_srcs: ["b.c"],
+		}
+		filegroup {
+			name: "unused",
+			srcs: ["*.cpp", "*.c"],
+			exclude_srcs: ["*.h"],
+		}
+		filegroup {
+			name: "allowed",
+			srcs: ["*.cpp"],
+			allow_empty_globs: ["*.cpp"],
+		}
+	`
+
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		warnings []string
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name: "enabled",
+			env:  map[string]string{lintEmptyGlobsEnvVar: "true"},
+			warnings: []string{
+				`Android.bp:7:3: "unused": glob "*.cpp" matches no files, remove it or add it to allow_empty_globs`,
+				`Android.bp:7:3: "unused": glob "*.h" matches no files, remove it or add it to allow_empty_globs`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := GroupFixturePreparers(
+				PrepareForTestWithFilegroup,
+				PrepareForTestWithEmptyGlobs,
+				FixtureMergeEnv(tc.env),
+				FixtureWithRootAndroidBp(bp),
+				FixtureMergeMockFs(MockFS{
+					"a.c": nil,
+					"b.c": nil,
+				}),
+			).RunTest(t)
+
+			s := result.SingletonForTests("empty_globs").Singleton().(*emptyGlobsSingleton)
+			AssertDeepEquals(t, "warnings", tc.warnings, s.warnings)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+)
+
+func init() {
+	RegisterInstalledFilesBuildComponents(InitRegistrationContext)
+}
+
+func RegisterInstalledFilesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("installed_files", installedFilesSingletonFactory)
+}
+
+var PrepareForTestWithInstalledFiles = FixtureRegisterWithContext(RegisterInstalledFilesBuildComponents)
+
+const installedFilesJsonFileName = "installed_files.json"
+
+// installedFileInfo is the entry of an installed file in installed_files.json.
+type installedFileInfo struct {
+	Module        string `json:"module"`
+	Variant       string `json:"variant,omitempty"`
+	Partition     string `json:"partition,omitempty"`
+	Source        string `json:"source,omitempty"`
+	SymlinkTarget string `json:"symlink_target,omitempty"`
+}
+
+// installedFilesSingleton writes $OUT_DIR/soong/installed_files.json, which maps the path of
+// every file that Soong creates an install rule for, whether the rule is run by Soong or
+// exported to Make, to the module that installs it and the file that is installed.  It is an
+// error for two modules, or two variants of a module, to install the same path.
+type installedFilesSingleton struct {
+	outputPath WritablePath
+
+	// The written entries, for tests.
+	installedFiles map[string]installedFileInfo
+}
+
+var _ SingletonMakeVarsProvider = (*installedFilesSingleton)(nil)
+
+func installedFilesSingletonFactory() Singleton {
+	return &installedFilesSingleton{}
+}
+
+func (s *installedFilesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	s.installedFiles = make(map[string]installedFileInfo)
+	ctx.VisitAllModules(func(m Module) {
+		// The packaging specs also describe the files that are only packaged, or that are not
+		// installed because the module skips its installation, keep the ones with install rules.
+		installed := make(map[string]bool)
+		for _, installPath := range m.base().installFiles {
+			installed[installPath.String()] = true
+		}
+		for _, spec := range m.base().packagingSpecs {
+			to := spec.installPath.String()
+			if !installed[to] {
+				continue
+			}
+			info := installedFileInfo{
+				Module:        ctx.ModuleName(m),
+				Variant:       ctx.ModuleSubDir(m),
+				Partition:     spec.partition,
+				SymlinkTarget: spec.symlinkTarget,
+			}
+			if spec.srcPath != nil {
+				info.Source = spec.srcPath.String()
+			}
+			if other, exists := s.installedFiles[to]; exists && other != info {
+				ctx.ModuleErrorf(m, "%s is also installed by module %q variant %q", to, other.Module, other.Variant)
+				continue
+			}
+			s.installedFiles[to] = info
+		}
+	})
+
+	// The keys of the map are sorted by the encoder, so the file of two builds can be diffed.
+	buf, err := json.MarshalIndent(s.installedFiles, "", "  ")
+	if err != nil {
+		ctx.Errorf("JSON marshal of the installed files failed: %s", err)
+		return
+	}
+	s.outputPath = PathForOutput(ctx, installedFilesJsonFileName)
+	if err := WriteFileToOutputDir(s.outputPath, buf, 0666); err != nil {
+		ctx.Errorf("Writing the installed files to %s failed: %s", s.outputPath, err)
+		return
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather
+	// than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: s.outputPath,
+	})
+}
+
+func (s *installedFilesSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.outputPath == nil {
+		return
+	}
+
+	ctx.DistForGoal("droid", s.outputPath)
+}
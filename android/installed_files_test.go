@@ -0,0 +1,114 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestInstalledFiles(t *testing.T) {
+	bp := `
+		deps {
+			name: "foo",
+		}
+
+		deps {
+			name: "bar",
+			enabled: false,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithInstalledFiles,
+	).RunTestWithBp(t, bp)
+
+	hostVariant := result.Config.BuildOSCommonTarget.String()
+	s := result.SingletonForTests("installed_files").Singleton().(*installedFilesSingleton)
+	AssertDeepEquals(t, "installed files", map[string]installedFileInfo{
+		"out/soong/target/product/test_device/system/foo": {
+			Module:    "foo",
+			Variant:   "android_common",
+			Partition: "system",
+			Source:    "out/soong/.intermediates/foo/android_common/foo",
+		},
+		"out/soong/target/product/test_device/system/symlinks/foo": {
+			Module:        "foo",
+			Variant:       "android_common",
+			Partition:     "system",
+			SymlinkTarget: "../foo",
+		},
+		"out/soong/host/linux-x86/foo": {
+			Module:  "foo",
+			Variant: hostVariant,
+			Source:  "out/soong/.intermediates/foo/" + hostVariant + "/foo",
+		},
+		"out/soong/host/linux-x86/symlinks/foo": {
+			Module:        "foo",
+			Variant:       hostVariant,
+			SymlinkTarget: "../foo",
+		},
+	}, s.installedFiles)
+
+	result.SingletonForTests("installed_files").Output(installedFilesJsonFileName)
+}
+
+type installedFilesTestModule struct {
+	ModuleBase
+	props struct {
+		Stem *string
+	}
+}
+
+func (m *installedFilesTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	ctx.InstallFile(PathForModuleInstall(ctx), String(m.props.Stem), outputFile)
+}
+
+func installedFilesTestModuleFactory() Module {
+	m := &installedFilesTestModule{}
+	m.AddProperties(&m.props)
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+func TestInstalledFilesDuplicate(t *testing.T) {
+	bp := `
+		installed_files_test {
+			name: "foo",
+			stem: "tool",
+		}
+
+		installed_files_test {
+			name: "bar",
+			stem: "tool",
+		}
+	`
+
+	GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithInstalledFiles,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("installed_files_test", installedFilesTestModuleFactory)
+		}),
+	).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`/system/tool is also installed by module "(foo|bar)" variant "android_common"`)).
+		RunTestWithBp(t, bp)
+}
@@ -47,6 +47,12 @@ func (m *licenseKindModule) GenerateAndroidBuildActions(ModuleContext) {
 	// Nothing to do.
 }
 
+// IsLicenseKindModule returns true if module is a license_kind module.
+func IsLicenseKindModule(module Module) bool {
+	_, ok := module.(*licenseKindModule)
+	return ok
+}
+
 func LicenseKindFactory() Module {
 	module := &licenseKindModule{}
 
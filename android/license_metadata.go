@@ -210,6 +210,18 @@ func licenseAnnotationsFromTag(tag blueprint.DependencyTag) string {
 	return ""
 }
 
+// HasLicenseAnnotation returns true if tag provides the LicenseAnnotation annotation.
+func HasLicenseAnnotation(tag blueprint.DependencyTag, annotation LicenseAnnotation) bool {
+	if annoTag, ok := tag.(LicenseAnnotationsDependencyTag); ok {
+		for _, a := range annoTag.LicenseAnnotations() {
+			if a == annotation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // LicenseAnnotationsDependencyTag is implemented by dependency tags in order to provide a
 // list of license dependency annotations.
 type LicenseAnnotationsDependencyTag interface {
@@ -79,6 +79,10 @@ func collectMetrics(config Config, eventHandler metrics.EventHandler) *soong_met
 		metrics.Events = append(metrics.Events, &perfInfo)
 	}
 
+	if config.moduleTypeTimings != nil {
+		metrics.ModuleTypeTimings = config.moduleTypeTimings.metrics()
+	}
+
 	return metrics
 }
 
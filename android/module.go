@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strings"
 	"text/scanner"
+	"time"
 
 	"android/soong/bazel"
 
@@ -326,6 +327,10 @@ type BaseModuleContext interface {
 	// AddMissingBp2buildDep stores the module name of a direct dependency that was not found.
 	AddMissingBp2buildDep(dep string)
 
+	// MarkBp2buildUnconvertible records that the module can't be converted via bp2build, and why.
+	// bp2build won't generate targets for the module.
+	MarkBp2buildUnconvertible(reasonType UnconvertedReasonType, detail string)
+
 	Target() Target
 	TargetPrimary() bool
 
@@ -517,6 +522,7 @@ type Module interface {
 	VintfFragments() Paths
 	NoticeFiles() Paths
 	EffectiveLicenseFiles() Paths
+	EffectiveLicenseKinds() []string
 
 	AddProperties(props ...interface{})
 	GetProperties() []interface{}
@@ -527,6 +533,7 @@ type Module interface {
 	Bp2buildTargets() []bp2buildInfo
 	GetUnconvertedBp2buildDeps() []string
 	GetMissingBp2buildDeps() []string
+	GetUnconvertedReason() *UnconvertedReason
 
 	BuildParamsForTests() []BuildParams
 	RuleParamsForTests() map[blueprint.Rule]blueprint.RuleParams
@@ -690,6 +697,12 @@ type commonProperties struct {
 	// and so prevent early detection of changes that have broken those modules.
 	Enabled *bool `android:"arch_variant"`
 
+	// If set to true, build and enable variants of the module for the cross-compiled host targets
+	// (generally Windows), which are otherwise disabled by default.  If set to false, no host cross
+	// variants are built.  It is an error to set it for module types that don't support host
+	// cross targets.
+	Host_cross_supported *bool
+
 	// Controls the visibility of this module to other modules. Allowable values are one or more of
 	// these formats:
 	//
@@ -801,6 +814,15 @@ type commonProperties struct {
 	// (or /system/system_ext if system_ext partition does not exist).
 	System_ext_specific *bool
 
+	// the partition the module is installed into, one of "system", "system_ext", "product",
+	// "vendor" or "odm", which must have its own image in the product and not be installed in a
+	// directory of another partition.  It takes precedence over
+	// vendor, proprietary, soc_specific, device_specific, product_specific and
+	// system_ext_specific, which can only be set to true when they select the same partition.
+	// It can be selected with soong_config_variables to move a module between partitions
+	// across configurations.
+	Install_partition *string
+
 	// Whether this module is installed to recovery partition
 	Recovery *bool
 
@@ -834,6 +856,35 @@ type commonProperties struct {
 	// relative path to a file to include in the list of notices for the device
 	Notice *string `android:"path"`
 
+	// Marks this module as deprecated. Every module that depends on it is reported at the end of
+	// analysis, as a warning or as an error depending on DEPRECATED_MODULE_ERROR_LEVEL.
+	Deprecation struct {
+		// How strongly this module is deprecated, either "soft" (the default) or "hard".
+		Level *string
+
+		// What to use instead of this module, included in the report.
+		Message *string
+	}
+
+	// Globs in the properties of this module, like srcs, that are expected to match no files, as
+	// written in the property.  They are not reported when SOONG_LINT_EMPTY_GLOBS is set.
+	Allow_empty_globs []string
+
+	// A command that checks the outputs of the module, like their size or the symbols they
+	// define, and fails the build when it exits with an error.  It runs as a ninja validation of
+	// the installation and of the checkbuild target of the module, so the modules that depend on
+	// the outputs don't wait for it.  $(out) is replaced with the default outputs of the module,
+	// $(location <label>) with the path of an entry of validator_srcs, and $$ with a literal $.
+	Validator_cmd *string
+
+	// The tools and other inputs of validator_cmd, the validation runs again when they change.
+	Validator_srcs []string `android:"path"`
+
+	// The maximum size of the default output of the module, as a number of bytes with an optional
+	// unit, like "512KB" or "4MB".  The units are powers of 1024.  The build fails when the output
+	// is larger, in a validation like the one of validator_cmd.
+	Max_size *string
+
 	// The OsType of artifacts that this module variant is responsible for creating.
 	//
 	// Set by osMutator
@@ -924,6 +975,9 @@ type commonProperties struct {
 
 	// MissingBp2buildDep stores the module names of direct dependency that were not found
 	MissingBp2buildDeps []string `blueprint:"mutated"`
+
+	// UnconvertedReason stores the reason that the module was marked as unconvertible via bp2build
+	UnconvertedReason *UnconvertedReason `blueprint:"mutated"`
 }
 
 // CommonAttributes represents the common Bazel attributes from which properties
@@ -1082,6 +1136,15 @@ func (k moduleKind) String() string {
 	}
 }
 
+// installPartitionKinds maps the values of install_partition to the kinds of modules.
+var installPartitionKinds = map[string]moduleKind{
+	"system":     platformModule,
+	"system_ext": systemExtSpecificModule,
+	"product":    productSpecificModule,
+	"vendor":     socSpecificModule,
+	"odm":        deviceSpecificModule,
+}
+
 func initAndroidModuleBase(m Module) {
 	m.base().module = m
 }
@@ -1370,6 +1433,12 @@ type ModuleBase struct {
 	checkbuildTarget WritablePath
 	blueprintDir     string
 
+	// The file written when validator_cmd succeeds, nil if it isn't set.
+	validatorTimestamp WritablePath
+
+	// The file written when the default output is not larger than max_size, nil if it isn't set.
+	maxSizeTimestamp WritablePath
+
 	hooks hooks
 
 	registerProps []interface{}
@@ -1388,6 +1457,10 @@ type ModuleBase struct {
 
 	// The path to the generated license metadata file for the module.
 	licenseMetadataFile WritablePath
+
+	// Globs in the properties of the module that matched no files, collected when
+	// SOONG_LINT_EMPTY_GLOBS is set.
+	emptyGlobs []string
 }
 
 // A struct containing all relevant information about a Bazel target converted via bp2build.
@@ -1426,6 +1499,9 @@ func (b bp2buildInfo) BazelAttributes() []interface{} {
 }
 
 func (m *ModuleBase) addBp2buildInfo(info bp2buildInfo) {
+	if m.commonProperties.UnconvertedReason != nil {
+		return
+	}
 	m.commonProperties.Bp2buildInfo = append(m.commonProperties.Bp2buildInfo, info)
 }
 
@@ -1451,6 +1527,19 @@ func (b *baseModuleContext) AddMissingBp2buildDep(dep string) {
 	*missingDeps = append(*missingDeps, dep)
 }
 
+// MarkBp2buildUnconvertible records that the module can't be converted to Bazel, and discards any
+// targets that were already created for it.
+func (b *baseModuleContext) MarkBp2buildUnconvertible(reasonType UnconvertedReasonType, detail string) {
+	m := b.Module().base()
+	if m.commonProperties.UnconvertedReason == nil {
+		m.commonProperties.UnconvertedReason = &UnconvertedReason{
+			ReasonType: reasonType,
+			Detail:     detail,
+		}
+	}
+	m.commonProperties.Bp2buildInfo = nil
+}
+
 // GetUnconvertedBp2buildDeps returns the list of module names of this module's direct dependencies that
 // were not converted to Bazel.
 func (m *ModuleBase) GetUnconvertedBp2buildDeps() []string {
@@ -1462,6 +1551,12 @@ func (m *ModuleBase) GetMissingBp2buildDeps() []string {
 	return FirstUniqueStrings(m.commonProperties.MissingBp2buildDeps)
 }
 
+// GetUnconvertedReason returns the reason the module was marked as unconvertible via bp2build, or
+// nil if it wasn't.
+func (m *ModuleBase) GetUnconvertedReason() *UnconvertedReason {
+	return m.commonProperties.UnconvertedReason
+}
+
 func (m *ModuleBase) AddJSONData(d *map[string]interface{}) {
 	(*d)["Android"] = map[string]interface{}{
 		// Properties set in Blueprint or in blueprint of a defaults modules
@@ -1775,10 +1870,13 @@ func (m *ModuleBase) HostSupported() bool {
 
 // HostCrossSupported returns true if the current module is supported and enabled for host cross
 // targets, i.e. the factory method set the HostOrDeviceSupported value to include host cross
-// support and the host cross support is enabled by default or enabled by the
-// host_supported property.
+// support and the host cross support is enabled by the host_cross_supported property, or enabled
+// by default or by the host_supported property.
 func (m *ModuleBase) HostCrossSupported() bool {
 	hod := m.commonProperties.HostOrDeviceSupported
+	if hod&hostCrossSupported != 0 && m.commonProperties.Host_cross_supported != nil {
+		return *m.commonProperties.Host_cross_supported
+	}
 	// hostEnabled is true if the host_supported property is true or the HostOrDeviceSupported
 	// value has the hostDefault bit set.
 	hostEnabled := proptools.BoolDefault(m.hostAndDeviceProperties.Host_supported, hod&hostDefault != 0)
@@ -1790,21 +1888,43 @@ func (m *ModuleBase) Platform() bool {
 }
 
 func (m *ModuleBase) DeviceSpecific() bool {
+	if kind, ok := m.installPartitionKind(); ok {
+		return kind == deviceSpecificModule
+	}
 	return Bool(m.commonProperties.Device_specific)
 }
 
 func (m *ModuleBase) SocSpecific() bool {
+	if kind, ok := m.installPartitionKind(); ok {
+		return kind == socSpecificModule
+	}
 	return Bool(m.commonProperties.Vendor) || Bool(m.commonProperties.Proprietary) || Bool(m.commonProperties.Soc_specific)
 }
 
 func (m *ModuleBase) ProductSpecific() bool {
+	if kind, ok := m.installPartitionKind(); ok {
+		return kind == productSpecificModule
+	}
 	return Bool(m.commonProperties.Product_specific)
 }
 
 func (m *ModuleBase) SystemExtSpecific() bool {
+	if kind, ok := m.installPartitionKind(); ok {
+		return kind == systemExtSpecificModule
+	}
 	return Bool(m.commonProperties.System_ext_specific)
 }
 
+// installPartitionKind returns the kind of the module selected by install_partition, and false if
+// it is not set to a known partition.
+func (m *ModuleBase) installPartitionKind() (moduleKind, bool) {
+	if m.commonProperties.Install_partition == nil {
+		return platformModule, false
+	}
+	kind, ok := installPartitionKinds[*m.commonProperties.Install_partition]
+	return kind, ok
+}
+
 // RequiresStableAPIs returns true if the module will be installed to a partition that may
 // be updated separately from the system image.
 func (m *ModuleBase) RequiresStableAPIs(ctx BaseModuleContext) bool {
@@ -1851,7 +1971,7 @@ func (m *ModuleBase) Enabled() bool {
 		return false
 	}
 	if m.commonProperties.Enabled == nil {
-		return !m.Os().DefaultDisabled
+		return !m.Os().DefaultDisabled || Bool(m.commonProperties.Host_cross_supported)
 	}
 	return *m.commonProperties.Enabled
 }
@@ -1911,6 +2031,10 @@ func (m *ModuleBase) EffectiveLicenseFiles() Paths {
 	return result
 }
 
+func (m *ModuleBase) EffectiveLicenseKinds() []string {
+	return m.commonProperties.Effective_license_kinds
+}
+
 // computeInstallDeps finds the installed paths of all dependencies that have a dependency
 // tag that is annotated as needing installation via the IsInstallDepNeeded method.
 func (m *ModuleBase) computeInstallDeps(ctx ModuleContext) ([]*installPathsDepSet, []*packagingSpecsDepSet) {
@@ -1971,6 +2095,9 @@ func (m *ModuleBase) InstallInRecovery() bool {
 }
 
 func (m *ModuleBase) InstallInVendor() bool {
+	if kind, ok := m.installPartitionKind(); ok {
+		return kind == socSpecificModule
+	}
 	return Bool(m.commonProperties.Vendor)
 }
 
@@ -2106,6 +2233,10 @@ func (m *ModuleBase) generateModuleTarget(ctx ModuleContext) {
 }
 
 func determineModuleKind(m *ModuleBase, ctx blueprint.EarlyModuleContext) moduleKind {
+	if m.commonProperties.Install_partition != nil {
+		return determineInstallPartitionKind(m, ctx)
+	}
+
 	var socSpecific = Bool(m.commonProperties.Vendor) || Bool(m.commonProperties.Proprietary) || Bool(m.commonProperties.Soc_specific)
 	var deviceSpecific = Bool(m.commonProperties.Device_specific)
 	var productSpecific = Bool(m.commonProperties.Product_specific)
@@ -2164,6 +2295,41 @@ func determineModuleKind(m *ModuleBase, ctx blueprint.EarlyModuleContext) module
 	}
 }
 
+// determineInstallPartitionKind returns the kind of a module that sets install_partition, and
+// reports the partition specific properties that are set to true for a different partition.
+func determineInstallPartitionKind(m *ModuleBase, ctx blueprint.EarlyModuleContext) moduleKind {
+	partition := *m.commonProperties.Install_partition
+	kind, ok := installPartitionKinds[partition]
+	if !ok {
+		ctx.PropertyErrorf("install_partition", "unknown partition %q, must be one of %s",
+			partition, strings.Join(SortedStringKeys(installPartitionKinds), ", "))
+		return platformModule
+	}
+	config := ctx.Config().(Config)
+	if !config.deviceConfig.InstallPartitionEnabled(partition) {
+		ctx.PropertyErrorf("install_partition", "partition %q is not enabled for the product, enabled partitions are %s",
+			partition, strings.Join(config.deviceConfig.InstallPartitions(), ", "))
+	}
+
+	for _, p := range []struct {
+		name  string
+		value *bool
+		kind  moduleKind
+	}{
+		{"vendor", m.commonProperties.Vendor, socSpecificModule},
+		{"proprietary", m.commonProperties.Proprietary, socSpecificModule},
+		{"soc_specific", m.commonProperties.Soc_specific, socSpecificModule},
+		{"device_specific", m.commonProperties.Device_specific, deviceSpecificModule},
+		{"product_specific", m.commonProperties.Product_specific, productSpecificModule},
+		{"system_ext_specific", m.commonProperties.System_ext_specific, systemExtSpecificModule},
+	} {
+		if Bool(p.value) && p.kind != kind {
+			ctx.PropertyErrorf(p.name, "contradicts install_partition: %q", partition)
+		}
+	}
+	return kind
+}
+
 func (m *ModuleBase) earlyModuleContextFactory(ctx blueprint.EarlyModuleContext) earlyModuleContext {
 	return earlyModuleContext{
 		EarlyModuleContext: ctx,
@@ -2191,6 +2357,13 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 		variables:         make(map[string]string),
 	}
 
+	if graph := ctx.Config().dependencyGraph; graph != nil {
+		graph.record(blueprintCtx)
+	}
+	if variants := ctx.Config().moduleVariants; variants != nil {
+		variants.record(blueprintCtx, m)
+	}
+
 	m.licenseMetadataFile = PathForModuleOut(ctx, "meta_lic")
 
 	dependencyInstallFiles, dependencyPackagingSpecs := m.computeInstallDeps(ctx)
@@ -2275,7 +2448,26 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			return
 		}
 
-		m.module.GenerateAndroidBuildActions(ctx)
+		if m.commonProperties.Validator_cmd != nil {
+			m.validatorTimestamp = PathForModuleOut(ctx, "validator.timestamp")
+		}
+		if m.commonProperties.Max_size != nil {
+			m.maxSizeTimestamp = PathForModuleOut(ctx, "max_size.timestamp")
+		}
+
+		if timings := ctx.Config().moduleTypeTimings; timings != nil {
+			start := time.Now()
+			m.module.GenerateAndroidBuildActions(ctx)
+			timings.record(ctx.ModuleType(), time.Since(start))
+		} else {
+			m.module.GenerateAndroidBuildActions(ctx)
+		}
+		if ctx.Failed() {
+			return
+		}
+
+		m.buildValidator(ctx)
+		m.buildMaxSizeCheck(ctx)
 		if ctx.Failed() {
 			return
 		}
@@ -2520,6 +2712,7 @@ type katiInstall struct {
 	to            InstallPath
 	implicitDeps  Paths
 	orderOnlyDeps Paths
+	validations   Paths
 	executable    bool
 	extraFiles    *extraFilesZip
 
@@ -3053,6 +3246,7 @@ func (b *baseModuleContext) PrimaryArch() bool {
 // Makes this module a platform module, i.e. not specific to soc, device,
 // product, or system_ext.
 func (m *ModuleBase) MakeAsPlatform() {
+	m.commonProperties.Install_partition = nil
 	m.commonProperties.Vendor = boolPtr(false)
 	m.commonProperties.Proprietary = boolPtr(false)
 	m.commonProperties.Soc_specific = boolPtr(false)
@@ -3061,6 +3255,7 @@ func (m *ModuleBase) MakeAsPlatform() {
 }
 
 func (m *ModuleBase) MakeAsSystemExt() {
+	m.commonProperties.Install_partition = nil
 	m.commonProperties.Vendor = boolPtr(false)
 	m.commonProperties.Proprietary = boolPtr(false)
 	m.commonProperties.Soc_specific = boolPtr(false)
@@ -3164,6 +3359,7 @@ func (m *moduleContext) packageFile(fullInstallPath InstallPath, srcPath Path, e
 		executable:            executable,
 		effectiveLicenseFiles: &licenseFiles,
 		partition:             fullInstallPath.partition,
+		installPath:           fullInstallPath,
 	}
 	m.packagingSpecs = append(m.packagingSpecs, spec)
 	return spec
@@ -3178,7 +3374,13 @@ func (m *moduleContext) installFile(installPath InstallPath, name string, srcPat
 	if !m.skipInstall() {
 		deps = append(deps, m.module.base().installFilesDepSet.ToList().Paths()...)
 
-		var implicitDeps, orderOnlyDeps Paths
+		var implicitDeps, orderOnlyDeps, validations Paths
+		if timestamp := m.module.base().validatorTimestamp; timestamp != nil {
+			validations = append(validations, timestamp)
+		}
+		if timestamp := m.module.base().maxSizeTimestamp; timestamp != nil {
+			validations = append(validations, timestamp)
+		}
 
 		if m.Host() {
 			// Installed host modules might be used during the build, depend directly on their
@@ -3197,6 +3399,7 @@ func (m *moduleContext) installFile(installPath InstallPath, name string, srcPat
 				to:            fullInstallPath,
 				implicitDeps:  implicitDeps,
 				orderOnlyDeps: orderOnlyDeps,
+				validations:   validations,
 				executable:    executable,
 				extraFiles:    extraZip,
 			})
@@ -3221,6 +3424,7 @@ func (m *moduleContext) installFile(installPath InstallPath, name string, srcPat
 				Input:       srcPath,
 				Implicits:   implicitDeps,
 				OrderOnly:   orderOnlyDeps,
+				Validations: validations,
 				Default:     !m.Config().KatiEnabled(),
 				Args: map[string]string{
 					"extraCmds": extraCmds,
@@ -3283,6 +3487,7 @@ func (m *moduleContext) InstallSymlink(installPath InstallPath, name string, src
 		symlinkTarget:    relPath,
 		executable:       false,
 		partition:        fullInstallPath.partition,
+		installPath:      fullInstallPath,
 	})
 
 	return fullInstallPath
@@ -3324,6 +3529,7 @@ func (m *moduleContext) InstallAbsoluteSymlink(installPath InstallPath, name str
 		symlinkTarget:    absPath,
 		executable:       false,
 		partition:        fullInstallPath.partition,
+		installPath:      fullInstallPath,
 	})
 
 	return fullInstallPath
@@ -0,0 +1,148 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	RegisterModuleAliasBuildComponents(InitRegistrationContext)
+}
+
+func RegisterModuleAliasBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("module_alias", ModuleAliasFactory)
+	ctx.RegisterSingletonType("module_aliases", moduleAliasesSingletonFactory)
+}
+
+var PrepareForTestWithModuleAliases = FixtureRegisterWithContext(RegisterModuleAliasBuildComponents)
+
+type moduleAliasProperties struct {
+	// The name of the module that dependencies on this alias resolve to.  It may be another
+	// module_alias.
+	Actual *string
+
+	// A message to add to the warning that is reported when the alias is used.
+	Message *string
+}
+
+// ModuleAlias is a second name for a module.  It is resolved by the NameResolver, so a dependency
+// on the alias is a dependency on the actual module and selects its variants like any other one.
+type ModuleAlias struct {
+	ModuleBase
+
+	properties moduleAliasProperties
+
+	// The namespace that the alias is defined in, which is used to look up the actual module.
+	namespace *Namespace
+	resolver  *NameResolver
+
+	// Set to 1 when a dependency was added through the alias.
+	used int32
+}
+
+// module_alias defines a name that resolves to another module, to be used while the module is
+// renamed so that both names work.  Dependencies on the alias are dependencies on the actual
+// module, and a warning is reported for each alias that is used.
+func ModuleAliasFactory() Module {
+	module := &ModuleAlias{}
+	module.AddProperties(&module.nameProperties, &module.properties)
+	return module
+}
+
+func (a *ModuleAlias) Name() string {
+	return proptools.String(a.nameProperties.Name)
+}
+
+func (a *ModuleAlias) actual() string {
+	return proptools.String(a.properties.Actual)
+}
+
+func (a *ModuleAlias) DepsMutator(ctx BottomUpMutatorContext) {
+	// The aliases are checked in the deps mutator so the errors are reported with the ones of the
+	// dependencies that fail to resolve through them.
+	if a.actual() == "" {
+		ctx.PropertyErrorf("actual", "missing")
+		return
+	}
+	if _, err := a.resolver.resolveAlias(a); err != nil {
+		ctx.PropertyErrorf("actual", "%s", err)
+	}
+}
+
+func (a *ModuleAlias) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func (a *ModuleAlias) GenerateBuildActions(ctx blueprint.ModuleContext) {
+}
+
+// resolveAlias returns the module group that alias resolves to, following the aliases of aliases.
+func (r *NameResolver) resolveAlias(alias *ModuleAlias) (blueprint.ModuleGroup, error) {
+	chain := []string{alias.Name()}
+	seen := map[*ModuleAlias]bool{alias: true}
+	for {
+		next, group, found := r.moduleOrAliasFromName(alias.actual(), alias.namespace)
+		if !found {
+			return blueprint.ModuleGroup{}, fmt.Errorf("module %q of alias %q doesn't exist",
+				alias.actual(), alias.Name())
+		}
+		if next == nil {
+			return group, nil
+		}
+		chain = append(chain, next.Name())
+		if seen[next] {
+			return blueprint.ModuleGroup{}, fmt.Errorf("circular aliases: %s", strings.Join(chain, " -> "))
+		}
+		seen[next] = true
+		alias = next
+	}
+}
+
+// moduleAliasesSingleton reports every alias that a dependency was resolved through.
+type moduleAliasesSingleton struct {
+	// The reported warnings, for tests.
+	warnings []string
+}
+
+func moduleAliasesSingletonFactory() Singleton {
+	return &moduleAliasesSingleton{}
+}
+
+func (s *moduleAliasesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	found := make(map[string]bool)
+	ctx.VisitAllModules(func(m Module) {
+		alias, ok := m.(*ModuleAlias)
+		if !ok || atomic.LoadInt32(&alias.used) == 0 {
+			return
+		}
+		warning := fmt.Sprintf("%s: %q is an alias of %q, depend on %q instead",
+			modulePosition(ctx, m), alias.Name(), alias.actual(), alias.actual())
+		if message := proptools.String(alias.properties.Message); message != "" {
+			warning += ": " + message
+		}
+		found[warning] = true
+	})
+
+	s.warnings = SortedStringKeys(found)
+	for _, warning := range s.warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
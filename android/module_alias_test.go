@@ -0,0 +1,158 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type aliasTestModule struct {
+	ModuleBase
+	properties struct {
+		Deps []string
+
+		// Names that are only looked up, without adding dependencies on them.
+		Exists []string
+	}
+}
+
+func (m *aliasTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), nil, m.properties.Deps...)
+	for _, name := range m.properties.Exists {
+		ctx.OtherModuleExists(name)
+	}
+}
+
+func (m *aliasTestModule) GenerateAndroidBuildActions(ModuleContext) {
+}
+
+func aliasTestModuleFactory() Module {
+	m := &aliasTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, HostAndDeviceDefault, MultilibFirst)
+	return m
+}
+
+var prepareForModuleAliasTest = GroupFixturePreparers(
+	PrepareForTestWithArchMutator,
+	PrepareForTestWithModuleAliases,
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("test_module", aliasTestModuleFactory)
+	}),
+)
+
+func TestModuleAlias(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleAliasTest,
+		FixtureWithRootAndroidBp(`
+			test_module {
+				name: "new",
+			}
+			module_alias {
+				name: "old",
+				actual: "new",
+				message: "old was renamed to new",
+			}
+			module_alias {
+				name: "older",
+				actual: "old",
+			}
+			module_alias {
+				name: "unused",
+				actual: "new",
+			}
+			test_module {
+				name: "user",
+				deps: ["old", "older"],
+				exists: ["unused"],
+			}
+		`),
+	).RunTest(t)
+
+	for _, variant := range []string{"android_arm64_armv8-a", result.Config.BuildOSTarget.String()} {
+		user := result.ModuleForTests("user", variant).Module()
+		newModule := result.ModuleForTests("new", variant).Module()
+		var deps []blueprint.Module
+		result.VisitDirectDeps(user, func(dep blueprint.Module) {
+			deps = append(deps, dep)
+		})
+		AssertDeepEquals(t, "dependencies of "+variant, []blueprint.Module{newModule, newModule}, deps)
+	}
+
+	s := result.SingletonForTests("module_aliases").Singleton().(*moduleAliasesSingleton)
+	AssertDeepEquals(t, "warnings", []string{
+		`Android.bp:5:4: "old" is an alias of "new", depend on "new" instead: old was renamed to new`,
+		`Android.bp:10:4: "older" is an alias of "old", depend on "old" instead`,
+	}, s.warnings)
+}
+
+func TestModuleAliasErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "self",
+			bp: `
+				module_alias {
+					name: "old",
+					actual: "old",
+				}
+			`,
+			err: `module "old": actual: circular aliases: old -> old`,
+		},
+		{
+			name: "circular",
+			bp: `
+				module_alias {
+					name: "a",
+					actual: "b",
+				}
+				module_alias {
+					name: "b",
+					actual: "a",
+				}
+				test_module {
+					name: "user",
+					deps: ["a"],
+				}
+			`,
+			err: `module "a": actual: circular aliases: a -> b -> a`,
+		},
+		{
+			name: "missing",
+			bp: `
+				module_alias {
+					name: "old",
+					actual: "new",
+				}
+			`,
+			err: `module "old": actual: module "new" of alias "old" doesn't exist`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForModuleAliasTest,
+				FixtureWithRootAndroidBp(tc.bp),
+			).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(tc.err)).
+				RunTest(t)
+		})
+	}
+}
@@ -15,9 +15,13 @@
 package android
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"testing"
+
+	"github.com/google/blueprint/pathtools"
 )
 
 func TestSrcIsModule(t *testing.T) {
@@ -199,6 +203,7 @@ type depsModule struct {
 	props struct {
 		Deps []string
 	}
+	outputFile Path
 }
 
 func (m *depsModule) GenerateAndroidBuildActions(ctx ModuleContext) {
@@ -209,6 +214,14 @@ func (m *depsModule) GenerateAndroidBuildActions(ctx ModuleContext) {
 	})
 	installFile := ctx.InstallFile(PathForModuleInstall(ctx), ctx.ModuleName(), outputFile)
 	ctx.InstallSymlink(PathForModuleInstall(ctx, "symlinks"), ctx.ModuleName(), installFile)
+	m.outputFile = outputFile
+}
+
+func (m *depsModule) OutputFiles(tag string) (Paths, error) {
+	if tag != "" {
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+	return Paths{m.outputFile}, nil
 }
 
 func (m *depsModule) DepsMutator(ctx BottomUpMutatorContext) {
@@ -243,6 +256,83 @@ func TestErrorDependsOnDisabledModule(t *testing.T) {
 		RunTestWithBp(t, bp)
 }
 
+func TestInstallPartition(t *testing.T) {
+	bp := `
+		deps {
+			name: "foo",
+			install_partition: "vendor",
+		}
+		deps {
+			name: "bar",
+			install_partition: "odm",
+			device_specific: true,
+		}
+		deps {
+			name: "baz",
+			install_partition: "system",
+			product_specific: false,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+	).RunTestWithBp(t, bp)
+
+	for name, partition := range map[string]string{"foo": "vendor", "bar": "odm", "baz": "system"} {
+		module := result.ModuleForTests(name, "android_common")
+		module.Output(filepath.Join("out/soong/target/product/test_device", partition, name))
+		AssertBoolEquals(t, name+" installed in vendor", partition == "vendor", module.Module().base().InstallInVendor())
+	}
+}
+
+func TestInstallPartitionErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		bp      string
+		odmPath string
+		err     string
+	}{
+		{
+			name: "unknown",
+			bp:   `install_partition: "data"`,
+			err:  `install_partition: unknown partition "data", must be one of odm, product, system, system_ext, vendor`,
+		},
+		{
+			name:    "disabled",
+			bp:      `install_partition: "odm"`,
+			odmPath: "vendor/odm",
+			err:     `install_partition: partition "odm" is not enabled for the product, enabled partitions are system, system_ext, product, vendor`,
+		},
+		{
+			name: "contradiction",
+			bp:   `install_partition: "product", soc_specific: true`,
+			err:  `soc_specific: contradicts install_partition: "product"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForModuleTests,
+				PrepareForTestWithArchMutator,
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					if tc.odmPath != "" {
+						variables.OdmPath = stringPtr(tc.odmPath)
+					}
+				}),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.err))).
+				RunTestWithBp(t, `
+					deps {
+						name: "foo",
+						`+tc.bp+`,
+					}
+				`)
+		})
+	}
+}
+
 func TestValidateCorrectBuildParams(t *testing.T) {
 	config := TestConfig(t.TempDir(), nil, "", nil)
 	pathContext := PathContextForTesting(config)
@@ -911,3 +1001,64 @@ func TestSortedUniqueNamedPaths(t *testing.T) {
 		})
 	}
 }
+
+// globBenchmarkModule calls the glob function of the benchmark from GenerateAndroidBuildActions.
+type globBenchmarkModule struct {
+	ModuleBase
+	glob func(ctx ModuleContext)
+}
+
+func (m *globBenchmarkModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.glob(ctx)
+}
+
+// BenchmarkGlobWithDeps compares globbing a tree with many subdirectories for every call with
+// GlobWithDeps, which Blueprint memoizes for the whole run so that modules globbing the same
+// pattern don't walk the tree again.
+func BenchmarkGlobWithDeps(b *testing.B) {
+	fs := MockFS{}
+	for i := 0; i < 1000; i++ {
+		fs[fmt.Sprintf("dirs/dir%d/src/com/android/File%d.java", i, i)] = nil
+	}
+	const pattern = "dirs/**/*.java"
+
+	runGlobs := func(b *testing.B, glob func(ctx ModuleContext) error) {
+		config := TestConfig(b.TempDir(), nil, `glob_benchmark { name: "foo" }`, fs)
+		ctx := NewTestContext(config)
+		ctx.RegisterModuleType("glob_benchmark", func() Module {
+			m := &globBenchmarkModule{glob: func(ctx ModuleContext) {
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := glob(ctx); err != nil {
+						ctx.ModuleErrorf("glob failed: %s", err)
+						return
+					}
+				}
+				b.StopTimer()
+			}}
+			InitAndroidModule(m)
+			return m
+		})
+		ctx.Register()
+		if _, errs := ctx.ParseBlueprintsFiles("Android.bp"); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+		if _, errs := ctx.PrepareBuildActions(config); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+	}
+
+	b.Run("walk", func(b *testing.B) {
+		runGlobs(b, func(ctx ModuleContext) error {
+			_, err := ctx.Config().fs.Glob(pattern, nil, pathtools.FollowSymlinks)
+			return err
+		})
+	})
+
+	b.Run("GlobWithDeps", func(b *testing.B) {
+		runGlobs(b, func(ctx ModuleContext) error {
+			_, err := ctx.GlobWithDeps(pattern, nil)
+			return err
+		})
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+// moduleTypeTimingsTopN is the number of module types reported in the soong_build metrics.
+const moduleTypeTimingsTopN = 20
+
+// moduleTypeTimings aggregates the wall time spent in GenerateAndroidBuildActions by module type.
+// It is only allocated when SOONG_MODULE_TYPE_TIMINGS is set, so that normal builds only pay for
+// a nil check per module.
+type moduleTypeTimings struct {
+	lock    sync.Mutex
+	timings map[string]*moduleTypeTiming
+}
+
+type moduleTypeTiming struct {
+	moduleType string
+	modules    uint32
+	realTime   time.Duration
+}
+
+func newModuleTypeTimings() *moduleTypeTimings {
+	return &moduleTypeTimings{
+		timings: make(map[string]*moduleTypeTiming),
+	}
+}
+
+// record adds the time spent generating the build actions of one module of type moduleType.
+func (t *moduleTypeTimings) record(moduleType string, d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	timing := t.timings[moduleType]
+	if timing == nil {
+		timing = &moduleTypeTiming{moduleType: moduleType}
+		t.timings[moduleType] = timing
+	}
+	timing.modules++
+	timing.realTime += d
+}
+
+// top returns the n module types with the largest total time, slowest first.
+func (t *moduleTypeTimings) top(n int) []moduleTypeTiming {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ret := make([]moduleTypeTiming, 0, len(t.timings))
+	for _, timing := range t.timings {
+		ret = append(ret, *timing)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].realTime != ret[j].realTime {
+			return ret[i].realTime > ret[j].realTime
+		}
+		return ret[i].moduleType < ret[j].moduleType
+	})
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+func (t *moduleTypeTimings) metrics() []*soong_metrics_proto.ModuleTypeTiming {
+	var ret []*soong_metrics_proto.ModuleTypeTiming
+	for _, timing := range t.top(moduleTypeTimingsTopN) {
+		ret = append(ret, &soong_metrics_proto.ModuleTypeTiming{
+			ModuleType: proto.String(timing.moduleType),
+			Modules:    proto.Uint32(timing.modules),
+			RealTime:   proto.Uint64(uint64(timing.realTime.Nanoseconds())),
+		})
+	}
+	return ret
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestModuleTypeTimings(t *testing.T) {
+	timings := newModuleTypeTimings()
+	timings.record("cc_library", 3*time.Millisecond)
+	timings.record("java_library", 4*time.Millisecond)
+	timings.record("cc_library", 2*time.Millisecond)
+	timings.record("genrule", time.Millisecond)
+	timings.record("filegroup", time.Millisecond)
+
+	expected := []moduleTypeTiming{
+		{moduleType: "cc_library", modules: 2, realTime: 5 * time.Millisecond},
+		{moduleType: "java_library", modules: 1, realTime: 4 * time.Millisecond},
+		{moduleType: "filegroup", modules: 1, realTime: time.Millisecond},
+	}
+	if got := timings.top(3); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	metrics := timings.metrics()
+	if len(metrics) != 4 {
+		t.Fatalf("expected 4 module types in metrics, got %d", len(metrics))
+	}
+	if metrics[0].GetModuleType() != "cc_library" || metrics[0].GetModules() != 2 ||
+		metrics[0].GetRealTime() != uint64(5*time.Millisecond) {
+		t.Errorf("unexpected metrics for slowest module type: %v", metrics[0])
+	}
+}
+
+func TestModuleTypeTimingsCollected(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureModifyConfig(func(config Config) {
+			config.moduleTypeTimings = newModuleTypeTimings()
+		}),
+	).RunTestWithBp(t, `
+		filegroup {
+			name: "foo",
+		}
+		filegroup {
+			name: "bar",
+		}
+	`)
+
+	top := result.Config.moduleTypeTimings.top(moduleTypeTimingsTopN)
+	if len(top) != 1 || top[0].moduleType != "filegroup" || top[0].modules != 2 {
+		t.Errorf("expected two filegroup modules to be timed, got %v", top)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/shared"
+)
+
+// moduleVariants records every variant of the modules with a given name while build actions are
+// generated, so that they can be written out with WriteModuleVariants.
+type moduleVariants struct {
+	name string
+
+	// If true, the resolved properties of the variants are recorded too.
+	resolvedProperties bool
+
+	lock     sync.Mutex
+	variants []shared.ModuleVariant
+}
+
+// EnableModuleVariants makes the build record the variants of the modules called name so that
+// they can be written with WriteModuleVariants.
+func (c *config) EnableModuleVariants(name string) {
+	c.moduleVariants = &moduleVariants{name: name}
+}
+
+// EnableModuleVariantProperties makes the variants recorded after EnableModuleVariants contain
+// the values of all their properties.
+func (c *config) EnableModuleVariantProperties() {
+	c.moduleVariants.resolvedProperties = true
+}
+
+// record adds the module variant being visited by ctx if it has the requested name.
+func (v *moduleVariants) record(ctx blueprint.ModuleContext, m *ModuleBase) {
+	if ctx.ModuleName() != v.name {
+		return
+	}
+
+	variations := make(map[string]string)
+	for i, mutator := range m.commonProperties.DebugMutators {
+		variations[mutator] = m.commonProperties.DebugVariations[i]
+	}
+
+	target := m.Target()
+	properties := map[string]string{
+		"enabled": strconv.FormatBool(m.Enabled()),
+		"os":      target.Os.String(),
+		"arch":    target.Arch.String(),
+		"primary": strconv.FormatBool(m.commonProperties.CompilePrimary),
+	}
+	if target.NativeBridge == NativeBridgeEnabled {
+		properties["native_bridge"] = target.NativeBridgeRelativePath
+	}
+	if multilib := proptools.String(m.commonProperties.Compile_multilib); multilib != "" {
+		properties["compile_multilib"] = multilib
+	}
+	if apexInfo := ctx.Provider(ApexInfoProvider).(ApexInfo); !apexInfo.IsForPlatform() {
+		properties["apex"] = apexInfo.ApexVariationName
+	}
+	if m.commonProperties.HideFromMake {
+		properties["hide_from_make"] = "true"
+	}
+	if m.commonProperties.SkipInstall {
+		properties["skip_install"] = "true"
+	}
+
+	variant := shared.ModuleVariant{
+		Name:       ctx.ModuleName(),
+		Dir:        ctx.ModuleDir(),
+		Type:       ctx.ModuleType(),
+		Variant:    ctx.ModuleSubDir(),
+		Variations: variations,
+		Properties: properties,
+	}
+	if v.resolvedProperties {
+		variant.ResolvedProperties = resolvedProperties(m.GetProperties())
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.variants = append(v.variants, variant)
+}
+
+// resolvedProperties returns the values of the properties in the property structs of a module by
+// their names in Android.bp files.  Properties that are not set and mutated properties, which
+// can't be set in Android.bp files, are omitted.  When property structs share a property the last
+// one wins, like it does for the module types that read it.
+func resolvedProperties(props []interface{}) map[string]interface{} {
+	ret := make(map[string]interface{})
+	for _, p := range props {
+		v := reflect.ValueOf(p)
+		if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+			addResolvedProperties(ret, v.Elem())
+		}
+	}
+	return ret
+}
+
+func addResolvedProperties(ret map[string]interface{}, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || proptools.HasTag(field, "blueprint", "mutated") {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			addResolvedProperties(ret, value)
+			continue
+		}
+		if resolved, ok := resolvedPropertyValue(value); ok {
+			ret[proptools.PropertyNameForField(field.Name)] = resolved
+		}
+	}
+}
+
+// resolvedPropertyValue returns the value of a property, with nested property structs as maps,
+// or false if the property is not set.
+func resolvedPropertyValue(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return resolvedPropertyValue(v.Elem())
+	case reflect.Struct:
+		nested := make(map[string]interface{})
+		addResolvedProperties(nested, v)
+		return nested, len(nested) > 0
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+		return v.Interface(), true
+	default:
+		return v.Interface(), true
+	}
+}
+
+// WriteModuleVariants writes the module variants recorded since EnableModuleVariants was called
+// to path as JSON, sorted by directory and variant.
+func WriteModuleVariants(config Config, path string) error {
+	if config.moduleVariants == nil {
+		return fmt.Errorf("EnableModuleVariants was not called")
+	}
+
+	config.moduleVariants.lock.Lock()
+	variants := append([]shared.ModuleVariant{}, config.moduleVariants.variants...)
+	config.moduleVariants.lock.Unlock()
+
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].Dir != variants[j].Dir {
+			return variants[i].Dir < variants[j].Dir
+		}
+		return variants[i].Variant < variants[j].Variant
+	})
+
+	data, err := json.MarshalIndent(variants, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(absolutePath(path), append(data, '\n'), 0666)
+}
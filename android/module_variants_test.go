@@ -0,0 +1,157 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/shared"
+)
+
+func TestModuleVariants(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		FixtureModifyConfig(func(config Config) {
+			config.EnableModuleVariants("foo")
+		}),
+	).RunTestWithBp(t, `
+		deps {
+			name: "foo",
+			host_supported: true,
+			deps: ["bar"],
+		}
+		deps {
+			name: "bar",
+			host_supported: true,
+		}
+	`)
+
+	path := filepath.Join(t.TempDir(), "variants.json")
+	if err := WriteModuleVariants(result.Config, path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var variants []shared.ModuleVariant
+	if err := json.Unmarshal(data, &variants); err != nil {
+		t.Fatalf("failed to parse %s: %s", path, err)
+	}
+
+	buildOS := result.Config.BuildOS.String()
+	var names []string
+	for _, variant := range variants {
+		AssertStringEquals(t, "name", "foo", variant.Name)
+		AssertStringEquals(t, "type", "deps", variant.Type)
+		names = append(names, variant.Variant)
+	}
+	AssertDeepEquals(t, "variants", []string{"android_common", buildOS + "_common"}, names)
+
+	AssertStringEquals(t, "os variation", "android", variants[0].Variations["os"])
+	AssertStringEquals(t, "os property", "android", variants[0].Properties["os"])
+	AssertStringEquals(t, "enabled property", "true", variants[0].Properties["enabled"])
+	AssertStringEquals(t, "host os variation", buildOS, variants[1].Variations["os"])
+}
+
+func TestWriteModuleVariantsNotEnabled(t *testing.T) {
+	config := TestConfig(t.TempDir(), nil, "", nil)
+	if err := WriteModuleVariants(config, filepath.Join(t.TempDir(), "variants.json")); err == nil {
+		t.Errorf("expected an error when module variants were not enabled")
+	}
+}
+
+func TestModuleVariantResolvedProperties(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		FixtureModifyConfig(func(config Config) {
+			config.EnableModuleVariants("foo")
+			config.EnableModuleVariantProperties()
+		}),
+	).RunTestWithBp(t, `
+		deps {
+			name: "foo",
+			deps: ["bar"],
+			target: {
+				android: {
+					required: ["baz"],
+				},
+			},
+		}
+		deps {
+			name: "bar",
+		}
+	`)
+
+	path := filepath.Join(t.TempDir(), "variants.json")
+	if err := WriteModuleVariants(result.Config, path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var variants []shared.ModuleVariant
+	if err := json.Unmarshal(data, &variants); err != nil {
+		t.Fatalf("failed to parse %s: %s", path, err)
+	}
+	AssertIntEquals(t, "variants", 1, len(variants))
+
+	props := variants[0].ResolvedProperties
+	AssertDeepEquals(t, "deps", []interface{}{"bar"}, props["deps"])
+	AssertDeepEquals(t, "name", "foo", props["name"])
+	// The arch specific properties were merged by the arch mutator.
+	AssertDeepEquals(t, "required", []interface{}{"baz"}, props["required"])
+	if _, ok := props["debug_mutators"]; ok {
+		t.Errorf("expected no mutated properties, got %v", props["debug_mutators"])
+	}
+}
+
+func TestResolvedProperties(t *testing.T) {
+	type Embedded struct {
+		Embedded_prop *bool
+	}
+	props := struct {
+		Embedded
+		Set     *string
+		Unset   *string
+		List    []string
+		Nested  struct{ Value *int64 }
+		Empty   struct{ Value *int64 }
+		Mutated string `blueprint:"mutated"`
+	}{
+		Embedded: Embedded{Embedded_prop: proptools.BoolPtr(true)},
+		Set:      proptools.StringPtr("value"),
+		List:     []string{"a", "b"},
+		Mutated:  "mutated",
+	}
+	props.Nested.Value = proptools.Int64Ptr(3)
+
+	got := resolvedProperties([]interface{}{&props})
+	want := map[string]interface{}{
+		"embedded_prop": true,
+		"set":           "value",
+		"list":          []string{"a", "b"},
+		"nested":        map[string]interface{}{"value": int64(3)},
+	}
+	AssertDeepEquals(t, "resolved properties", want, got)
+}
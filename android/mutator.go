@@ -193,7 +193,11 @@ var postDeps = []RegisterMutatorFunc{
 	RegisterOverridePostDepsMutators,
 }
 
-var finalDeps = []RegisterMutatorFunc{}
+var finalDeps = []RegisterMutatorFunc{
+	// Enforce //apex:<name> visibility rules. This must come after the apex mutators, which add
+	// the ApexInfo used to decide which APEXes a module is part of.
+	RegisterVisibilityApexRuleEnforcer,
+}
 
 func PreArchMutators(f RegisterMutatorFunc) {
 	preArch = append(preArch, f)
@@ -457,17 +461,33 @@ func (mutator *mutator) componentName() string {
 
 func (mutator *mutator) register(ctx *Context) {
 	blueprintCtx := ctx.Context
+	bottomUpMutator, topDownMutator := mutator.bottomUpMutator, mutator.topDownMutator
+	if profile := ctx.config.mutatorProfile; profile != nil {
+		if bottomUpMutator != nil {
+			bottomUpMutator = profile.bottomUp(mutator.name, bottomUpMutator)
+		} else if topDownMutator != nil {
+			topDownMutator = profile.topDown(mutator.name, topDownMutator)
+		}
+	}
 	var handle blueprint.MutatorHandle
-	if mutator.bottomUpMutator != nil {
-		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, mutator.bottomUpMutator)
-	} else if mutator.topDownMutator != nil {
-		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, mutator.topDownMutator)
+	if bottomUpMutator != nil {
+		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, bottomUpMutator)
+	} else if topDownMutator != nil {
+		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, topDownMutator)
 	}
-	if mutator.parallel {
+	if mutator.parallel && !ctx.config.singleThreadedMutators {
 		handle.Parallel()
 	}
 }
 
+// EnableSingleThreadedMutators makes the mutators that are registered as parallel run on one
+// module at a time, in the same order on every run, so that bugs that depend on the order of the
+// parallel calls can be reproduced.  It is only meant for debugging, as it makes the analysis
+// much slower.
+func (c *config) EnableSingleThreadedMutators() {
+	c.singleThreadedMutators = true
+}
+
 type MutatorHandle interface {
 	Parallel() MutatorHandle
 }
@@ -589,6 +609,7 @@ func (b *bottomUpMutatorContext) Rename(name string) {
 }
 
 func (b *bottomUpMutatorContext) AddDependency(module blueprint.Module, tag blueprint.DependencyTag, name ...string) []blueprint.Module {
+	markModuleAliasesUsed(b.bp.Namespace(), name)
 	return b.bp.AddDependency(module, tag, name...)
 }
 
@@ -642,6 +663,7 @@ func (b *bottomUpMutatorContext) SetDefaultDependencyVariation(variation *string
 
 func (b *bottomUpMutatorContext) AddVariationDependencies(variations []blueprint.Variation, tag blueprint.DependencyTag,
 	names ...string) []blueprint.Module {
+	markModuleAliasesUsed(b.bp.Namespace(), names)
 	if b.bazelConversionMode {
 		_, noSelfDeps := RemoveFromList(b.ModuleName(), names)
 		if len(noSelfDeps) == 0 {
@@ -658,6 +680,7 @@ func (b *bottomUpMutatorContext) AddVariationDependencies(variations []blueprint
 
 func (b *bottomUpMutatorContext) AddFarVariationDependencies(variations []blueprint.Variation,
 	tag blueprint.DependencyTag, names ...string) []blueprint.Module {
+	markModuleAliasesUsed(b.bp.Namespace(), names)
 	if b.bazelConversionMode {
 		// In Bazel conversion mode, mutators should not have created any variants. So, when adding a
 		// dependency, the variations would not exist and the dependency could not be added, by
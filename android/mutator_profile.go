@@ -0,0 +1,174 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/blueprint"
+)
+
+// mutatorProfile records the time spent in every mutator while the mutators run, so that it can
+// be written as a Chrome trace with WriteMutatorProfile.  Recording a module for every mutator
+// takes a lock, so the mutators are only wrapped when EnableMutatorProfile was called.
+//
+// Writing an event per module would make the trace too large to load for a full tree, so the
+// calls of a mutator are aggregated by lane.  A lane stands for one of the goroutines that run a
+// parallel mutator: every call takes the lowest lane that is not running another call.
+type mutatorProfile struct {
+	lock  sync.Mutex
+	start time.Time
+	busy  []bool
+	spans map[mutatorProfileLane]*mutatorProfileSpan
+}
+
+type mutatorProfileLane struct {
+	mutator string
+	lane    int
+}
+
+type mutatorProfileSpan struct {
+	start, end time.Time
+	modules    int
+	busy       time.Duration
+}
+
+func newMutatorProfile() *mutatorProfile {
+	return &mutatorProfile{
+		start: time.Now(),
+		spans: make(map[mutatorProfileLane]*mutatorProfileSpan),
+	}
+}
+
+// EnableMutatorProfile makes the build record the time spent in every mutator so that it can be
+// written with WriteMutatorProfile.
+func (c *config) EnableMutatorProfile() {
+	c.mutatorProfile = newMutatorProfile()
+}
+
+// begin takes a free lane for a call of a mutator.
+func (p *mutatorProfile) begin() (int, time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	lane := 0
+	for lane < len(p.busy) && p.busy[lane] {
+		lane++
+	}
+	if lane == len(p.busy) {
+		p.busy = append(p.busy, true)
+	} else {
+		p.busy[lane] = true
+	}
+	return lane, time.Now()
+}
+
+// end records a call of mutator on a lane taken by begin and frees the lane.
+func (p *mutatorProfile) end(mutator string, lane int, start time.Time) {
+	end := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.busy[lane] = false
+	key := mutatorProfileLane{mutator, lane}
+	span := p.spans[key]
+	if span == nil {
+		span = &mutatorProfileSpan{start: start}
+		p.spans[key] = span
+	}
+	span.end = end
+	span.modules++
+	span.busy += end.Sub(start)
+}
+
+func (p *mutatorProfile) bottomUp(name string, m blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	return func(ctx blueprint.BottomUpMutatorContext) {
+		lane, start := p.begin()
+		defer p.end(name, lane, start)
+		m(ctx)
+	}
+}
+
+func (p *mutatorProfile) topDown(name string, m blueprint.TopDownMutator) blueprint.TopDownMutator {
+	return func(ctx blueprint.TopDownMutatorContext) {
+		lane, start := p.begin()
+		defer p.end(name, lane, start)
+		m(ctx)
+	}
+}
+
+// mutatorTraceEvent is a complete event of the Chrome trace event format, with the times in
+// microseconds.
+type mutatorTraceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat"`
+	Phase string                 `json:"ph"`
+	Time  int64                  `json:"ts"`
+	Dur   int64                  `json:"dur"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args"`
+}
+
+// events returns an event for each lane of each mutator, sorted by time.  An event lasts from the
+// first to the last call of the mutator on the lane, and its args contain the number of modules
+// and the time spent in them, as there may be gaps between the calls.
+func (p *mutatorProfile) events() []mutatorTraceEvent {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	micros := func(t time.Time) int64 {
+		return t.Sub(p.start).Microseconds()
+	}
+	var events []mutatorTraceEvent
+	for key, span := range p.spans {
+		events = append(events, mutatorTraceEvent{
+			Name:  key.mutator,
+			Cat:   "mutator",
+			Phase: "X",
+			Time:  micros(span.start),
+			Dur:   micros(span.end) - micros(span.start),
+			Tid:   key.lane,
+			Args: map[string]interface{}{
+				"modules": span.modules,
+				"busy_ms": float64(span.busy.Microseconds()) / 1000,
+			},
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Time != events[j].Time {
+			return events[i].Time < events[j].Time
+		}
+		return events[i].Tid < events[j].Tid
+	})
+	return events
+}
+
+// WriteMutatorProfile writes the time spent in the mutators since EnableMutatorProfile was called
+// to path as a Chrome trace, that can be loaded in chrome://tracing or Perfetto.
+func WriteMutatorProfile(config Config, path string) error {
+	if config.mutatorProfile == nil {
+		return fmt.Errorf("EnableMutatorProfile was not called")
+	}
+	data, err := json.Marshal(struct {
+		TraceEvents []mutatorTraceEvent `json:"traceEvents"`
+	}{config.mutatorProfile.events()})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(absolutePath(path), append(data, '\n'), 0666)
+}
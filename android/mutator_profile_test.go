@@ -0,0 +1,81 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMutatorProfileLanes(t *testing.T) {
+	profile := newMutatorProfile()
+	lane0, start0 := profile.begin()
+	lane1, start1 := profile.begin()
+	profile.end("deps", lane0, start0)
+	lane2, start2 := profile.begin()
+	profile.end("deps", lane1, start1)
+	profile.end("deps", lane2, start2)
+
+	if lane0 != 0 || lane1 != 1 || lane2 != 0 {
+		t.Errorf("expected lanes 0, 1 and 0, got %d, %d and %d", lane0, lane1, lane2)
+	}
+
+	events := profile.events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	for i, modules := range []int{2, 1} {
+		event := events[i]
+		if event.Name != "deps" || event.Tid != i || event.Phase != "X" || event.Args["modules"] != modules {
+			t.Errorf("unexpected event for lane %d: %v", i, event)
+		}
+	}
+}
+
+func TestMutatorProfileCollected(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.PreDepsMutators(func(ctx RegisterMutatorsContext) {
+				ctx.BottomUp("slow", func(ctx BottomUpMutatorContext) {
+					time.Sleep(time.Millisecond)
+				}).Parallel()
+			})
+		}),
+		FixtureModifyConfig(func(config Config) {
+			config.EnableMutatorProfile()
+		}),
+	).RunTestWithBp(t, `
+		filegroup {
+			name: "foo",
+		}
+		filegroup {
+			name: "bar",
+		}
+	`)
+
+	modules := 0
+	for _, event := range result.Config.mutatorProfile.events() {
+		if event.Name == "slow" {
+			modules += event.Args["modules"].(int)
+			if event.Dur < 1000 {
+				t.Errorf("expected the slow mutator to last at least 1ms on lane %d, got %dus", event.Tid, event.Dur)
+			}
+		}
+	}
+	if modules != 2 {
+		t.Errorf("expected the slow mutator to run on 2 modules, got %d", modules)
+	}
+}
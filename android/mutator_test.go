@@ -17,7 +17,9 @@ package android
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/blueprint"
 )
@@ -267,3 +269,52 @@ func TestNoCreateVariationsInFinalDeps(t *testing.T) {
 		FixtureWithRootAndroidBp(`test {name: "foo"}`),
 	).RunTest(t)
 }
+
+func TestSingleThreadedMutators(t *testing.T) {
+	bp := ""
+	for i := 0; i < 10; i++ {
+		bp += fmt.Sprintf("test {\n\tname: \"mod%d\",\n}\n", i)
+	}
+
+	// visited runs a parallel mutator with single threaded mutators enabled, and returns the order
+	// in which it visited the modules and the largest number of concurrent calls.
+	visited := func() ([]string, int) {
+		var lock sync.Mutex
+		var order []string
+		running, maxRunning := 0, 0
+		GroupFixturePreparers(
+			FixtureRegisterWithContext(func(ctx RegistrationContext) {
+				ctx.RegisterModuleType("test", mutatorTestModuleFactory)
+				ctx.PreDepsMutators(func(ctx RegisterMutatorsContext) {
+					ctx.BottomUp("visit", func(ctx BottomUpMutatorContext) {
+						lock.Lock()
+						running++
+						if running > maxRunning {
+							maxRunning = running
+						}
+						order = append(order, ctx.ModuleName())
+						lock.Unlock()
+
+						time.Sleep(time.Millisecond)
+
+						lock.Lock()
+						running--
+						lock.Unlock()
+					}).Parallel()
+				})
+			}),
+			FixtureModifyConfig(func(config Config) {
+				config.EnableSingleThreadedMutators()
+			}),
+			FixtureWithRootAndroidBp(bp),
+		).RunTest(t)
+		return order, maxRunning
+	}
+
+	first, firstMaxRunning := visited()
+	second, secondMaxRunning := visited()
+	AssertIntEquals(t, "concurrent calls of the first run", 1, firstMaxRunning)
+	AssertIntEquals(t, "concurrent calls of the second run", 1, secondMaxRunning)
+	AssertIntEquals(t, "visited modules", 10, len(first))
+	AssertDeepEquals(t, "order of the second run", first, second)
+}
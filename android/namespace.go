@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/blueprint"
 )
@@ -89,6 +90,9 @@ type NameResolver struct {
 
 	// func telling whether to export a namespace to Kati
 	namespaceExportFilter func(*Namespace) bool
+
+	// set to 1 once a module_alias has been defined, for atomic.LoadInt32
+	hasAliases int32
 }
 
 func NewNameResolver(namespaceExportFilter func(*Namespace) bool) *NameResolver {
@@ -105,6 +109,7 @@ func NewNameResolver(namespaceExportFilter func(*Namespace) bool) *NameResolver
 
 func (r *NameResolver) newNamespace(path string) *Namespace {
 	namespace := NewNamespace(path)
+	namespace.resolver = r
 
 	namespace.exportToKati = r.namespaceExportFilter(namespace)
 
@@ -198,6 +203,13 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 		return nil, errs
 	}
 
+	if alias, ok := module.(*ModuleAlias); ok {
+		alias.namespace = ns
+		alias.resolver = r
+		ns.aliases[alias.Name()] = alias
+		atomic.StoreInt32(&r.hasAliases, 1)
+	}
+
 	amod, ok := module.(Module)
 	if ok {
 		// inform the module whether its namespace is one that we want to export to Make
@@ -250,24 +262,45 @@ func (r *NameResolver) getNamespacesToSearchForModule(sourceNamespace blueprint.
 }
 
 func (r *NameResolver) ModuleFromName(name string, namespace blueprint.Namespace) (group blueprint.ModuleGroup, found bool) {
+	alias, group, found := r.moduleOrAliasFromName(name, namespace)
+	if alias != nil {
+		// A dependency on an alias is a dependency on the module it resolves to.  Errors
+		// resolving it are reported by the alias, the dependency is reported as missing.
+		group, err := r.resolveAlias(alias)
+		return group, err == nil
+	}
+	return group, found
+}
+
+// moduleOrAliasFromName returns the module_alias with the name if there is one, otherwise the
+// module group with the name.
+func (r *NameResolver) moduleOrAliasFromName(name string, namespace blueprint.Namespace) (alias *ModuleAlias,
+	group blueprint.ModuleGroup, found bool) {
+
 	// handle fully qualified references like "//namespace_path:module_name"
 	nsName, moduleName, isAbs := r.parseFullyQualifiedName(name)
 	if isAbs {
 		namespace, found := r.namespaceAt(nsName)
 		if !found {
-			return blueprint.ModuleGroup{}, false
+			return nil, blueprint.ModuleGroup{}, false
+		}
+		if alias, ok := namespace.aliases[moduleName]; ok {
+			return alias, blueprint.ModuleGroup{}, true
 		}
 		container := namespace.moduleContainer
-		return container.ModuleFromName(moduleName, nil)
+		group, found = container.ModuleFromName(moduleName, nil)
+		return nil, group, found
 	}
 	for _, candidate := range r.getNamespacesToSearchForModule(namespace) {
+		if alias, ok := candidate.aliases[name]; ok {
+			return alias, blueprint.ModuleGroup{}, true
+		}
 		group, found = candidate.moduleContainer.ModuleFromName(name, nil)
 		if found {
-			return group, true
+			return nil, group, true
 		}
 	}
-	return blueprint.ModuleGroup{}, false
-
+	return nil, blueprint.ModuleGroup{}, false
 }
 
 func (r *NameResolver) Rename(oldName string, newName string, namespace blueprint.Namespace) []error {
@@ -365,10 +398,35 @@ type Namespace struct {
 	exportToKati bool
 
 	moduleContainer blueprint.NameInterface
+
+	// the module_alias modules in this namespace, by name
+	aliases map[string]*ModuleAlias
+
+	// the NameResolver that the namespace belongs to
+	resolver *NameResolver
+}
+
+// markModuleAliasesUsed records that dependencies were added on the names that are module_alias
+// modules.  It is called when dependencies are added rather than from ModuleFromName, as the
+// lookups of ModuleFromName are also used to check whether modules exist.
+func markModuleAliasesUsed(namespace blueprint.Namespace, names []string) {
+	ns, ok := namespace.(*Namespace)
+	if !ok || ns.resolver == nil || atomic.LoadInt32(&ns.resolver.hasAliases) == 0 {
+		return
+	}
+	for _, name := range names {
+		if alias, _, _ := ns.resolver.moduleOrAliasFromName(name, ns); alias != nil {
+			atomic.StoreInt32(&alias.used, 1)
+		}
+	}
 }
 
 func NewNamespace(path string) *Namespace {
-	return &Namespace{Path: path, moduleContainer: blueprint.NewSimpleNameInterface()}
+	return &Namespace{
+		Path:            path,
+		moduleContainer: blueprint.NewSimpleNameInterface(),
+		aliases:         make(map[string]*ModuleAlias),
+	}
 }
 
 var _ blueprint.Namespace = (*Namespace)(nil)
@@ -23,6 +23,8 @@ import (
 	"strings"
 
 	"github.com/google/blueprint/proptools"
+
+	"android/soong/android/allowlists"
 )
 
 // "neverallow" rules for the build system.
@@ -42,6 +44,7 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "Without" properties matched (same rules as above)
+// - it is not one of the "NotModuleName" modules
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
@@ -58,6 +61,7 @@ func init() {
 	AddNeverAllowRules(createMakefileGoalRules()...)
 	AddNeverAllowRules(createInitFirstStageRules()...)
 	AddNeverAllowRules(createProhibitFrameworkAccessRules()...)
+	AddNeverAllowRules(createDeprecatedDependencyRules(allowlists.DeprecatedDependencies)...)
 }
 
 // Add a NeverAllow rule to the set of rules to apply.
@@ -238,6 +242,20 @@ func createProhibitFrameworkAccessRules() []Rule {
 	}
 }
 
+// createDeprecatedDependencyRules returns a rule for each deprecated module that prevents modules
+// other than those in its allowlist from depending on it.
+func createDeprecatedDependencyRules(deprecated []allowlists.DeprecatedDependency) []Rule {
+	rules := make([]Rule, 0, len(deprecated))
+	for _, d := range deprecated {
+		rules = append(rules, NeverAllow().
+			InDirectDeps(d.Name).
+			NotModuleName(d.Allowlist...).
+			Because(d.Name+" is deprecated and no new dependencies on it may be added, see "+
+				d.MigrationDoc+" for how to migrate away from it."))
+	}
+	return rules
+}
+
 func neverallowMutator(ctx BottomUpMutatorContext) {
 	m, ok := ctx.Module().(Module)
 	if !ok {
@@ -259,6 +277,10 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
+		if !n.appliesToModuleName(ctx.ModuleName()) {
+			continue
+		}
+
 		if !n.appliesToProperties(properties) {
 			continue
 		}
@@ -386,6 +408,8 @@ type Rule interface {
 
 	NotModuleType(types ...string) Rule
 
+	NotModuleName(names ...string) Rule
+
 	With(properties, value string) Rule
 
 	WithMatcher(properties string, matcher ValueMatcher) Rule
@@ -411,6 +435,8 @@ type rule struct {
 	moduleTypes       []string
 	unlessModuleTypes []string
 
+	unlessModuleNames []string
+
 	props       ruleProperties
 	unlessProps ruleProperties
 
@@ -460,6 +486,12 @@ func (r *rule) NotModuleType(types ...string) Rule {
 	return r
 }
 
+// NotModuleName adds module name(s) that this rule does not apply to.
+func (r *rule) NotModuleName(names ...string) Rule {
+	r.unlessModuleNames = append(r.unlessModuleNames, names...)
+	return r
+}
+
 // With specifies property/value combinations that are restricted for this rule.
 func (r *rule) With(properties, value string) Rule {
 	return r.WithMatcher(properties, selectMatcher(value))
@@ -524,6 +556,9 @@ func (r *rule) String() string {
 	if len(r.unlessModuleTypes) > 0 {
 		s = append(s, fmt.Sprintf("EXCEPT module types: %q", r.unlessModuleTypes))
 	}
+	if len(r.unlessModuleNames) > 0 {
+		s = append(s, fmt.Sprintf("EXCEPT modules: %q", r.unlessModuleNames))
+	}
 	if len(r.unlessProps) > 0 {
 		s = append(s, fmt.Sprintf("EXCEPT properties matching: %q", r.unlessProps))
 	}
@@ -576,6 +611,10 @@ func (r *rule) appliesToModuleType(moduleType string) bool {
 	return (len(r.moduleTypes) == 0 || InList(moduleType, r.moduleTypes)) && !InList(moduleType, r.unlessModuleTypes)
 }
 
+func (r *rule) appliesToModuleName(name string) bool {
+	return !InList(name, r.unlessModuleNames)
+}
+
 func (r *rule) appliesToProperties(properties []interface{}) bool {
 	includeProps := hasAllProperties(properties, r.props)
 	excludeProps := hasAnyProperty(properties, r.unlessProps)
@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/google/blueprint"
+
+	"android/soong/android/allowlists"
 )
 
 var neverallowTests = []struct {
@@ -89,6 +91,37 @@ var neverallowTests = []struct {
 	EXCEPT module types: ["cc_binary"]`),
 		},
 	},
+	{
+		name: "deprecated dependency",
+		rules: createDeprecatedDependencyRules([]allowlists.DeprecatedDependency{
+			{
+				Name:         "libdeprecated",
+				MigrationDoc: "https://example.com/migrate-libdeprecated",
+				Allowlist:    []string{"libexisting"},
+			},
+		}),
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				cc_library {
+					name: "libdeprecated",
+				}`),
+			"other/Android.bp": []byte(`
+				cc_library {
+					name: "libexisting",
+					static_libs: ["libdeprecated"],
+				}
+				cc_library {
+					name: "libnew",
+					shared_libs: ["libdeprecated"],
+				}`),
+		},
+		expectedErrors: []string{
+			regexp.QuoteMeta(`module "libnew": violates neverallow requirements. Not allowed:
+	dep(s): ["libdeprecated"]
+	EXCEPT modules: ["libexisting"]
+	 which is restricted because libdeprecated is deprecated and no new dependencies on it may be added, see https://example.com/migrate-libdeprecated for how to migrate away from it.`),
+		},
+	},
 
 	// Test android specific rules
 
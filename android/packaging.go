@@ -42,6 +42,9 @@ type PackagingSpec struct {
 	effectiveLicenseFiles *Paths
 
 	partition string
+
+	// The path the file is installed to if the module installs it.
+	installPath InstallPath
 }
 
 // Get file name of installed package
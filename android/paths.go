@@ -23,6 +23,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/bootstrap"
@@ -405,6 +406,25 @@ func PathsForModuleSrc(ctx ModuleMissingDepsPathContext, paths []string) Paths {
 	return PathsForModuleSrcExcludes(ctx, paths, nil)
 }
 
+// PathsForModuleData returns the Paths of the test data listed in the data property, like
+// PathsForModuleSrc.  A reference to a module that produces multiple output files must select
+// one of them with a tag, since each output is staged at a path that the test has to know.
+func PathsForModuleData(ctx ModuleContext, data []string) Paths {
+	for _, d := range data {
+		moduleName, tag := SrcIsModuleWithTag(d)
+		if moduleName == "" || tag != "" {
+			continue
+		}
+		if producer, ok := GetModuleFromPathDep(ctx, moduleName, "").(OutputFileProducer); ok {
+			if outputs, err := producer.OutputFiles(""); err == nil && len(outputs) > 1 {
+				ctx.PropertyErrorf("data", "module %q has %d output files, select one with a tag like %q",
+					moduleName, len(outputs), ":"+moduleName+"{"+outputs[0].Rel()+"}")
+			}
+		}
+	}
+	return PathsForModuleSrc(ctx, data)
+}
+
 type SourceInput struct {
 	Context      ModuleMissingDepsPathContext
 	Paths        []string
@@ -589,6 +609,11 @@ func PathsAndMissingDepsRelativeToModuleSourceDir(input SourceInput) (Paths, []s
 			}
 		} else {
 			expandedExcludes = append(expandedExcludes, filepath.Join(prefix, e))
+			if pathtools.IsGlob(e) && lintEmptyGlobs(input.Context.Config()) {
+				if matches, err := input.Context.GlobWithDeps(filepath.Join(prefix, e), nil); err == nil && len(matches) == 0 {
+					recordEmptyGlob(input.Context, e)
+				}
+			}
 		}
 	}
 
@@ -659,6 +684,9 @@ func expandOneSrcPath(input sourcePathInput) (Paths, error) {
 		p := pathForModuleSrc(input.context, input.path)
 		if pathtools.IsGlob(input.path) {
 			paths := GlobFiles(input.context, p.String(), input.expandedExcludes)
+			if len(paths) == 0 && lintEmptyGlobs(input.context.Config()) {
+				recordEmptyGlob(input.context, input.path)
+			}
 			return PathsWithModuleSrcSubDir(input.context, paths, ""), nil
 		} else {
 			if exists, _, err := input.context.Config().fs.Exists(p.String()); err != nil {
@@ -1162,6 +1190,21 @@ func ExistentPathForSource(ctx PathContext, pathComponents ...string) OptionalPa
 	return OptionalPathForPath(path)
 }
 
+// ReadSourceFile returns the contents of a file in the source tree.  The file is added as a
+// dependency of the build.ninja file, so the build actions are regenerated when it changes.
+func ReadSourceFile(ctx PathContext, path Path) ([]byte, error) {
+	if _, ok := path.(SourcePath); !ok {
+		return nil, fmt.Errorf("%s is not a source file", path)
+	}
+	ctx.AddNinjaFileDeps(path.String())
+	r, err := ctx.Config().fs.Open(path.String())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
 func (p SourcePath) String() string {
 	return filepath.Join(p.srcDir, p.path)
 }
@@ -1465,6 +1508,9 @@ type ModuleOutPathContext interface {
 	ModuleSubDir() string
 }
 
+// pathForModuleOut returns the intermediates directory of the variant of the module.  The subdir
+// joins the names of the variations of the variant in the order of the mutators that created them,
+// so it does not depend on the order in which the modules or the variations were visited.
 func pathForModuleOut(ctx ModuleOutPathContext) OutputPath {
 	return PathForOutput(ctx, ".intermediates", ctx.ModuleDir(), ctx.ModuleName(), ctx.ModuleSubDir())
 }
@@ -2094,6 +2140,12 @@ func maybeRelErr(basePath string, targetPath string) (string, bool, error) {
 	return rel, true, nil
 }
 
+// The files written with WriteFileToOutputDir.
+var outputDirFilesWritten struct {
+	sync.Mutex
+	paths []string
+}
+
 // Writes a file to the output directory.  Attempting to write directly to the output directory
 // will fail due to the sandbox of the soong_build process.
 func WriteFileToOutputDir(path WritablePath, data []byte, perm os.FileMode) error {
@@ -2102,9 +2154,20 @@ func WriteFileToOutputDir(path WritablePath, data []byte, perm os.FileMode) erro
 	if err != nil {
 		return err
 	}
+	outputDirFilesWritten.Lock()
+	outputDirFilesWritten.paths = append(outputDirFilesWritten.paths, path.String())
+	outputDirFilesWritten.Unlock()
 	return ioutil.WriteFile(absPath, data, perm)
 }
 
+// OutputDirFilesWritten returns the sorted paths of the files that have been written with
+// WriteFileToOutputDir.
+func OutputDirFilesWritten() []string {
+	outputDirFilesWritten.Lock()
+	defer outputDirFilesWritten.Unlock()
+	return SortedUniqueStrings(outputDirFilesWritten.paths)
+}
+
 func RemoveAllOutputDir(path WritablePath) error {
 	return os.RemoveAll(absolutePath(path.String()))
 }
@@ -1041,6 +1041,8 @@ type pathForModuleSrcTestModule struct {
 
 		Src *string `android:"path"`
 
+		Data []string `android:"path"`
+
 		Module_handles_missing_deps bool
 	}
 
@@ -1050,6 +1052,8 @@ type pathForModuleSrcTestModule struct {
 	srcs []string
 	rels []string
 
+	data []string
+
 	missingDeps []string
 }
 
@@ -1081,6 +1085,10 @@ func (p *pathForModuleSrcTestModule) GenerateAndroidBuildActions(ctx ModuleConte
 		}
 	}
 
+	if p.props.Data != nil {
+		p.data = PathsForModuleData(ctx, p.props.Data).Strings()
+	}
+
 	if !p.props.Module_handles_missing_deps {
 		p.missingDeps = ctx.GetMissingDependencies()
 	}
@@ -1469,6 +1477,71 @@ func TestPathsForModuleSrc_AllowMissingDependencies(t *testing.T) {
 	AssertArrayString(t, "bar srcs", []string{}, bar.srcs)
 }
 
+func TestPathsForModuleData(t *testing.T) {
+	preparer := GroupFixturePreparers(
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test", pathForModuleSrcTestModuleFactory)
+			ctx.RegisterModuleType("output_file_provider", pathForModuleSrcOutputFileProviderModuleFactory)
+		}),
+		PrepareForTestWithFilegroup,
+		FixtureMergeMockFs(MockFS{
+			"foo/data.txt": nil,
+			"fg/a":         nil,
+			"fg/b":         nil,
+		}),
+		FixtureWithRootAndroidBp(`
+			output_file_provider {
+				name: "single",
+				outs: ["gen/single"],
+			}
+
+			output_file_provider {
+				name: "multiple",
+				outs: ["gen/b", "gen/c"],
+				tagged: ["gen/c"],
+			}
+
+			filegroup {
+				name: "fg",
+				srcs: ["fg/a", "fg/b"],
+			}
+		`),
+		FixtureAddTextFile("foo/Android.bp", `
+			test {
+				name: "foo",
+				data: [
+					"data.txt",
+					":single",
+					":multiple{.tagged}",
+					":fg",
+				],
+			}
+		`),
+	)
+
+	result := preparer.RunTest(t)
+	foo := result.ModuleForTests("foo", "").Module().(*pathForModuleSrcTestModule)
+	AssertStringPathsRelativeToTopEquals(t, "foo data", result.Config, []string{
+		"foo/data.txt",
+		"out/soong/.intermediates/single/gen/single",
+		"out/soong/.intermediates/multiple/gen/c",
+		"fg/a",
+		"fg/b",
+	}, foo.data)
+
+	GroupFixturePreparers(
+		preparer,
+		FixtureAddTextFile("bar/Android.bp", `
+			test {
+				name: "bar",
+				data: [":multiple"],
+			}
+		`),
+	).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`module "bar": data: module "multiple" has 2 output files, select one with a tag like ":multiple{gen/b}"`)).
+		RunTest(t)
+}
+
 func TestPathRelativeToTop(t *testing.T) {
 	testConfig := pathTestConfig("/tmp/build/top")
 	deviceTarget := Target{Os: Android, Arch: Arch{ArchType: Arm64}}
@@ -17,7 +17,9 @@ package android
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -26,6 +28,10 @@ import (
 // This file implements common functionality for handling modules that may exist as prebuilts,
 // source, or both.
 
+func init() {
+	RegisterMakeVarsProvider(pctx, productPrebuiltSelectionsMakeVarsProvider)
+}
+
 func RegisterPrebuiltMutators(ctx RegistrationContext) {
 	ctx.PreArchMutators(RegisterPrebuiltsPreArchMutators)
 	ctx.PostDepsMutators(RegisterPrebuiltsPostDepsMutators)
@@ -459,6 +465,11 @@ func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module, prebuil
 		return true
 	}
 
+	// The product configuration overrides the module's own preference.
+	if usePrebuilt, ok := productPrebuiltPreference(ctx, source.Name()); ok {
+		return usePrebuilt
+	}
+
 	// If the use_source_config_var property is set then it overrides the prefer property setting.
 	if configVar := p.properties.Use_source_config_var; configVar != nil {
 		return !ctx.Config().VendorConfig(proptools.String(configVar.Config_namespace)).Bool(proptools.String(configVar.Var_name))
@@ -468,6 +479,75 @@ func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module, prebuil
 	return Bool(p.properties.Prefer)
 }
 
+// productPrebuiltSelections records the modules for which the product configuration selected the
+// prebuilt or the source module, so that the selection, which changes without any change to the
+// Android.bp files, can be inspected in the Make variables.
+type productPrebuiltSelections struct {
+	lock      sync.Mutex
+	prebuilts map[string]bool
+	sources   map[string]bool
+}
+
+var productPrebuiltSelectionsKey = NewOnceKey("productPrebuiltSelections")
+
+func getProductPrebuiltSelections(config Config) *productPrebuiltSelections {
+	return config.Once(productPrebuiltSelectionsKey, func() interface{} {
+		return &productPrebuiltSelections{
+			prebuilts: make(map[string]bool),
+			sources:   make(map[string]bool),
+		}
+	}).(*productPrebuiltSelections)
+}
+
+func (s *productPrebuiltSelections) add(name string, usePrebuilt bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if usePrebuilt {
+		s.prebuilts[name] = true
+	} else {
+		s.sources[name] = true
+	}
+}
+
+// lists returns the sorted names of the modules for which the product configuration selected the
+// prebuilt and the source module.
+func (s *productPrebuiltSelections) lists() (prebuilts, sources []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name := range s.prebuilts {
+		prebuilts = append(prebuilts, name)
+	}
+	for name := range s.sources {
+		sources = append(sources, name)
+	}
+	sort.Strings(prebuilts)
+	sort.Strings(sources)
+	return prebuilts, sources
+}
+
+func productPrebuiltSelectionsMakeVarsProvider(ctx MakeVarsContext) {
+	prebuilts, sources := getProductPrebuiltSelections(ctx.Config()).lists()
+	ctx.Strict("SOONG_PRODUCT_SELECTED_PREBUILTS", strings.Join(prebuilts, " "))
+	ctx.Strict("SOONG_PRODUCT_SELECTED_SOURCES", strings.Join(sources, " "))
+}
+
+// productPrebuiltPreference returns whether the product configuration selects the prebuilt or the
+// source module called name, and false for ok if it selects neither. The selection is recorded in
+// the SOONG_PRODUCT_SELECTED_PREBUILTS and SOONG_PRODUCT_SELECTED_SOURCES Make variables.
+func productPrebuiltPreference(ctx TopDownMutatorContext, name string) (usePrebuilt bool, ok bool) {
+	preferPrebuilt := InList(name, ctx.Config().PreferPrebuiltModules())
+	preferSource := InList(name, ctx.Config().PreferSourceModules())
+	if preferPrebuilt && preferSource {
+		ctx.ModuleErrorf("%q is in both PreferPrebuiltModules and PreferSourceModules", name)
+		return false, false
+	} else if !preferPrebuilt && !preferSource {
+		return false, false
+	}
+
+	getProductPrebuiltSelections(ctx.Config()).add(name, preferPrebuilt)
+	return preferPrebuilt, true
+}
+
 func (p *Prebuilt) SourceExists() bool {
 	return p.properties.SourceExists
 }
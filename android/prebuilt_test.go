@@ -375,6 +375,77 @@ func TestPrebuilts(t *testing.T) {
 			// Although the environment variable says to use source there is no source available.
 			prebuilt: []OsType{Android, buildOS},
 		},
+		{
+			name: "prebuilt not preferred - in PreferPrebuiltModules",
+			modules: `
+				source {
+					name: "bar",
+				}
+
+				prebuilt {
+					name: "bar",
+					prefer: false,
+					srcs: ["prebuilt_file"],
+				}`,
+			preparer: FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PreferPrebuiltModules = []string{"bar"}
+			}),
+			prebuilt: []OsType{Android, buildOS},
+		},
+		{
+			name: "prebuilt preferred - in PreferSourceModules",
+			modules: `
+				source {
+					name: "bar",
+				}
+
+				prebuilt {
+					name: "bar",
+					prefer: true,
+					srcs: ["prebuilt_file"],
+				}`,
+			preparer: FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PreferSourceModules = []string{"bar"}
+			}),
+			prebuilt: nil,
+		},
+		{
+			name: "prebuilt use_source_config_var - in PreferPrebuiltModules",
+			modules: `
+				source {
+					name: "bar",
+				}
+
+				prebuilt {
+					name: "bar",
+					use_source_config_var: {config_namespace: "acme", var_name: "use_source"},
+					srcs: ["prebuilt_file"],
+				}`,
+			preparer: FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.VendorVars = map[string]map[string]string{
+					"acme": {
+						"use_source": "true",
+					},
+				}
+				variables.PreferPrebuiltModules = []string{"bar"}
+			}),
+			// The product configuration takes precedence over the Soong config variable.
+			prebuilt: []OsType{Android, buildOS},
+		},
+		{
+			name: "PreferSourceModules without source",
+			modules: `
+				prebuilt {
+					name: "bar",
+					prefer: true,
+					srcs: ["prebuilt_file"],
+				}`,
+			preparer: FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PreferSourceModules = []string{"bar"}
+			}),
+			// There is no source module to use.
+			prebuilt: []OsType{Android, buildOS},
+		},
 	}
 
 	fs := MockFS{
@@ -497,6 +568,48 @@ func TestPrebuilts(t *testing.T) {
 	}
 }
 
+func TestProductPrebuiltSelections(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithPrebuilts,
+		MockFS{"prebuilt_file": nil}.AddToFixture(),
+		FixtureRegisterWithContext(registerTestPrebuiltModules),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.PreferPrebuiltModules = []string{"bar"}
+			variables.PreferSourceModules = []string{"baz", "qux"}
+		}),
+	).RunTestWithBp(t, `
+		source {
+			name: "bar",
+		}
+
+		prebuilt {
+			name: "bar",
+			srcs: ["prebuilt_file"],
+		}
+
+		source {
+			name: "baz",
+		}
+
+		prebuilt {
+			name: "baz",
+			prefer: true,
+			srcs: ["prebuilt_file"],
+		}
+
+		// Without a source module there is nothing for the product to select.
+		prebuilt {
+			name: "qux",
+			srcs: ["prebuilt_file"],
+		}
+	`)
+
+	prebuilts, sources := getProductPrebuiltSelections(result.Config).lists()
+	AssertArrayString(t, "selected prebuilts", []string{"bar"}, prebuilts)
+	AssertArrayString(t, "selected sources", []string{"baz"}, sources)
+}
+
 func registerTestPrebuiltBuildComponents(ctx RegistrationContext) {
 	registerTestPrebuiltModules(ctx)
 
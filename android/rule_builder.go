@@ -57,6 +57,7 @@ type RuleBuilder struct {
 	sboxInputs       bool
 	sboxManifestPath WritablePath
 	missingDeps      []string
+	writableOutputs  bool
 }
 
 // NewRuleBuilder returns a newly created RuleBuilder.
@@ -106,6 +107,15 @@ func (r *RuleBuilder) Restat() *RuleBuilder {
 	return r
 }
 
+// WritableOutputs keeps the write permissions of the outputs of the rule when
+// Config.ReadOnlyOutputs is set, for rules whose commands update their outputs in place.  Restat
+// rules that don't run in sbox keep them without calling this, as they may write to an output
+// that already exists.
+func (r *RuleBuilder) WritableOutputs() *RuleBuilder {
+	r.writableOutputs = true
+	return r
+}
+
 // HighMem marks the rule as a high memory rule, which will limit how many run in parallel with other high memory
 // rules.
 func (r *RuleBuilder) HighMem() *RuleBuilder {
@@ -274,6 +284,29 @@ func (r *RuleBuilder) OrderOnlys() Paths {
 	return orderOnlyList
 }
 
+// VolatileInputs returns the list of paths that were passed to
+// RuleBuilderCommand.VolatileInput or RuleBuilderCommand.PathForVolatileInput.  The list is sorted
+// and duplicates removed.
+func (r *RuleBuilder) VolatileInputs() Paths {
+	volatileInputs := make(map[string]Path)
+	for _, c := range r.commands {
+		for _, volatileInput := range c.volatileInputs {
+			volatileInputs[volatileInput.String()] = volatileInput
+		}
+	}
+
+	var volatileInputList Paths
+	for _, volatileInput := range volatileInputs {
+		volatileInputList = append(volatileInputList, volatileInput)
+	}
+
+	sort.Slice(volatileInputList, func(i, j int) bool {
+		return volatileInputList[i].String() < volatileInputList[j].String()
+	})
+
+	return volatileInputList
+}
+
 // Validations returns the list of paths that were passed to RuleBuilderCommand.Validation or
 // RuleBuilderCommand.Validations.  The list is sorted and duplicates removed.
 func (r *RuleBuilder) Validations() Paths {
@@ -458,6 +491,37 @@ type BuilderContext interface {
 var _ BuilderContext = ModuleContext(nil)
 var _ BuilderContext = SingletonContext(nil)
 
+// readOnlyOutputsCmds adds a command that removes the write permissions of the outputs after the
+// other commands.  Outside of sbox, which removes the outputs itself, the outputs of the previous
+// run are removed first so that the commands can write them again.  Symlinks are skipped, as chmod
+// would change the file they point to.
+func (r *RuleBuilder) readOnlyOutputsCmds() {
+	symlinkOutputs := r.symlinkOutputSet()
+	var outputs WritablePaths
+	for _, output := range r.Outputs() {
+		if _, isSymlink := symlinkOutputs[output.String()]; !isSymlink {
+			outputs = append(outputs, output)
+		}
+	}
+	if len(outputs) == 0 {
+		return
+	}
+
+	chmod := r.Command().Text("chmod a-w")
+	for _, output := range outputs {
+		chmod.Text(chmod.PathForOutput(output))
+	}
+
+	if !r.sbox {
+		rm := &RuleBuilderCommand{rule: r}
+		rm.Text("rm -f")
+		for _, output := range outputs {
+			rm.Text(rm.PathForOutput(output))
+		}
+		r.commands = append([]*RuleBuilderCommand{rm}, r.commands...)
+	}
+}
+
 func (r *RuleBuilder) depFileMergerCmd(depFiles WritablePaths) *RuleBuilderCommand {
 	return r.Command().
 		builtToolWithoutDeps("dep_fixer").
@@ -500,6 +564,10 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		}
 	}
 
+	if r.ctx.Config().ReadOnlyOutputs() && !r.writableOutputs && (r.sbox || !r.restat) {
+		r.readOnlyOutputsCmds()
+	}
+
 	tools := r.Tools()
 	commands := r.Commands()
 	outputs := r.Outputs()
@@ -513,6 +581,14 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		panic("No outputs specified from any Commands")
 	}
 
+	inputStrings := inputs.Strings()
+	for _, volatileInput := range r.ctx.Config().VolatileInputs(r.ctx) {
+		if InList(volatileInput.String(), inputStrings) {
+			ReportPathErrorf(r.ctx, "%s changes in every build and cannot be an input of rule %q, "+
+				"use RuleBuilderCommand.VolatileInput to read it", volatileInput, name)
+		}
+	}
+
 	commandString := strings.Join(commands, " && ")
 
 	if r.sbox {
@@ -558,6 +634,14 @@ func (r *RuleBuilder) Build(name string, desc string) {
 				})
 			}
 
+			// Volatile inputs are only order-only dependencies, but the command still reads them.
+			for _, volatileInput := range r.VolatileInputs() {
+				command.CopyBefore = append(command.CopyBefore, &sbox_proto.Copy{
+					From: proto.String(volatileInput.String()),
+					To:   proto.String(r.sboxPathForInputRel(volatileInput)),
+				})
+			}
+
 			// If using rsp files copy them and their contents into the sbox directory with
 			// the appropriate path mappings.
 			for _, rspFile := range rspFiles {
@@ -748,6 +832,7 @@ type RuleBuilderCommand struct {
 	inputs         Paths
 	implicits      Paths
 	orderOnlys     Paths
+	volatileInputs Paths
 	validations    Paths
 	outputs        WritablePaths
 	symlinkOutputs WritablePaths
@@ -1110,6 +1195,24 @@ func (c *RuleBuilderCommand) OrderOnlys(paths Paths) *RuleBuilderCommand {
 	return c
 }
 
+// PathForVolatileInput adds the specified input path, that changes in every build like
+// Config.BuildNumberFile, to the dependencies returned by RuleBuilder.OrderOnlys and returns the
+// path to use on the command line.  The command reads the current contents of the file whenever
+// it runs for other reasons, but a change of the file alone doesn't rerun it, so the rules that
+// depend on its outputs aren't rerun in every build either.  If sbox input sandboxing is enabled
+// the file is copied into the sandbox.
+func (c *RuleBuilderCommand) PathForVolatileInput(path Path) string {
+	c.addOrderOnly(path)
+	c.volatileInputs = append(c.volatileInputs, path)
+	return c.PathForInput(path)
+}
+
+// VolatileInput adds the specified input path, that changes in every build, to the command line
+// with the semantics of RuleBuilderCommand.PathForVolatileInput.
+func (c *RuleBuilderCommand) VolatileInput(path Path) *RuleBuilderCommand {
+	return c.Text(c.PathForVolatileInput(path))
+}
+
 // Validation adds the specified input path to the validation dependencies by
 // RuleBuilder.Validations without modifying the command line.
 func (c *RuleBuilderCommand) Validation(path Path) *RuleBuilderCommand {
@@ -720,6 +720,87 @@ func TestRuleBuilder_Build(t *testing.T) {
 	})
 }
 
+func TestRuleBuilderVolatileInput(t *testing.T) {
+	ctx := builderContext()
+	buildNumberFile := ctx.Config().BuildNumberFile(ctx)
+
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Tool(PathForSource(ctx, "cp")).
+		VolatileInput(buildNumberFile).
+		Output(PathForOutput(ctx, "build_number_copy.txt"))
+
+	AssertStringEquals(t, "command", "cp out/soong/build_number.txt out/soong/build_number_copy.txt",
+		strings.Join(rule.Commands(), " && "))
+	AssertArrayString(t, "volatile inputs", []string{buildNumberFile.String()}, rule.VolatileInputs().Strings())
+	AssertArrayString(t, "order only", []string{buildNumberFile.String()}, rule.OrderOnlys().Strings())
+	AssertIntEquals(t, "inputs", 0, len(rule.Inputs()))
+}
+
+func TestRuleBuilderReadOnlyOutputs(t *testing.T) {
+	ctx := BuilderContextForTesting(TestConfig("out", map[string]string{
+		"SOONG_READ_ONLY_OUTPUTS": "true",
+	}, "", map[string][]byte{
+		"cp": nil,
+		"ln": nil,
+		"a":  nil,
+	}))
+
+	build := func(modify func(rule *RuleBuilder)) []string {
+		rule := NewRuleBuilder(pctx, ctx)
+		rule.Command().
+			Tool(PathForSource(ctx, "cp")).
+			Input(PathForSource(ctx, "a")).
+			Output(PathForOutput(ctx, "b"))
+		modify(rule)
+		rule.Build("rule", "desc")
+		return rule.Commands()
+	}
+
+	t.Run("read only", func(t *testing.T) {
+		commands := build(func(rule *RuleBuilder) {
+			rule.Command().
+				Tool(PathForSource(ctx, "ln")).
+				Text("-s b").
+				SymlinkOutput(PathForOutput(ctx, "c"))
+		})
+		AssertArrayString(t, "commands", []string{
+			"rm -f out/soong/b",
+			"cp a out/soong/b",
+			"ln -s b out/soong/c",
+			"chmod a-w out/soong/b",
+		}, commands)
+	})
+
+	t.Run("restat", func(t *testing.T) {
+		commands := build(func(rule *RuleBuilder) {
+			rule.Restat()
+		})
+		AssertArrayString(t, "commands", []string{"cp a out/soong/b"}, commands)
+	})
+
+	t.Run("writable", func(t *testing.T) {
+		commands := build(func(rule *RuleBuilder) {
+			rule.WritableOutputs()
+		})
+		AssertArrayString(t, "commands", []string{"cp a out/soong/b"}, commands)
+	})
+
+	t.Run("sbox", func(t *testing.T) {
+		rule := NewRuleBuilder(pctx, ctx).Sbox(PathForOutput(ctx, "gen"), PathForOutput(ctx, "sbox.textproto"))
+		rule.Restat()
+		rule.Command().
+			Tool(PathForSource(ctx, "cp")).
+			Input(PathForSource(ctx, "a")).
+			Output(PathForOutput(ctx, "gen", "b"))
+		rule.Build("rule", "desc")
+		AssertArrayString(t, "commands", []string{
+			"cp a __SBOX_SANDBOX_DIR__/out/b",
+			"chmod a-w __SBOX_SANDBOX_DIR__/out/b",
+		}, rule.Commands())
+	})
+}
+
 func TestRuleBuilderHashInputs(t *testing.T) {
 	// The basic idea here is to verify that the command (in the case of a
 	// non-sbox rule) or the sbox textproto manifest contain a hash of the
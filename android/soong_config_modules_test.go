@@ -15,6 +15,7 @@
 package android
 
 import (
+	"regexp"
 	"testing"
 )
 
@@ -41,6 +42,7 @@ type soongConfigTestModule struct {
 
 type soongConfigTestModuleProperties struct {
 	Cflags []string
+	Srcs   []string
 }
 
 func soongConfigTestModuleFactory() Module {
@@ -426,6 +428,90 @@ func TestDuplicateStringValueInSoongConfigStringVariable(t *testing.T) {
 	})).RunTest(t)
 }
 
+func TestSoongConfigModuleStringVariableSrcs(t *testing.T) {
+	bp := `
+		soong_config_module_type {
+			name: "acme_test",
+			module_type: "test",
+			config_namespace: "acme",
+			variables: ["board"],
+			properties: ["srcs"],
+		}
+
+		soong_config_string_variable {
+			name: "board",
+			values: ["soc_a", "soc_b"],
+		}
+
+		acme_test {
+			name: "foo",
+			srcs: ["common.cpp"],
+			soong_config_variables: {
+				board: {
+					soc_a: {
+						srcs: ["soc_a.cpp"],
+					},
+					conditions_default: {
+						srcs: ["generic.cpp"],
+					},
+				},
+			},
+		}
+	`
+
+	testCases := []struct {
+		name      string
+		vars      map[string]string
+		wantSrcs  []string
+		wantError string
+	}{
+		{
+			name:     "unset",
+			wantSrcs: []string{"common.cpp", "generic.cpp"},
+		},
+		{
+			name:     "set",
+			vars:     map[string]string{"board": "soc_a"},
+			wantSrcs: []string{"common.cpp", "soc_a.cpp"},
+		},
+		{
+			name:     "set_without_srcs",
+			vars:     map[string]string{"board": "soc_b"},
+			wantSrcs: []string{"common.cpp", "generic.cpp"},
+		},
+		{
+			name:      "unknown",
+			vars:      map[string]string{"board": "soc_z"},
+			wantError: `soong_config_variables.board: invalid value "soc_z", the allowed values are soc_a, soc_b`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fixture := GroupFixturePreparers(
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.VendorVars = map[string]map[string]string{"acme": tc.vars}
+				}),
+				PrepareForTestWithDefaults,
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("soong_config_module_type", SoongConfigModuleTypeFactory)
+					ctx.RegisterModuleType("soong_config_string_variable", SoongConfigStringVariableDummyFactory)
+					ctx.RegisterModuleType("test", soongConfigTestModuleFactory)
+				}),
+				FixtureWithRootAndroidBp(bp),
+			)
+			if tc.wantError != "" {
+				fixture.ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.wantError))).
+					RunTest(t)
+				return
+			}
+			result := fixture.RunTest(t)
+			foo := result.ModuleForTests("foo", "").Module().(*soongConfigTestModule)
+			AssertDeepEquals(t, "foo srcs", tc.wantSrcs, foo.props.Srcs)
+		})
+	}
+}
+
 func testConfigWithVendorVars(buildDir, bp string, fs map[string][]byte, vendorVars map[string]map[string]string) Config {
 	config := TestConfig(buildDir, nil, bp, fs)
 
@@ -637,20 +637,43 @@ func (s *stringVariable) initializeProperties(v reflect.Value, typ reflect.Type)
 }
 
 // Extracts an interface from values containing the properties to apply based on config.
-// If config does not match a value with a non-nil property set, the default value will be returned.
+// If the variable is unset or empty, or set to a value with no properties in the Android.bp file,
+// the default value will be returned.  If the module sets properties for the variable and it is
+// set to a value that is not one of the values of the soong_config_string_variable, an error
+// listing the allowed values is returned instead.
 func (s *stringVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	configValue := CanonicalizeToProperty(config.String(s.variable))
+	known := configValue == ""
 	for j, v := range s.values {
 		f := values.Field(j)
-		if config.String(s.variable) == v && !f.Elem().IsNil() {
-			return f.Interface(), nil
+		if configValue == v {
+			if !f.Elem().IsNil() {
+				return f.Interface(), nil
+			}
+			known = true
 		}
 	}
+	if !known && s.isReferenced(values) {
+		return nil, fmt.Errorf("soong_config_variables.%s: invalid value %q, the allowed values are %s",
+			s.variable, config.String(s.variable), strings.Join(s.values, ", "))
+	}
 	// if we have reached this point, we have checked all valid values of string and either:
 	//   * the value was not set
 	//   * the value was set but that value was not specified in the Android.bp file
 	return values.Field(len(s.values)).Interface(), nil
 }
 
+// isReferenced returns whether the module sets properties for a value of the variable or for
+// conditions_default.
+func (s *stringVariable) isReferenced(values reflect.Value) bool {
+	for j := 0; j <= len(s.values); j++ {
+		if !values.Field(j).Elem().IsNil() {
+			return true
+		}
+	}
+	return false
+}
+
 // Struct to allow conditions set based on a boolean variable
 type boolVariable struct {
 	baseVariable
@@ -365,6 +365,122 @@ func Test_PropertiesToApply(t *testing.T) {
 	}
 }
 
+type srcsProperties struct {
+	Srcs []string
+}
+
+type stringVarValues struct {
+	Soc_a              interface{}
+	Soc_b              interface{}
+	Conditions_default interface{}
+}
+
+func Test_PropertiesToApplyStringVariable(t *testing.T) {
+	mt := &ModuleType{
+		Variables: []soongConfigVariable{
+			&stringVariable{
+				baseVariable: baseVariable{variable: "board"},
+				values:       []string{"soc_a", "soc_b"},
+			},
+		},
+	}
+	socA := &srcsProperties{Srcs: []string{"soc_a.cpp"}}
+	conditionsDefault := &srcsProperties{Srcs: []string{"generic.cpp"}}
+	props := reflect.ValueOf(&struct {
+		Soong_config_variables struct {
+			Board stringVarValues
+		}
+	}{
+		Soong_config_variables: struct {
+			Board stringVarValues
+		}{
+			Board: stringVarValues{
+				Soc_a:              socA,
+				Soc_b:              (*srcsProperties)(nil),
+				Conditions_default: conditionsDefault,
+			},
+		},
+	})
+	unreferenced := reflect.ValueOf(&struct {
+		Soong_config_variables struct {
+			Board stringVarValues
+		}
+	}{
+		Soong_config_variables: struct {
+			Board stringVarValues
+		}{
+			Board: stringVarValues{
+				Soc_a:              (*srcsProperties)(nil),
+				Soc_b:              (*srcsProperties)(nil),
+				Conditions_default: (*srcsProperties)(nil),
+			},
+		},
+	})
+
+	testCases := []struct {
+		name      string
+		config    SoongConfig
+		props     reflect.Value
+		wantProps []interface{}
+		wantErr   string
+	}{
+		{
+			name:      "unset",
+			config:    Config(map[string]string{}),
+			props:     props,
+			wantProps: []interface{}{conditionsDefault},
+		},
+		{
+			name:      "empty",
+			config:    Config(map[string]string{"board": ""}),
+			props:     props,
+			wantProps: []interface{}{conditionsDefault},
+		},
+		{
+			name:      "set",
+			config:    Config(map[string]string{"board": "soc_a"}),
+			props:     props,
+			wantProps: []interface{}{socA},
+		},
+		{
+			name:      "set_without_properties",
+			config:    Config(map[string]string{"board": "soc_b"}),
+			props:     props,
+			wantProps: []interface{}{conditionsDefault},
+		},
+		{
+			name:    "unknown",
+			config:  Config(map[string]string{"board": "soc_z"}),
+			props:   props,
+			wantErr: `soong_config_variables.board: invalid value "soc_z", the allowed values are soc_a, soc_b`,
+		},
+		{
+			name:      "unknown_unreferenced",
+			config:    Config(map[string]string{"board": "soc_z"}),
+			props:     unreferenced,
+			wantProps: []interface{}{(*srcsProperties)(nil)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotProps, err := PropertiesToApply(mt, tc.props, tc.config)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error in PropertiesToApply: %s", err)
+			}
+			if !reflect.DeepEqual(gotProps, tc.wantProps) {
+				t.Errorf("Expected %s, got %s", tc.wantProps, gotProps)
+			}
+		})
+	}
+}
+
 func Test_Bp2BuildSoongConfigDefinitions(t *testing.T) {
 	testCases := []struct {
 		desc     string
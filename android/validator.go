@@ -0,0 +1,133 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// buildValidator creates the rule that runs the validator_cmd property on the default outputs of
+// the module and writes validatorTimestamp when it succeeds.  The timestamp is a validation of the
+// install rules of the module, which are created before this, and a checkbuild file, so the
+// command runs whenever the module is built without delaying the rules that use its outputs.
+func (m *ModuleBase) buildValidator(ctx ModuleContext) {
+	if m.validatorTimestamp == nil {
+		return
+	}
+
+	var outputs Paths
+	if producer, ok := m.module.(OutputFileProducer); ok {
+		outputs, _ = producer.OutputFiles("")
+	}
+	if len(outputs) == 0 {
+		ctx.PropertyErrorf("validator_cmd", "the module has no outputs to validate")
+		return
+	}
+
+	srcs := make(map[string]Paths)
+	var allSrcs Paths
+	for _, src := range m.commonProperties.Validator_srcs {
+		paths := PathsForModuleSrc(ctx, []string{src})
+		srcs[src] = paths
+		allSrcs = append(allSrcs, paths...)
+	}
+
+	rule := NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command()
+	command, err := Expand(String(m.commonProperties.Validator_cmd), func(name string) (string, error) {
+		switch {
+		case name == "out":
+			return strings.Join(cmd.PathsForInputs(outputs), " "), nil
+		case strings.HasPrefix(name, "location "):
+			label := strings.TrimSpace(strings.TrimPrefix(name, "location "))
+			paths, ok := srcs[label]
+			if !ok {
+				return "", fmt.Errorf("unknown location label %q is not in validator_srcs", label)
+			} else if len(paths) != 1 {
+				return "", fmt.Errorf("label %q has %d files, expected exactly one", label, len(paths))
+			}
+			return cmd.PathForInput(paths[0]), nil
+		default:
+			return "", fmt.Errorf("unknown variable '$(%s)'", name)
+		}
+	})
+	if err != nil {
+		ctx.PropertyErrorf("validator_cmd", "%s", err.Error())
+		return
+	}
+
+	cmd.Text("(" + command + ")").Implicits(outputs).Implicits(allSrcs)
+	rule.Command().Text("touch").Output(m.validatorTimestamp)
+	rule.Build("validator", "validate "+ctx.ModuleName())
+
+	m.checkbuildFiles = append(m.checkbuildFiles, m.validatorTimestamp)
+}
+
+// buildMaxSizeCheck creates the rule that fails when the default output of the module is larger
+// than the max_size property, and writes maxSizeTimestamp otherwise.  Like the validator, the
+// timestamp is a validation of the install rules and a checkbuild file.
+func (m *ModuleBase) buildMaxSizeCheck(ctx ModuleContext) {
+	if m.maxSizeTimestamp == nil {
+		return
+	}
+
+	maxSize, err := parseSize(String(m.commonProperties.Max_size))
+	if err != nil {
+		ctx.PropertyErrorf("max_size", "%s", err.Error())
+		return
+	}
+
+	var outputs Paths
+	if producer, ok := m.module.(OutputFileProducer); ok {
+		outputs, _ = producer.OutputFiles("")
+	}
+	if len(outputs) != 1 {
+		ctx.PropertyErrorf("max_size", "the module must have exactly one default output, found %d", len(outputs))
+		return
+	}
+
+	rule := NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command()
+	cmd.Textf("size=$(wc -c < %s) &&", cmd.PathForInput(outputs[0])).
+		Textf(`if [ "$size" -gt %d ]; then`, maxSize).
+		Textf(`echo "%s: %s is $size bytes, larger than max_size: %s (%d bytes)" >&2; exit 1; fi`,
+			ctx.ModuleName(), outputs[0].Base(), String(m.commonProperties.Max_size), maxSize).
+		Implicit(outputs[0])
+	rule.Command().Text("touch").Output(m.maxSizeTimestamp)
+	rule.Build("max_size", "check the size of "+ctx.ModuleName())
+
+	m.checkbuildFiles = append(m.checkbuildFiles, m.maxSizeTimestamp)
+}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+// parseSize parses a number of bytes with an optional unit, like "4MB".
+func parseSize(s string) (int64, error) {
+	number := strings.TrimRightFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[len(number):]))]
+	size, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if !ok || err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a number of bytes with an optional unit B, KB, MB or GB", s)
+	}
+	return size * unit, nil
+}
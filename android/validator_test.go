@@ -0,0 +1,148 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestValidatorCmd(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		FixtureAddFile("check_size.sh", nil),
+	).RunTestWithBp(t, `
+		deps {
+			name: "foo",
+			validator_cmd: "$(location check_size.sh) --max $$MAX_SIZE $(out)",
+			validator_srcs: ["check_size.sh"],
+		}
+
+		deps {
+			name: "bar",
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	validator := foo.Output("validator.timestamp")
+	AssertStringDoesContain(t, "command", validator.RuleParams.Command,
+		"(check_size.sh --max $$MAX_SIZE out/soong/.intermediates/foo/android_common/foo) && touch out/soong/.intermediates/foo/android_common/validator.timestamp")
+	AssertPathsRelativeToTopEquals(t, "validator inputs",
+		[]string{"check_size.sh", "out/soong/.intermediates/foo/android_common/foo"}, validator.Implicits)
+
+	// The validation doesn't delay the modules that use the install, but runs with it.
+	install := foo.Output(filepath.Join("out/soong/target/product/test_device/system", "foo"))
+	AssertPathsRelativeToTopEquals(t, "install validations",
+		[]string{"out/soong/.intermediates/foo/android_common/validator.timestamp"}, install.Validations)
+	AssertStringListContains(t, "checkbuild files", foo.Module().base().checkbuildFiles.RelativeToTop().Strings(),
+		"out/soong/.intermediates/foo/android_common/validator.timestamp")
+
+	bar := result.ModuleForTests("bar", "android_common")
+	if rule := bar.MaybeOutput("validator.timestamp"); rule.Rule != nil {
+		t.Errorf("expected no validator for bar")
+	}
+	barInstall := bar.Output(filepath.Join("out/soong/target/product/test_device/system", "bar"))
+	AssertIntEquals(t, "bar install validations", 0, len(barInstall.Validations))
+}
+
+func TestValidatorCmdErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "unknown location",
+			bp: `
+				deps {
+					name: "foo",
+					validator_cmd: "$(location check.sh) $(out)",
+				}`,
+			err: `validator_cmd: unknown location label "check.sh" is not in validator_srcs`,
+		},
+		{
+			name: "unknown variable",
+			bp: `
+				deps {
+					name: "foo",
+					validator_cmd: "check $(in)",
+				}`,
+			err: `validator_cmd: unknown variable '$(in)'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForModuleTests,
+				PrepareForTestWithArchMutator,
+			).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.err))).
+				RunTestWithBp(t, tc.bp)
+		})
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+	).RunTestWithBp(t, `
+		deps {
+			name: "foo",
+			max_size: "4MB",
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	check := foo.Output("max_size.timestamp")
+	AssertStringDoesContain(t, "command", check.RuleParams.Command,
+		`size=$$(wc -c < out/soong/.intermediates/foo/android_common/foo) && if [ "$$size" -gt 4194304 ]; then`)
+	AssertStringDoesContain(t, "command", check.RuleParams.Command,
+		`echo "foo: foo is $$size bytes, larger than max_size: 4MB (4194304 bytes)" >&2; exit 1; fi`)
+
+	install := foo.Output(filepath.Join("out/soong/target/product/test_device/system", "foo"))
+	AssertPathsRelativeToTopEquals(t, "install validations",
+		[]string{"out/soong/.intermediates/foo/android_common/max_size.timestamp"}, install.Validations)
+	AssertStringListContains(t, "checkbuild files", foo.Module().base().checkbuildFiles.RelativeToTop().Strings(),
+		"out/soong/.intermediates/foo/android_common/max_size.timestamp")
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"100":    100,
+		"100B":   100,
+		"512KB":  512 << 10,
+		"4MB":    4 << 20,
+		"4 mb":   4 << 20,
+		"1GB":    1 << 30,
+		"0":      0,
+		"10 GB ": 10 << 30,
+	} {
+		got, err := parseSize(s)
+		if err != nil {
+			t.Errorf("parseSize(%q): unexpected error %s", s, err)
+		} else if got != want {
+			t.Errorf("parseSize(%q): want %d, got %d", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "4.5MB", "4TB", "-1", "4 M B"} {
+		if _, err := parseSize(s); err == nil {
+			t.Errorf("parseSize(%q): expected an error", s)
+		}
+	}
+}
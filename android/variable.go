@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 
 	"android/soong/android/soongconfig"
 	"android/soong/bazel"
@@ -178,6 +179,104 @@ type variableProperties struct {
 
 var defaultProductVariables interface{} = variableProperties{}
 
+// boolProductVariables is the list of product_variables fields added with
+// RegisterBoolProductVariable.
+var boolProductVariables []reflect.StructField
+
+// RegisterBoolProductVariable adds a boolean product variable called name that modules can use in
+// their product_variables property, e.g.
+//
+//	product_variables: {
+//	    my_feature: {
+//	        cflags: ["-DMY_FEATURE"],
+//	        shared_libs: ["libmy_feature"],
+//	    },
+//	},
+//
+// properties is a pointer to a struct containing the properties that the variable can set. A
+// module can only set the properties in it that the module itself has. When the variable is true
+// the properties are appended to the module's properties, in the same way as for the built in
+// product variables.
+//
+// Products set the variable in their makefiles through the bool_product_variables soong config
+// namespace:
+//
+//	SOONG_CONFIG_NAMESPACES += bool_product_variables
+//	SOONG_CONFIG_bool_product_variables += my_feature
+//	SOONG_CONFIG_bool_product_variables_my_feature := true
+//
+// The BoolVariables of the soong.variables file take precedence over the namespace.
+//
+// It must be called from an init() function, before any modules are created.
+func RegisterBoolProductVariable(name string, properties interface{}) {
+	if defaultProductVariablesInitialized {
+		panic(fmt.Errorf("product variable %q must be registered before any modules are created", name))
+	}
+
+	fieldName := proptools.FieldNameForProperty(name)
+	productVariablesType, _ := reflect.TypeOf(variableProperties{}).FieldByName("Product_variables")
+	_, isPropertyField := productVariablesType.Type.FieldByName(fieldName)
+	_, isConfigField := reflect.TypeOf(productVariables{}).FieldByName(fieldName)
+	if isPropertyField || isConfigField {
+		panic(fmt.Errorf("product variable %q is already defined", name))
+	}
+	for _, f := range boolProductVariables {
+		if f.Name == fieldName {
+			panic(fmt.Errorf("product variable %q is already registered", name))
+		}
+	}
+
+	typ := reflect.TypeOf(properties)
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("properties of product variable %q must be a pointer to a struct, not %s", name, typ))
+	}
+	boolProductVariables = append(boolProductVariables, reflect.StructField{
+		Name: fieldName,
+		Type: typ.Elem(),
+	})
+}
+
+// boolProductVariablesNamespace is the soong config namespace that products use to set the
+// variables added with RegisterBoolProductVariable.
+const boolProductVariablesNamespace = "bool_product_variables"
+
+// boolProductVariable returns the value of a variable added with RegisterBoolProductVariable, and
+// whether the product sets it.
+func boolProductVariable(config Config, name string) (value bool, ok bool) {
+	if value, ok := config.productVariables.BoolVariables[name]; ok {
+		return value, true
+	}
+	vars := config.VendorConfig(boolProductVariablesNamespace)
+	if vars.IsSet(name) {
+		return vars.Bool(name), true
+	}
+	return false, false
+}
+
+var (
+	defaultProductVariablesOnce        sync.Once
+	defaultProductVariablesInitialized bool
+)
+
+// productVariablesWithRegisteredVariables returns the default product_variables property struct,
+// extended with the variables added with RegisterBoolProductVariable.
+func productVariablesWithRegisteredVariables() interface{} {
+	defaultProductVariablesOnce.Do(func() {
+		defaultProductVariablesInitialized = true
+		if len(boolProductVariables) == 0 {
+			return
+		}
+		field, _ := reflect.TypeOf(defaultProductVariables).FieldByName("Product_variables")
+		var fields []reflect.StructField
+		for i := 0; i < field.Type.NumField(); i++ {
+			fields = append(fields, field.Type.Field(i))
+		}
+		field.Type = reflect.StructOf(append(fields, boolProductVariables...))
+		defaultProductVariables = reflect.New(reflect.StructOf([]reflect.StructField{field})).Elem().Interface()
+	})
+	return defaultProductVariables
+}
+
 type productVariables struct {
 	// Suffix to add to generated Makefiles
 	Make_suffix *string `json:",omitempty"`
@@ -302,6 +401,10 @@ type productVariables struct {
 	ProductPath   *string `json:",omitempty"`
 	SystemExtPath *string `json:",omitempty"`
 
+	// Ninja pools as <name>:<depth>, that soong_ui declares in the combined ninja file and that
+	// modules can run their memory-hungry actions in, like with the link_pool of cc modules.
+	NinjaPools []string `json:",omitempty"`
+
 	ClangTidy  *bool   `json:",omitempty"`
 	TidyChecks *string `json:",omitempty"`
 
@@ -375,6 +478,10 @@ type productVariables struct {
 
 	VendorVars map[string]map[string]string `json:",omitempty"`
 
+	// The values of the product variables added with RegisterBoolProductVariable. Products usually
+	// set them through the bool_product_variables soong config namespace instead.
+	BoolVariables map[string]bool `json:",omitempty"`
+
 	Ndk_abis *bool `json:",omitempty"`
 
 	Flatten_apex                 *bool `json:",omitempty"`
@@ -390,6 +497,11 @@ type productVariables struct {
 	CertificateOverrides         []string `json:",omitempty"`
 	PackageNameOverrides         []string `json:",omitempty"`
 
+	// Names of modules that have both a source and a prebuilt module, for which this product
+	// always uses the prebuilt or the source module regardless of the prebuilt's prefer property.
+	PreferPrebuiltModules []string `json:",omitempty"`
+	PreferSourceModules   []string `json:",omitempty"`
+
 	ApexGlobalMinSdkVersionOverride *string `json:",omitempty"`
 
 	EnforceSystemCertificate          *bool    `json:",omitempty"`
@@ -1024,6 +1136,12 @@ func VariableMutator(mctx BottomUpMutatorContext) {
 
 		// Check that the variable was set for the product
 		val := productVariables.FieldByName(name)
+		if !val.IsValid() {
+			// The variable was added with RegisterBoolProductVariable.
+			if set, ok := boolProductVariable(mctx.Config(), proptools.PropertyNameForField(name)); ok {
+				val = reflect.ValueOf(&set)
+			}
+		}
 		if !val.IsValid() || val.Kind() != reflect.Ptr || val.IsNil() {
 			continue
 		}
@@ -1161,7 +1279,7 @@ func initProductVariableModule(m Module) {
 
 	// Allow tests to override the default product variables
 	if base.variableProperties == nil {
-		base.variableProperties = defaultProductVariables
+		base.variableProperties = productVariablesWithRegisteredVariables()
 	}
 	// Filter the product variables properties to the ones that exist on this module
 	base.variableProperties = createVariableProperties(m.GetProperties(), base.variableProperties)
@@ -307,6 +307,117 @@ func TestProductVariablesDefaults(t *testing.T) {
 	AssertDeepEquals(t, "foo", want, foo.properties.Foo)
 }
 
+func init() {
+	RegisterBoolProductVariable("test_bool_variable", &struct {
+		Cflags      []string
+		Shared_libs []string
+	}{})
+}
+
+type boolProductVariableTestModule struct {
+	ModuleBase
+	properties struct {
+		Cflags []string
+	}
+}
+
+func (m *boolProductVariableTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func boolProductVariableTestModuleFactory() Module {
+	m := &boolProductVariableTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func TestBoolProductVariables(t *testing.T) {
+	bp := `
+		test {
+			name: "foo",
+			cflags: ["-DFOO"],
+			product_variables: {
+				test_bool_variable: {
+					cflags: ["-DTEST_BOOL_VARIABLE"],
+				},
+			},
+		}
+	`
+
+	prepareForTest := GroupFixturePreparers(
+		PrepareForTestWithVariables,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test", boolProductVariableTestModuleFactory)
+		}),
+	)
+
+	for _, tc := range []struct {
+		name       string
+		variables  map[string]bool
+		vendorVars map[string]string
+		expected   []string
+	}{
+		{
+			name:     "unset",
+			expected: []string{"-DFOO"},
+		},
+		{
+			name:      "false",
+			variables: map[string]bool{"test_bool_variable": false},
+			expected:  []string{"-DFOO"},
+		},
+		{
+			name:      "true",
+			variables: map[string]bool{"test_bool_variable": true},
+			expected:  []string{"-DFOO", "-DTEST_BOOL_VARIABLE"},
+		},
+		{
+			name:       "soong config namespace",
+			vendorVars: map[string]string{"test_bool_variable": "true"},
+			expected:   []string{"-DFOO", "-DTEST_BOOL_VARIABLE"},
+		},
+		{
+			name:       "overridden by BoolVariables",
+			variables:  map[string]bool{"test_bool_variable": false},
+			vendorVars: map[string]string{"test_bool_variable": "true"},
+			expected:   []string{"-DFOO"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := GroupFixturePreparers(
+				prepareForTest,
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.BoolVariables = tc.variables
+					if tc.vendorVars != nil {
+						variables.VendorVars = map[string]map[string]string{
+							"bool_product_variables": tc.vendorVars,
+						}
+					}
+				}),
+			).RunTestWithBp(t, bp)
+
+			foo := result.ModuleForTests("foo", "").Module().(*boolProductVariableTestModule)
+			AssertDeepEquals(t, "cflags", tc.expected, foo.properties.Cflags)
+		})
+	}
+
+	t.Run("unknown variable", func(t *testing.T) {
+		prepareForTest.
+			ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`unrecognized property "product_variables.unknown_variable`)).
+			RunTestWithBp(t, `
+				test {
+					name: "foo",
+					product_variables: {
+						unknown_variable: {
+							cflags: ["-DUNKNOWN"],
+						},
+					},
+				}
+			`)
+	})
+}
+
 func BenchmarkSliceToTypeArray(b *testing.B) {
 	for _, n := range []int{1, 2, 4, 8, 100} {
 		var propStructs []interface{}
@@ -162,6 +162,32 @@ func (r subpackagesRule) String() string {
 	return fmt.Sprintf("//%s:__subpackages__", r.pkgPrefix)
 }
 
+// An apexRule is a visibility rule that matches modules that are part of a specific APEX. Whether
+// a module is part of an APEX is not known until the apex mutators have run so it never matches a
+// qualifiedModuleName on its own, see visibilityApexRuleEnforcer.
+type apexRule struct {
+	apex string
+}
+
+func (r apexRule) matches(_ qualifiedModuleName) bool {
+	return false
+}
+
+func (r apexRule) String() string {
+	return fmt.Sprintf("//apex:%s", r.apex)
+}
+
+// apexRules returns the names of the APEXes that the apexRules in c make the module visible to.
+func (c compositeRule) apexRules() []string {
+	var apexes []string
+	for _, r := range c {
+		if a, ok := r.(apexRule); ok {
+			apexes = append(apexes, a.apex)
+		}
+	}
+	return apexes
+}
+
 // visibilityRule for //visibility:public
 type publicRule struct{}
 
@@ -209,6 +235,7 @@ func registerVisibilityMutators(ctx RegistrationContext) {
 	ctx.PreArchMutators(RegisterVisibilityRuleChecker)
 	ctx.PreArchMutators(RegisterVisibilityRuleGatherer)
 	ctx.PostDepsMutators(RegisterVisibilityRuleEnforcer)
+	ctx.FinalDepsMutators(RegisterVisibilityApexRuleEnforcer)
 }
 
 // The rule checker needs to be registered before defaults expansion to correctly check that
@@ -232,6 +259,12 @@ func RegisterVisibilityRuleEnforcer(ctx RegisterMutatorsContext) {
 	ctx.TopDown("visibilityRuleEnforcer", visibilityRuleEnforcer).Parallel()
 }
 
+// Registers the function that enforces //apex:<name> rules. This must be registered after the apex
+// mutators have run so that the ApexInfo of each module is available.
+func RegisterVisibilityApexRuleEnforcer(ctx RegisterMutatorsContext) {
+	ctx.TopDown("visibilityApexRuleEnforcer", visibilityApexRuleEnforcer).Parallel()
+}
+
 // Checks the per-module visibility rule lists before defaults expansion.
 func visibilityRuleChecker(ctx BottomUpMutatorContext) {
 	qualified := createQualifiedModuleName(ctx)
@@ -261,6 +294,13 @@ func checkRules(ctx BaseModuleContext, currentPkg, property string, visibility [
 			continue
 		}
 
+		if pkg == "apex" {
+			if name == "__pkg__" || name == "__subpackages__" {
+				ctx.PropertyErrorf(property, "%q must specify an APEX, e.g. //apex:com.android.foo", v)
+			}
+			continue
+		}
+
 		if pkg == "visibility" {
 			switch name {
 			case "private", "public":
@@ -353,6 +393,8 @@ func parseRules(ctx BaseModuleContext, currentPkg, property string, visibility [
 				// This does not actually create a rule so continue onto the next rule.
 				continue
 			}
+		} else if pkg == "apex" {
+			r = apexRule{name}
 		} else {
 			switch name {
 			case "__pkg__":
@@ -455,12 +497,69 @@ func visibilityRuleEnforcer(ctx TopDownMutatorContext) {
 		}
 
 		rule := effectiveVisibilityRules(ctx.Config(), depQualified)
+		if len(rule.apexRules()) > 0 {
+			// Whether this module is in the APEX is not known yet, leave the check to
+			// visibilityApexRuleEnforcer.
+			return
+		}
 		if !rule.matches(qualified) {
 			ctx.ModuleErrorf("depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
 		}
 	})
 }
 
+// Checks the dependencies on modules whose visibility contains //apex:<name> rules. A module can
+// see such a dependency if any of the non-APEX rules match it, if it is part of one of the named
+// APEXes or if it is one of the named APEX modules.
+func visibilityApexRuleEnforcer(ctx TopDownMutatorContext) {
+	if _, ok := ctx.Module().(Module); !ok {
+		return
+	}
+
+	// A platform variant that is not available to the platform is never built, so do not check
+	// its dependencies.
+	if am, ok := ctx.Module().(ApexModule); ok && am.NotAvailableForPlatform() {
+		return
+	}
+
+	qualified := createQualifiedModuleName(ctx)
+	apexInfo := ctx.Provider(ApexInfoProvider).(ApexInfo)
+
+	ctx.VisitDirectDeps(func(dep Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		if _, ok := tag.(ExcludeFromVisibilityEnforcementTag); ok {
+			return
+		}
+
+		depName := ctx.OtherModuleName(dep)
+		depDir := ctx.OtherModuleDir(dep)
+		depQualified := qualifiedModuleName{depDir, depName}
+
+		if depQualified.pkg == qualified.pkg {
+			return
+		}
+
+		rule := effectiveVisibilityRules(ctx.Config(), depQualified)
+		apexes := rule.apexRules()
+		if len(apexes) == 0 || rule.matches(qualified) {
+			return
+		}
+		for _, apex := range apexes {
+			if apex == ctx.ModuleName() || apexInfo.InApexVariant(apex) || apexInfo.InApexModule(apex) {
+				return
+			}
+		}
+
+		if apexInfo.IsForPlatform() {
+			ctx.ModuleErrorf("depends on %s which is only visible to modules in the APEXes %q, but %s is not in an APEX",
+				depQualified, apexes, qualified)
+		} else {
+			ctx.ModuleErrorf("depends on %s which is only visible to modules in the APEXes %q, but %s is only in %q",
+				depQualified, apexes, qualified, apexInfo.InApexVariants)
+		}
+	})
+}
+
 // Default visibility is public.
 var defaultVisibility = compositeRule{publicRule{}}
 
@@ -1137,6 +1137,54 @@ var visibilityTests = []struct {
 				}`),
 		},
 	},
+	{
+		name: "//apex: must specify an APEX",
+		fs: MockFS{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//apex"],
+				}`),
+		},
+		expectedErrors: []string{`visibility: "//apex" must specify an APEX`},
+	},
+	{
+		name: "//apex: not visible to platform modules",
+		fs: MockFS{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//apex:com.android.foo"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libother": depends on //top:libexample which is only visible to modules in the` +
+				` APEXes \["com.android.foo"\], but //other:libother is not in an APEX`,
+		},
+	},
+	{
+		name: "//apex: composes with other rules",
+		fs: MockFS{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//apex:com.android.foo", "//other"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+		},
+		effectiveVisibility: map[qualifiedModuleName][]string{
+			qualifiedModuleName{pkg: "top", name: "libexample"}: {"//apex:com.android.foo", "//other"},
+		},
+	},
 }
 
 func TestVisibility(t *testing.T) {
@@ -0,0 +1,73 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"path/filepath"
+	"text/scanner"
+
+	"github.com/google/blueprint/parser"
+)
+
+// modulePositions maps the names of the modules defined in a Blueprint file to the positions of
+// their definitions.
+type modulePositions map[string]scanner.Position
+
+// modulePosition returns the position of the definition of m as "file:line:col", for the warnings
+// that singletons print themselves, as only errors are reported with a position by blueprint. It
+// returns the Blueprint file if the module isn't defined in it, e.g. when it was created by a load
+// hook.
+func modulePosition(ctx SingletonContext, m Module) string {
+	file := ctx.BlueprintFile(m)
+
+	type onceKeyType string
+	key := NewCustomOnceKey(onceKeyType("module_positions:" + filepath.Clean(file)))
+	positions := ctx.Config().Once(key, func() interface{} {
+		return parseModulePositions(ctx.Config(), file)
+	}).(modulePositions)
+
+	if pos, ok := positions[m.base().BaseModuleName()]; ok {
+		return pos.String()
+	}
+	return file
+}
+
+// parseModulePositions returns the positions of the modules with a literal name in file. The file
+// has already been parsed successfully by blueprint, so errors only result in missing positions.
+func parseModulePositions(config Config, file string) modulePositions {
+	positions := make(modulePositions)
+	r, err := config.fs.Open(file)
+	if err != nil {
+		return positions
+	}
+	defer r.Close()
+
+	tree, errs := parser.Parse(file, r, parser.NewScope(nil))
+	if len(errs) > 0 {
+		return positions
+	}
+	for _, def := range tree.Defs {
+		module, ok := def.(*parser.Module)
+		if !ok {
+			continue
+		}
+		if prop, ok := module.GetProperty("name"); ok {
+			if name, ok := prop.Value.(*parser.String); ok {
+				positions[name.Value] = module.TypePos
+			}
+		}
+	}
+	return positions
+}
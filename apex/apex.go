@@ -159,6 +159,12 @@ type apexBundleProperties struct {
 	// or else conflicting build rules may be created.
 	Multi_install_skip_symbol_files *bool
 
+	// Whether the files in this APEX keep the modification times of the files they are copied
+	// from. By default the modification times of all files in the APEX are reset to the epoch
+	// so that building the same APEX twice produces identical outputs. Only supported by image
+	// APEXes. Default is false.
+	Preserve_timestamps *bool
+
 	// The type of APEX to build. Controls what the APEX payload is. Either 'image', 'zip' or
 	// 'both'. When set to image, contents are stored in a filesystem image inside a zip
 	// container. When set to zip, contents are stored in a zip container directly. This type is
@@ -190,6 +196,11 @@ type apexBundleProperties struct {
 	// with the tool to sign payload contents.
 	Custom_sign_tool *string
 
+	// List of license_kind modules, e.g. SPDX-license-identifier-GPL-2.0, that must not reach this
+	// APEX. Each entry must name an existing license_kind module. It is an error if a module included
+	// in the APEX, directly or transitively, has one of these license kinds. Dependencies that are only used as a toolchain are not checked.
+	Forbidden_license_kinds []string
+
 	// Canonical name of this APEX bundle. Used to determine the path to the
 	// activated APEX on device (i.e. /apex/<apexVariationName>), and used for the
 	// apex mutator variations. For override_apex modules, this is the name of the
@@ -624,6 +635,7 @@ var (
 	javaLibTag      = dependencyTag{name: "javaLib", payload: true}
 	jniLibTag       = dependencyTag{name: "jniLib", payload: true}
 	keyTag          = dependencyTag{name: "key"}
+	licenseKindTag  = dependencyTag{name: "licenseKind"}
 	prebuiltTag     = dependencyTag{name: "prebuilt", payload: true}
 	rroTag          = dependencyTag{name: "rro", payload: true}
 	sharedLibTag    = dependencyTag{name: "sharedLib", payload: true}
@@ -784,6 +796,9 @@ func (a *apexBundle) DepsMutator(ctx android.BottomUpMutatorContext) {
 	commonVariation := ctx.Config().AndroidCommonTarget.Variations()
 	ctx.AddFarVariationDependencies(commonVariation, fsTag, a.properties.Filesystems...)
 	ctx.AddFarVariationDependencies(commonVariation, compatConfigTag, a.properties.Compat_configs...)
+
+	// license_kind modules have no variations.
+	ctx.AddVariationDependencies(nil, licenseKindTag, a.properties.Forbidden_license_kinds...)
 }
 
 // DepsMutator for the overridden properties.
@@ -1779,6 +1794,7 @@ func (a *apexBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	////////////////////////////////////////////////////////////////////////////////////////////
 	// 1) do some validity checks such as apex_available, min_sdk_version, etc.
 	a.checkApexAvailability(ctx)
+	a.checkForbiddenLicenseKinds(ctx)
 	a.checkUpdatable(ctx)
 	a.CheckMinSdkVersion(ctx)
 	a.checkStaticLinkingToStubLibraries(ctx)
@@ -2611,6 +2627,44 @@ func (a *apexBundle) checkJavaStableSdkVersion(ctx android.ModuleContext) {
 	})
 }
 
+// checkForbiddenLicenseKinds ensures that none of the modules included in the APEX have a license
+// kind listed in the forbidden_license_kinds property.
+func (a *apexBundle) checkForbiddenLicenseKinds(ctx android.ModuleContext) {
+	var forbidden []string
+	for _, dep := range ctx.GetDirectDepsWithTag(licenseKindTag) {
+		if !android.IsLicenseKindModule(dep) {
+			ctx.PropertyErrorf("forbidden_license_kinds", "%q is not a license_kind module", ctx.OtherModuleName(dep))
+			continue
+		}
+		forbidden = append(forbidden, ctx.OtherModuleName(dep))
+	}
+	if len(forbidden) == 0 {
+		return
+	}
+
+	a.WalkPayloadDeps(ctx, func(ctx android.ModuleContext, from blueprint.Module, to android.ApexModule, externalDep bool) bool {
+		// Modules outside of the APEX are not part of it so their licenses don't reach it.
+		if externalDep {
+			return false
+		}
+		if am, ok := from.(android.DepIsInSameApex); ok && !am.DepIsInSameApex(ctx, to) {
+			return false
+		}
+		// Neither do the licenses of toolchains, e.g. a compiler used to build a module.
+		if android.HasLicenseAnnotation(ctx.OtherModuleDependencyTag(to), android.LicenseAnnotationToolchain) {
+			return false
+		}
+
+		for _, kind := range to.EffectiveLicenseKinds() {
+			if android.InList(kind, forbidden) {
+				ctx.PropertyErrorf("forbidden_license_kinds", "%q has the forbidden license kind %q."+
+					"\n\nDependency path:%s", ctx.OtherModuleName(to), kind, ctx.GetPathString(true))
+			}
+		}
+		return true
+	})
+}
+
 // checkApexAvailability ensures that the all the dependencies are marked as available for this APEX.
 func (a *apexBundle) checkApexAvailability(ctx android.ModuleContext) {
 	// Let's be practical. Availability for test, host, and the VNDK apex isn't important
@@ -1045,6 +1045,147 @@ func TestApexCanUsePrivateApis(t *testing.T) {
 	ensureContains(t, rustDeps, "libfoo.shared_from_rust/android_arm64_armv8-a_shared/libfoo.shared_from_rust.so")
 }
 
+func TestApexReproducible(t *testing.T) {
+	t.Parallel()
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			native_shared_libs: ["mylib", "mylib2"],
+			updatable: false,
+		}
+
+		apex {
+			name: "otherapex",
+			key: "myapex.key",
+			native_shared_libs: ["mylib2", "mylib"],
+			updatable: false,
+		}
+
+		apex {
+			name: "timestampsapex",
+			key: "myapex.key",
+			native_shared_libs: ["mylib"],
+			preserve_timestamps: true,
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		cc_library {
+			name: "mylib",
+			srcs: ["mylib.cpp"],
+			system_shared_libs: [],
+			stl: "none",
+			apex_available: ["myapex", "otherapex", "timestampsapex"],
+		}
+
+		cc_library {
+			name: "mylib2",
+			srcs: ["mylib.cpp"],
+			system_shared_libs: [],
+			stl: "none",
+			apex_available: ["myapex", "otherapex"],
+		}
+	`)
+
+	copyCmds := ctx.ModuleForTests("myapex", "android_common_myapex_image").Rule("apexRule").Args["copy_commands"]
+	otherCopyCmds := ctx.ModuleForTests("otherapex", "android_common_otherapex_image").Rule("apexRule").Args["copy_commands"]
+
+	// The files are copied in the same order regardless of the order of the properties.
+	android.AssertStringEquals(t, "copy commands", copyCmds, strings.ReplaceAll(otherCopyCmds, "otherapex", "myapex"))
+
+	// The modification times are reset after everything is copied.
+	ensureContains(t, copyCmds, "cp -f ")
+	if !strings.HasSuffix(copyCmds, "image.apex -exec touch -h -d @0 {} +") {
+		t.Errorf("expected copy commands to end by resetting the timestamps, got %q", copyCmds)
+	}
+
+	timestampsCopyCmds := ctx.ModuleForTests("timestampsapex", "android_common_timestampsapex_image").Rule("apexRule").Args["copy_commands"]
+	ensureContains(t, timestampsCopyCmds, "cp -f --preserve=timestamps ")
+	ensureNotContains(t, timestampsCopyCmds, "touch")
+}
+
+func TestApexReproducibleAcrossBuilds(t *testing.T) {
+	t.Parallel()
+	bp := func(libs string) string {
+		return `
+			apex {
+				name: "myapex",
+				key: "myapex.key",
+				native_shared_libs: ` + libs + `,
+				payload_type: "both",
+				updatable: false,
+			}
+
+			apex_key {
+				name: "myapex.key",
+				public_key: "testkey.avbpubkey",
+				private_key: "testkey.pem",
+			}
+
+			cc_library {
+				name: "mylib",
+				srcs: ["mylib.cpp"],
+				system_shared_libs: [],
+				stl: "none",
+				apex_available: ["myapex"],
+			}
+
+			cc_library {
+				name: "mylib2",
+				srcs: ["mylib.cpp"],
+				system_shared_libs: [],
+				stl: "none",
+				apex_available: ["myapex"],
+			}
+		`
+	}
+
+	// Build the APEX twice, listing its contents in a different order, and compare the rules
+	// that create the image and the zip payloads.
+	first := testApex(t, bp(`["mylib", "mylib2"]`))
+	second := testApex(t, bp(`["mylib2", "mylib"]`))
+
+	for _, tc := range []struct{ variant, rule string }{
+		{"android_common_myapex_image", "apexRule"},
+		{"android_common_myapex_zip", "zipApexRule"},
+	} {
+		firstRule := first.ModuleForTests("myapex", tc.variant).Rule(tc.rule)
+		secondRule := second.ModuleForTests("myapex", tc.variant).Rule(tc.rule)
+		android.AssertDeepEquals(t, tc.rule+" args", firstRule.Args, secondRule.Args)
+		android.AssertDeepEquals(t, tc.rule+" implicits",
+			firstRule.Implicits.Strings(), secondRule.Implicits.Strings())
+		if !strings.HasSuffix(firstRule.Args["copy_commands"], "-exec touch -h -d @0 {} +") {
+			t.Errorf("%s: expected the copy commands to reset the timestamps, got %q",
+				tc.rule, firstRule.Args["copy_commands"])
+		}
+	}
+}
+
+func TestApexPreserveTimestampsZip(t *testing.T) {
+	t.Parallel()
+	testApexError(t, `preserve_timestamps: is not supported by zip APEXes`, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			payload_type: "zip",
+			preserve_timestamps: true,
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+	`)
+}
+
 func TestApexWithStubsWithMinSdkVersion(t *testing.T) {
 	t.Parallel()
 	ctx := testApex(t, `
@@ -3404,6 +3545,8 @@ func getFiles(t *testing.T, ctx *android.TestContext, moduleName, variant string
 		var isLink bool
 		switch terms[0] {
 		case "mkdir":
+		case "find":
+			// Resets the modification times of the files in the image.
 		case "cp":
 			if len(terms) != 3 && len(terms) != 4 {
 				t.Fatal("copyCmds contains invalid cp command", cmd)
@@ -3420,7 +3563,7 @@ func getFiles(t *testing.T, ctx *android.TestContext, moduleName, variant string
 			src = terms[len(terms)-2]
 			isLink = true
 		default:
-			t.Fatalf("copyCmds should contain mkdir/cp commands only: %q", cmd)
+			t.Fatalf("copyCmds should contain mkdir/cp/ln/find commands only: %q", cmd)
 		}
 		if dst != "" {
 			index := strings.Index(dst, imageApexDir)
@@ -5891,6 +6034,175 @@ func TestApexPropertiesShouldBeDefaultable(t *testing.T) {
 	}`)
 }
 
+func TestApexVisibility(t *testing.T) {
+	bp := `
+	apex {
+		name: "myapex",
+		key: "myapex.key",
+		native_shared_libs: ["libfoo"],
+		updatable: false,
+	}
+
+	apex_key {
+		name: "myapex.key",
+		public_key: "testkey.avbpubkey",
+		private_key: "testkey.pem",
+	}
+
+	cc_library {
+		name: "libfoo",
+		shared_libs: ["libbar"],
+		system_shared_libs: [],
+		stl: "none",
+		apex_available: ["myapex"],
+	}
+	`
+	libbar := withFiles(android.MockFS{
+		"bar/Android.bp": []byte(`
+			cc_library {
+				name: "libbar",
+				system_shared_libs: [],
+				stl: "none",
+				visibility: ["//apex:myapex"],
+				apex_available: ["//apex_available:platform", "myapex", "otherapex"],
+			}
+		`),
+	})
+
+	t.Run("in apex", func(t *testing.T) {
+		testApex(t, bp, libbar)
+	})
+
+	t.Run("platform", func(t *testing.T) {
+		testApexError(t, `module "libplatform".*: depends on //bar:libbar which is only visible to modules in`+
+			` the APEXes \["myapex"\], but //:libplatform is not in an APEX`, bp+`
+		cc_library {
+			name: "libplatform",
+			shared_libs: ["libbar"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+		`, libbar)
+	})
+
+	t.Run("other apex", func(t *testing.T) {
+		testApexError(t, `module "libother".*: depends on //bar:libbar which is only visible to modules in`+
+			` the APEXes \["myapex"\], but //:libother is only in \["otherapex"\]`, bp+`
+		apex {
+			name: "otherapex",
+			key: "myapex.key",
+			native_shared_libs: ["libother"],
+			updatable: false,
+		}
+
+		cc_library {
+			name: "libother",
+			shared_libs: ["libbar"],
+			system_shared_libs: [],
+			stl: "none",
+			apex_available: ["otherapex"],
+		}
+		`, libbar)
+	})
+}
+
+func TestApexForbiddenLicenseKinds(t *testing.T) {
+	bp := `
+	apex {
+		name: "myapex",
+		key: "myapex.key",
+		native_shared_libs: ["libfoo"],
+		updatable: false,
+		forbidden_license_kinds: ["SPDX-license-identifier-GPL-2.0"],
+	}
+
+	apex_key {
+		name: "myapex.key",
+		public_key: "testkey.avbpubkey",
+		private_key: "testkey.pem",
+	}
+
+	license_kind {
+		name: "SPDX-license-identifier-GPL-2.0",
+		conditions: ["restricted"],
+	}
+
+	license_kind {
+		name: "SPDX-license-identifier-Apache-2.0",
+		conditions: ["notice"],
+	}
+
+	license {
+		name: "gpl_license",
+		license_kinds: ["SPDX-license-identifier-GPL-2.0"],
+	}
+
+	license {
+		name: "apache_license",
+		license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+	}
+
+	cc_library {
+		name: "libfoo",
+		static_libs: ["libbar"],
+		system_shared_libs: [],
+		stl: "none",
+		licenses: ["apache_license"],
+		apex_available: ["myapex"],
+	}
+	`
+
+	t.Run("allowed", func(t *testing.T) {
+		testApex(t, bp+`
+		cc_library {
+			name: "libbar",
+			system_shared_libs: [],
+			stl: "none",
+			licenses: ["apache_license"],
+			apex_available: ["myapex"],
+		}
+		`, android.PrepareForTestWithLicenses)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		testApexError(t, `forbidden_license_kinds: "libbar" has the forbidden license kind "SPDX-license-identifier-GPL-2.0"`, bp+`
+		cc_library {
+			name: "libbar",
+			system_shared_libs: [],
+			stl: "none",
+			licenses: ["gpl_license"],
+			apex_available: ["myapex"],
+		}
+		`, android.PrepareForTestWithLicenses)
+	})
+
+	t.Run("not a license_kind", func(t *testing.T) {
+		testApexError(t, `forbidden_license_kinds: "gpl_license" is not a license_kind module`,
+			strings.Replace(bp, `forbidden_license_kinds: ["SPDX-license-identifier-GPL-2.0"]`,
+				`forbidden_license_kinds: ["gpl_license"]`, 1)+`
+		cc_library {
+			name: "libbar",
+			system_shared_libs: [],
+			stl: "none",
+			apex_available: ["myapex"],
+		}
+		`, android.PrepareForTestWithLicenses)
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		testApexError(t, `depends on undefined module "SPDX-license-identifier-GPL-3.0"`,
+			strings.Replace(bp, `forbidden_license_kinds: ["SPDX-license-identifier-GPL-2.0"]`,
+				`forbidden_license_kinds: ["SPDX-license-identifier-GPL-3.0"]`, 1)+`
+		cc_library {
+			name: "libbar",
+			system_shared_libs: [],
+			stl: "none",
+			apex_available: ["myapex"],
+		}
+		`, android.PrepareForTestWithLicenses)
+	})
+}
+
 func TestApexAvailable_DirectDep(t *testing.T) {
 	// libfoo is not available to myapex, but only to otherapex
 	testApexError(t, "requires \"libfoo\" that doesn't list the APEX under 'apex_available'.", `
@@ -406,6 +406,17 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext) {
 	// set of dependency module:location mappings
 	installMapSet := make(map[string]bool)
 
+	preserveTimestamps := proptools.Bool(a.properties.Preserve_timestamps)
+	if preserveTimestamps && a.properties.ApexType == zipApex {
+		// apexer zips the payload of zip APEXes itself, without the modification times of the files.
+		ctx.PropertyErrorf("preserve_timestamps", "is not supported by zip APEXes, only by payload_type \"image\"")
+		return
+	}
+	cp := "cp -f "
+	if preserveTimestamps {
+		cp = "cp -f --preserve=timestamps "
+	}
+
 	// TODO(jiyong): use the RuleBuilder
 	var copyCommands []string
 	var implicitInputs []android.Path
@@ -438,7 +449,7 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext) {
 						fi.stem(), fi.builtFile, fi.module.(*java.AndroidAppSet).PackedAdditionalOutputs())
 				}
 			} else {
-				copyCommands = append(copyCommands, "cp -f "+fi.builtFile.String()+" "+destPath)
+				copyCommands = append(copyCommands, cp+fi.builtFile.String()+" "+destPath)
 				if installSymbolFiles {
 					installedPath = ctx.InstallFile(pathWhenActivated.Join(ctx, fi.installDir), fi.stem(), fi.builtFile)
 				}
@@ -473,12 +484,18 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext) {
 
 			dataDest := imageDir.Join(ctx, fi.apexRelativePath(relPath), d.RelativeInstallPath).String()
 
-			copyCommands = append(copyCommands, "cp -f "+d.SrcPath.String()+" "+dataDest)
+			copyCommands = append(copyCommands, cp+d.SrcPath.String()+" "+dataDest)
 			implicitInputs = append(implicitInputs, d.SrcPath)
 		}
 
 		installMapSet[installMapPath.String()+":"+fi.installDir+"/"+fi.builtFile.Base()] = true
 	}
+
+	// Reset the modification times of everything in the image directory, including the
+	// directories and symlinks, so that the APEX doesn't depend on when the files were built.
+	if !preserveTimestamps {
+		copyCommands = append(copyCommands, "find "+imageDir.String()+" -exec touch -h -d @0 {} +")
+	}
 	implicitInputs = append(implicitInputs, a.manifestPbOut)
 	if installSymbolFiles {
 		installedManifest := ctx.InstallFile(pathWhenActivated, "apex_manifest.pb", a.manifestPbOut)
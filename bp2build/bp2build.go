@@ -46,6 +46,12 @@ func Codegen(ctx *CodegenContext) CodegenMetrics {
 	soongInjectionDir := android.PathForOutput(ctx, bazel.SoongInjectionDirName)
 	writeFiles(ctx, soongInjectionDir, CreateSoongInjectionFiles(ctx.Config(), res.metrics))
 
+	// The conversion report lives outside of the bp2build directory, as it isn't a Bazel file.
+	reportPath := android.PathForOutput(ctx, bp2buildConversionReportFilename)
+	if err := writeFile(ctx, reportPath, res.metrics.ConversionReport()); err != nil {
+		panic(fmt.Errorf("Failed to write %q due to %q", reportPath, err))
+	}
+
 	return res.metrics
 }
 
@@ -1033,6 +1033,68 @@ filegroup { name: "opt-out-h", bazel_module: { bp2build_available: false } }
 	}
 }
 
+func TestConversionReport(t *testing.T) {
+	bp := `
+filegroup {
+    name: "converted",
+    bazel_module: { bp2build_available: true },
+}
+
+filegroup {
+    name: "not_allowlisted",
+    bazel_module: { bp2build_available: false },
+}
+
+custom_test {
+    name: "unsupported_module_type",
+}
+`
+	config := android.TestConfig(buildDir, nil, bp, nil)
+	ctx := android.NewTestContext(config)
+	ctx.RegisterModuleType("filegroup", android.FileGroupFactory)
+	ctx.RegisterModuleType("custom_test", customTestModuleFactory)
+	ctx.RegisterForBazelConversion()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.ResolveDependencies(config)
+	android.FailIfErrored(t, errs)
+
+	codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+	res, errs := GenerateBazelTargets(codegenCtx, false)
+	android.FailIfErrored(t, errs)
+
+	android.AssertStringEquals(t, "conversion report", `{
+  "modules": [
+    {
+      "name": "converted",
+      "type": "filegroup",
+      "dir": ".",
+      "converted": true
+    },
+    {
+      "name": "not_allowlisted",
+      "type": "filegroup",
+      "dir": ".",
+      "converted": false,
+      "reason": {
+        "type": "not_allowlisted"
+      }
+    },
+    {
+      "name": "unsupported_module_type",
+      "type": "custom_test",
+      "dir": ".",
+      "converted": false,
+      "reason": {
+        "type": "unsupported_module_type"
+      }
+    }
+  ]
+}
+`, res.metrics.ConversionReport())
+}
+
 func TestCombineBuildFilesBp2buildTargets(t *testing.T) {
 	testCases := []bp2buildTestCase{
 		{
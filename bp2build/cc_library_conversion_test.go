@@ -2457,3 +2457,87 @@ func TestCcLibraryEscapeLdflags(t *testing.T) {
 		}),
 	})
 }
+
+func TestCcLibraryStl(t *testing.T) {
+	type testCase struct {
+		desc          string
+		stl           string
+		expectedAttrs attrNameToString
+	}
+
+	testCases := []testCase{
+		{
+			desc:          "default",
+			stl:           "",
+			expectedAttrs: attrNameToString{},
+		},
+		{
+			desc:          "explicit",
+			stl:           `stl: "libc++_static",`,
+			expectedAttrs: attrNameToString{"stl": `"libc++_static"`},
+		},
+		{
+			desc: "arch variant",
+			stl: `stl: "libc++",
+    arch: {
+        arm: {
+            stl: "none",
+        },
+    },`,
+			expectedAttrs: attrNameToString{"stl": `select({
+        "//build/bazel/platforms/arch:arm": "none",
+        "//conditions:default": "libc++",
+    })`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			runCcLibraryTestCase(t, bp2buildTestCase{
+				moduleTypeUnderTest:        "cc_library",
+				moduleTypeUnderTestFactory: cc.LibraryFactory,
+				blueprint: soongCcLibraryPreamble + fmt.Sprintf(`
+cc_library {
+    name: "foo",
+    %s
+    include_build_directory: false,
+}`, tc.stl),
+				expectedBazelTargets: makeCcLibraryTargets("foo", tc.expectedAttrs),
+			})
+		})
+	}
+}
+
+func TestCcLibraryUnsupportedStl(t *testing.T) {
+	runCcLibraryTestCase(t, bp2buildTestCase{
+		moduleTypeUnderTest:        "cc_library",
+		moduleTypeUnderTestFactory: cc.LibraryFactory,
+		blueprint: soongCcLibraryPreamble + `
+cc_library {
+    name: "foo",
+    stl: "libstdc++",
+    include_build_directory: false,
+}`,
+		expectedBazelTargets: []string{},
+	})
+}
+
+func TestCcLibraryUnsupportedArchStl(t *testing.T) {
+	runCcLibraryTestCase(t, bp2buildTestCase{
+		description:                "cc_library with an unsupported stl for one arch is not converted",
+		moduleTypeUnderTest:        "cc_library",
+		moduleTypeUnderTestFactory: cc.LibraryFactory,
+		blueprint: soongCcLibraryPreamble + `
+cc_library {
+    name: "foo",
+    stl: "libc++",
+    arch: {
+        arm: {
+            stl: "libstdc++",
+        },
+    },
+    include_build_directory: false,
+}`,
+		expectedBazelTargets: []string{},
+	})
+}
@@ -421,3 +421,37 @@ func TestCcObjectSelectOnLinuxAndBionicArchs(t *testing.T) {
 		},
 	})
 }
+
+func TestCcObjectGlobalSymbolsOneArch(t *testing.T) {
+	runCcObjectTestCase(t, bp2buildTestCase{
+		description: "cc_object with global_symbols for one arch is not converted",
+		blueprint: `cc_object {
+    name: "foo",
+    system_shared_libs: [],
+    srcs: ["base.cpp"],
+    arch: {
+        arm64: {
+            global_symbols: ["foo_init"],
+        },
+    },
+    include_build_directory: false,
+}
+`,
+		expectedBazelTargets: []string{},
+	})
+}
+
+func TestCcObjectWholeArchive(t *testing.T) {
+	runCcObjectTestCase(t, bp2buildTestCase{
+		description: "cc_object with whole_archive is not converted",
+		blueprint: `cc_object {
+    name: "foo",
+    system_shared_libs: [],
+    srcs: ["base.cpp"],
+    whole_archive: false,
+    include_build_directory: false,
+}
+`,
+		expectedBazelTargets: []string{},
+	})
+}
@@ -1,9 +1,11 @@
 package bp2build
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"android/soong/android"
@@ -44,6 +46,10 @@ type CodegenMetrics struct {
 	// Counts of total modules by module type.
 	totalModuleTypeCount map[string]uint64
 
+	// Whether each module was converted, and why not if it wasn't
+	// NOTE: NOT in the .proto
+	moduleConversions []moduleConversion
+
 	Events []*bp2build_metrics_proto.Event
 }
 
@@ -125,9 +131,21 @@ func (metrics *CodegenMetrics) IncrementRuleClassCount(ruleClass string) {
 	metrics.ruleClassCount[ruleClass] += 1
 }
 
-func (metrics *CodegenMetrics) AddUnconvertedModule(moduleType string) {
+func (metrics *CodegenMetrics) AddUnconvertedModule(m blueprint.Module, moduleType string, moduleDir string) {
 	metrics.unconvertedModuleCount += 1
 	metrics.totalModuleTypeCount[moduleType] += 1
+
+	// Modules that aren't android.Modules, like the bootstrap Go modules, never have a converter.
+	reason := android.UnconvertedReason{ReasonType: android.UnconvertedReasonTypeUnsupportedModuleType}
+	if aModule, ok := m.(android.Module); ok && aModule.GetUnconvertedReason() != nil {
+		reason = *aModule.GetUnconvertedReason()
+	}
+	metrics.moduleConversions = append(metrics.moduleConversions, moduleConversion{
+		Name:   android.RemoveOptionalPrebuiltPrefix(m.Name()),
+		Type:   moduleType,
+		Dir:    moduleDir,
+		Reason: &reason,
+	})
 }
 
 func (metrics *CodegenMetrics) TotalModuleCount() uint64 {
@@ -149,12 +167,18 @@ const (
 	Handcrafted
 )
 
-func (metrics *CodegenMetrics) AddConvertedModule(m blueprint.Module, moduleType string, conversionType ConversionType) {
+func (metrics *CodegenMetrics) AddConvertedModule(m blueprint.Module, moduleType string, moduleDir string, conversionType ConversionType) {
 	// Undo prebuilt_ module name prefix modifications
 	moduleName := android.RemoveOptionalPrebuiltPrefix(m.Name())
 	metrics.convertedModules = append(metrics.convertedModules, moduleName)
 	metrics.convertedModuleTypeCount[moduleType] += 1
 	metrics.totalModuleTypeCount[moduleType] += 1
+	metrics.moduleConversions = append(metrics.moduleConversions, moduleConversion{
+		Name:      moduleName,
+		Type:      moduleType,
+		Dir:       moduleDir,
+		Converted: true,
+	})
 
 	if conversionType == Handcrafted {
 		metrics.handCraftedModuleCount += 1
@@ -162,3 +186,41 @@ func (metrics *CodegenMetrics) AddConvertedModule(m blueprint.Module, moduleType
 		metrics.generatedModuleCount += 1
 	}
 }
+
+const bp2buildConversionReportFilename = "bp2build_conversion_report.json"
+
+// moduleConversion is an entry of the conversion report for a single module.
+type moduleConversion struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Dir       string `json:"dir"`
+	Converted bool   `json:"converted"`
+
+	// Why the module wasn't converted, nil if it was.
+	Reason *android.UnconvertedReason `json:"reason,omitempty"`
+}
+
+// ConversionReport returns a JSON report of whether each module was converted, and the reason
+// for each module that wasn't. The modules are sorted by directory, name and type so that the
+// report is stable across runs.
+func (metrics *CodegenMetrics) ConversionReport() string {
+	modules := append([]moduleConversion{}, metrics.moduleConversions...)
+	sort.SliceStable(modules, func(i, j int) bool {
+		if modules[i].Dir != modules[j].Dir {
+			return modules[i].Dir < modules[j].Dir
+		}
+		if modules[i].Name != modules[j].Name {
+			return modules[i].Name < modules[j].Name
+		}
+		return modules[i].Type < modules[j].Type
+	})
+
+	report := struct {
+		Modules []moduleConversion `json:"modules"`
+	}{modules}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(data) + "\n"
+}
@@ -56,6 +56,23 @@ type BinaryLinkerProperties struct {
 
 	// Inject boringssl hash into the shared library.  This is only intended for use by external/boringssl.
 	Inject_bssl_hash *bool `android:"arch_variant"`
+
+	// Embed the source revision, product and optionally the build time into the binary.
+	Build_metadata BuildMetadataProperties
+
+	// build a position independent executable.  Defaults to true.  Only host binaries that
+	// target glibc, musl or Darwin can set it to false, Bionic requires position independent
+	// executables.
+	Pie *bool `android:"arch_variant"`
+
+	// require the host binary for darwin to be a universal binary, that combines the binaries of
+	// the x86_64 and arm64 darwin host architectures and is installed in place of the x86_64 one.
+	// Darwin binaries are universal binaries whenever both architectures are enabled, this makes
+	// it an error not to build one.  The arm64 darwin host architecture must be enabled both for the
+	// build, with arm64 as the secondary host architecture, and for the module, so it can't be
+	// disabled in target: { darwin_arm64: {...} }.  Only supported for darwin, set it in
+	// target: { darwin: {...} } for binaries that are built for other host OSes.
+	Universal_binary *bool `android:"arch_variant"`
 }
 
 func init() {
@@ -97,6 +114,9 @@ type binaryDecorator struct {
 	// Location of the linked, unstripped binary
 	unstrippedOutputFile android.Path
 
+	// Location of the debug info of the stripped binary when strip.split_debug is set
+	splitDebugFile android.OptionalPath
+
 	// Names of symlinks to be installed for use in LOCAL_MODULE_SYMLINKS
 	symlinks []string
 
@@ -246,15 +266,29 @@ func (binary *binaryDecorator) binary() bool {
 	return true
 }
 
+func (binary *binaryDecorator) pie() bool {
+	return BoolDefault(binary.Properties.Pie, true)
+}
+
 // linkerFlags returns a Flags object containing linker flags that are defined
 // by this binary, or that are implied by attributes of this binary. These flags are
 // combined with the given flags.
 func (binary *binaryDecorator) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 	flags = binary.baseLinker.linkerFlags(ctx, flags)
 
+	if binary.Properties.Build_metadata.enabled() {
+		flags = buildMetadataFlags(ctx, flags)
+	}
+
+	if !binary.pie() && ctx.toolchain().Bionic() {
+		ctx.PropertyErrorf("pie", "non-PIE binaries are not supported for %s", ctx.Os())
+	}
+
 	// Passing -pie to clang for Windows binaries causes a warning that -pie is unused.
 	if ctx.Host() && !ctx.Windows() && !binary.static() {
-		if !ctx.Config().IsEnvTrue("DISABLE_HOST_PIE") {
+		if !binary.pie() {
+			flags.Global.LdFlags = append(flags.Global.LdFlags, "-no-pie")
+		} else if !ctx.Config().IsEnvTrue("DISABLE_HOST_PIE") {
 			flags.Global.LdFlags = append(flags.Global.LdFlags, "-pie")
 		}
 	}
@@ -263,7 +297,12 @@ func (binary *binaryDecorator) linkerFlags(ctx ModuleContext, flags Flags) Flags
 	// all code is position independent, and then those warnings get promoted to
 	// errors.
 	if !ctx.Windows() {
-		flags.Global.CFlags = append(flags.Global.CFlags, "-fPIE")
+		if binary.pie() {
+			flags.Global.CFlags = append(flags.Global.CFlags, "-fPIE")
+		} else {
+			// Clang defaults to position independent code on some hosts.
+			flags.Global.CFlags = append(flags.Global.CFlags, "-fno-PIE")
+		}
 	}
 
 	if ctx.toolchain().Bionic() {
@@ -351,12 +390,29 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--no-dynamic-linker")
 	}
 
+	if Bool(binary.Properties.Universal_binary) {
+		if !ctx.Darwin() {
+			ctx.PropertyErrorf("universal_binary", "universal binaries are only supported for darwin, not %s", ctx.Os())
+		} else if !ctx.Target().HostCross && !deps.DarwinSecondArchOutput.Valid() {
+			ctx.PropertyErrorf("universal_binary", "requires both the x86_64 and arm64 darwin host architectures, darwin_arm64 is not enabled")
+		}
+	}
+
 	if ctx.Darwin() && deps.DarwinSecondArchOutput.Valid() {
 		fatOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "pre-fat", fileName)
 		transformDarwinUniversalBinary(ctx, fatOutputFile, outputFile, deps.DarwinSecondArchOutput.Path())
 	}
 
+	if binary.Properties.Build_metadata.enabled() {
+		objs = objs.Append(compileBuildMetadata(ctx, &binary.Properties.Build_metadata, flags, objs))
+	}
+
+	var implicitOutputs android.WritablePaths
+	if mapFile := binary.linkerMapFile(ctx, &flags, fileName); mapFile != nil {
+		implicitOutputs = append(implicitOutputs, mapFile)
+	}
+
 	builderFlags := flagsToBuilderFlags(flags)
 	stripFlags := flagsToStripFlags(flags)
 	if binary.stripper.NeedsStrip(ctx) {
@@ -365,7 +421,7 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 		}
 		strippedOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unstripped", fileName)
-		binary.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile, stripFlags)
+		binary.splitDebugFile = binary.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile, stripFlags)
 	}
 
 	binary.unstrippedOutputFile = outputFile
@@ -430,10 +486,17 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 	validations = append(validations, objs.tidyDepFiles...)
 	linkerDeps = append(linkerDeps, flags.LdFlagsDeps...)
 
+	if checkFlags, checkFile := binary.allowedUndefinedSymbolsCheck(ctx, builderFlags, fileName); checkFile != nil {
+		transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs, deps.StaticLibs,
+			deps.LateStaticLibs, deps.WholeStaticLibs, linkerDeps, deps.CrtBegin, deps.CrtEnd, true,
+			checkFlags, checkFile, nil, nil)
+		validations = append(validations, checkFile)
+	}
+
 	// Register link action.
 	transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs, deps.StaticLibs,
 		deps.LateStaticLibs, deps.WholeStaticLibs, linkerDeps, deps.CrtBegin, deps.CrtEnd, true,
-		builderFlags, outputFile, nil, validations)
+		builderFlags, outputFile, implicitOutputs, validations)
 
 	objs.coverageFiles = append(objs.coverageFiles, deps.StaticLibObjs.coverageFiles...)
 	objs.coverageFiles = append(objs.coverageFiles, deps.WholeStaticLibObjs.coverageFiles...)
@@ -450,6 +513,10 @@ func (binary *binaryDecorator) unstrippedOutputFilePath() android.Path {
 	return binary.unstrippedOutputFile
 }
 
+func (binary *binaryDecorator) splitDebugFilePath() android.OptionalPath {
+	return binary.splitDebugFile
+}
+
 func (binary *binaryDecorator) setSymlinkList(ctx ModuleContext) {
 	for _, symlink := range binary.Properties.Symlinks {
 		binary.symlinks = append(binary.symlinks,
@@ -671,7 +738,7 @@ type binaryAttributes struct {
 	Use_version_lib bazel.BoolAttribute
 
 	Rtti    bazel.BoolAttribute
-	Stl     *string
+	Stl     bazel.StringAttribute
 	Cpp_std *string
 
 	Strip stripAttributes
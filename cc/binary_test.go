@@ -15,6 +15,8 @@
 package cc
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -49,3 +51,248 @@ cc_binary {
 	expectedUnStrippedFile := "outputbase/execroot/__main__/foo"
 	android.AssertStringEquals(t, "Unstripped output file", expectedUnStrippedFile, unStrippedFilePath.String())
 }
+
+func TestCcBinaryLongLinkCommand(t *testing.T) {
+	// Enough static libraries to exceed the linker command line limit on every build OS.
+	var staticLibs []string
+	var modules strings.Builder
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("libstatic_with_a_long_name_to_make_the_command_line_longer_%d", i)
+		staticLibs = append(staticLibs, name)
+		fmt.Fprintf(&modules, "cc_library_static { name: %q, srcs: [\"foo.c\"] }\n", name)
+	}
+
+	bp := modules.String() + fmt.Sprintf(`
+		cc_binary {
+			name: "many_libs",
+			srcs: ["foo.c"],
+			static_libs: [%q],
+		}
+		cc_binary {
+			name: "few_libs",
+			srcs: ["foo.c"],
+			static_libs: [%q],
+		}
+	`, strings.Join(staticLibs, `", "`), staticLibs[0])
+
+	result := prepareForCcTest.RunTestWithBp(t, bp)
+
+	manyLibs := result.ModuleForTests("many_libs", "android_arm64_armv8-a").Rule("ld")
+	android.AssertStringEquals(t, "libFlags of long command", "", manyLibs.Args["libFlags"])
+	android.AssertStringDoesContain(t, "rspLibFlags of long command", manyLibs.Args["rspLibFlags"],
+		staticLibs[len(staticLibs)-1]+".a")
+
+	fewLibs := result.ModuleForTests("few_libs", "android_arm64_armv8-a").Rule("ld")
+	android.AssertStringEquals(t, "rspLibFlags of short command", "", fewLibs.Args["rspLibFlags"])
+	android.AssertStringDoesContain(t, "libFlags of short command", fewLibs.Args["libFlags"],
+		staticLibs[0]+".a")
+}
+
+func TestCcBinaryStripKeepSymbolTable(t *testing.T) {
+	ctx := testCc(t, `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	strip: {
+		keep_symbol_table: true,
+	},
+}`)
+
+	stripRule := ctx.ModuleForTests("foo", "android_arm64_armv8-a").Rule("android/soong/cc.strip")
+	android.AssertStringDoesContain(t, "strip args", stripRule.Args["args"], "--keep-symbol-table")
+	android.AssertStringDoesNotContain(t, "strip args", stripRule.Args["args"], "--keep-mini-debug-info")
+}
+
+func TestCcBinaryStripKeepSymbolTableConflicts(t *testing.T) {
+	testCcError(t, `"foo" .*: strip.keep_symbol_table: cannot be used together with strip.keep_symbols`, `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	strip: {
+		keep_symbol_table: true,
+		keep_symbols: true,
+	},
+}`)
+}
+
+func TestCcBinaryStripPropertiesErrorReportedOnce(t *testing.T) {
+	prepareForCcTest.ExtendWithErrorHandler(android.FixtureCustomErrorHandler(func(t *testing.T, result *android.TestResult) {
+		var matching []error
+		for _, err := range result.Errs {
+			if strings.Contains(err.Error(), "strip.keep_symbol_table: cannot be used together with strip.all") {
+				matching = append(matching, err)
+			}
+		}
+		if len(matching) != 1 {
+			t.Errorf("expected the strip error to be reported once, got %q", matching)
+		}
+	})).RunTestWithBp(t, `
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.cc"],
+			compile_multilib: "first",
+			strip: {
+				keep_symbol_table: true,
+				all: true,
+			},
+		}
+	`)
+}
+
+func TestCcBinaryStripSplitDebug(t *testing.T) {
+	ctx := testCc(t, `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	strip: {
+		split_debug: true,
+		keep_symbols: true,
+	},
+}`)
+
+	foo := ctx.ModuleForTests("foo", "android_arm64_armv8-a")
+	stripRule := foo.Rule("android/soong/cc.strip")
+	debugFile := "out/soong/.intermediates/foo/android_arm64_armv8-a/foo.debug"
+	android.AssertStringDoesContain(t, "strip args", stripRule.Args["args"], "--keep-symbols")
+	android.AssertStringDoesContain(t, "strip args", stripRule.Args["args"], "-s "+debugFile)
+	android.AssertStringDoesNotContain(t, "strip args", stripRule.Args["args"], "--keep-mini-debug-info")
+	android.AssertStringDoesNotContain(t, "strip args", stripRule.Args["args"], "--add-gnu-debuglink")
+	android.AssertPathsRelativeToTopEquals(t, "strip implicit outputs", []string{debugFile},
+		stripRule.ImplicitOutputs.Paths())
+
+	outputFiles, err := foo.Module().(android.OutputFileProducer).OutputFiles(".debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	android.AssertPathsRelativeToTopEquals(t, ".debug output files", []string{debugFile}, outputFiles)
+}
+
+func TestCcBinaryStripSplitDebugConflicts(t *testing.T) {
+	testCcError(t, `"foo" .*: strip.split_debug: cannot be used together with strip.none`, `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	strip: {
+		split_debug: true,
+		none: true,
+	},
+}`)
+}
+
+func TestCcBinaryBuildMetadata(t *testing.T) {
+	bp := `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	build_metadata: {
+		enabled: true,
+	},
+}
+cc_binary {
+	name: "foo_timestamp",
+	srcs: ["foo.cc"],
+	build_metadata: {
+		enabled: true,
+		timestamp: true,
+	},
+}
+cc_binary {
+	name: "bar",
+	srcs: ["foo.cc"],
+}`
+	ctx := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{
+			"BUILD_DATETIME_FILE": "out/build_date.txt",
+		}),
+	).RunTestWithBp(t, bp).TestContext
+
+	foo := ctx.ModuleForTests("foo", "android_arm64_armv8-a")
+	gen := foo.Rule("genBuildMetadata")
+	android.AssertStringEquals(t, "product", "test_product", gen.Args["product"])
+	android.AssertStringEquals(t, "timestamp", `""`, gen.Args["timestamp"])
+	android.AssertPathsRelativeToTopEquals(t, "inputs of the generated source",
+		[]string{"out/soong/.intermediates/foo/android_arm64_armv8-a/obj/foo.o"}, gen.Implicits)
+	// The build number is read without depending on it.
+	android.AssertStringDoesContain(t, "build number", gen.Args["buildNumber"], "cat out/soong/build_number.txt")
+	android.AssertPathsRelativeToTopEquals(t, "order-only dependencies of the generated source",
+		[]string{"out/soong/build_number.txt"}, gen.OrderOnly)
+
+	obj := foo.Output("out/soong/.intermediates/foo/android_arm64_armv8-a/obj/build_metadata/build_metadata/build_metadata.o")
+	android.AssertStringDoesContain(t, "include path for the header", obj.Args["cFlags"],
+		"-Iout/soong/.intermediates/foo/android_arm64_armv8-a/gen/build_metadata")
+
+	ld := foo.Rule("ld")
+	android.AssertPathsRelativeToTopEquals(t, "linked objects", []string{
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/obj/foo.o",
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/obj/build_metadata/build_metadata/build_metadata.o",
+	}, ld.Inputs)
+
+	fooTimestamp := ctx.ModuleForTests("foo_timestamp", "android_arm64_armv8-a")
+	android.AssertStringDoesContain(t, "timestamp", fooTimestamp.Rule("genBuildMetadata").Args["timestamp"],
+		"cat out/build_date.txt")
+
+	// Without BUILD_DATETIME_FILE the build time is left empty.
+	fooTimestamp = testCc(t, bp).ModuleForTests("foo_timestamp", "android_arm64_armv8-a")
+	android.AssertStringEquals(t, "timestamp without BUILD_DATETIME_FILE", `""`,
+		fooTimestamp.Rule("genBuildMetadata").Args["timestamp"])
+
+	bar := ctx.ModuleForTests("bar", "android_arm64_armv8-a")
+	if gen := bar.MaybeRule("genBuildMetadata"); gen.Rule != nil {
+		t.Errorf("expected no build metadata for a binary without build_metadata")
+	}
+}
+
+func TestCcBinaryNoPie(t *testing.T) {
+	ctx := testCc(t, `
+cc_binary_host {
+	name: "foo",
+	srcs: ["foo.cc"],
+	pie: false,
+}
+cc_binary_host {
+	name: "bar",
+	srcs: ["foo.cc"],
+}`)
+
+	foo := ctx.ModuleForTests("foo", "linux_glibc_x86_64")
+	android.AssertStringDoesContain(t, "foo ldflags", foo.Rule("ld").Args["ldFlags"], "-no-pie")
+	android.AssertStringDoesNotContain(t, "foo ldflags", foo.Rule("ld").Args["ldFlags"], " -pie")
+	android.AssertStringDoesContain(t, "foo cflags", foo.Rule("cc").Args["cFlags"], "-fno-PIE")
+	android.AssertStringDoesNotContain(t, "foo cflags", foo.Rule("cc").Args["cFlags"], "-fPIE")
+
+	bar := ctx.ModuleForTests("bar", "linux_glibc_x86_64")
+	android.AssertStringDoesContain(t, "bar ldflags", bar.Rule("ld").Args["ldFlags"], "-pie")
+	android.AssertStringDoesNotContain(t, "bar ldflags", bar.Rule("ld").Args["ldFlags"], "-no-pie")
+	android.AssertStringDoesContain(t, "bar cflags", bar.Rule("cc").Args["cFlags"], "-fPIE")
+}
+
+func TestCcBinaryNoPieDevice(t *testing.T) {
+	testCcError(t, `"foo" .*: pie: non-PIE binaries are not supported for android`, `
+cc_binary {
+	name: "foo",
+	srcs: ["foo.cc"],
+	pie: false,
+}`)
+}
+
+func TestCcBinaryUniversalBinary(t *testing.T) {
+	testCcError(t, `"foo" .*: universal_binary: universal binaries are only supported for darwin, not linux_glibc`, `
+cc_binary_host {
+	name: "foo",
+	srcs: ["foo.cc"],
+	universal_binary: true,
+}`)
+
+	// Binaries that only require universal binaries for darwin can be built for other OSes.
+	testCc(t, `
+cc_binary_host {
+	name: "foo",
+	srcs: ["foo.cc"],
+	target: {
+		darwin: {
+			universal_binary: true,
+		},
+	},
+}`)
+}
@@ -292,9 +292,9 @@ type compilerAttributes struct {
 	hdrs bazel.LabelListAttribute
 
 	rtti bazel.BoolAttribute
+	stl  bazel.StringAttribute
 
 	// Not affected by arch variants
-	stl    *string
 	cStd   *string
 	cppStd *string
 
@@ -358,19 +358,29 @@ func (ca *compilerAttributes) bp2buildForAxisAndConfig(ctx android.BazelConversi
 	ca.rtti.SetSelectValue(axis, config, props.Rtti)
 }
 
-func (ca *compilerAttributes) convertStlProps(ctx android.ArchVariantContext, module *Module) {
-	stlPropsByArch := module.GetArchVariantProperties(ctx, &StlProperties{})
-	for _, configToProps := range stlPropsByArch {
-		for _, props := range configToProps {
+// bp2buildStlValues are the values of the stl property that the Bazel cc rules understand.
+var bp2buildStlValues = map[string]bool{
+	"system":        true,
+	"libc++":        true,
+	"libc++_static": true,
+	"c++_shared":    true,
+	"c++_static":    true,
+	"none":          true,
+}
+
+func (ca *compilerAttributes) convertStlProps(ctx android.BazelConversionPathContext, module *Module) {
+	for axis, configToProps := range module.GetArchVariantProperties(ctx, &StlProperties{}) {
+		for config, props := range configToProps {
 			if stlProps, ok := props.(*StlProperties); ok {
 				if stlProps.Stl == nil {
 					continue
 				}
-				if ca.stl == nil {
-					ca.stl = stlProps.Stl
-				} else if ca.stl != stlProps.Stl {
-					ctx.ModuleErrorf("Unsupported conversion: module with different stl for different variants: %s and %s", *ca.stl, stlProps.Stl)
+				if !bp2buildStlValues[*stlProps.Stl] {
+					ctx.MarkBp2buildUnconvertible(android.UnconvertedReasonTypeUnsupportedProperty,
+						fmt.Sprintf("stl: %q is not supported", *stlProps.Stl))
+					return
 				}
+				ca.stl.SetSelectValue(axis, config, stlProps.Stl)
 			}
 		}
 	}
@@ -0,0 +1,136 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+var (
+	// Rule to write the source file that defines the build metadata symbols.  The revision and
+	// the build number are read at build time, and the output is only replaced when it changes
+	// so that the binary isn't relinked when the metadata is the same.
+	genBuildMetadata = pctx.AndroidStaticRule("genBuildMetadata",
+		blueprint.RuleParams{
+			Command: `rev=$$(git -C $srcDir rev-parse HEAD 2>/dev/null || echo unknown) && ` +
+				`buildNumber=$buildNumber && ` +
+				`timestamp=$timestamp && ` +
+				`( echo '#include "build_metadata.h"' && ` +
+				`echo "const char soong_build_metadata_revision[] = \"$$rev\";" && ` +
+				`echo "const char soong_build_metadata_build_number[] = \"$$buildNumber\";" && ` +
+				`echo "const char soong_build_metadata_product[] = \"$product\";" && ` +
+				`echo "const char soong_build_metadata_timestamp[] = \"$$timestamp\";" ) > $out.tmp && ` +
+				`if cmp -s $out.tmp $out; then rm $out.tmp; else mv $out.tmp $out; fi`,
+			Restat: true,
+		},
+		"srcDir", "buildNumber", "product", "timestamp")
+)
+
+const buildMetadataHeader = `#pragma once
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// The git revision of the project that contains the module, or "unknown".
+extern const char soong_build_metadata_revision[];
+// The build number, or "" if the product doesn't set one.
+extern const char soong_build_metadata_build_number[];
+// The product being built.
+extern const char soong_build_metadata_product[];
+// The build time in seconds since the epoch, or "" if build_metadata.timestamp is not set or
+// the build time is not known.
+extern const char soong_build_metadata_timestamp[];
+
+#ifdef __cplusplus
+}
+#endif
+`
+
+type BuildMetadataProperties struct {
+	// Compile in a generated source that defines the soong_build_metadata_revision,
+	// soong_build_metadata_build_number, soong_build_metadata_product and
+	// soong_build_metadata_timestamp strings, which are declared in the generated
+	// "build_metadata.h" header.
+	Enabled *bool
+
+	// Record the build time in soong_build_metadata_timestamp.  This makes the binary differ
+	// between builds of the same source, so it defaults to false to keep builds reproducible.
+	Timestamp *bool
+}
+
+func (p *BuildMetadataProperties) enabled() bool {
+	return proptools.Bool(p.Enabled)
+}
+
+func buildMetadataPath(ctx android.ModuleContext, paths ...string) android.ModuleGenPath {
+	return android.PathForModuleGen(ctx, append([]string{"build_metadata"}, paths...)...)
+}
+
+// buildMetadataFlags adds the include path and dependency for the generated build metadata
+// header.
+func buildMetadataFlags(ctx ModuleContext, flags Flags) Flags {
+	header := buildMetadataPath(ctx, "build_metadata.h")
+	android.WriteFileRule(ctx, header, buildMetadataHeader)
+	flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-I"+buildMetadataPath(ctx).String())
+	flags.CFlagsDeps = append(flags.CFlagsDeps, header)
+	return flags
+}
+
+// compileBuildMetadata generates and compiles the source that defines the build metadata.  The
+// source is regenerated whenever one of objs is rebuilt, which avoids running git in every
+// incremental build while keeping the revision current for the module's own changes.
+func compileBuildMetadata(ctx ModuleContext, p *BuildMetadataProperties, flags Flags,
+	objs Objects) Objects {
+
+	src := buildMetadataPath(ctx, "build_metadata.c")
+
+	// The build number and the build date change in every build, they are read without depending
+	// on them so that the binary isn't rebuilt in every build.
+	var orderOnly android.Paths
+	buildNumber := `""`
+	if ctx.Config().HasBuildNumberFile() {
+		buildNumberFile := ctx.Config().BuildNumberFile(ctx)
+		orderOnly = append(orderOnly, buildNumberFile)
+		buildNumber = "$$(cat " + buildNumberFile.String() + ")"
+	}
+
+	timestamp := `""`
+	// BUILD_DATETIME_FILE is written by soong_ui before it runs ninja, so it needs no dependency.
+	// It is not set when soong_build runs on its own, and the build time is then left empty.
+	if dateFile := ctx.Config().Getenv("BUILD_DATETIME_FILE"); proptools.Bool(p.Timestamp) && dateFile != "" {
+		timestamp = "$$(cat " + proptools.ShellEscape(dateFile) + ")"
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        genBuildMetadata,
+		Description: "build metadata",
+		Output:      src,
+		Implicits:   objs.objFiles,
+		OrderOnly:   orderOnly,
+		Args: map[string]string{
+			"srcDir":      proptools.ShellEscape(ctx.ModuleDir()),
+			"buildNumber": buildNumber,
+			"product":     ctx.Config().DeviceProduct(),
+			"timestamp":   timestamp,
+		},
+	})
+
+	return compileObjs(ctx, flagsToBuilderFlags(flags), "build_metadata", android.Paths{src},
+		nil, nil, nil, flags.CFlagsDeps)
+}
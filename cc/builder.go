@@ -26,6 +26,7 @@ import (
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/pathtools"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/cc/config"
@@ -45,7 +46,17 @@ var (
 		blueprint.RuleParams{
 			Depfile:     "${out}.d",
 			Deps:        blueprint.DepsGCC,
-			Command:     "$relPwd ${config.CcWrapper}$ccCmd -c $cFlags -MD -MF ${out}.d -o $out $in",
+			Command:     "$relPwd $hostToolCache${config.CcWrapper}$ccCmd -c $cFlags -MD -MF ${out}.d -o $out $in",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags", "hostToolCache")
+
+	// Rule to compile a source file to LLVM bitcode with the flags of its object.
+	ccBitcode = pctx.AndroidStaticRule("ccBitcode",
+		blueprint.RuleParams{
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+			Command:     "$relPwd ${config.CcWrapper}$ccCmd -c -emit-llvm $cFlags -MD -MF ${out}.d -o $out $in",
 			CommandDeps: []string{"$ccCmd"},
 		},
 		"ccCmd", "cFlags")
@@ -59,17 +70,20 @@ var (
 		"ccCmd", "cFlags")
 
 	// Rules to invoke ld to link binaries. Uses a .rsp file to list dependencies, as there may
-	// be many.
-	ld, ldRE = pctx.RemoteStaticRules("ld",
-		blueprint.RuleParams{
-			Command: "$reTemplate$ldCmd ${crtBegin} @${out}.rsp " +
-				"${libFlags} ${crtEnd} -o ${out} ${ldFlags} ${extraLibFlags}",
-			CommandDeps:    []string{"$ldCmd"},
-			Rspfile:        "${out}.rsp",
-			RspfileContent: "${in}",
-			// clang -Wl,--out-implib doesn't update its output file if it hasn't changed.
-			Restat: true,
-		},
+	// be many. The library arguments are passed in rspLibFlags instead of libFlags when they
+	// would make the command line too long, see moveLinkerLibFlagsToRspFile.
+	ldParams = blueprint.RuleParams{
+		Command: "$reTemplate$ldCmd ${crtBegin} @${out}.rsp " +
+			"${libFlags} ${crtEnd} -o ${out} ${ldFlags} ${extraLibFlags}",
+		CommandDeps:    []string{"$ldCmd"},
+		Rspfile:        "${out}.rsp",
+		RspfileContent: "${in} ${rspLibFlags}",
+		// clang -Wl,--out-implib doesn't update its output file if it hasn't changed.
+		Restat: true,
+	}
+	ldArgs = []string{"ldCmd", "crtBegin", "libFlags", "rspLibFlags", "crtEnd", "ldFlags", "extraLibFlags"}
+
+	ld, ldRE = pctx.RemoteStaticRules("ld", ldParams,
 		&remoteexec.REParams{
 			Labels:          map[string]string{"type": "link", "tool": "clang"},
 			ExecStrategy:    "${config.RECXXLinksExecStrategy}",
@@ -78,7 +92,7 @@ var (
 			OutputFiles:     []string{"${out}", "$implicitOutputs"},
 			ToolchainInputs: []string{"$ldCmd"},
 			Platform:        map[string]string{remoteexec.PoolKey: "${config.RECXXLinksPool}"},
-		}, []string{"ldCmd", "crtBegin", "libFlags", "crtEnd", "ldFlags", "extraLibFlags"}, []string{"implicitInputs", "implicitOutputs"})
+		}, ldArgs, []string{"implicitInputs", "implicitOutputs"})
 
 	// Rules for .o files to combine to other .o files, using ld partial linking.
 	partialLd, partialLdRE = pctx.RemoteStaticRules("partialLd",
@@ -125,6 +139,14 @@ var (
 		},
 		"objcopyCmd", "prefix")
 
+	// Rule to make all global symbols local except the ones passed with --keep-global-symbol.
+	keepGlobalSymbols = pctx.AndroidStaticRule("keepGlobalSymbols",
+		blueprint.RuleParams{
+			Command:     "$objcopyCmd ${keepFlags} ${in} ${out}",
+			CommandDeps: []string{"$objcopyCmd"},
+		},
+		"objcopyCmd", "keepFlags")
+
 	_ = pctx.SourcePathVariable("stripPath", "build/soong/scripts/strip.sh")
 	_ = pctx.SourcePathVariable("xzCmd", "prebuilts/build-tools/${config.HostPrebuiltTag}/bin/xz")
 	_ = pctx.SourcePathVariable("createMiniDebugInfo", "prebuilts/build-tools/${config.HostPrebuiltTag}/bin/create_minidebuginfo")
@@ -296,7 +318,9 @@ var (
 	sAbiDiff = pctx.RuleFunc("sAbiDiff",
 		func(ctx android.PackageRuleContext) blueprint.RuleParams {
 			commandStr := "($sAbiDiffer ${extraFlags} -lib ${libName} -arch ${arch} -o ${out} -new ${in} -old ${referenceDump})"
-			commandStr += "|| (echo 'error: Please update ABI references with: $$ANDROID_BUILD_TOP/development/vndk/tools/header-checker/utils/create_reference_dumps.py ${createReferenceDumpFlags} -l ${libName}'"
+			commandStr += "|| (cat ${out}"
+			commandStr += " && echo 'error: The ABI of ${libName} differs from the reference dump ${referenceDump}, see the report above.'"
+			commandStr += " && echo '${symbolFileAdvice}Please update ABI references with: $$ANDROID_BUILD_TOP/development/vndk/tools/header-checker/utils/create_reference_dumps.py ${createReferenceDumpFlags} -l ${libName}'"
 			commandStr += " && (mkdir -p $$DIST_DIR/abidiffs && cp ${out} $$DIST_DIR/abidiffs/)"
 			commandStr += " && exit 1)"
 			return blueprint.RuleParams{
@@ -304,7 +328,7 @@ var (
 				CommandDeps: []string{"$sAbiDiffer"},
 			}
 		},
-		"extraFlags", "referenceDump", "libName", "arch", "createReferenceDumpFlags")
+		"extraFlags", "referenceDump", "libName", "arch", "createReferenceDumpFlags", "symbolFileAdvice")
 
 	// Rule to unzip a reference abi dump.
 	unzipRefSAbiDump = pctx.AndroidStaticRule("unzipRefSAbiDump",
@@ -394,6 +418,8 @@ type builderFlags struct {
 	aidlFlags     string // Flags that apply to aidl source files
 	rsFlags       string // Flags that apply to renderscript source files
 	toolchain     config.Toolchain
+	clangBin      string         // Overrides ${config.ClangBin} for compilation when set.
+	ldRule        blueprint.Rule // Overrides the ld rule for linking when set.
 
 	// True if these extra features are enabled.
 	tidy          bool
@@ -402,6 +428,10 @@ type builderFlags struct {
 	sAbiDump      bool
 	emitXrefs     bool
 
+	emitLLVMBitcode bool
+
+	srcCFlags map[string][]string // Flags that apply to individual source files, by path.
+
 	assemblerWithCpp bool // True if .s files should be processed with the c preprocessor.
 
 	systemIncludeFlags string
@@ -421,6 +451,7 @@ type StripFlags struct {
 	StripKeepSymbols              bool
 	StripKeepSymbolsList          string
 	StripKeepSymbolsAndDebugFrame bool
+	StripKeepSymbolTable          bool
 	StripKeepMiniDebugInfo        bool
 	StripAddGnuDebuglink          bool
 	StripUseGnuStrip              bool
@@ -434,6 +465,7 @@ type Objects struct {
 	coverageFiles android.Paths
 	sAbiDumpFiles android.Paths
 	kytheFiles    android.Paths
+	bitcodeFiles  android.Paths
 }
 
 func (a Objects) Copy() Objects {
@@ -444,6 +476,7 @@ func (a Objects) Copy() Objects {
 		coverageFiles: append(android.Paths{}, a.coverageFiles...),
 		sAbiDumpFiles: append(android.Paths{}, a.sAbiDumpFiles...),
 		kytheFiles:    append(android.Paths{}, a.kytheFiles...),
+		bitcodeFiles:  append(android.Paths{}, a.bitcodeFiles...),
 	}
 }
 
@@ -455,6 +488,7 @@ func (a Objects) Append(b Objects) Objects {
 		coverageFiles: append(a.coverageFiles, b.coverageFiles...),
 		sAbiDumpFiles: append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
 		kytheFiles:    append(a.kytheFiles, b.kytheFiles...),
+		bitcodeFiles:  append(a.bitcodeFiles, b.bitcodeFiles...),
 	}
 }
 
@@ -484,10 +518,18 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 	if flags.gcovCoverage {
 		coverageFiles = make(android.Paths, 0, len(srcFiles))
 	}
+	clangBin := "${config.ClangBin}"
+	if flags.clangBin != "" {
+		clangBin = flags.clangBin
+	}
 	var kytheFiles android.Paths
 	if flags.emitXrefs {
 		kytheFiles = make(android.Paths, 0, len(srcFiles))
 	}
+	var bitcodeFiles android.Paths
+	if flags.emitLLVMBitcode {
+		bitcodeFiles = make(android.Paths, 0, len(srcFiles))
+	}
 
 	// Produce fully expanded flags for use by C tools, C compiles, C++ tools, C++ compiles, and asm compiles
 	// respectively.
@@ -534,18 +576,15 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		sAbiDumpFiles = make(android.Paths, 0, len(srcFiles))
 	}
 
-	cflags += " ${config.NoOverrideGlobalCflags}"
-	toolingCflags += " ${config.NoOverrideGlobalCflags}"
-	cppflags += " ${config.NoOverrideGlobalCflags}"
-	toolingCppflags += " ${config.NoOverrideGlobalCflags}"
-
+	noOverrideCflags := " ${config.NoOverrideGlobalCflags}"
 	modulePath := android.PathForModuleSrc(ctx).String()
 	if android.IsThirdPartyPath(modulePath) {
-		cflags += " ${config.NoOverrideExternalGlobalCflags}"
-		toolingCflags += " ${config.NoOverrideExternalGlobalCflags}"
-		cppflags += " ${config.NoOverrideExternalGlobalCflags}"
-		toolingCppflags += " ${config.NoOverrideExternalGlobalCflags}"
+		noOverrideCflags += " ${config.NoOverrideExternalGlobalCflags}"
 	}
+	cflags += noOverrideCflags
+	toolingCflags += noOverrideCflags
+	cppflags += noOverrideCflags
+	toolingCppflags += noOverrideCflags
 
 	// Multiple source files have build rules usually share the same cFlags or tidyFlags.
 	// Define only one version in this module and share it in multiple build rules.
@@ -553,7 +592,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 	shared := ctx.getSharedFlags()
 
 	// Share flags only when there are multiple files or tidy rules.
-	var hasMultipleRules = len(srcFiles) > 1 || flags.tidy
+	var hasMultipleRules = len(srcFiles) > 1 || flags.tidy || flags.emitLLVMBitcode
 
 	var shareFlags = func(kind string, flags string) string {
 		if !hasMultipleRules || len(flags) < 60 {
@@ -606,6 +645,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		dump := flags.sAbiDump
 		rule := cc
 		emitXref := flags.emitXrefs
+		bitcode := flags.emitLLVMBitcode
 
 		switch srcFile.Ext() {
 		case ".s":
@@ -620,13 +660,14 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 			coverage = false
 			dump = false
 			emitXref = false
+			bitcode = false
 		case ".c":
 			ccCmd = "clang"
-			moduleFlags = cflags
+			moduleFlags = overrideSrcCFlags(cflags, noOverrideCflags, flags.srcCFlags[srcFile.String()])
 			moduleToolingFlags = toolingCflags
 		case ".cpp", ".cc", ".cxx", ".mm":
 			ccCmd = "clang++"
-			moduleFlags = cppflags
+			moduleFlags = overrideSrcCFlags(cppflags, noOverrideCflags, flags.srcCFlags[srcFile.String()])
 			moduleToolingFlags = toolingCppflags
 		case ".h", ".hpp":
 			ctx.PropertyErrorf("srcs", "Header file %s is not supported, instead use export_include_dirs or local_include_dirs.", srcFile)
@@ -638,7 +679,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 
 		ccDesc := ccCmd
 
-		ccCmd = "${config.ClangBin}/" + ccCmd
+		ccCmd = clangBin + "/" + ccCmd
 
 		var implicitOutputs android.WritablePaths
 		if coverage {
@@ -647,20 +688,46 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
 
+		ccArgs := map[string]string{
+			"cFlags": shareFlags("cFlags", moduleFlags),
+			"ccCmd":  ccCmd, // short and not shared
+		}
+		implicits := cFlagsDeps
+		if rule == cc && ctx.Host() {
+			if cache := hostToolCacheCommand(ctx, ccCmd, objFile, implicitOutputs, cFlagsDeps); cache != "" {
+				ccArgs["hostToolCache"] = cache
+				implicits = append(android.Paths{ctx.Config().HostToolPath(ctx, "host_tool_cache")}, cFlagsDeps...)
+			}
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
 			Description:     ccDesc + " " + srcFile.Rel(),
 			Output:          objFile,
 			ImplicitOutputs: implicitOutputs,
 			Input:           srcFile,
-			Implicits:       cFlagsDeps,
+			Implicits:       implicits,
 			OrderOnly:       pathDeps,
-			Args: map[string]string{
-				"cFlags": shareFlags("cFlags", moduleFlags),
-				"ccCmd":  ccCmd, // short and not shared
-			},
+			Args:            ccArgs,
 		})
 
+		if bitcode {
+			bitcodeFile := android.ObjPathWithExt(ctx, subdir, srcFile, "bc")
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        ccBitcode,
+				Description: ccDesc + " bitcode " + srcFile.Rel(),
+				Output:      bitcodeFile,
+				Input:       srcFile,
+				Implicits:   cFlagsDeps,
+				OrderOnly:   pathDeps,
+				Args: map[string]string{
+					"cFlags": shareFlags("cFlags", moduleFlags),
+					"ccCmd":  ccCmd,
+				},
+			})
+			bitcodeFiles = append(bitcodeFiles, bitcodeFile)
+		}
+
 		// Register post-process build statements (such as for tidy or kythe).
 		if emitXref {
 			kytheFile := android.ObjPathWithExt(ctx, subdir, srcFile, "kzip")
@@ -758,9 +825,35 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		coverageFiles: coverageFiles,
 		sAbiDumpFiles: sAbiDumpFiles,
 		kytheFiles:    kytheFiles,
+		bitcodeFiles:  bitcodeFiles,
 	}
 }
 
+// overrideSrcCFlags adds the srcs_cflags of a source file to the flags of the module, or removes
+// the ones with a "!" prefix, before the flags that cannot be overridden.
+func overrideSrcCFlags(moduleFlags, noOverrideCflags string, srcFlags []string) string {
+	if len(srcFlags) == 0 {
+		return moduleFlags
+	}
+	var add []string
+	remove := make(map[string]bool)
+	for _, flag := range srcFlags {
+		if strings.HasPrefix(flag, "!") {
+			remove[flag[1:]] = true
+		} else {
+			add = append(add, flag)
+		}
+	}
+
+	var ret []string
+	for _, flag := range strings.Fields(strings.TrimSuffix(moduleFlags, noOverrideCflags)) {
+		if !remove[flag] {
+			ret = append(ret, flag)
+		}
+	}
+	return strings.Join(append(ret, add...), " ") + noOverrideCflags
+}
+
 // Generate a rule for compiling multiple .o files to a static library (.a)
 func transformObjToStaticLib(ctx android.ModuleContext,
 	objFiles android.Paths, wholeStaticLibs android.Paths,
@@ -854,6 +947,9 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 	deps = append(deps, crtEnd...)
 
 	rule := ld
+	if flags.ldRule != nil {
+		rule = flags.ldRule
+	}
 	args := map[string]string{
 		"ldCmd":         ldCmd,
 		"crtBegin":      strings.Join(crtBegin.Strings(), " "),
@@ -862,6 +958,7 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 		"ldFlags":       flags.globalLdFlags + " " + flags.localLdFlags,
 		"crtEnd":        strings.Join(crtEnd.Strings(), " "),
 	}
+	moveLinkerLibFlagsToRspFile(ctx.Config(), args)
 	if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CXX_LINKS") {
 		rule = ldRE
 		args["implicitOutputs"] = strings.Join(implicitOutputs.Strings(), ",")
@@ -881,6 +978,42 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 	})
 }
 
+// ldRuleWithPool returns a rule of the module that links like the ld rule in the given ninja pool.
+func ldRuleWithPool(ctx android.ModuleContext, pool blueprint.Pool) blueprint.Rule {
+	params := ldParams
+	params.Command = strings.ReplaceAll(params.Command, "$reTemplate", "")
+	params.Pool = pool
+	return ctx.Rule(pctx, "ld_pool", params, ldArgs...)
+}
+
+// linkerCommandLineLimit returns the length of the arguments of a link command above which the
+// library arguments are moved into the response file.  Ninja runs each command as a single
+// argument to "/bin/sh -c", which Linux limits to MAX_ARG_STRLEN (128KiB), while macOS limits the
+// total size of the arguments and the environment to 256KiB.  Leave room for the expansion of
+// Ninja variables in the command.
+func linkerCommandLineLimit(config android.Config) int {
+	if config.BuildOS == android.Darwin {
+		return 128 * 1024
+	}
+	return 64 * 1024
+}
+
+// moveLinkerLibFlagsToRspFile moves the library arguments of an ld rule into its response file if
+// the command line would otherwise be too long.  The response file content is part of the command
+// that Ninja hashes to decide whether to rerun the link, so this doesn't affect incremental builds.
+// The libraries are listed after the objects in the response file, which keeps them in the same
+// order on the linker command line.
+func moveLinkerLibFlagsToRspFile(config android.Config, args map[string]string) {
+	length := 0
+	for _, arg := range []string{"crtBegin", "libFlags", "crtEnd", "ldFlags", "extraLibFlags"} {
+		length += len(args[arg])
+	}
+	if length > linkerCommandLineLimit(config) {
+		args["rspLibFlags"] = args["libFlags"]
+		args["libFlags"] = ""
+	}
+}
+
 // Generate a rule to combine .dump sAbi dump files from multiple source files
 // into a single .ldump sAbi dump file
 func transformDumpToLinkedDump(ctx android.ModuleContext, sAbiDumps android.Paths, soFile android.Path,
@@ -944,7 +1077,7 @@ func unzipRefDump(ctx android.ModuleContext, zippedRefDump android.Path, baseNam
 
 // sourceAbiDiff registers a build statement to compare linked sAbi dump files (.lsdump).
 func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceDump android.Path,
-	baseName, exportedHeaderFlags string, diffFlags []string,
+	baseName, exportedHeaderFlags string, symbolFile android.OptionalPath, diffFlags []string,
 	checkAllApis, isLlndk, isNdk, isVndkExt bool) android.OptionalPath {
 
 	outputFile := android.PathForModuleOut(ctx, baseName+".abidiff")
@@ -979,6 +1112,13 @@ func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 	// TODO(b/232891473): Simplify the above logic with diffFlags.
 	extraFlags = append(extraFlags, diffFlags...)
 
+	// Libraries with a symbol file must version any new symbols in it.
+	symbolFileAdvice := ""
+	if symbolFile.Valid() {
+		symbolFileAdvice = "If the change is intentional, add new symbols to a new version in " +
+			symbolFile.String() + ". "
+	}
+
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        sAbiDiff,
 		Description: "header-abi-diff " + outputFile.Base(),
@@ -991,6 +1131,7 @@ func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 			"arch":                     ctx.Arch().ArchType.Name,
 			"extraFlags":               strings.Join(extraFlags, " "),
 			"createReferenceDumpFlags": createReferenceDumpFlags,
+			"symbolFileAdvice":         symbolFileAdvice,
 		},
 	})
 	return android.OptionalPathForPath(outputFile)
@@ -1020,6 +1161,40 @@ func TransformSharedObjectToToc(ctx android.ModuleContext, inputFile android.Pat
 	})
 }
 
+// hostToolCacheCommand returns the host_tool_cache command line that the compile command of a host
+// object is prefixed with when SOONG_HOST_TOOL_CACHE is set, which shares the object with the
+// builds in other output directories, or "" if it is not set.  Remote builds have their own cache,
+// so they don't use it.  The compiler is hashed as an implicit input, so that a prebuilt updated
+// in place doesn't share objects with the old one.  The headers of its resource directory that
+// the source includes are listed in the depfile like the other headers.
+func hostToolCacheCommand(ctx android.ModuleContext, ccCmd string, objFile android.WritablePath,
+	implicitOutputs android.WritablePaths, implicits android.Paths) string {
+
+	cacheDir := ctx.Config().HostToolCacheDir()
+	if cacheDir == "" || ctx.Config().UseRemoteBuild() {
+		return ""
+	}
+
+	args := []string{
+		ctx.Config().HostToolPath(ctx, "host_tool_cache").String(),
+		"-cache_dir", cacheDir,
+		"-out_dir", ctx.Config().OutDir(),
+		"-o", objFile.String(),
+		"-depfile", objFile.String() + ".d",
+	}
+	for _, output := range implicitOutputs {
+		args = append(args, "-o", output.String())
+	}
+	for _, implicit := range implicits {
+		args = append(args, "-i", implicit.String())
+	}
+	if maxSize := ctx.Config().HostToolCacheMaxSize(); maxSize != "" {
+		args = append(args, "-max_size", maxSize)
+	}
+	// ccCmd refers to Ninja variables, so it is not escaped.
+	return strings.Join(proptools.NinjaAndShellEscapeList(args), " ") + " -i " + ccCmd + " -- "
+}
+
 // Generate a rule for compiling multiple .o files to a .o using ld partial linking
 func transformObjsToObj(ctx android.ModuleContext, objFiles android.Paths,
 	flags builderFlags, outputFile android.WritablePath, deps android.Paths) {
@@ -1064,9 +1239,35 @@ func transformBinaryPrefixSymbols(ctx android.ModuleContext, prefix string, inpu
 	})
 }
 
+// Generate a rule for running objcopy --keep-global-symbol on an object to make all of its global
+// symbols except symbols local
+func transformObjKeepGlobalSymbols(ctx android.ModuleContext, symbols []string, inputFile android.Path,
+	flags builderFlags, outputFile android.WritablePath) {
+
+	objcopyCmd := "${config.ClangBin}/llvm-objcopy"
+
+	var keepFlags []string
+	for _, symbol := range symbols {
+		keepFlags = append(keepFlags, "--keep-global-symbol="+proptools.ShellEscape(symbol))
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        keepGlobalSymbols,
+		Description: "localize symbols " + outputFile.Base(),
+		Output:      outputFile,
+		Input:       inputFile,
+		Args: map[string]string{
+			"objcopyCmd": objcopyCmd,
+			"keepFlags":  strings.Join(keepFlags, " "),
+		},
+	})
+}
+
 // Registers a build statement to invoke `strip` (to discard symbols and data from object files).
+// If splitDebugFile is not nil the debug info is written to it, and the gnu-debuglink of the output
+// file points at it.
 func transformStrip(ctx android.ModuleContext, inputFile android.Path,
-	outputFile android.WritablePath, flags StripFlags) {
+	outputFile, splitDebugFile android.WritablePath, flags StripFlags) {
 
 	args := ""
 	if flags.StripAddGnuDebuglink {
@@ -1084,12 +1285,21 @@ func transformStrip(ctx android.ModuleContext, inputFile android.Path,
 	if flags.StripKeepSymbolsAndDebugFrame {
 		args += " --keep-symbols-and-debug-frame"
 	}
+	if flags.StripKeepSymbolTable {
+		args += " --keep-symbol-table"
+	}
+	var implicitOutputs android.WritablePaths
+	if splitDebugFile != nil {
+		args += " -s " + splitDebugFile.String()
+		implicitOutputs = append(implicitOutputs, splitDebugFile)
+	}
 
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        strip,
-		Description: "strip " + outputFile.Base(),
-		Output:      outputFile,
-		Input:       inputFile,
+		Rule:            strip,
+		Description:     "strip " + outputFile.Base(),
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Input:           inputFile,
 		Args: map[string]string{
 			"args": args,
 		},
@@ -71,6 +71,7 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 		ctx.BottomUp("lto", ltoMutator).Parallel()
 
 		ctx.BottomUp("check_linktype", checkLinkTypeMutator).Parallel()
+		ctx.BottomUp("check_libcxx", checkLibcxxMutator).Parallel()
 		ctx.TopDown("double_loadable", checkDoubleLoadableLibraries).Parallel()
 	})
 
@@ -162,6 +163,7 @@ type PathDeps struct {
 	ReexportedDirs             android.Paths
 	ReexportedSystemDirs       android.Paths
 	ReexportedFlags            []string
+	ReexportedDefines          []string
 	ReexportedGeneratedHeaders android.Paths
 	ReexportedDeps             android.Paths
 
@@ -217,11 +219,22 @@ type Flags struct {
 	SAbiDump      bool // True if header abi dumps should be generated.
 	EmitXrefs     bool // If true, generate Ninja rules to generate emitXrefs input files for Kythe
 
+	EmitLLVMBitcode bool // True if .bc files should be generated next to the objects.
+
+	// The srcs_cflags of individual source files by path, with a "!" prefix for removed flags.
+	SrcCFlags map[string][]string
+
+	// The directory containing the clang binaries to compile with, or empty for the default.
+	ClangBin string
+
 	// The instruction set required for clang ("arm" or "thumb").
 	RequiredInstructionSet string
 	// The target-device system path to the dynamic linker.
 	DynamicLinker string
 
+	// The rule to link with instead of the default one, to run the links in a ninja pool.
+	ldRule blueprint.Rule
+
 	CFlagsDeps  android.Paths // Files depended on by compiler flags
 	LdFlagsDeps android.Paths // Files depended on by linker flags
 
@@ -267,6 +280,14 @@ type BaseProperties struct {
 	// If true, always create an sdk variant and don't create a platform variant.
 	Sdk_variant_only *bool
 
+	// If true, the sdk variant is compiled against the unified headers of the prebuilt NDK
+	// sysroot in prebuilts/ndk/current/sysroot/usr/include, instead of the NDK headers built from
+	// the platform sources.  It is an error if the prebuilt NDK has no libraries for the API level
+	// of sdk_version in prebuilts/ndk/current/platforms/android-<level>/arch-<arch>/usr/lib, or if
+	// sdk_version is "current".  The headers expose the API of the level of the target, set by
+	// min_sdk_version, which defaults to sdk_version.
+	Prebuilt_ndk_sysroot *bool
+
 	AndroidMkSharedLibs       []string `blueprint:"mutated"`
 	AndroidMkStaticLibs       []string `blueprint:"mutated"`
 	AndroidMkRuntimeLibs      []string `blueprint:"mutated"`
@@ -845,6 +866,9 @@ type Module struct {
 	kytheFiles android.Paths
 	// Object .o file output paths for this compilation module
 	objFiles android.Paths
+	// LLVM bitcode .bc file output paths for this compilation module, including the ones of the
+	// reused objects of the static variant
+	bitcodeFiles android.Paths
 	// Tidy .tidy file output paths for this compilation module
 	tidyFiles android.Paths
 
@@ -1903,6 +1927,7 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		c.kytheFiles = objs.kytheFiles
 		c.objFiles = objs.objFiles
 		c.tidyFiles = objs.tidyFiles
+		c.bitcodeFiles = append(objs.bitcodeFiles, deps.Objs.bitcodeFiles...)
 	}
 
 	if c.linker != nil {
@@ -2623,6 +2648,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 		depPaths.ReexportedDirs = append(depPaths.ReexportedDirs, exporter.IncludeDirs...)
 		depPaths.ReexportedSystemDirs = append(depPaths.ReexportedSystemDirs, exporter.SystemIncludeDirs...)
 		depPaths.ReexportedFlags = append(depPaths.ReexportedFlags, exporter.Flags...)
+		depPaths.ReexportedDefines = append(depPaths.ReexportedDefines, exporter.Defines...)
 		depPaths.ReexportedDeps = append(depPaths.ReexportedDeps, exporter.Deps...)
 		depPaths.ReexportedGeneratedHeaders = append(depPaths.ReexportedGeneratedHeaders, exporter.GeneratedHeaders...)
 	}
@@ -2641,6 +2667,8 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 		c.apexSdkVersion = android.FutureApiLevel
 	}
 
+	exportedDefines := make(map[string]exportedDefine)
+
 	ctx.VisitDirectDeps(func(dep android.Module) {
 		depName := ctx.OtherModuleName(dep)
 		depTag := ctx.OtherModuleDependencyTag(dep)
@@ -2885,6 +2913,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			depPaths.SystemIncludeDirs = append(depPaths.SystemIncludeDirs, depExporterInfo.SystemIncludeDirs...)
 			depPaths.GeneratedDeps = append(depPaths.GeneratedDeps, depExporterInfo.Deps...)
 			depPaths.Flags = append(depPaths.Flags, depExporterInfo.Flags...)
+			checkExportedDefines(ctx, exportedDefines, depName, depExporterInfo)
 
 			if libDepTag.reexportFlags {
 				reexportExporter(depExporterInfo)
@@ -2966,6 +2995,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	depPaths.ReexportedDirs = android.FirstUniquePaths(depPaths.ReexportedDirs)
 	depPaths.ReexportedSystemDirs = android.FirstUniquePaths(depPaths.ReexportedSystemDirs)
 	depPaths.ReexportedFlags = android.FirstUniqueStrings(depPaths.ReexportedFlags)
+	depPaths.ReexportedDefines = android.FirstUniqueStrings(depPaths.ReexportedDefines)
 	depPaths.ReexportedDeps = android.FirstUniquePaths(depPaths.ReexportedDeps)
 	depPaths.ReexportedGeneratedHeaders = android.FirstUniquePaths(depPaths.ReexportedGeneratedHeaders)
 
@@ -2976,6 +3006,74 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	return depPaths
 }
 
+// exportedDefine is a macro exported with export_defines by a dependency.
+type exportedDefine struct {
+	flag string
+	dep  string
+}
+
+// checkExportedDefines reports an error if a macro exported with export_defines by the dependency
+// depName has a different value than the same macro exported with export_defines by an earlier
+// dependency, as the value the module is compiled with would then depend on the order of its
+// dependencies.  The value is the one that the exported flags leave the macro with, so that a
+// later -U of the macro in export_cflags is taken into account.  Macros only defined by
+// export_cflags are not checked.
+func checkExportedDefines(ctx android.ModuleContext, defines map[string]exportedDefine, depName string, exporter FlagExporterInfo) {
+	if len(exporter.Defines) == 0 {
+		return
+	}
+	names := make(map[string]bool)
+	for _, define := range exporter.Defines {
+		names[strings.SplitN(define, "=", 2)[0]] = true
+	}
+
+	// The flag, as NAME=VALUE or -UNAME, that the exported flags leave each macro with.
+	final := make(map[string]string)
+	var order []string
+	flags := exporter.Flags
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		var arg string
+		switch {
+		case flag == "-D" || flag == "-U":
+			if i+1 >= len(flags) {
+				continue
+			}
+			i++
+			arg = flags[i]
+		case strings.HasPrefix(flag, "-D") || strings.HasPrefix(flag, "-U"):
+			arg = flag[2:]
+		default:
+			continue
+		}
+		split := strings.SplitN(strings.TrimSpace(arg), "=", 2)
+		name := split[0]
+		if !names[name] {
+			continue
+		}
+		if _, ok := final[name]; !ok {
+			order = append(order, name)
+		}
+		if strings.HasPrefix(flag, "-U") {
+			final[name] = "-U" + name
+		} else if len(split) == 2 {
+			final[name] = name + "=" + split[1]
+		} else {
+			final[name] = name + "=1"
+		}
+	}
+
+	for _, name := range order {
+		flag := final[name]
+		if prev, ok := defines[name]; !ok {
+			defines[name] = exportedDefine{flag: flag, dep: depName}
+		} else if prev.flag != flag && prev.dep != depName {
+			ctx.ModuleErrorf("conflicting definitions of %q exported by %q (%s) and %q (%s)",
+				name, prev.dep, prev.flag, depName, flag)
+		}
+	}
+}
+
 // ChooseStubOrImpl determines whether a given dependency should be redirected to the stub variant
 // of the dependency or not, and returns the SharedLibraryInfo and FlagExporterInfo for the right
 // dependency. The stub variant is selected when the dependency crosses a boundary where each side
@@ -3203,6 +3301,32 @@ func (c *Module) OutputFiles(tag string) (android.Paths, error) {
 			return android.Paths{c.outputFile.Path()}, nil
 		}
 		return android.Paths{}, nil
+	case ".map":
+		if linker, ok := c.linker.(interface {
+			linkerMapFilePath() android.OptionalPath
+		}); ok && linker.linkerMapFilePath().Valid() {
+			return android.Paths{linker.linkerMapFilePath().Path()}, nil
+		}
+		return nil, fmt.Errorf("%q requires map_file: true", tag)
+	case ".headers.zip":
+		if library, ok := c.linker.(interface {
+			exportedHeadersZipPath() android.OptionalPath
+		}); ok && library.exportedHeadersZipPath().Valid() {
+			return android.Paths{library.exportedHeadersZipPath().Path()}, nil
+		}
+		return nil, fmt.Errorf("%q requires export_headers_zip: true", tag)
+	case ".bc":
+		if c.compiler != nil && c.flags.EmitLLVMBitcode {
+			return c.bitcodeFiles, nil
+		}
+		return nil, fmt.Errorf("%q requires emit_llvm_bitcode: true", tag)
+	case ".debug":
+		if linker, ok := c.linker.(interface {
+			splitDebugFilePath() android.OptionalPath
+		}); ok && linker.splitDebugFilePath().Valid() {
+			return android.Paths{linker.splitDebugFilePath().Path()}, nil
+		}
+		return nil, fmt.Errorf("%q requires strip: { split_debug: true }", tag)
 	default:
 		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 	}
@@ -3181,6 +3181,75 @@ func TestDataLibsPrebuiltSharedTestLibrary(t *testing.T) {
 	}
 }
 
+func TestTestOptionsEnvironment(t *testing.T) {
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_test {
+			name: "main_test",
+			gtest: false,
+			test_options: {
+				environment: ["DATA_DIR=/data/local/tmp/$(MODULE_NAME)", "TEST_ARCH=$(ARCH)", "LITERAL=$$HOME"],
+			},
+		}`)
+
+	autogen := result.ModuleForTests("main_test", "android_arm64_armv8-a").Rule("autogen")
+	for _, expected := range []string{
+		`<option name="environment" key="DATA_DIR" value="/data/local/tmp/main_test" />`,
+		`<option name="environment" key="TEST_ARCH" value="arm64" />`,
+		`<option name="environment" key="LITERAL" value="$$HOME" />`,
+	} {
+		android.AssertStringDoesContain(t, "extraConfigs", autogen.Args["extraConfigs"], expected)
+	}
+}
+
+func TestTestOptionsEnvironmentErrors(t *testing.T) {
+	PrepareForIntegrationTestWithCc.
+		ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`test_options.environment: expected KEY=VALUE, got "NO_VALUE"`,
+			`test_options.environment: invalid environment variable name "1BAD"`,
+			`test_options.environment: duplicate environment variable "FOO"`,
+			`test_options.environment: BAR: unknown variable \$\(UNKNOWN\)`,
+		})).
+		RunTestWithBp(t, `
+		cc_test {
+			name: "main_test",
+			gtest: false,
+			compile_multilib: "first",
+			test_options: {
+				environment: ["NO_VALUE", "1BAD=x", "FOO=1", "FOO=2", "BAR=$(UNKNOWN)"],
+			},
+		}`)
+}
+
+func TestHostToolCache(t *testing.T) {
+	bp := `
+		cc_library_static {
+			name: "libfoo",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}`
+
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.FixtureMergeEnv(map[string]string{"SOONG_HOST_TOOL_CACHE": "/cache"}),
+	).RunTestWithBp(t, bp)
+
+	hostVariant := result.Config.BuildOSTarget.String() + "_static"
+	hostCc := result.ModuleForTests("libfoo", hostVariant).Rule("cc")
+	android.AssertStringDoesContain(t, "host compile command", hostCc.Args["hostToolCache"], "-cache_dir /cache")
+	android.AssertStringDoesContain(t, "host compile command", hostCc.Args["hostToolCache"],
+		"/obj/foo.o.d -i ${config.ClangBin}/clang -- ")
+	android.AssertStringListContains(t, "host compile implicits", hostCc.Implicits.Strings(),
+		result.Config.HostToolPath(android.PathContextForTesting(result.Config), "host_tool_cache").String())
+
+	deviceCc := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Rule("cc")
+	android.AssertStringEquals(t, "device compile command", "", deviceCc.Args["hostToolCache"])
+
+	// Without the environment variable nothing is cached.
+	result = PrepareForIntegrationTestWithCc.RunTestWithBp(t, bp)
+	hostCc = result.ModuleForTests("libfoo", hostVariant).Rule("cc")
+	android.AssertStringEquals(t, "host compile command", "", hostCc.Args["hostToolCache"])
+}
+
 func TestVersionedStubs(t *testing.T) {
 	ctx := testCc(t, `
 		cc_library_shared {
@@ -3355,6 +3424,51 @@ func TestFuzzTarget(t *testing.T) {
 	ctx.ModuleForTests("fuzz_smoke_test", variant).Rule("cc")
 }
 
+func TestFuzzCorpus(t *testing.T) {
+	prepareForFuzzCorpusTest := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"corpus/a/seed1": nil,
+			"corpus/b/seed1": nil,
+			"corpus/seed2":   nil,
+			"fuzz.dict":      nil,
+		}),
+	)
+
+	t.Run("corpus", func(t *testing.T) {
+		result := prepareForFuzzCorpusTest.RunTestWithBp(t, `
+			cc_fuzz {
+				name: "fuzz_corpus",
+				srcs: ["foo.c"],
+				corpus: ["corpus/a/seed1", "corpus/seed2"],
+				dictionary: "fuzz.dict",
+			}
+		`)
+
+		fuzz := result.ModuleForTests("fuzz_corpus", "android_arm64_armv8-a_fuzzer")
+		copyCorpus := fuzz.Rule("copy_corpus")
+		android.AssertPathsRelativeToTopEquals(t, "copy_corpus outputs", []string{
+			"out/soong/.intermediates/fuzz_corpus/android_arm64_armv8-a_fuzzer/corpus/seed1",
+			"out/soong/.intermediates/fuzz_corpus/android_arm64_armv8-a_fuzzer/corpus/seed2",
+		}, append(android.Paths{copyCorpus.Output}, copyCorpus.ImplicitOutputs.Paths()...))
+		android.AssertPathsRelativeToTopEquals(t, "copy_corpus inputs",
+			[]string{"corpus/a/seed1", "corpus/seed2"}, copyCorpus.Implicits)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		prepareForFuzzCorpusTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`corpus: "corpus/a/seed1" and "corpus/b/seed1" are both installed as corpus/seed1`)).
+			RunTestWithBp(t, `
+				cc_fuzz {
+					name: "fuzz_corpus",
+					srcs: ["foo.c"],
+					corpus: ["corpus/a/seed1", "corpus/b/seed1"],
+				}
+			`)
+	})
+}
+
 func TestAidl(t *testing.T) {
 }
 
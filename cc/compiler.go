@@ -42,6 +42,10 @@ type BaseCompilerProperties struct {
 	// list of source files that should not be compiled with clang-tidy.
 	Tidy_disabled_srcs []string `android:"path,arch_variant"`
 
+	// whether to skip clang-tidy on the generated sources of the module, the ones generated from
+	// srcs like .y, .l or .aidl files and the ones produced by other modules like genrule.
+	Tidy_disabled_generated_srcs *bool `android:"arch_variant"`
+
 	// list of source files that should not be compiled by clang-tidy when TIDY_TIMEOUT is set.
 	Tidy_timeout_srcs []string `android:"path,arch_variant"`
 
@@ -212,6 +216,28 @@ type BaseCompilerProperties struct {
 
 	// Build and link with OpenMP
 	Openmp *bool `android:"arch_variant"`
+
+	// The version of the clang prebuilts to compile with instead of the default version, for
+	// example "clang-r450784d". The version must be present in prebuilts/clang/host. Only
+	// compilation is pinned: linking and clang-tidy still use the default toolchain.
+	Toolchain_version *string
+
+	// The path of the toolchain_version prebuilts, resolved once when the dependencies are added.
+	ToolchainPath string `blueprint:"mutated"`
+
+	// cflags for individual source files, as "<file>: <flags>" entries where the file is listed in
+	// srcs, for example "miscompiled.cpp: -O0".  The flags are added after the flags of the module
+	// when compiling that file only.  A flag prefixed with "!" is removed from the flags of the file
+	// instead, which works for the flags of the module and its dependencies but not for the global
+	// flags of the toolchain, that can be overridden by adding the opposite flag.
+	Srcs_cflags []string `android:"arch_variant"`
+
+	// Also compile each C and C++ source file to LLVM bitcode with the same flags, into a .bc file
+	// next to its object.  The .bc files can be used by other modules with the ":module{.bc}"
+	// syntax, and are not used for linking.  When lto is enabled the flags contain -flto, so the
+	// .bc files contain the same (Thin)LTO summaries as the objects, before any cross-module
+	// optimization.  Assembly sources have no bitcode.
+	Emit_llvm_bitcode *bool
 }
 
 func NewBaseCompiler() *baseCompiler {
@@ -282,6 +308,18 @@ func (compiler *baseCompiler) compilerDeps(ctx DepsContext, deps Deps) Deps {
 		deps.StaticLibs = append(deps.StaticLibs, "libomp")
 	}
 
+	if version := String(compiler.Properties.Toolchain_version); version != "" {
+		if !config.ClangVersionRegexp.MatchString(version) {
+			ctx.PropertyErrorf("toolchain_version", "invalid clang version %q, expected a version like %q",
+				version, config.ClangDefaultVersion)
+		} else if path := config.ClangPathForVersion(ctx, version); !path.Valid() {
+			ctx.PropertyErrorf("toolchain_version", "clang version %q is not available in prebuilts: %s",
+				version, path.InvalidReason())
+		} else {
+			compiler.Properties.ToolchainPath = path.String()
+		}
+	}
+
 	return deps
 }
 
@@ -356,6 +394,15 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 
 	flags.Yacc = compiler.Properties.Yacc
 	flags.Lex = compiler.Properties.Lex
+	flags.EmitLLVMBitcode = Bool(compiler.Properties.Emit_llvm_bitcode)
+	flags.SrcCFlags = compiler.srcCFlags(ctx)
+
+	if toolchainPath := compiler.Properties.ToolchainPath; toolchainPath != "" {
+		// Depend on the pinned clang so that updating the prebuilt rebuilds the objects.
+		clangBin := android.PathForSource(ctx, toolchainPath, "bin")
+		flags.ClangBin = clangBin.String()
+		flags.CFlagsDeps = append(flags.CFlagsDeps, clangBin.Join(ctx, "clang"))
+	}
 
 	// Include dir cflags
 	localIncludeDirs := android.PathsForModuleSrc(ctx, compiler.Properties.Local_include_dirs)
@@ -388,9 +435,17 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 		// typical Soong approach would be to only make the headers for the
 		// library you're using available, we're trying to emulate the NDK
 		// behavior here, and the NDK always has all the NDK headers available.
-		flags.SystemIncludeFlags = append(flags.SystemIncludeFlags,
-			"-isystem "+getCurrentIncludePath(ctx).String(),
-			"-isystem "+getCurrentIncludePath(ctx).Join(ctx, config.NDKTriple(tc)).String())
+		if Bool(ctx.Module().(*Module).Properties.Prebuilt_ndk_sysroot) {
+			if includeDir, ok := prebuiltNdkSysrootIncludeDir(ctx); ok {
+				flags.SystemIncludeFlags = append(flags.SystemIncludeFlags,
+					"-isystem "+includeDir.String(),
+					"-isystem "+includeDir.Join(ctx, config.NDKTriple(tc)).String())
+			}
+		} else {
+			flags.SystemIncludeFlags = append(flags.SystemIncludeFlags,
+				"-isystem "+getCurrentIncludePath(ctx).String(),
+				"-isystem "+getCurrentIncludePath(ctx).Join(ctx, config.NDKTriple(tc)).String())
+		}
 	}
 
 	if ctx.useVndk() {
@@ -610,6 +665,54 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	return flags
 }
 
+// srcCFlags parses the srcs_cflags property into the escaped flags of each source file, by the
+// path of the file.  The flags to remove keep their "!" prefix.
+func (compiler *baseCompiler) srcCFlags(ctx ModuleContext) map[string][]string {
+	if len(compiler.Properties.Srcs_cflags) == 0 {
+		return nil
+	}
+
+	// The shared variant of a library that reuses the objects of the static variant has no srcs.
+	srcs := append(android.Paths{}, compiler.srcsBeforeGen...)
+	srcs = append(srcs, android.PathsForModuleSrcExcludes(ctx, compiler.Properties.OriginalSrcs,
+		compiler.Properties.Exclude_srcs)...)
+	inSrcs := make(map[string]bool)
+	for _, src := range srcs {
+		inSrcs[src.String()] = true
+	}
+
+	ret := make(map[string][]string)
+	for _, entry := range compiler.Properties.Srcs_cflags {
+		split := strings.SplitN(entry, ":", 2)
+		file := strings.TrimSpace(split[0])
+		if len(split) != 2 || file == "" {
+			ctx.PropertyErrorf("srcs_cflags", "%q must have the form \"<file>: <flags>\"", entry)
+			continue
+		}
+		path := filepath.Join(ctx.ModuleDir(), file)
+		if !inSrcs[path] {
+			ctx.PropertyErrorf("srcs_cflags", "%q is not in srcs", file)
+			continue
+		}
+		switch filepath.Ext(file) {
+		case ".c", ".cpp", ".cc", ".cxx", ".mm":
+		default:
+			ctx.PropertyErrorf("srcs_cflags", "%q is not a C or C++ source file", file)
+			continue
+		}
+
+		for _, flag := range strings.Fields(split[1]) {
+			if strings.HasPrefix(flag, "!") {
+				ret[path] = append(ret[path], "!"+proptools.NinjaAndShellEscape(flag[1:]))
+			} else {
+				CheckBadCompilerFlags(ctx, "srcs_cflags", []string{flag})
+				ret[path] = append(ret[path], proptools.NinjaAndShellEscape(flag))
+			}
+		}
+	}
+	return ret
+}
+
 func (compiler *baseCompiler) hasSrcExt(ext string) bool {
 	for _, src := range compiler.srcsBeforeGen {
 		if src.Ext() == ext {
@@ -669,7 +772,7 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 
 	// Compile files listed in c.Properties.Srcs into objects
 	objs := compileObjs(ctx, buildFlags, "", srcs,
-		android.PathsForModuleSrc(ctx, compiler.Properties.Tidy_disabled_srcs),
+		compiler.tidyDisabledSrcs(ctx, srcs, compiler.Properties.Tidy_disabled_srcs),
 		android.PathsForModuleSrc(ctx, compiler.Properties.Tidy_timeout_srcs),
 		pathDeps, compiler.cFlagsDeps)
 
@@ -680,6 +783,21 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 	return objs
 }
 
+// tidyDisabledSrcs returns the sources that should not be compiled with clang-tidy, the ones in
+// disabledSrcs and, if tidy_disabled_generated_srcs is set, the ones in srcs that are not in the
+// source tree.
+func (compiler *baseCompiler) tidyDisabledSrcs(ctx ModuleContext, srcs android.Paths, disabledSrcs []string) android.Paths {
+	ret := android.PathsForModuleSrc(ctx, disabledSrcs)
+	if Bool(compiler.Properties.Tidy_disabled_generated_srcs) {
+		for _, src := range srcs {
+			if _, ok := src.(android.SourcePath); !ok {
+				ret = append(ret, src)
+			}
+		}
+	}
+	return ret
+}
+
 // Compile a list of source files into objects a specified subdirectory
 func compileObjs(ctx ModuleContext, flags builderFlags, subdir string,
 	srcFiles, noTidySrcs, timeoutTidySrcs, pathDeps android.Paths, cFlagsDeps android.Paths) Objects {
@@ -15,6 +15,9 @@
 package cc
 
 import (
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -44,3 +47,287 @@ func TestIsThirdParty(t *testing.T) {
 		}
 	}
 }
+
+func TestToolchainVersion(t *testing.T) {
+	prepareForToolchainVersionTest := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddFile("prebuilts/clang/host/linux-x86/clang-r123456b/bin/clang", nil),
+	)
+
+	t.Run("pinned", func(t *testing.T) {
+		result := prepareForToolchainVersionTest.RunTestWithBp(t, `
+			cc_library_static {
+				name: "libpinned",
+				srcs: ["foo.c"],
+				toolchain_version: "clang-r123456b",
+			}
+			cc_library_shared {
+				name: "libpinned_shared",
+				srcs: ["foo.c"],
+				toolchain_version: "clang-r123456b",
+			}
+			cc_library_static {
+				name: "libdefault",
+				srcs: ["foo.c"],
+			}
+		`)
+
+		pinned := result.ModuleForTests("libpinned", "android_arm64_armv8-a_static").Rule("cc")
+		android.AssertStringEquals(t, "pinned ccCmd",
+			"prebuilts/clang/host/linux-x86/clang-r123456b/bin/clang", pinned.Args["ccCmd"])
+		android.AssertPathsRelativeToTopEquals(t, "pinned implicits",
+			[]string{"prebuilts/clang/host/linux-x86/clang-r123456b/bin/clang"}, pinned.Implicits)
+
+		unpinned := result.ModuleForTests("libdefault", "android_arm64_armv8-a_static").Rule("cc")
+		android.AssertStringEquals(t, "default ccCmd", "${config.ClangBin}/clang", unpinned.Args["ccCmd"])
+
+		// Only compilation is pinned, the library is still linked with the default toolchain.
+		shared := result.ModuleForTests("libpinned_shared", "android_arm64_armv8-a_shared")
+		android.AssertStringEquals(t, "pinned shared ccCmd",
+			"prebuilts/clang/host/linux-x86/clang-r123456b/bin/clang", shared.Rule("cc").Args["ccCmd"])
+		android.AssertStringEquals(t, "pinned shared ldCmd", "${config.ClangBin}/clang++", shared.Rule("ld").Args["ldCmd"])
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		prepareForToolchainVersionTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`toolchain_version: invalid clang version "14.0.6"`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libpinned",
+					srcs: ["foo.c"],
+					toolchain_version: "14.0.6",
+				}
+			`)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		prepareForToolchainVersionTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`toolchain_version: clang version "clang-r999999" is not available in prebuilts`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libpinned",
+					srcs: ["foo.c"],
+					toolchain_version: "clang-r999999",
+				}
+			`)
+	})
+}
+
+func TestEmitLLVMBitcode(t *testing.T) {
+	result := prepareForCcTest.RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c", "bar.S"],
+			shared: {
+				srcs: ["baz.cpp"],
+			},
+			emit_llvm_bitcode: true,
+		}
+		cc_library_static {
+			name: "libbar",
+			srcs: ["foo.c"],
+		}
+	`)
+
+	static := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+	bitcode := static.Output("obj/foo.bc")
+	android.AssertStringEquals(t, "rule", "ccBitcode", bitcode.Rule.String())
+	android.AssertStringEquals(t, "cFlags", static.Output("obj/foo.o").Args["cFlags"], bitcode.Args["cFlags"])
+	android.AssertPathsRelativeToTopEquals(t, "archived objects", []string{
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_static/obj/foo.o",
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_static/obj/bar.o",
+	}, static.Output("libfoo.a").Inputs)
+
+	sharedModule := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Module()
+	outputFiles, err := sharedModule.(android.OutputFileProducer).OutputFiles(".bc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	android.AssertPathsRelativeToTopEquals(t, "shared .bc files", []string{
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/baz.bc",
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_static/obj/foo.bc",
+	}, outputFiles)
+
+	bar := result.ModuleForTests("libbar", "android_arm64_armv8-a_static").Module()
+	if _, err := bar.(android.OutputFileProducer).OutputFiles(".bc"); err == nil {
+		t.Errorf("expected an error for the .bc files of a module without emit_llvm_bitcode")
+	}
+}
+
+func TestSrcsCflags(t *testing.T) {
+	t.Run("override", func(t *testing.T) {
+		result := prepareForCcTest.RunTestWithBp(t, `
+			cc_library_static {
+				name: "libfoo",
+				srcs: ["foo.c", "bar.cpp", "baz.c"],
+				cflags: ["-DFOO", "-Werror"],
+				srcs_cflags: [
+					"bar.cpp: -O0 !-Werror",
+					"baz.c: -DBAZ",
+				],
+			}
+		`)
+
+		libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+		// The flags of multiple source files are shared through module variables.
+		cFlags := func(obj string) string {
+			flags := libfoo.Output(obj).Args["cFlags"]
+			if strings.HasPrefix(flags, "$") {
+				return libfoo.Module().VariablesForTests()[flags[1:]]
+			}
+			return flags
+		}
+		foo := cFlags("obj/foo.o")
+		bar := cFlags("obj/bar.o")
+		baz := cFlags("obj/baz.o")
+
+		android.AssertStringDoesNotContain(t, "foo cFlags", foo, "-O0")
+		android.AssertStringDoesNotContain(t, "foo cFlags", foo, "-DBAZ")
+		android.AssertStringDoesContain(t, "foo cFlags", foo, "-Werror")
+
+		android.AssertStringDoesContain(t, "bar cFlags", bar, "-DFOO")
+		android.AssertStringDoesNotContain(t, "bar cFlags", bar, "-Werror")
+		if !strings.HasSuffix(bar, " -O0 ${config.NoOverrideGlobalCflags}") {
+			t.Errorf("expected -O0 before the flags that cannot be overridden, got %q", bar)
+		}
+
+		android.AssertStringDoesContain(t, "baz cFlags", baz, "-Werror")
+		if !strings.HasSuffix(baz, " -DBAZ ${config.NoOverrideGlobalCflags}") {
+			t.Errorf("expected -DBAZ before the flags that cannot be overridden, got %q", baz)
+		}
+	})
+
+	t.Run("not in srcs", func(t *testing.T) {
+		prepareForCcTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`srcs_cflags: "bar.c" is not in srcs`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libfoo",
+					srcs: ["foo.c"],
+					srcs_cflags: ["bar.c: -O0"],
+				}
+			`)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		prepareForCcTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`srcs_cflags: "-O0" must have the form "<file>: <flags>"`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libfoo",
+					srcs: ["foo.c"],
+					srcs_cflags: ["-O0"],
+				}
+			`)
+	})
+}
+
+func TestPrebuiltNdkSysroot(t *testing.T) {
+	// The layout of the prebuilt NDK: unified headers, and libraries per API level.
+	prepareForPrebuiltNdkSysrootTest := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"prebuilts/ndk/current/sysroot/usr/include/stdio.h":                           nil,
+			"prebuilts/ndk/current/sysroot/usr/include/aarch64-linux-android/asm/types.h": nil,
+			"prebuilts/ndk/current/sysroot/usr/include/arm-linux-androideabi/asm/types.h": nil,
+			"prebuilts/ndk/current/platforms/android-29/arch-arm64/usr/lib/crtbegin_so.o": nil,
+			"prebuilts/ndk/current/platforms/android-29/arch-arm/usr/lib/crtbegin_so.o":   nil,
+		}),
+	)
+
+	t.Run("selected by sdk_version", func(t *testing.T) {
+		result := prepareForPrebuiltNdkSysrootTest.RunTestWithBp(t, `
+			cc_library_static {
+				name: "libfoo",
+				srcs: ["foo.c"],
+				sdk_version: "29",
+				prebuilt_ndk_sysroot: true,
+			}
+		`)
+
+		cFlags := result.ModuleForTests("libfoo", "android_arm64_armv8-a_sdk_static").Rule("cc").Args["cFlags"]
+		android.AssertStringDoesContain(t, "cFlags", cFlags,
+			"-isystem prebuilts/ndk/current/sysroot/usr/include "+
+				"-isystem prebuilts/ndk/current/sysroot/usr/include/aarch64-linux-android")
+		// The API level of the unified headers is selected by the level of the target.
+		android.AssertStringDoesContain(t, "cFlags", cFlags, "-target aarch64-linux-android29")
+		android.AssertStringDoesNotContain(t, "cFlags", cFlags, "out/soong/ndk/sysroot/usr/include")
+
+		// The platform variant doesn't use the NDK headers.
+		platformCFlags := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Rule("cc").Args["cFlags"]
+		android.AssertStringDoesNotContain(t, "platform cFlags", platformCFlags, "prebuilts/ndk/current/sysroot")
+	})
+
+	t.Run("missing level", func(t *testing.T) {
+		prepareForPrebuiltNdkSysrootTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`prebuilt_ndk_sysroot: the prebuilt NDK has no sysroot for API level 30, `+
+					`prebuilts/ndk/current/platforms/android-30/arch-arm64/usr/lib does not exist`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libfoo",
+					srcs: ["foo.c"],
+					sdk_version: "30",
+					prebuilt_ndk_sysroot: true,
+				}
+			`)
+	})
+
+	t.Run("current", func(t *testing.T) {
+		prepareForPrebuiltNdkSysrootTest.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`prebuilt_ndk_sysroot: requires a numbered sdk_version, got "current"`)).
+			RunTestWithBp(t, `
+				cc_library_static {
+					name: "libfoo",
+					srcs: ["foo.c"],
+					sdk_version: "current",
+					prebuilt_ndk_sysroot: true,
+				}
+			`)
+	})
+}
+
+func TestTidyDisabledGeneratedSrcs(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"parser.y": nil,
+		}),
+	).RunTestWithBp(t, `
+		genrule {
+			name: "gen_src",
+			cmd: "touch $(out)",
+			out: ["gen.cpp"],
+		}
+		cc_library_static {
+			name: "libfoo",
+			srcs: ["foo.c", "parser.y", ":gen_src"],
+			tidy: true,
+			tidy_disabled_generated_srcs: true,
+		}
+		cc_library_static {
+			name: "libbar",
+			srcs: ["foo.c", "parser.y", ":gen_src"],
+			tidy: true,
+		}
+	`)
+
+	tidyFiles := func(name string) []string {
+		var ret []string
+		for _, o := range result.ModuleForTests(name, "android_arm64_armv8-a_static").AllOutputs() {
+			if strings.HasSuffix(o, ".tidy") {
+				ret = append(ret, filepath.Base(o))
+			}
+		}
+		sort.Strings(ret)
+		return ret
+	}
+
+	android.AssertDeepEquals(t, "libfoo tidy files", []string{"foo.tidy"}, tidyFiles("libfoo"))
+	android.AssertDeepEquals(t, "libbar tidy files", []string{"foo.tidy", "gen.tidy", "parser.tidy"}, tidyFiles("libbar"))
+}
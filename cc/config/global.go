@@ -15,6 +15,7 @@
 package config
 
 import (
+	"regexp"
 	"runtime"
 	"strings"
 
@@ -431,6 +432,10 @@ func init() {
 
 var HostPrebuiltTag = exportedVars.ExportVariableConfigMethod("HostPrebuiltTag", android.Config.PrebuiltOS)
 
+// ClangVersionRegexp matches the names of the versioned clang prebuilt directories, for example
+// "clang-r450784d".
+var ClangVersionRegexp = regexp.MustCompile(`^clang-r[0-9]+[a-z]*$`)
+
 func ClangPath(ctx android.PathContext, file string) android.SourcePath {
 	type clangToolKey string
 
@@ -445,14 +450,31 @@ var clangPathKey = android.NewOnceKey("clangPath")
 
 func clangPath(ctx android.PathContext) android.SourcePath {
 	return ctx.Config().OnceSourcePath(clangPathKey, func() android.SourcePath {
-		clangBase := ClangDefaultBase
-		if override := ctx.Config().Getenv("LLVM_PREBUILTS_BASE"); override != "" {
-			clangBase = override
-		}
 		clangVersion := ClangDefaultVersion
 		if override := ctx.Config().Getenv("LLVM_PREBUILTS_VERSION"); override != "" {
 			clangVersion = override
 		}
-		return android.PathForSource(ctx, clangBase, ctx.Config().PrebuiltOS(), clangVersion)
+		return android.PathForSource(ctx, clangBase(ctx), ctx.Config().PrebuiltOS(), clangVersion)
 	})
 }
+
+func clangBase(ctx android.PathContext) string {
+	if override := ctx.Config().Getenv("LLVM_PREBUILTS_BASE"); override != "" {
+		return override
+	}
+	return ClangDefaultBase
+}
+
+// ClangPathForVersion returns the path to the given version of the clang prebuilts, or an invalid
+// OptionalPath if that version is not present in the source tree.
+func ClangPathForVersion(ctx android.PathContext, version string) android.OptionalPath {
+	return android.ExistentPathForSource(ctx, clangBase(ctx), ctx.Config().PrebuiltOS(), version)
+}
+
+// MoldPath returns the path to the prebuilt mold linker, or an invalid OptionalPath if it is not
+// present in the source tree.  mold is not part of the checked in prebuilts/build-tools, a tree
+// that uses it has to add a mold binary built from https://github.com/rui314/mold for the build
+// OS as prebuilts/build-tools/<os>-x86/bin/mold, like linux-x86/bin/mold.
+func MoldPath(ctx android.PathContext) android.OptionalPath {
+	return android.ExistentPathForSource(ctx, "prebuilts/build-tools", ctx.Config().PrebuiltOS(), "bin", "mold")
+}
@@ -24,9 +24,18 @@ import (
 
 const profileInstrFlag = "-fprofile-instr-generate=/data/misc/trace/clang-%p-%m.profraw"
 
+// Host binaries write their profiles to the working directory, unless LLVM_PROFILE_FILE is set.
+const hostProfileInstrFlag = "-fprofile-instr-generate=clang-%p-%m.profraw"
+
 type CoverageProperties struct {
 	Native_coverage *bool
 
+	// Instrument the host variants of this module for clang coverage, even when coverage is not
+	// enabled for the build.  The instrumented variant is used by the modules that set
+	// host_coverage too, like the test of the module, all the other modules keep using the
+	// uninstrumented one.  Only supported for Linux hosts.
+	Host_coverage *bool
+
 	NeedCoverageVariant bool `blueprint:"mutated"`
 	NeedCoverageBuild   bool `blueprint:"mutated"`
 
@@ -66,7 +75,8 @@ func getClangProfileLibraryName(ctx ModuleContextIntf) string {
 }
 
 func (cov *coverage) deps(ctx DepsContext, deps Deps) Deps {
-	if cov.Properties.NeedCoverageVariant {
+	// The profile extras libraries only support the device, host coverage doesn't need them.
+	if cov.Properties.NeedCoverageVariant && ctx.Device() {
 		ctx.AddVariationDependencies([]blueprint.Variation{
 			{Mutator: "link", Variation: "static"},
 		}, CoverageDepTag, getGcovProfileLibraryName(ctx))
@@ -84,6 +94,15 @@ func EnableContinuousCoverage(ctx android.BaseModuleContext) bool {
 func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags, PathDeps) {
 	clangCoverage := ctx.DeviceConfig().ClangCoverageEnabled()
 	gcovCoverage := ctx.DeviceConfig().GcovCoverageEnabled()
+	instrFlag := profileInstrFlag
+	continuousCoverage := ctx.Device() && EnableContinuousCoverage(ctx)
+	if ctx.Host() {
+		// Host modules are only instrumented by host_coverage, which uses clang coverage.  The
+		// coverage variants of the dependencies are only used by other coverage variants.
+		clangCoverage = cov.Properties.IsCoverageVariant
+		gcovCoverage = false
+		instrFlag = hostProfileInstrFlag
+	}
 
 	if !gcovCoverage && !clangCoverage {
 		return flags, deps
@@ -100,12 +119,12 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 			// flags that the module may use.
 			flags.Local.CFlags = append(flags.Local.CFlags, "-Wno-frame-larger-than=", "-O0")
 		} else if clangCoverage {
-			flags.Local.CommonFlags = append(flags.Local.CommonFlags, profileInstrFlag,
+			flags.Local.CommonFlags = append(flags.Local.CommonFlags, instrFlag,
 				"-fcoverage-mapping", "-Wno-pass-failed", "-D__ANDROID_CLANG_COVERAGE__")
 			// Override -Wframe-larger-than.  We can expect frame size increase after
 			// coverage instrumentation.
 			flags.Local.CFlags = append(flags.Local.CFlags, "-Wno-frame-larger-than=")
-			if EnableContinuousCoverage(ctx) {
+			if continuousCoverage {
 				flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-mllvm", "-runtime-counter-relocation")
 			}
 		}
@@ -158,14 +177,17 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 
 			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--wrap,getenv")
 		} else if clangCoverage {
-			flags.Local.LdFlags = append(flags.Local.LdFlags, profileInstrFlag)
-			if EnableContinuousCoverage(ctx) {
+			// The driver links the profile runtime for the flag, even with -nodefaultlibs.
+			flags.Local.LdFlags = append(flags.Local.LdFlags, instrFlag)
+			if continuousCoverage {
 				flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,-mllvm=-runtime-counter-relocation")
 			}
 
-			coverage := ctx.GetDirectDepWithTag(getClangProfileLibraryName(ctx), CoverageDepTag).(*Module)
-			deps.WholeStaticLibs = append(deps.WholeStaticLibs, coverage.OutputFile().Path())
-			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--wrap,open")
+			if ctx.Device() {
+				coverage := ctx.GetDirectDepWithTag(getClangProfileLibraryName(ctx), CoverageDepTag).(*Module)
+				deps.WholeStaticLibs = append(deps.WholeStaticLibs, coverage.OutputFile().Path())
+				flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--wrap,open")
+			}
 		}
 	}
 
@@ -174,8 +196,16 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 
 func (cov *coverage) begin(ctx BaseModuleContext) {
 	if ctx.Host() {
-		// TODO(dwillemsen): because of -nodefaultlibs, we must depend on libclang_rt.profile-*.a
-		// Just turn off for now.
+		// Coverage of the whole build is only supported for the device, host modules have to ask
+		// for it.
+		if Bool(cov.Properties.Host_coverage) && ctx.nativeCoverage() {
+			if !ctx.Os().Linux() {
+				ctx.PropertyErrorf("host_coverage", "not supported for %s", ctx.Os())
+				return
+			}
+			cov.Properties.NeedCoverageVariant = true
+			cov.Properties.NeedCoverageBuild = true
+		}
 	} else {
 		cov.Properties = SetCoverageProperties(ctx, cov.Properties, ctx.nativeCoverage(), ctx.useSdk(), ctx.sdkVersion())
 	}
@@ -230,17 +260,24 @@ func coverageMutator(mctx android.BottomUpMutatorContext) {
 		if needCoverageVariant {
 			m := mctx.CreateVariations("", "cov")
 
-			// Setup the non-coverage version and set HideFromMake and
-			// PreventInstall to true.
+			// Setup the non-coverage version.
 			m[0].(*Module).coverage.Properties.CoverageEnabled = false
 			m[0].(*Module).coverage.Properties.IsCoverageVariant = false
-			m[0].(*Module).Properties.HideFromMake = true
-			m[0].(*Module).Properties.PreventInstall = true
 
-			// The coverage-enabled version inherits HideFromMake,
-			// PreventInstall from the original module.
 			m[1].(*Module).coverage.Properties.CoverageEnabled = needCoverageBuild
 			m[1].(*Module).coverage.Properties.IsCoverageVariant = true
+
+			// Only one of the variants is installed, the other one has HideFromMake and
+			// PreventInstall set to true while the installed one inherits them from the
+			// original module.  host_coverage only instruments a host module for its tests,
+			// so outside of coverage builds the uninstrumented variant of a host module stays
+			// the installed one, unless the module is a test itself.
+			hidden := m[0].(*Module)
+			if mctx.Host() && !c.testBinary() && !mctx.DeviceConfig().NativeCoverageEnabled() {
+				hidden = m[1].(*Module)
+			}
+			hidden.Properties.HideFromMake = true
+			hidden.Properties.PreventInstall = true
 		}
 	} else if cov, ok := mctx.Module().(Coverage); ok && cov.IsNativeCoverageNeeded(mctx) {
 		// APEX and Rust modules fall here
@@ -0,0 +1,80 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestHostCoverage(t *testing.T) {
+	ctx := testCc(t, `
+cc_library_static {
+	name: "libfoo",
+	host_supported: true,
+	srcs: ["foo.cc"],
+	host_coverage: true,
+}
+cc_test_host {
+	name: "foo_test",
+	srcs: ["foo_test.cc"],
+	static_libs: ["libfoo"],
+	host_coverage: true,
+	gtest: false,
+}
+cc_binary_host {
+	name: "bar",
+	srcs: ["bar.cc"],
+	static_libs: ["libfoo"],
+}`)
+
+	libCov := ctx.ModuleForTests("libfoo", "linux_glibc_x86_64_static_cov")
+	android.AssertStringDoesContain(t, "libfoo cov cflags", libCov.Rule("cc").Args["cFlags"], "-fcoverage-mapping")
+	android.AssertStringDoesContain(t, "libfoo cov cflags", libCov.Rule("cc").Args["cFlags"], hostProfileInstrFlag)
+
+	lib := ctx.ModuleForTests("libfoo", "linux_glibc_x86_64_static")
+	android.AssertStringDoesNotContain(t, "libfoo cflags", lib.Rule("cc").Args["cFlags"], "-fcoverage-mapping")
+
+	// The device variant is not instrumented when coverage is not enabled for the build.
+	device := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+	android.AssertStringDoesNotContain(t, "libfoo device cflags", device.Rule("cc").Args["cFlags"], "-fcoverage-mapping")
+
+	test := ctx.ModuleForTests("foo_test", "linux_glibc_x86_64_cov").Rule("ld")
+	android.AssertStringDoesContain(t, "foo_test ldflags", test.Args["ldFlags"], hostProfileInstrFlag)
+	android.AssertStringListContains(t, "foo_test links the instrumented libfoo", test.Inputs.Strings(),
+		libCov.Output("libfoo.a").Output.String())
+
+	// Outside of coverage builds the uninstrumented host variant of a module stays the installed
+	// one, except for tests.
+	for _, tc := range []struct {
+		module, variant string
+		installed       bool
+	}{
+		{"libfoo", "linux_glibc_x86_64_static", true},
+		{"libfoo", "linux_glibc_x86_64_static_cov", false},
+		{"foo_test", "linux_glibc_x86_64", false},
+		{"foo_test", "linux_glibc_x86_64_cov", true},
+	} {
+		m := ctx.ModuleForTests(tc.module, tc.variant).Module().(*Module)
+		android.AssertBoolEquals(t, tc.module+" "+tc.variant+" installed", tc.installed, !m.Properties.PreventInstall)
+		android.AssertBoolEquals(t, tc.module+" "+tc.variant+" visible to make", tc.installed, !m.Properties.HideFromMake)
+	}
+
+	bar := ctx.ModuleForTests("bar", "linux_glibc_x86_64").Rule("ld")
+	android.AssertStringDoesNotContain(t, "bar ldflags", bar.Args["ldFlags"], hostProfileInstrFlag)
+	android.AssertStringListContains(t, "bar links the uninstrumented libfoo", bar.Inputs.Strings(),
+		lib.Output("libfoo.a").Output.String())
+}
@@ -44,6 +44,12 @@ type LibraryProperties struct {
 	// rename host libraries to prevent overlap with system installed libraries
 	Unique_host_soname *bool
 
+	// package the headers in the export_include_dirs and export_system_include_dirs of the library
+	// into a zip, with their paths relative to the include directories, for consumers outside of
+	// the tree.  The headers of the local include directories and of the dependencies are not
+	// included.  The zip is available with the {.headers.zip} tag.
+	Export_headers_zip *bool
+
 	Aidl struct {
 		// export headers generated from .aidl sources
 		Export_aidl_headers *bool
@@ -113,6 +119,10 @@ type LibraryProperties struct {
 
 		// Extra flags passed to header-abi-diff
 		Diff_flags []string
+
+		// Don't compare the ABI dump against the reference ABI dump, for libraries whose ABI is
+		// not stable yet. The ABI dump is still generated.
+		Skip_diff *bool
 	}
 
 	// Inject boringssl hash into the shared library.  This is only intended for use by external/boringssl.
@@ -202,6 +212,11 @@ type FlagExporterProperties struct {
 	// list of plain cc flags to be used for any module that links against this module.
 	Export_cflags []string  `android:"arch_variant"`
 
+	// list of macros, as NAME or NAME=VALUE, that are defined with -D for any module that links
+	// against this module.  They are reexported like export_cflags, and a module that gets
+	// different values of the same macro from two dependencies is an error.
+	Export_defines []string `android:"arch_variant"`
+
 	Target struct {
 		Vendor, Product struct {
 			// list of exported include directories, like
@@ -256,7 +271,7 @@ type bazelCcLibraryAttributes struct {
 	Use_libcrt             bazel.BoolAttribute
 	Rtti                   bazel.BoolAttribute
 
-	Stl     *string
+	Stl     bazel.StringAttribute
 	Cpp_std *string
 	C_std   *string
 
@@ -498,6 +513,7 @@ type flagExporter struct {
 	dirs       android.Paths // Include directories to be included with -I
 	systemDirs android.Paths // System include directories to be included with -isystem
 	flags      []string      // Exported raw flags.
+	defines    []string      // Macros exported with export_defines, also included in flags.
 	deps       android.Paths
 	headers    android.Paths
 }
@@ -524,8 +540,19 @@ func (f *flagExporter) exportIncludes(ctx ModuleContext) {
 
 func (f *flagExporter) exportExtraFlags(ctx ModuleContext) {
 	f.flags = append(f.flags, f.Properties.Export_cflags...)
+	for _, define := range f.Properties.Export_defines {
+		name := strings.SplitN(define, "=", 2)[0]
+		if !exportedDefineNameRegexp.MatchString(name) {
+			ctx.PropertyErrorf("export_defines", "invalid macro name %q in %q", name, define)
+			continue
+		}
+		f.flags = append(f.flags, "-D"+define)
+		f.defines = append(f.defines, define)
+	}
 }
 
+var exportedDefineNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // exportIncludesAsSystem registers the include directories and system include directories to be
 // exported transitively both as system include directories to modules depending on this module.
 func (f *flagExporter) exportIncludesAsSystem(ctx ModuleContext) {
@@ -556,6 +583,12 @@ func (f *flagExporter) reexportFlags(flags ...string) {
 	f.flags = append(f.flags, flags...)
 }
 
+// reexportDefines registers the macros, which must also be passed to reexportFlags, as exported
+// with export_defines by a dependency.
+func (f *flagExporter) reexportDefines(defines ...string) {
+	f.defines = append(f.defines, defines...)
+}
+
 func (f *flagExporter) reexportDeps(deps ...android.Path) {
 	f.deps = append(f.deps, deps...)
 }
@@ -574,6 +607,8 @@ func (f *flagExporter) setProvider(ctx android.ModuleContext) {
 		SystemIncludeDirs: android.FirstUniquePaths(f.systemDirs),
 		// Used in very few places as a one-off way of adding extra defines.
 		Flags: f.flags,
+		// Comes from Export_defines property, and those of exported transitive deps.
+		Defines: f.defines,
 		// Used sparingly, for extra files that need to be explicitly exported to dependers,
 		// or for phony files to minimize ninja.
 		Deps: f.deps,
@@ -627,6 +662,9 @@ type libraryDecorator struct {
 	// Location of the linked, unstripped library for shared libraries
 	unstrippedOutputFile android.Path
 
+	// Location of the debug info of the stripped shared library when strip.split_debug is set
+	splitDebugFile android.OptionalPath
+
 	// Location of the file that should be copied to dist dir when requested
 	distFile android.Path
 
@@ -648,6 +686,9 @@ type libraryDecorator struct {
 
 	collectedSnapshotHeaders android.Paths
 
+	// The zip of the exported headers, if export_headers_zip is set.
+	exportedHeadersZip android.OptionalPath
+
 	apiListCoverageXmlPath android.ModuleOutPath
 }
 
@@ -1094,13 +1135,13 @@ func (library *libraryDecorator) compile(ctx ModuleContext, flags Flags, deps Pa
 	if library.static() {
 		srcs := android.PathsForModuleSrc(ctx, library.StaticProperties.Static.Srcs)
 		objs = objs.Append(compileObjs(ctx, buildFlags, android.DeviceStaticLibrary, srcs,
-			android.PathsForModuleSrc(ctx, library.StaticProperties.Static.Tidy_disabled_srcs),
+			library.baseCompiler.tidyDisabledSrcs(ctx, srcs, library.StaticProperties.Static.Tidy_disabled_srcs),
 			android.PathsForModuleSrc(ctx, library.StaticProperties.Static.Tidy_timeout_srcs),
 			library.baseCompiler.pathDeps, library.baseCompiler.cFlagsDeps))
 	} else if library.shared() {
 		srcs := android.PathsForModuleSrc(ctx, library.SharedProperties.Shared.Srcs)
 		objs = objs.Append(compileObjs(ctx, buildFlags, android.DeviceSharedLibrary, srcs,
-			android.PathsForModuleSrc(ctx, library.SharedProperties.Shared.Tidy_disabled_srcs),
+			library.baseCompiler.tidyDisabledSrcs(ctx, srcs, library.SharedProperties.Shared.Tidy_disabled_srcs),
 			android.PathsForModuleSrc(ctx, library.SharedProperties.Shared.Tidy_timeout_srcs),
 			library.baseCompiler.pathDeps, library.baseCompiler.cFlagsDeps))
 	}
@@ -1467,6 +1508,9 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--out-implib="+importLibraryPath.String())
 		implicitOutputs = append(implicitOutputs, importLibraryPath)
 	}
+	if mapFile := library.linkerMapFile(ctx, &flags, fileName); mapFile != nil {
+		implicitOutputs = append(implicitOutputs, mapFile)
+	}
 
 	builderFlags := flagsToBuilderFlags(flags)
 
@@ -1494,7 +1538,7 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 		}
 		strippedOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unstripped", fileName)
-		library.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile, stripFlags)
+		library.splitDebugFile = library.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile, stripFlags)
 	}
 	library.unstrippedOutputFile = outputFile
 
@@ -1526,9 +1570,16 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	linkerDeps = append(linkerDeps, deps.EarlySharedLibsDeps...)
 	linkerDeps = append(linkerDeps, deps.SharedLibsDeps...)
 	linkerDeps = append(linkerDeps, deps.LateSharedLibsDeps...)
+	validations := objs.tidyDepFiles
+	if checkFlags, checkFile := library.allowedUndefinedSymbolsCheck(ctx, builderFlags, fileName); checkFile != nil {
+		transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs,
+			deps.StaticLibs, deps.LateStaticLibs, deps.WholeStaticLibs,
+			linkerDeps, deps.CrtBegin, deps.CrtEnd, false, checkFlags, checkFile, nil, nil)
+		validations = append(android.CopyOfPaths(validations), checkFile)
+	}
 	transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs,
 		deps.StaticLibs, deps.LateStaticLibs, deps.WholeStaticLibs,
-		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs, objs.tidyDepFiles)
+		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs, validations)
 
 	objs.coverageFiles = append(objs.coverageFiles, deps.StaticLibObjs.coverageFiles...)
 	objs.coverageFiles = append(objs.coverageFiles, deps.WholeStaticLibObjs.coverageFiles...)
@@ -1578,6 +1629,10 @@ func (library *libraryDecorator) unstrippedOutputFilePath() android.Path {
 	return library.unstrippedOutputFile
 }
 
+func (library *libraryDecorator) splitDebugFilePath() android.OptionalPath {
+	return library.splitDebugFile
+}
+
 func (library *libraryDecorator) disableStripping() {
 	library.stripper.StripProperties.Strip.None = BoolPtr(true)
 }
@@ -1637,17 +1692,22 @@ func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objec
 			SourceAbiFlags = append(SourceAbiFlags, "-I"+reexportedInclude)
 		}
 		exportedHeaderFlags := strings.Join(SourceAbiFlags, " ")
+		symbolFile := android.OptionalPathForModuleSrc(ctx, library.symbolFileForAbiCheck(ctx))
 		library.sAbiOutputFile = transformDumpToLinkedDump(ctx, objs.sAbiDumpFiles, soFile, fileName, exportedHeaderFlags,
-			android.OptionalPathForModuleSrc(ctx, library.symbolFileForAbiCheck(ctx)),
+			symbolFile,
 			library.Properties.Header_abi_checker.Exclude_symbol_versions,
 			library.Properties.Header_abi_checker.Exclude_symbol_tags)
 
 		addLsdumpPath(classifySourceAbiDump(ctx) + ":" + library.sAbiOutputFile.String())
 
+		if Bool(library.Properties.Header_abi_checker.Skip_diff) {
+			return
+		}
+
 		refAbiDumpFile := getRefAbiDumpFile(ctx, vndkVersion, fileName)
 		if refAbiDumpFile != nil {
 			library.sAbiDiff = sourceAbiDiff(ctx, library.sAbiOutputFile.Path(),
-				refAbiDumpFile, fileName, exportedHeaderFlags,
+				refAbiDumpFile, fileName, exportedHeaderFlags, symbolFile,
 				library.Properties.Header_abi_checker.Diff_flags,
 				Bool(library.Properties.Header_abi_checker.Check_all_apis),
 				ctx.IsLlndk(), ctx.isNdk(ctx.Config()), ctx.IsVndkExt())
@@ -1736,10 +1796,14 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 
 	// Export include paths and flags to be propagated up the tree.
 	library.exportIncludes(ctx)
+	if Bool(library.Properties.Export_headers_zip) {
+		library.exportedHeadersZip = android.OptionalPathForPath(library.buildExportedHeadersZip(ctx))
+	}
 	library.exportExtraFlags(ctx)
 	library.reexportDirs(deps.ReexportedDirs...)
 	library.reexportSystemDirs(deps.ReexportedSystemDirs...)
 	library.reexportFlags(deps.ReexportedFlags...)
+	library.reexportDefines(deps.ReexportedDefines...)
 	library.reexportDeps(deps.ReexportedDeps...)
 	library.addExportedGeneratedHeaders(deps.ReexportedGeneratedHeaders...)
 
@@ -1805,6 +1869,36 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 	return out
 }
 
+// buildExportedHeadersZip packages the headers in the include directories exported by the library
+// itself into a zip, keeping their paths relative to the include directories.
+func (library *libraryDecorator) buildExportedHeadersZip(ctx ModuleContext) android.Path {
+	zipFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".headers.zip")
+	dirs := append(library.flagExporter.exportedIncludes(ctx),
+		android.PathsForModuleSrc(ctx, library.flagExporter.Properties.Export_system_include_dirs)...)
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", zipFile)
+	for i, dir := range dirs {
+		headers := GlobHeadersForSnapshot(ctx, android.Paths{dir})
+		if len(headers) == 0 {
+			continue
+		}
+		rspFile := android.PathForModuleOut(ctx, "headers_zip", strconv.Itoa(i)+".rsp")
+		cmd.FlagWithArg("-C ", dir.String()).
+			FlagWithRspFileInputList("-r ", rspFile, headers)
+	}
+	rule.Build("headers_zip", "headers zip "+ctx.ModuleName())
+	return zipFile
+}
+
+// exportedHeadersZipPath returns the zip of the exported headers written when
+// export_headers_zip is set.
+func (library *libraryDecorator) exportedHeadersZipPath() android.OptionalPath {
+	return library.exportedHeadersZip
+}
+
 func (library *libraryDecorator) exportVersioningMacroIfNeeded(ctx android.BaseModuleContext) {
 	if library.buildStubs() && library.stubsVersion() != "" && !library.skipAPIDefine {
 		name := versioningMacroName(ctx.Module().(*Module).ImplementationModuleName(ctx))
@@ -2589,7 +2683,7 @@ type bazelCcLibraryStaticAttributes struct {
 	Use_version_lib bazel.BoolAttribute
 
 	Rtti    bazel.BoolAttribute
-	Stl     *string
+	Stl     bazel.StringAttribute
 	Cpp_std *string
 	C_std   *string
 
@@ -2618,7 +2712,7 @@ type bazelCcLibrarySharedAttributes struct {
 	Use_version_lib bazel.BoolAttribute
 
 	Rtti    bazel.BoolAttribute
-	Stl     *string
+	Stl     bazel.StringAttribute
 	Cpp_std *string
 	C_std   *string
 
@@ -97,3 +97,111 @@ func TestPrebuiltLibraryHeadersPreferred(t *testing.T) {
 		})
 	}
 }
+
+func TestLibraryHeadersExportDefines(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_headers {
+			name: "config_headers",
+			export_defines: ["USE_FOO", "FOO_LEVEL=2"],
+		}
+		cc_library_headers {
+			name: "headers",
+			header_libs: ["config_headers"],
+			export_header_lib_headers: ["config_headers"],
+			export_defines: ["BAR"],
+		}
+		cc_library_headers {
+			name: "private_headers",
+			header_libs: ["config_headers"],
+		}
+		cc_library_static {
+			name: "lib",
+			srcs: ["foo.c"],
+			header_libs: ["headers"],
+		}
+		cc_library_static {
+			name: "lib_private",
+			srcs: ["foo.c"],
+			header_libs: ["private_headers"],
+		}
+	`)
+
+	cFlags := ctx.ModuleForTests("lib", "android_arm64_armv8-a_static").Rule("cc").Args["cFlags"]
+	for _, flag := range []string{" -DBAR ", " -DUSE_FOO ", " -DFOO_LEVEL=2 "} {
+		android.AssertStringDoesContain(t, "cFlags for lib", cFlags, flag)
+	}
+
+	// Defines of header libraries that are not reexported are not propagated.
+	cFlags = ctx.ModuleForTests("lib_private", "android_arm64_armv8-a_static").Rule("cc").Args["cFlags"]
+	android.AssertStringDoesNotContain(t, "cFlags for lib_private", cFlags, "-DUSE_FOO")
+}
+
+func TestLibraryHeadersConflictingDefines(t *testing.T) {
+	testCcError(t, `conflicting definitions of "FOO_LEVEL" exported by "headers_a" \(FOO_LEVEL=1\) and "headers_b" \(FOO_LEVEL=2\)`, `
+		cc_library_headers {
+			name: "headers_a",
+			export_defines: ["FOO_LEVEL"],
+		}
+		cc_library_headers {
+			name: "headers_b",
+			export_defines: ["FOO_LEVEL=2"],
+		}
+		cc_library_static {
+			name: "lib",
+			srcs: ["foo.c"],
+			header_libs: ["headers_a", "headers_b"],
+		}
+	`)
+}
+
+func TestLibraryHeadersInvalidDefine(t *testing.T) {
+	testCcError(t, `export_defines: invalid macro name "-DFOO" in "-DFOO"`, `
+		cc_library_headers {
+			name: "headers",
+			export_defines: ["-DFOO"],
+		}
+	`)
+}
+
+func TestLibraryHeadersExportCflagsDefinesNotChecked(t *testing.T) {
+	// Macros only defined by export_cflags are not checked for conflicts.
+	testCc(t, `
+		cc_library_headers {
+			name: "headers_a",
+			export_cflags: ["-DFOO_LEVEL=1"],
+		}
+		cc_library_headers {
+			name: "headers_b",
+			export_cflags: ["-D", "FOO_LEVEL=2"],
+		}
+		cc_library_static {
+			name: "lib",
+			srcs: ["foo.c"],
+			header_libs: ["headers_a", "headers_b"],
+		}
+	`)
+}
+
+func TestLibraryHeadersUndefinedDefine(t *testing.T) {
+	testCcError(t, `conflicting definitions of "FOO_LEVEL" exported by "headers_a" \(FOO_LEVEL=1\) and "headers_b" \(-UFOO_LEVEL\)`, `
+		cc_library_headers {
+			name: "headers_a",
+			export_defines: ["FOO_LEVEL"],
+		}
+		cc_library_headers {
+			name: "undef_headers",
+			export_cflags: ["-U", "FOO_LEVEL"],
+		}
+		cc_library_headers {
+			name: "headers_b",
+			export_defines: ["FOO_LEVEL"],
+			header_libs: ["undef_headers"],
+			export_header_lib_headers: ["undef_headers"],
+		}
+		cc_library_static {
+			name: "lib",
+			srcs: ["foo.c"],
+			header_libs: ["headers_a", "headers_b"],
+		}
+	`)
+}
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -318,6 +319,69 @@ func TestLibraryVersionScript(t *testing.T) {
 
 }
 
+func TestLibraryArchVersionScript(t *testing.T) {
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			version_script: "foo.map.txt",
+			arch: {
+				arm64: {
+					version_script: "foo.arm64.map.txt",
+				},
+			},
+		}
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c"],
+			arch: {
+				arm64: {
+					version_script: "bar.arm64.map.txt",
+				},
+			},
+		}`)
+
+	ldFlags := func(module, variant string) string {
+		return result.ModuleForTests(module, variant).Rule("ld").Args["ldFlags"]
+	}
+
+	arm64 := "android_arm64_armv8-a_shared"
+	arm := "android_arm_armv7-a-neon_shared"
+
+	android.AssertStringDoesContain(t, "arm64 version_script", ldFlags("libfoo", arm64),
+		"-Wl,--version-script,foo.arm64.map.txt")
+	android.AssertStringDoesNotContain(t, "top-level version_script on arm64", ldFlags("libfoo", arm64),
+		"-Wl,--version-script,foo.map.txt")
+	android.AssertStringDoesContain(t, "fallback to the top-level version_script", ldFlags("libfoo", arm),
+		"-Wl,--version-script,foo.map.txt")
+
+	android.AssertStringDoesContain(t, "arm64 version_script", ldFlags("libbar", arm64),
+		"-Wl,--version-script,bar.arm64.map.txt")
+	android.AssertStringDoesNotContain(t, "no version_script on arm", ldFlags("libbar", arm),
+		"--version-script")
+}
+
+func TestLibraryArchVersionScriptMissing(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.PrepareForTestDisallowNonExistentPaths,
+		android.FixtureAddFile("foo.c", nil),
+		android.FixtureAddFile("foo.map.txt", nil),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`module "libfoo" variant "android_arm64_armv8-a_shared": .*module source path "foo.arm64.map.txt" does not exist`)).
+		RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			version_script: "foo.map.txt",
+			arch: {
+				arm64: {
+					version_script: "foo.arm64.map.txt",
+				},
+			},
+		}`)
+}
+
 func TestLibraryDynamicList(t *testing.T) {
 	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
 		cc_library {
@@ -335,6 +399,237 @@ func TestLibraryDynamicList(t *testing.T) {
 
 }
 
+func TestLinkerMapFile(t *testing.T) {
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			map_file: true,
+		}
+
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.c"],
+			use_clang_lld: false,
+			map_file: true,
+		}
+
+		genrule {
+			name: "sizes",
+			srcs: [":libfoo{.map}"],
+			out: ["sizes.txt"],
+			cmd: "cat $(in) > $(out)",
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	libfooMap := libfoo.Output("libfoo.so.map")
+	android.AssertStringDoesContain(t, "missing lld map flag",
+		libfooMap.Args["ldFlags"], "-Wl,--Map="+libfooMap.Output.String())
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a")
+	fooMap := foo.Output("foo.map")
+	android.AssertStringDoesContain(t, "missing gold map flag",
+		fooMap.Args["ldFlags"], "-Wl,-Map,"+fooMap.Output.String())
+
+	sizes := result.ModuleForTests("sizes", "").Output("sizes.txt")
+	android.AssertStringListContains(t, "genrule inputs", sizes.Implicits.Strings(), libfooMap.Output.String())
+
+	// The static variant isn't linked, so it has no map file.
+	static := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Module().(*Module)
+	if _, err := static.OutputFiles(".map"); err == nil {
+		t.Errorf("expected an error for the map file of a static library")
+	}
+}
+
+func TestAllowedUndefinedSymbols(t *testing.T) {
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			allowed_undefined_symbols: ["host_callback", "host_data"],
+			map_file: true,
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	link := libfoo.Output("libfoo.so.map")
+	android.AssertStringDoesNotContain(t, "link ldflags", link.Args["ldFlags"], "-Wl,--no-undefined")
+	android.AssertStringDoesContain(t, "link ldflags", link.Args["ldFlags"], "-Wl,--unresolved-symbols=ignore-all")
+
+	// A second link defines the allowed symbols and reports the other undefined ones.
+	check := libfoo.Output("allowed_undefined_symbols/libfoo.so")
+	android.AssertStringDoesContain(t, "check ldflags", check.Args["ldFlags"],
+		"-Wl,--unresolved-symbols=report-all -Wl,--defsym=host_callback=0 -Wl,--defsym=host_data=0")
+	android.AssertStringDoesContain(t, "check map file", check.Args["ldFlags"],
+		"-Wl,--Map="+check.Output.String()+".map")
+	android.AssertPathsRelativeToTopEquals(t, "link validations",
+		[]string{check.Output.String()}, link.Validations)
+}
+
+func TestAllowedUndefinedSymbolsErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`allowed_undefined_symbols: invalid symbol name ""`,
+	)).RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			allowed_undefined_symbols: [""],
+		}`)
+}
+
+func TestLinker(t *testing.T) {
+	prepareForLinkerTest := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.FixtureMergeMockFs(android.MockFS{
+			"prebuilts/build-tools/linux-x86/bin/mold": nil,
+		}),
+	)
+
+	result := prepareForLinkerTest.RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			linker: "mold",
+		}
+
+		cc_library_shared {
+			name: "libfoo_unpacked",
+			srcs: ["foo.c"],
+			linker: "mold",
+			pack_relocations: false,
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.c"],
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("ld")
+	android.AssertStringDoesContain(t, "libfoo ldflags", libfoo.Args["ldFlags"],
+		"--ld-path=prebuilts/build-tools/linux-x86/bin/mold")
+	android.AssertStringListContains(t, "libfoo implicits", libfoo.Implicits.Strings(),
+		"prebuilts/build-tools/linux-x86/bin/mold")
+
+	// mold only takes the SHT_RELR relocation packing flags.
+	android.AssertStringDoesContain(t, "libfoo ldflags", libfoo.Args["ldFlags"], "-Wl,-z,pack-relative-relocs")
+	android.AssertStringDoesNotContain(t, "libfoo ldflags", libfoo.Args["ldFlags"], "--pack-dyn-relocs")
+	android.AssertStringDoesNotContain(t, "libfoo ldflags", libfoo.Args["ldFlags"], "--use-android-relr-tags")
+
+	libfooUnpacked := result.ModuleForTests("libfoo_unpacked", "android_arm64_armv8-a_shared").Rule("ld")
+	android.AssertStringDoesContain(t, "libfoo_unpacked ldflags", libfooUnpacked.Args["ldFlags"],
+		"-Wl,-z,nopack-relative-relocs")
+	android.AssertStringDoesNotContain(t, "libfoo_unpacked ldflags", libfooUnpacked.Args["ldFlags"], "--pack-dyn-relocs")
+
+	libbar := result.ModuleForTests("libbar", "android_arm64_armv8-a_shared").Rule("ld")
+	android.AssertStringDoesNotContain(t, "libbar ldflags", libbar.Args["ldFlags"], "--ld-path")
+	android.AssertStringDoesContain(t, "libbar ldflags", libbar.Args["ldFlags"], "-Wl,--pack-dyn-relocs=android+relr")
+
+	t.Run("unknown", func(t *testing.T) {
+		prepareForLinkerTest.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`linker: "gold" is not supported, use "lld" or "mold"`,
+		)).RunTestWithBp(t, `
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["foo.c"],
+				linker: "gold",
+			}`)
+	})
+
+	t.Run("gnu ld", func(t *testing.T) {
+		prepareForLinkerTest.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`linker: "mold" cannot be used together with use_clang_lld: false`,
+		)).RunTestWithBp(t, `
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["foo.c"],
+				use_clang_lld: false,
+				linker: "mold",
+			}`)
+	})
+}
+
+func TestLinkPool(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			link_pool: "link_pool",
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.c"],
+		}`
+
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.NinjaPools = []string{"link_pool:2"}
+		}),
+	).RunTestWithBp(t, bp)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("ld_pool")
+	if libfoo.RuleParams.Pool == nil || libfoo.RuleParams.Pool.String() != "link_pool" {
+		t.Errorf("expected the link of libfoo in link_pool, got %v", libfoo.RuleParams.Pool)
+	}
+	libbar := result.ModuleForTests("libbar", "android_arm64_armv8-a_shared")
+	if rule := libbar.MaybeRule("ld_pool"); rule.Rule != nil {
+		t.Errorf("expected the link of libbar without a pool")
+	}
+
+	android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`link_pool: unknown ninja pool "link_pool"`,
+	)).RunTestWithBp(t, bp)
+}
+
+func TestExportHeadersZip(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.FixtureAddFile("include/foo/foo.h", nil),
+		android.FixtureAddFile("include/foo/README", nil),
+		android.FixtureAddFile("sysinclude/bar.h", nil),
+		android.FixtureAddFile("internal/baz.h", nil),
+	).RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			local_include_dirs: ["internal"],
+			export_include_dirs: ["include"],
+			export_system_include_dirs: ["sysinclude"],
+			export_headers_zip: true,
+		}
+
+		genrule {
+			name: "headers",
+			srcs: [":libfoo{.headers.zip}"],
+			out: ["headers.zip"],
+			cmd: "cp $(in) $(out)",
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	zip := libfoo.Output("libfoo.headers.zip")
+	android.AssertStringDoesContain(t, "headers zip command", zip.RuleParams.Command,
+		"-C include -r out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/headers_zip/0.rsp "+
+			"-C sysinclude -r out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/headers_zip/1.rsp")
+	inputs := zip.Implicits.Strings()
+	android.AssertStringListContains(t, "headers zip inputs", inputs, "include/foo/foo.h")
+	android.AssertStringListContains(t, "headers zip inputs", inputs, "sysinclude/bar.h")
+	android.AssertStringListDoesNotContain(t, "headers zip inputs", inputs, "include/foo/README")
+	android.AssertStringListDoesNotContain(t, "headers zip inputs", inputs, "internal/baz.h")
+
+	headers := result.ModuleForTests("headers", "").Output("headers.zip")
+	android.AssertStringListContains(t, "genrule inputs", headers.Implicits.Strings(), zip.Output.String())
+
+	// The static variant packages the headers too.
+	static := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Module().(*Module)
+	if _, err := static.OutputFiles(".headers.zip"); err != nil {
+		t.Errorf("unexpected error for the headers zip of the static variant: %s", err)
+	}
+}
+
 func TestCcLibrarySharedWithBazel(t *testing.T) {
 	bp := `
 cc_library_shared {
@@ -453,3 +748,46 @@ func TestWholeStaticLibPrebuilts(t *testing.T) {
 	android.AssertStringDoesContain(t, "missing flag for baz.o",
 		libtransitiveWithSrcs.Args["arObjs"], bazObj.Output.String())
 }
+
+func TestHeaderAbiCheckerDiff(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			header_abi_checker: {
+				enabled: true,
+				symbol_file: "libfoo.map.txt",
+				skip_diff: %t,
+			},
+		}
+	`
+	prepareForAbiDiffTest := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Platform_vndk_version = StringPtr("29")
+		}),
+		android.FixtureAddFile("libfoo.map.txt", nil),
+		android.FixtureAddFile("prebuilts/abi-dumps/platform/29/64/arm64/source-based/libfoo.so.lsdump", nil),
+	)
+
+	t.Run("checked", func(t *testing.T) {
+		result := prepareForAbiDiffTest.RunTestWithBp(t, fmt.Sprintf(bp, false))
+		diff := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("sAbiDiff")
+		android.AssertStringEquals(t, "symbolFileAdvice",
+			"If the change is intentional, add new symbols to a new version in libfoo.map.txt. ",
+			diff.Args["symbolFileAdvice"])
+		android.AssertStringDoesContain(t, "referenceDump", diff.Args["referenceDump"],
+			"prebuilts/abi-dumps/platform/29/64/arm64/source-based/libfoo.so.lsdump")
+	})
+
+	t.Run("skip_diff", func(t *testing.T) {
+		result := prepareForAbiDiffTest.RunTestWithBp(t, fmt.Sprintf(bp, true))
+		libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+		if diff := libfoo.MaybeRule("sAbiDiff"); diff.Rule != nil {
+			t.Errorf("expected no ABI diff with skip_diff: true, got %s", diff.Output)
+		}
+		if dump := libfoo.MaybeOutput("libfoo.so.lsdump"); dump.Rule == nil {
+			t.Errorf("expected the ABI dump to still be generated with skip_diff: true")
+		}
+	})
+}
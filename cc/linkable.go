@@ -382,6 +382,7 @@ type FlagExporterInfo struct {
 	IncludeDirs       android.Paths // Include directories to be included with -I
 	SystemIncludeDirs android.Paths // System include directories to be included with -isystem
 	Flags             []string      // Exported raw flags.
+	Defines           []string      // Macros exported with export_defines, also included in Flags.
 	Deps              android.Paths
 	GeneratedHeaders  android.Paths
 }
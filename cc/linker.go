@@ -16,6 +16,7 @@ package cc
 
 import (
 	"fmt"
+	"strings"
 
 	"android/soong/android"
 	"android/soong/cc/config"
@@ -59,14 +60,36 @@ type BaseLinkerProperties struct {
 	// modules cannot contain undefined symbols that are not satisified by their immediate
 	// dependencies.  Set this flag to true to remove --no-undefined from the linker flags.
 	// This flag should only be necessary for compiling low-level libraries like libc.
+	// Use allowed_undefined_symbols instead to allow only specific symbols.
 	Allow_undefined_symbols *bool `android:"arch_variant"`
 
+	// list of undefined symbols that the module is allowed to contain, like symbols that are
+	// provided at runtime by the executable that loads the library.  Unlike allow_undefined_symbols,
+	// other undefined symbols are still link errors.  Not supported for windows.
+	Allowed_undefined_symbols []string `android:"arch_variant"`
+
+	// name of a ninja pool declared by the NinjaPools product variable to run the links of the
+	// module in, to limit the number of concurrent memory-hungry links separately from the other
+	// actions.  Links that run remotely are not limited.
+	Link_pool *string
+
 	// don't link in libclang_rt.builtins-*.a
 	No_libcrt *bool `android:"arch_variant"`
 
 	// Use clang lld instead of gnu ld.
 	Use_clang_lld *bool `android:"arch_variant"`
 
+	// The linker to link the module with, either "lld", the default, or "mold" to try a
+	// different linker on some modules.  The linker is passed the flags of lld, so it cannot be
+	// used together with use_clang_lld: false, except for the relocation packing flags that are
+	// translated for mold, which only packs relocations in the SHT_RELR format.  mold is taken from
+	// prebuilts/build-tools and only links ELF files for arm, arm64, x86 and x86_64.
+	Linker *string `android:"arch_variant"`
+
+	// If true, the linker writes a map file of the shared library or binary next to the linked
+	// output, which other modules can reference as ":module{.map}".
+	Map_file *bool `android:"arch_variant"`
+
 	// -l arguments to pass to linker for host-provided shared libraries
 	Host_ldlibs []string `android:"arch_variant"`
 
@@ -221,7 +244,8 @@ type BaseLinkerProperties struct {
 	// Generate compact dynamic relocation table, default true.
 	Pack_relocations *bool `android:"arch_variant"`
 
-	// local file name to pass to the linker as --version_script
+	// local file name to pass to the linker as --version_script.  A version_script set for an
+	// architecture in arch: { ... } replaces the top-level one for that architecture.
 	Version_script *string `android:"path,arch_variant"`
 
 	// local file name to pass to the linker as --dynamic-list
@@ -272,6 +296,8 @@ type baseLinker struct {
 	}
 
 	sanitize *sanitize
+
+	mapFile android.OptionalPath
 }
 
 func (linker *baseLinker) appendLdflags(flags []string) {
@@ -446,6 +472,122 @@ func (linker *baseLinker) useClangLld(ctx ModuleContext) bool {
 	return true
 }
 
+// customLinkerFlags adds the flags to replace lld with the linker set by the linker property, which
+// takes the flags of lld.  The linker is a dependency of the link, so that the module is relinked
+// when it changes.
+func (linker *baseLinker) customLinkerFlags(ctx ModuleContext, flags Flags) Flags {
+	switch name := String(linker.Properties.Linker); name {
+	case "", "lld":
+	case "mold":
+		switch ctx.Os() {
+		case android.Android, android.Linux, android.LinuxBionic:
+		default:
+			ctx.PropertyErrorf("linker", "%q is not supported for %s", name, ctx.Os())
+			return flags
+		}
+		switch ctx.Arch().ArchType {
+		case android.Arm, android.Arm64, android.X86, android.X86_64:
+		default:
+			ctx.PropertyErrorf("linker", "%q is not supported for %s", name, ctx.Arch().ArchType)
+			return flags
+		}
+		if !linker.useClangLld(ctx) {
+			ctx.PropertyErrorf("linker", "%q cannot be used together with use_clang_lld: false", name)
+			return flags
+		}
+		path := config.MoldPath(ctx)
+		if !path.Valid() {
+			ctx.PropertyErrorf("linker", "%q is not available in prebuilts: %s", name, path.InvalidReason())
+			return flags
+		}
+		// --ld-path takes precedence over the -fuse-ld=lld in the global flags.
+		flags.Global.LdFlags = append(flags.Global.LdFlags, "--ld-path="+path.String())
+		flags.LdFlagsDeps = append(flags.LdFlagsDeps, path.Path())
+	default:
+		ctx.PropertyErrorf("linker", "%q is not supported, use \"lld\" or \"mold\"", name)
+	}
+	return flags
+}
+
+// linkerMapFile adds the flags to write a linker map file for the output fileName to flags if
+// map_file is set, and returns the path of the map file to declare as an output of the link.
+func (linker *baseLinker) linkerMapFile(ctx ModuleContext, flags *Flags, fileName string) android.WritablePath {
+	if !Bool(linker.Properties.Map_file) {
+		return nil
+	}
+
+	mapFile := android.PathForModuleOut(ctx, fileName+".map")
+	switch {
+	case ctx.Darwin():
+		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,-map,"+mapFile.String())
+	case linker.useClangLld(ctx):
+		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--Map="+mapFile.String())
+	default:
+		// Older versions of gold only accept the file name as a separate argument.
+		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,-Map,"+mapFile.String())
+	}
+	linker.mapFile = android.OptionalPathForPath(mapFile)
+	return mapFile
+}
+
+// linkerMapFilePath returns the linker map file written when map_file is set.
+func (linker *baseLinker) linkerMapFilePath() android.OptionalPath {
+	return linker.mapFile
+}
+
+// allowedUndefinedSymbolsFlags returns the linker flags that allow the symbols listed in
+// allowed_undefined_symbols to be undefined.  The ELF linkers can only allow all undefined symbols,
+// so allowedUndefinedSymbolsCheck adds a separate link that reports the other ones.
+func (linker *baseLinker) allowedUndefinedSymbolsFlags(ctx ModuleContext) []string {
+	var ldFlags []string
+	for _, symbol := range linker.Properties.Allowed_undefined_symbols {
+		if symbol == "" || strings.ContainsAny(symbol, " \t,=") {
+			ctx.PropertyErrorf("allowed_undefined_symbols", "invalid symbol name %q", symbol)
+			continue
+		}
+		if ctx.Darwin() {
+			// C symbols are prefixed with an underscore in Mach-O.
+			ldFlags = append(ldFlags, "-Wl,-U,_"+symbol)
+		}
+	}
+	switch {
+	case ctx.Windows():
+		ctx.PropertyErrorf("allowed_undefined_symbols", "not supported for windows")
+	case !ctx.Darwin():
+		ldFlags = append(ldFlags, "-Wl,--unresolved-symbols=ignore-all")
+	}
+	return ldFlags
+}
+
+// allowedUndefinedSymbolsCheck returns the builder flags and the output file of a link of the
+// module that only reports the undefined symbols that are not in allowed_undefined_symbols, by
+// defining the allowed ones.  It is used as a validation of the real link, and returns a nil path
+// when no check is needed.
+func (linker *baseLinker) allowedUndefinedSymbolsCheck(ctx ModuleContext, flags builderFlags,
+	fileName string) (builderFlags, android.WritablePath) {
+
+	if len(linker.Properties.Allowed_undefined_symbols) == 0 || Bool(linker.Properties.Allow_undefined_symbols) ||
+		ctx.Darwin() || ctx.Windows() {
+		return flags, nil
+	}
+
+	checkFile := android.PathForModuleOut(ctx, "allowed_undefined_symbols", fileName)
+	ldFlags := []string{flags.localLdFlags, "-Wl,--unresolved-symbols=report-all"}
+	for _, symbol := range linker.Properties.Allowed_undefined_symbols {
+		ldFlags = append(ldFlags, "-Wl,--defsym="+symbol+"=0")
+	}
+	if linker.mapFile.Valid() {
+		// Don't write the map file of the real link, the last flag wins.
+		if linker.useClangLld(ctx) {
+			ldFlags = append(ldFlags, "-Wl,--Map="+checkFile.String()+".map")
+		} else {
+			ldFlags = append(ldFlags, "-Wl,-Map,"+checkFile.String()+".map")
+		}
+	}
+	flags.localLdFlags = strings.Join(ldFlags, " ")
+	return flags, checkFile
+}
+
 // Check whether the SDK version is not older than the specific one
 func CheckSdkVersionAtLeast(ctx ModuleContext, SdkVersion android.ApiLevel) bool {
 	if ctx.minSdkVersion() == "current" {
@@ -463,6 +605,39 @@ func CheckSdkVersionAtLeast(ctx ModuleContext, SdkVersion android.ApiLevel) bool
 	return true
 }
 
+// packRelocationsFlags returns the flags that select the packing of the relocations for the linker
+// set by the linker property.  mold only supports the SHT_RELR format, so it doesn't pack the
+// relocations of modules for the API levels that predate it.
+func (linker *baseLinker) packRelocationsFlags(ctx ModuleContext) []string {
+	mold := String(linker.Properties.Linker) == "mold"
+	if !BoolDefault(linker.Properties.Pack_relocations, packRelocationsDefault) {
+		if mold {
+			return []string{"-Wl,-z,nopack-relative-relocs"}
+		}
+		return []string{"-Wl,--pack-dyn-relocs=none"}
+	} else if !ctx.Device() {
+		return nil
+	}
+
+	// SHT_RELR relocations are only supported at API level >= 30.
+	// ANDROID_RELR relocations were supported at API level >= 28.
+	// Relocation packer was supported at API level >= 23.
+	// Do the best we can...
+	if (!ctx.useSdk() && ctx.minSdkVersion() == "") || CheckSdkVersionAtLeast(ctx, android.FirstShtRelrVersion) {
+		if mold {
+			return []string{"-Wl,-z,pack-relative-relocs"}
+		}
+		return []string{"-Wl,--pack-dyn-relocs=android+relr"}
+	} else if mold {
+		return nil
+	} else if CheckSdkVersionAtLeast(ctx, android.FirstAndroidRelrVersion) {
+		return []string{"-Wl,--pack-dyn-relocs=android+relr", "-Wl,--use-android-relr-tags"}
+	} else if CheckSdkVersionAtLeast(ctx, android.FirstPackedRelocationsVersion) {
+		return []string{"-Wl,--pack-dyn-relocs=android"}
+	}
+	return nil
+}
+
 // ModuleContext extends BaseModuleContext
 // BaseModuleContext should know if LLD is used?
 func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
@@ -475,35 +650,30 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 
 	if linker.useClangLld(ctx) {
 		flags.Global.LdFlags = append(flags.Global.LdFlags, fmt.Sprintf("${config.%sGlobalLldflags}", hod))
-		if !BoolDefault(linker.Properties.Pack_relocations, packRelocationsDefault) {
-			flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--pack-dyn-relocs=none")
-		} else if ctx.Device() {
-			// SHT_RELR relocations are only supported at API level >= 30.
-			// ANDROID_RELR relocations were supported at API level >= 28.
-			// Relocation packer was supported at API level >= 23.
-			// Do the best we can...
-			if (!ctx.useSdk() && ctx.minSdkVersion() == "") || CheckSdkVersionAtLeast(ctx, android.FirstShtRelrVersion) {
-				flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--pack-dyn-relocs=android+relr")
-			} else if CheckSdkVersionAtLeast(ctx, android.FirstAndroidRelrVersion) {
-				flags.Global.LdFlags = append(flags.Global.LdFlags,
-					"-Wl,--pack-dyn-relocs=android+relr",
-					"-Wl,--use-android-relr-tags")
-			} else if CheckSdkVersionAtLeast(ctx, android.FirstPackedRelocationsVersion) {
-				flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--pack-dyn-relocs=android")
-			}
-		}
+		flags.Global.LdFlags = append(flags.Global.LdFlags, linker.packRelocationsFlags(ctx)...)
 	} else {
 		flags.Global.LdFlags = append(flags.Global.LdFlags, fmt.Sprintf("${config.%sGlobalLdflags}", hod))
 	}
+	flags = linker.customLinkerFlags(ctx, flags)
 	if Bool(linker.Properties.Allow_undefined_symbols) {
 		if ctx.Darwin() {
 			// darwin defaults to treating undefined symbols as errors
 			flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,-undefined,dynamic_lookup")
 		}
+	} else if len(linker.Properties.Allowed_undefined_symbols) > 0 {
+		flags.Global.LdFlags = append(flags.Global.LdFlags, linker.allowedUndefinedSymbolsFlags(ctx)...)
 	} else if !ctx.Darwin() && !ctx.Windows() {
 		flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--no-undefined")
 	}
 
+	if pool := String(linker.Properties.Link_pool); pool != "" {
+		if ninjaPool, ok := ctx.Config().NinjaPool(pool); ok {
+			flags.ldRule = ldRuleWithPool(ctx, ninjaPool)
+		} else {
+			ctx.PropertyErrorf("link_pool", "unknown ninja pool %q, pools are declared by the NinjaPools product variable", pool)
+		}
+	}
+
 	if linker.useClangLld(ctx) {
 		flags.Global.LdFlags = append(flags.Global.LdFlags, toolchain.Lldflags())
 	} else {
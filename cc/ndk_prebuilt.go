@@ -35,14 +35,53 @@ func init() {
 // than to the system image).
 
 func getNdkLibDir(ctx android.ModuleContext, toolchain config.Toolchain, version string) android.SourcePath {
+	return android.PathForSource(ctx, ndkLibDir(ctx, toolchain, version))
+}
+
+// ndkLibDir returns the directory of the prebuilt NDK libraries for the API level version.
+func ndkLibDir(ctx android.BaseModuleContext, toolchain config.Toolchain, version string) string {
 	suffix := ""
 	// Most 64-bit NDK prebuilts store libraries in "lib64", except for arm64 which is not a
 	// multilib toolchain and stores the libraries in "lib".
 	if toolchain.Is64Bit() && ctx.Arch().ArchType != android.Arm64 {
 		suffix = "64"
 	}
-	return android.PathForSource(ctx, fmt.Sprintf("prebuilts/ndk/current/platforms/android-%s/arch-%s/usr/lib%s",
-		version, toolchain.Name(), suffix))
+	return fmt.Sprintf("prebuilts/ndk/current/platforms/android-%s/arch-%s/usr/lib%s",
+		version, toolchain.Name(), suffix)
+}
+
+// prebuiltNdkSysrootIncludeDir returns the include directory of the prebuilt NDK sysroot for a
+// module with prebuilt_ndk_sysroot, and false after reporting an error if the sysroot is missing or
+// has no libraries for the API level of the sdk_version.  The NDK ships unified headers, shared by
+// all the API levels, in which the level of the -target, set from min_sdk_version, defines
+// __ANDROID_API__ and selects the declarations, while the libraries are in a directory per level.
+// The directories are looked up with a glob, so the build is regenerated when they are added or
+// removed.
+func prebuiltNdkSysrootIncludeDir(ctx ModuleContext) (android.SourcePath, bool) {
+	apiLevel, err := nativeApiLevelFromUser(ctx, ctx.sdkVersion())
+	if err != nil {
+		ctx.PropertyErrorf("sdk_version", "%s", err.Error())
+		return android.SourcePath{}, false
+	}
+	if apiLevel.IsPreview() {
+		ctx.PropertyErrorf("prebuilt_ndk_sysroot", "requires a numbered sdk_version, got %q",
+			ctx.sdkVersion())
+		return android.SourcePath{}, false
+	}
+
+	libDir := ndkLibDir(ctx, ctx.toolchain(), apiLevel.String())
+	if !android.ExistentPathForSource(ctx, libDir).Valid() {
+		ctx.PropertyErrorf("prebuilt_ndk_sysroot",
+			"the prebuilt NDK has no sysroot for API level %s, %s does not exist", apiLevel.String(), libDir)
+		return android.SourcePath{}, false
+	}
+
+	const includeDir = "prebuilts/ndk/current/sysroot/usr/include"
+	if !android.ExistentPathForSource(ctx, includeDir).Valid() {
+		ctx.PropertyErrorf("prebuilt_ndk_sysroot", "the prebuilt NDK has no headers, %s does not exist", includeDir)
+		return android.SourcePath{}, false
+	}
+	return android.PathForSource(ctx, includeDir), true
 }
 
 func ndkPrebuiltModuleToPath(ctx android.ModuleContext, toolchain config.Toolchain,
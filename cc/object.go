@@ -82,6 +82,18 @@ type ObjectLinkerProperties struct {
 	// names of other cc_object modules to link into this module using partial linking
 	Objs []string `android:"arch_variant"`
 
+	// list of static library modules to link into this module using partial linking.
+	Whole_static_libs []string `android:"arch_variant,variant_prepend"`
+
+	// if false, only the members of whole_static_libs that resolve undefined symbols of the other
+	// objects are linked into this module, instead of all of them as with --whole-archive.
+	// Defaults to true.
+	Whole_archive *bool
+
+	// if set, only these global symbols are kept global in the combined object, all other global
+	// symbols are made local.  The names are before prefix_symbols is applied.
+	Global_symbols []string `android:"arch_variant"`
+
 	// if set, add an extra objcopy --prefix-symbols= step
 	Prefix_symbols *string
 
@@ -131,7 +143,7 @@ type bazelObjectAttributes struct {
 	Asflags             bazel.StringListAttribute
 	Local_includes      bazel.StringListAttribute
 	Absolute_includes   bazel.StringListAttribute
-	Stl                 *string
+	Stl                 bazel.StringAttribute
 	Linker_script       bazel.LabelAttribute
 	sdkAttributes
 }
@@ -155,6 +167,20 @@ func objectBp2Build(ctx android.TopDownMutatorContext, m *Module) {
 	for axis, configToProps := range m.GetArchVariantProperties(ctx, &ObjectLinkerProperties{}) {
 		for config, props := range configToProps {
 			if objectLinkerProps, ok := props.(*ObjectLinkerProperties); ok {
+				// The Bazel cc_object rule has no equivalent of the partial link options.
+				var unsupported string
+				if len(objectLinkerProps.Whole_static_libs) > 0 {
+					unsupported = "whole_static_libs"
+				} else if objectLinkerProps.Whole_archive != nil {
+					unsupported = "whole_archive"
+				} else if len(objectLinkerProps.Global_symbols) > 0 {
+					unsupported = "global_symbols"
+				}
+				if unsupported != "" {
+					ctx.MarkBp2buildUnconvertible(android.UnconvertedReasonTypeUnsupportedProperty,
+						unsupported+" is not supported")
+					return
+				}
 				if objectLinkerProps.Linker_script != nil {
 					label := android.BazelLabelForModuleSrcSingle(ctx, *objectLinkerProps.Linker_script)
 					linkerScript.SetSelectValue(axis, config, label)
@@ -218,6 +244,7 @@ func (object *objectLinker) linkerDeps(ctx DepsContext, deps Deps) Deps {
 	deps.SharedLibs = append(deps.SharedLibs, object.Properties.Shared_libs...)
 	deps.StaticLibs = append(deps.StaticLibs, object.Properties.Static_libs...)
 	deps.ObjFiles = append(deps.ObjFiles, object.Properties.Objs...)
+	deps.WholeStaticLibs = append(deps.WholeStaticLibs, object.Properties.Whole_static_libs...)
 
 	deps.SystemSharedLibs = object.Properties.System_shared_libs
 	if deps.SystemSharedLibs == nil {
@@ -245,10 +272,25 @@ func (object *objectLinker) link(ctx ModuleContext,
 
 	objs = objs.Append(deps.Objs)
 
+	if len(deps.WholeStaticLibs) > 0 {
+		// The libraries follow the objects on the ld -r command line, so without --whole-archive
+		// only the members needed by the objects are linked.
+		wholeArchive := BoolDefault(object.Properties.Whole_archive, true)
+		if wholeArchive {
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--whole-archive")
+		}
+		flags.Local.LdFlags = append(flags.Local.LdFlags, deps.WholeStaticLibs.Strings()...)
+		if wholeArchive {
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--no-whole-archive")
+		}
+		flags.LdFlagsDeps = append(flags.LdFlagsDeps, deps.WholeStaticLibs...)
+	}
+
 	var outputFile android.Path
 	builderFlags := flagsToBuilderFlags(flags)
 
-	if len(objs.objFiles) == 1 && String(object.Properties.Linker_script) == "" {
+	if len(objs.objFiles) == 1 && String(object.Properties.Linker_script) == "" &&
+		len(deps.WholeStaticLibs) == 0 && len(object.Properties.Global_symbols) == 0 {
 		outputFile = objs.objFiles[0]
 
 		if String(object.Properties.Prefix_symbols) != "" {
@@ -268,6 +310,13 @@ func (object *objectLinker) link(ctx ModuleContext,
 			output = input
 		}
 
+		if len(object.Properties.Global_symbols) > 0 {
+			input := android.PathForModuleOut(ctx, "unlocalized", ctx.ModuleName()+objectExtension)
+			transformObjKeepGlobalSymbols(ctx, object.Properties.Global_symbols, input,
+				builderFlags, output)
+			output = input
+		}
+
 		transformObjsToObj(ctx, objs.objFiles, builderFlags, output, flags.LdFlagsDeps)
 	}
 
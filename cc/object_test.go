@@ -107,3 +107,59 @@ cc_object {
 	expectedOutputFiles := []string{"outputbase/execroot/__main__/bazel_out.o"}
 	android.AssertDeepEquals(t, "output files", expectedOutputFiles, outputFiles.Strings())
 }
+
+func TestCcObjectPartialLinkWholeStaticLibs(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_static {
+			name: "libfoo",
+			srcs: ["foo.c"],
+		}
+		cc_object {
+			name: "whole",
+			srcs: ["bar.c"],
+			whole_static_libs: ["libfoo"],
+		}
+		cc_object {
+			name: "needed",
+			srcs: ["bar.c"],
+			whole_static_libs: ["libfoo"],
+			whole_archive: false,
+		}
+	`)
+
+	libfoo := "out/soong/.intermediates/libfoo/android_arm64_armv8-a_static/libfoo.a"
+
+	whole := ctx.ModuleForTests("whole", "android_arm64_armv8-a").Output("whole.o")
+	android.AssertStringDoesContain(t, "ldFlags", whole.Args["ldFlags"],
+		"-Wl,--whole-archive "+libfoo+" -Wl,--no-whole-archive")
+	android.AssertPathsRelativeToTopEquals(t, "implicits", []string{libfoo}, whole.Implicits)
+
+	needed := ctx.ModuleForTests("needed", "android_arm64_armv8-a").Output("needed.o")
+	android.AssertStringDoesContain(t, "ldFlags", needed.Args["ldFlags"], libfoo)
+	android.AssertStringDoesNotContain(t, "ldFlags", needed.Args["ldFlags"], "--whole-archive")
+}
+
+func TestCcObjectGlobalSymbols(t *testing.T) {
+	ctx := testCc(t, `
+		cc_object {
+			name: "foo",
+			srcs: ["foo.c"],
+			global_symbols: ["foo_init", "foo_run"],
+			prefix_symbols: "p_",
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_arm64_armv8-a")
+	link := foo.Output("unlocalized/foo.o")
+	android.AssertPathsRelativeToTopEquals(t, "linked objects",
+		[]string{"out/soong/.intermediates/foo/android_arm64_armv8-a/obj/foo.o"}, link.Inputs)
+
+	localize := foo.Rule("keepGlobalSymbols")
+	android.AssertStringEquals(t, "keepFlags", "--keep-global-symbol=foo_init --keep-global-symbol=foo_run",
+		localize.Args["keepFlags"])
+	android.AssertStringEquals(t, "localize input", "unlocalized/foo.o", localize.Input.Rel())
+	android.AssertStringEquals(t, "localize output", "unprefixed/foo.o", localize.Output.Rel())
+
+	prefix := foo.Rule("prefixSymbols")
+	android.AssertStringEquals(t, "prefix output", "foo.o", prefix.Output.Rel())
+}
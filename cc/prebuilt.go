@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -42,6 +43,8 @@ type prebuiltLinkerInterface interface {
 
 type prebuiltLinkerProperties struct {
 	// a prebuilt library or binary. Can reference a genrule module that generates an executable file.
+	// Set it in arch: { <arch>: { srcs: [...] } } to select a different file for each architecture,
+	// building the library for an architecture without one is an error.
 	Srcs []string `android:"path,arch_variant"`
 
 	Sanitized Sanitized `android:"arch_variant"`
@@ -105,6 +108,7 @@ func (p *prebuiltLibraryLinker) link(ctx ModuleContext,
 	p.libraryDecorator.flagExporter.reexportDirs(deps.ReexportedDirs...)
 	p.libraryDecorator.flagExporter.reexportSystemDirs(deps.ReexportedSystemDirs...)
 	p.libraryDecorator.flagExporter.reexportFlags(deps.ReexportedFlags...)
+	p.libraryDecorator.flagExporter.reexportDefines(deps.ReexportedDefines...)
 	p.libraryDecorator.flagExporter.reexportDeps(deps.ReexportedDeps...)
 	p.libraryDecorator.flagExporter.addExportedGeneratedHeaders(deps.ReexportedGeneratedHeaders...)
 
@@ -201,6 +205,10 @@ func (p *prebuiltLibraryLinker) link(ctx ModuleContext,
 
 			return outputFile
 		}
+	} else if p.missingArchSrcs(ctx) {
+		// Libraries are often built for architectures that nothing uses them for, so only fail
+		// when the variant is built.
+		return p.missingArchSrcsOutput(ctx, flags)
 	}
 
 	if p.header() {
@@ -233,6 +241,67 @@ func (p *prebuiltLibraryLinker) prebuiltSrcs(ctx android.BaseModuleContext) []st
 	return srcs
 }
 
+// missingArchSrcs returns true if the variant is for a linkage that the library is built for, but the
+// library has no source file for the architecture of the variant in any linkage, and no source module
+// with the same name replaces it.
+func (p *prebuiltLibraryLinker) missingArchSrcs(ctx ModuleContext) bool {
+	if !(p.static() && p.buildStatic()) && !(p.shared() && p.buildShared()) {
+		return false
+	}
+	if p.prebuilt().SourceExists() {
+		return false
+	}
+	static := p.libraryDecorator.StaticProperties.Static
+	shared := p.libraryDecorator.SharedProperties.Shared
+	for _, srcs := range [][]string{p.properties.Srcs, static.Srcs, shared.Srcs} {
+		if len(srcs) > 0 {
+			return false
+		}
+	}
+	for _, sanitized := range []Sanitized{p.properties.Sanitized, static.Sanitized, shared.Sanitized} {
+		if len(sanitized.None.Srcs) > 0 || len(sanitized.Address.Srcs) > 0 || len(sanitized.Hwaddress.Srcs) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// missingArchSrcsOutput returns an output for a variant without a source file that fails to build,
+// and provides it like the library it replaces.
+func (p *prebuiltLibraryLinker) missingArchSrcsOutput(ctx ModuleContext, flags Flags) android.Path {
+	libName := p.libraryDecorator.getLibName(ctx)
+	if p.static() {
+		libName += staticLibraryExtension
+	} else {
+		libName += flags.Toolchain.ShlibSuffix()
+	}
+	outputFile := android.PathForModuleOut(ctx, libName)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:   android.ErrorRule,
+		Output: outputFile,
+		Args: map[string]string{
+			"error": fmt.Sprintf("module %q variant %q: srcs: no prebuilt source file for arch %s",
+				ctx.ModuleName(), ctx.ModuleSubDir(), ctx.Arch().ArchType),
+		},
+	})
+
+	if p.static() {
+		depSet := android.NewDepSetBuilder(android.TOPOLOGICAL).Direct(outputFile).Build()
+		ctx.SetProvider(StaticLibraryInfoProvider, StaticLibraryInfo{
+			StaticLibrary: outputFile,
+
+			TransitiveStaticLibrariesForOrdering: depSet,
+		})
+	} else {
+		p.unstrippedOutputFile = outputFile
+		ctx.SetProvider(SharedLibraryInfoProvider, SharedLibraryInfo{
+			SharedLibrary: outputFile,
+			Target:        ctx.Target(),
+		})
+	}
+	return outputFile
+}
+
 func (p *prebuiltLibraryLinker) shared() bool {
 	return p.libraryDecorator.shared()
 }
@@ -228,6 +228,78 @@ func TestPrebuiltLibrary(t *testing.T) {
 	assertString(t, static.OutputFile().Path().Base(), "libf.a")
 }
 
+func TestPrebuiltLibraryArchSrcs(t *testing.T) {
+	ctx := testPrebuilt(t, `
+	cc_prebuilt_library_shared {
+		name: "libtest",
+		export_include_dirs: ["include"],
+		arch: {
+			arm64: {
+				srcs: ["arm64/libtest.so"],
+			},
+			arm: {
+				srcs: ["arm/libtest.so"],
+			},
+		},
+		strip: {
+			none: true,
+		},
+	}
+	`, map[string][]byte{
+		"arm64/libtest.so": nil,
+		"arm/libtest.so":   nil,
+	})
+
+	for variant, src := range map[string]string{
+		"android_arm64_armv8-a_shared":    "arm64/libtest.so",
+		"android_arm_armv7-a-neon_shared": "arm/libtest.so",
+	} {
+		module := ctx.ModuleForTests("libtest", variant)
+		android.AssertPathRelativeToTopEquals(t, variant+" source", src, module.Output("libtest.so").Input)
+
+		exported := ctx.ModuleProvider(module.Module(), FlagExporterInfoProvider).(FlagExporterInfo)
+		android.AssertPathsRelativeToTopEquals(t, variant+" exported include dirs", []string{"include"},
+			exported.IncludeDirs)
+	}
+}
+
+func TestPrebuiltLibraryMissingArchSrcs(t *testing.T) {
+	bp := `
+	cc_prebuilt_library {
+		name: "libtest",
+		arch: {
+			arm64: {
+				srcs: ["arm64/libtest.so"],
+			},
+		},
+	}
+	`
+	// The variants without a source file only fail when they are built.
+	ctx := testPrebuilt(t, bp, map[string][]byte{
+		"arm64/libtest.so": nil,
+	})
+	for variant, output := range map[string]string{
+		"android_arm_armv7-a-neon_shared": "libtest.so",
+		"android_arm_armv7-a-neon_static": "libtest.a",
+	} {
+		rule := ctx.ModuleForTests("libtest", variant).Output(output)
+		android.AssertDeepEquals(t, variant+" rule", android.ErrorRule, rule.Rule)
+		android.AssertStringDoesContain(t, variant+" error", rule.Args["error"],
+			`module "libtest" variant "`+variant+`": srcs: no prebuilt source file for arch arm`)
+	}
+	shared := ctx.ModuleForTests("libtest", "android_arm64_armv8-a_shared").Output("libtest.so")
+	android.AssertPathRelativeToTopEquals(t, "arm64 source", "arm64/libtest.so", shared.Input)
+
+	// A source module with the same name is used for the architectures without a prebuilt.
+	testPrebuilt(t, bp+`
+	cc_library {
+		name: "libtest",
+	}
+	`, map[string][]byte{
+		"arm64/libtest.so": nil,
+	})
+}
+
 func TestPrebuiltLibraryStem(t *testing.T) {
 	ctx := testPrebuilt(t, `
 	cc_prebuilt_library {
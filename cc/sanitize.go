@@ -221,6 +221,9 @@ type SanitizeUserProps struct {
 	Safestack *bool `android:"arch_variant"`
 	// cfi sanitizer, incompatible with asan, hwasan, fuzzer, or Darwin
 	Cfi *bool `android:"arch_variant"`
+	// Honor cfi: true even when CFI is disabled for the product, so that it can be tried on a
+	// single module.  The static libraries the module links are built in cfi variants.
+	Cfi_ignore_product_config *bool `android:"arch_variant"`
 	// signed/unsigned integer overflow sanitizer, incompatible with Darwin.
 	Integer_overflow *bool `android:"arch_variant"`
 	// scudo sanitizer, incompatible with asan, hwasan, tsan
@@ -328,6 +331,10 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		}
 	}
 
+	// CFI requested by the module itself, as opposed to by the product or by CFI_INCLUDE_PATHS, may
+	// opt out of the product config.
+	moduleCfi := Bool(s.Cfi) && Bool(s.Cfi_ignore_product_config)
+
 	var globalSanitizers []string
 	var globalSanitizersDiag []string
 
@@ -454,7 +461,7 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 	}
 
 	// Is CFI actually enabled?
-	if !ctx.Config().EnableCFI() {
+	if !ctx.Config().EnableCFI() && !moduleCfi {
 		s.Cfi = nil
 		s.Diag.Cfi = nil
 	}
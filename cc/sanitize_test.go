@@ -551,3 +551,117 @@ func TestSanitizeMemtagHeapWithSanitizeDeviceDiag(t *testing.T) {
 	checkHasMemtagNote(t, ctx.ModuleForTests("unset_test_override_default_disable", variant), Sync)
 	checkHasMemtagNote(t, ctx.ModuleForTests("unset_test_override_default_sync", variant), Sync)
 }
+
+func TestCfiEnabledForModule(t *testing.T) {
+	bp := `
+		cc_binary {
+			name: "bin_with_cfi",
+			static_libs: ["libstatic", "libnocfi"],
+			shared_libs: ["libshared"],
+			sanitize: {
+				cfi: true,
+				cfi_ignore_product_config: true,
+			},
+		}
+
+		cc_binary {
+			name: "bin_no_cfi",
+			static_libs: ["libstatic"],
+		}
+
+		cc_binary {
+			name: "bin_product_cfi",
+			sanitize: {
+				cfi: true,
+			},
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			srcs: ["foo.c"],
+		}
+
+		cc_library_static {
+			name: "libnocfi",
+			srcs: ["foo.c"],
+			sanitize: {
+				cfi: false,
+			},
+		}
+
+		cc_library_shared {
+			name: "libshared",
+			srcs: ["foo.c"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		// CFI requested by a module that ignores the product config is honored even when it is
+		// disabled for the product.
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.EnableCFI = BoolPtr(false)
+		}),
+	).RunTestWithBp(t, bp)
+
+	variant := "android_arm64_armv8-a"
+	staticVariant := variant + "_static"
+	staticCfiVariant := staticVariant + "_cfi"
+
+	expectCfiFlags := func(t *testing.T, name string, m android.TestingModule, cfi bool) {
+		t.Helper()
+		cFlags := m.Rule("cc").Args["cFlags"]
+		for _, flag := range cfiCflags {
+			if cfi {
+				android.AssertStringDoesContain(t, name+" cFlags", cFlags, flag)
+			} else {
+				android.AssertStringDoesNotContain(t, name+" cFlags", cFlags, flag)
+			}
+		}
+	}
+
+	binWithCfi := result.ModuleForTests("bin_with_cfi", variant+"_cfi")
+	android.AssertStringDoesContain(t, "bin_with_cfi ldFlags",
+		binWithCfi.Rule("ld").Args["ldFlags"], "-fsanitize=cfi")
+
+	// The static library has a cfi variant for bin_with_cfi, and a non-cfi variant for bin_no_cfi.
+	libStaticCfi := result.ModuleForTests("libstatic", staticCfiVariant)
+	expectCfiFlags(t, "libstatic cfi variant", libStaticCfi, true)
+	expectCfiFlags(t, "libstatic", result.ModuleForTests("libstatic", staticVariant), false)
+	android.AssertStringListContains(t, "bin_with_cfi inputs",
+		binWithCfi.Rule("ld").Implicits.Strings(), libStaticCfi.Output("libstatic.a").Output.String())
+
+	binNoCfi := result.ModuleForTests("bin_no_cfi", variant)
+	android.AssertStringDoesNotContain(t, "bin_no_cfi ldFlags",
+		binNoCfi.Rule("ld").Args["ldFlags"], "-fsanitize=cfi")
+
+	// Without the opt-in, the product config still disables cfi.
+	binProductCfi := result.ModuleForTests("bin_product_cfi", variant)
+	android.AssertStringDoesNotContain(t, "bin_product_cfi ldFlags",
+		binProductCfi.Rule("ld").Args["ldFlags"], "-fsanitize=cfi")
+
+	// A static library that disables cfi is linked without it, and shared libraries are not split.
+	expectCfiFlags(t, "libnocfi", result.ModuleForTests("libnocfi", staticVariant), false)
+	expectCfiFlags(t, "libshared", result.ModuleForTests("libshared", variant+"_shared"), false)
+}
+
+func TestCfiDisabledByAddressSanitizer(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForAsanTest,
+	).RunTestWithBp(t, `
+		cc_binary {
+			name: "bin",
+			srcs: ["foo.c"],
+			sanitize: {
+				cfi: true,
+				cfi_ignore_product_config: true,
+				address: true,
+			},
+		}`)
+
+	// The address sanitizer silently disables cfi, even when it ignores the product config.
+	bin := result.ModuleForTests("bin", "android_arm64_armv8-a_asan")
+	android.AssertStringDoesContain(t, "bin ldFlags", bin.Rule("ld").Args["ldFlags"], "-fsanitize=address")
+	android.AssertStringDoesNotContain(t, "bin cFlags", bin.Rule("cc").Args["cFlags"], "-fsanitize-cfi-cross-dso")
+}
@@ -468,6 +468,7 @@ func (p *snapshotLibraryDecorator) link(ctx ModuleContext, flags Flags, deps Pat
 	p.libraryDecorator.reexportDirs(deps.ReexportedDirs...)
 	p.libraryDecorator.reexportSystemDirs(deps.ReexportedSystemDirs...)
 	p.libraryDecorator.reexportFlags(deps.ReexportedFlags...)
+	p.libraryDecorator.reexportDefines(deps.ReexportedDefines...)
 	p.libraryDecorator.reexportDeps(deps.ReexportedDeps...)
 	p.libraryDecorator.addExportedGeneratedHeaders(deps.ReexportedGeneratedHeaders...)
 
@@ -47,7 +47,18 @@ type StlProperties struct {
 	// default.
 	Stl *string `android:"arch_variant"`
 
+	// Select how libc++ is linked: "shared", "static" or "none".  Unlike stl, the choice is
+	// also enforced on the modules that link a static library that sets it: the binaries and shared
+	// libraries that link it, directly or through other static libraries, must link libc++ the same
+	// way.  Cannot be set together with stl.
+	Libcxx *string `android:"arch_variant"`
+
 	SelectedStl string `blueprint:"mutated"`
+
+	// The libc++ linkage required by the libcxx property of this static library or of one of its
+	// static dependencies, and the name of the module that sets it.
+	RequiredLibcxx   string `blueprint:"mutated"`
+	RequiredLibcxxBy string `blueprint:"mutated"`
 }
 
 type stl struct {
@@ -61,7 +72,27 @@ func (stl *stl) props() []interface{} {
 func (stl *stl) begin(ctx BaseModuleContext) {
 	stl.Properties.SelectedStl = func() string {
 		s := ""
-		if stl.Properties.Stl != nil {
+		if stl.Properties.Libcxx != nil {
+			if stl.Properties.Stl != nil {
+				ctx.PropertyErrorf("libcxx", "cannot be set together with stl")
+			}
+			switch *stl.Properties.Libcxx {
+			case "shared":
+				if ctx.Windows() {
+					ctx.PropertyErrorf("libcxx", "shared libc++ is not supported for windows")
+					return ""
+				}
+				s = "libc++"
+			case "static":
+				s = "libc++_static"
+			case "none":
+				s = "none"
+			default:
+				ctx.PropertyErrorf("libcxx", "%q is not supported, use \"shared\", \"static\" or \"none\"",
+					*stl.Properties.Libcxx)
+				return ""
+			}
+		} else if stl.Properties.Stl != nil {
 			s = *stl.Properties.Stl
 		} else if ctx.header() {
 			s = "none"
@@ -117,6 +148,75 @@ func (stl *stl) begin(ctx BaseModuleContext) {
 	}()
 }
 
+// libcxxLinkage returns how a selected STL links libc++: "shared", "static", or "" if it doesn't
+// use libc++.
+func libcxxLinkage(selectedStl string) string {
+	switch selectedStl {
+	case "libc++", "ndk_libc++_shared":
+		return "shared"
+	case "libc++_static", "ndk_libc++_static":
+		return "static"
+	default:
+		return ""
+	}
+}
+
+// checkLibcxxMutator propagates the libc++ linkage required by the libcxx property of static
+// libraries to the static libraries that link them, and reports an error when a binary or a shared
+// library links libc++ differently or doesn't link it at all, or when a link mixes static libraries
+// that require both linkages.
+func checkLibcxxMutator(ctx android.BottomUpMutatorContext) {
+	c, ok := ctx.Module().(*Module)
+	if !ok || c.stl == nil {
+		return
+	}
+	props := &c.stl.Properties
+
+	var linkage, linkageBy string
+	staticLibrary := c.CcLibraryInterface() && c.static()
+	switch {
+	case staticLibrary:
+		// A static library that doesn't set libcxx, or sets it to "none", only passes on the
+		// requirements of its static dependencies.
+		if props.Libcxx != nil && libcxxLinkage(props.SelectedStl) != "" {
+			linkage, linkageBy = libcxxLinkage(props.SelectedStl), ctx.ModuleName()
+		}
+	case c.Binary(), c.CcLibraryInterface() && c.Shared() && !c.IsStubs() && !c.IsPrebuilt():
+		linkage, linkageBy = libcxxLinkage(props.SelectedStl), ctx.ModuleName()
+	default:
+		return
+	}
+
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		tag, ok := ctx.OtherModuleDependencyTag(dep).(libraryDependencyTag)
+		ccDep, _ := dep.(*Module)
+		if !ok || !tag.static() || ccDep == nil || ccDep.stl == nil {
+			return
+		}
+		required, requiredBy := ccDep.stl.Properties.RequiredLibcxx, ccDep.stl.Properties.RequiredLibcxxBy
+		if required == "" || required == linkage {
+			return
+		}
+		if linkageBy == ctx.ModuleName() {
+			uses := "does not use libc++"
+			if linkage != "" {
+				uses = "uses " + linkage + " libc++"
+			}
+			ctx.ModuleErrorf("%s, but its static dependency %q requires %s libc++ set by the libcxx property of %q",
+				uses, ctx.OtherModuleName(dep), required, requiredBy)
+		} else if linkage == "" {
+			linkage, linkageBy = required, requiredBy
+		} else {
+			ctx.ModuleErrorf("links static libraries that require %s libc++ set by the libcxx property of %q and %s libc++ set by the libcxx property of %q",
+				linkage, linkageBy, required, requiredBy)
+		}
+	})
+
+	if staticLibrary {
+		props.RequiredLibcxx, props.RequiredLibcxxBy = linkage, linkageBy
+	}
+}
+
 func needsLibAndroidSupport(ctx BaseModuleContext) bool {
 	version := nativeApiLevelOrPanic(ctx, ctx.sdkVersion())
 	return version.LessThan(android.FirstNonLibAndroidSupportVersion)
@@ -0,0 +1,159 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestLibcxx(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_static {
+			name: "libstatic_cxx",
+			srcs: ["foo.cpp"],
+			libcxx: "static",
+		}
+
+		cc_library_static {
+			name: "libnone",
+			srcs: ["foo.c"],
+			libcxx: "none",
+			static_libs: ["libstatic_cxx"],
+		}
+
+		cc_binary {
+			name: "bin",
+			srcs: ["bin.cpp"],
+			libcxx: "static",
+			static_libs: ["libnone"],
+		}
+
+		cc_binary {
+			name: "bin_default",
+			srcs: ["bin.cpp"],
+		}`)
+
+	selectedStl := func(name, variant string) string {
+		return ctx.ModuleForTests(name, variant).Module().(*Module).SelectedStl()
+	}
+	android.AssertStringEquals(t, "bin stl", "libc++_static", selectedStl("bin", "android_arm64_armv8-a"))
+	android.AssertStringEquals(t, "bin_default stl", "libc++", selectedStl("bin_default", "android_arm64_armv8-a"))
+	android.AssertStringEquals(t, "libnone stl", "", selectedStl("libnone", "android_arm64_armv8-a_static"))
+
+	// libnone doesn't use libc++, but its dependents must link libc++ statically for libstatic_cxx.
+	props := ctx.ModuleForTests("libnone", "android_arm64_armv8-a_static").Module().(*Module).stl.Properties
+	android.AssertStringEquals(t, "libnone required libc++", "static", props.RequiredLibcxx)
+	android.AssertStringEquals(t, "libnone required libc++ by", "libstatic_cxx", props.RequiredLibcxxBy)
+}
+
+func TestLibcxxErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "default linkage of a binary",
+			bp: `
+				cc_library_static {
+					name: "libstatic_cxx",
+					srcs: ["foo.cpp"],
+					libcxx: "static",
+				}
+
+				cc_library_static {
+					name: "libwrapper",
+					srcs: ["foo.cpp"],
+					whole_static_libs: ["libstatic_cxx"],
+				}
+
+				cc_binary {
+					name: "bin",
+					srcs: ["bin.cpp"],
+					static_libs: ["libwrapper"],
+				}`,
+			err: `module "bin" .*: uses shared libc\+\+, but its static dependency "libwrapper" requires static libc\+\+ set by the libcxx property of "libstatic_cxx"`,
+		},
+		{
+			name: "binary without libc++",
+			bp: `
+				cc_library_static {
+					name: "libstatic_cxx",
+					srcs: ["foo.cpp"],
+					libcxx: "static",
+				}
+
+				cc_binary {
+					name: "bin",
+					srcs: ["bin.c"],
+					stl: "none",
+					static_libs: ["libstatic_cxx"],
+				}`,
+			err: `module "bin" .*: does not use libc\+\+, but its static dependency "libstatic_cxx" requires static libc\+\+ set by the libcxx property of "libstatic_cxx"`,
+		},
+		{
+			name: "static libraries that require both linkages",
+			bp: `
+				cc_library_static {
+					name: "libstatic_cxx",
+					srcs: ["foo.cpp"],
+					libcxx: "static",
+				}
+
+				cc_library_static {
+					name: "libshared_cxx",
+					srcs: ["foo.cpp"],
+					libcxx: "shared",
+				}
+
+				cc_library_static {
+					name: "libwrapper",
+					srcs: ["foo.cpp"],
+					libcxx: "none",
+					static_libs: ["libstatic_cxx", "libshared_cxx"],
+				}`,
+			err: `module "libwrapper" .*: links static libraries that require static libc\+\+ set by the libcxx property of "libstatic_cxx" and shared libc\+\+ set by the libcxx property of "libshared_cxx"`,
+		},
+		{
+			name: "libcxx and stl",
+			bp: `
+				cc_binary {
+					name: "bin",
+					srcs: ["bin.cpp"],
+					stl: "libc++",
+					libcxx: "shared",
+				}`,
+			err: `libcxx: cannot be set together with stl`,
+		},
+		{
+			name: "unsupported libcxx",
+			bp: `
+				cc_binary {
+					name: "bin",
+					srcs: ["bin.cpp"],
+					libcxx: "libc++",
+				}`,
+			err: `libcxx: "libc\+\+" is not supported, use "shared", "static" or "none"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testCcError(t, tc.err, tc.bp)
+		})
+	}
+}
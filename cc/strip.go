@@ -41,52 +41,114 @@ type StripProperties struct {
 
 		// keep_symbols_and_debug_frame enables stripping but keeps all symbols and debug frames.
 		Keep_symbols_and_debug_frame *bool `android:"arch_variant"`
+
+		// keep_symbol_table enables stripping of the debug info but keeps the symbol table and
+		// the dynamic symbol table, so that crashes can still be symbolized. It cannot be used
+		// together with all, none or any of the keep_symbols options.
+		Keep_symbol_table *bool `android:"arch_variant"`
+
+		// split_debug writes the debug info of binaries and shared libraries to a .debug file
+		// next to the stripped file, and points the gnu-debuglink of the stripped file at it so
+		// that debuggers and symbolizers can find it.  The .debug file is available with the
+		// ".debug" output tag.  It can be combined with the other strip options except none, and
+		// replaces the mini debug info that is kept by default.  It has no effect on darwin.
+		Split_debug *bool `android:"arch_variant"`
 	} `android:"arch_variant"`
 }
 
 // Stripper defines the stripping actions and properties for a module.
 type Stripper struct {
 	StripProperties StripProperties
+
+	// propertiesChecked is set once checkStripProperties has run, so that the errors are only
+	// reported once although NeedsStrip is called several times per module.
+	propertiesChecked bool
 }
 
 // NeedsStrip determines if stripping is required for a module.
 func (stripper *Stripper) NeedsStrip(actx android.ModuleContext) bool {
+	stripper.checkStripProperties(actx)
 	forceDisable := Bool(stripper.StripProperties.Strip.None)
 	defaultEnable := (!actx.Config().KatiEnabled() || actx.Device())
 	forceEnable := Bool(stripper.StripProperties.Strip.All) ||
 		Bool(stripper.StripProperties.Strip.Keep_symbols) ||
-		Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame)
+		Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame) ||
+		Bool(stripper.StripProperties.Strip.Keep_symbol_table) ||
+		Bool(stripper.StripProperties.Strip.Split_debug)
 	return !forceDisable && (forceEnable || defaultEnable)
 }
 
+// checkStripProperties reports an error if keep_symbol_table is set together with a property
+// that selects a different kind of stripping, or if split_debug is set together with none.
+func (stripper *Stripper) checkStripProperties(actx android.ModuleContext) {
+	if stripper.propertiesChecked {
+		return
+	}
+	stripper.propertiesChecked = true
+	strip := stripper.StripProperties.Strip
+	if Bool(strip.Split_debug) && Bool(strip.None) {
+		actx.PropertyErrorf("strip.split_debug", "cannot be used together with strip.none")
+	}
+	if !Bool(strip.Keep_symbol_table) {
+		return
+	}
+	conflicts := []struct {
+		name string
+		set  bool
+	}{
+		{"none", Bool(strip.None)},
+		{"all", Bool(strip.All)},
+		{"keep_symbols", Bool(strip.Keep_symbols)},
+		{"keep_symbols_list", len(strip.Keep_symbols_list) > 0},
+		{"keep_symbols_and_debug_frame", Bool(strip.Keep_symbols_and_debug_frame)},
+	}
+	for _, conflict := range conflicts {
+		if conflict.set {
+			actx.PropertyErrorf("strip.keep_symbol_table", "cannot be used together with strip.%s", conflict.name)
+		}
+	}
+}
+
 // Keep this consistent with //build/bazel/rules/stripped_shared_library.bzl.
 func (stripper *Stripper) strip(actx android.ModuleContext, in android.Path, out android.ModuleOutPath,
-	flags StripFlags, isStaticLib bool) {
+	flags StripFlags, isStaticLib bool) android.OptionalPath {
+	var splitDebugFile android.WritablePath
 	if actx.Darwin() {
 		transformDarwinStrip(actx, in, out)
 	} else {
+		splitDebug := Bool(stripper.StripProperties.Strip.Split_debug) && !isStaticLib
 		if Bool(stripper.StripProperties.Strip.Keep_symbols) {
 			flags.StripKeepSymbols = true
 		} else if Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame) {
 			flags.StripKeepSymbolsAndDebugFrame = true
+		} else if Bool(stripper.StripProperties.Strip.Keep_symbol_table) {
+			flags.StripKeepSymbolTable = true
 		} else if len(stripper.StripProperties.Strip.Keep_symbols_list) > 0 {
 			flags.StripKeepSymbolsList = strings.Join(stripper.StripProperties.Strip.Keep_symbols_list, ",")
-		} else if !Bool(stripper.StripProperties.Strip.All) {
+		} else if !Bool(stripper.StripProperties.Strip.All) && !splitDebug {
 			flags.StripKeepMiniDebugInfo = true
 		}
-		if actx.Config().Debuggable() && !flags.StripKeepMiniDebugInfo && !isStaticLib {
+		if splitDebug {
+			// The gnu-debuglink points at the .debug file instead of the unstripped file.
+			splitDebugFile = out.InSameDir(actx, out.Base()+".debug")
+		} else if actx.Config().Debuggable() && !flags.StripKeepMiniDebugInfo && !isStaticLib {
 			flags.StripAddGnuDebuglink = true
 		}
-		transformStrip(actx, in, out, flags)
+		transformStrip(actx, in, out, splitDebugFile, flags)
+	}
+	if splitDebugFile == nil {
+		return android.OptionalPath{}
 	}
+	return android.OptionalPathForPath(splitDebugFile)
 }
 
 // StripExecutableOrSharedLib strips a binary or shared library from its debug
 // symbols and other debugging information. The helper function
-// flagsToStripFlags may be used to generate the flags argument.
+// flagsToStripFlags may be used to generate the flags argument. It returns the
+// .debug file written next to out when split_debug is set.
 func (stripper *Stripper) StripExecutableOrSharedLib(actx android.ModuleContext, in android.Path,
-	out android.ModuleOutPath, flags StripFlags) {
-	stripper.strip(actx, in, out, flags, false)
+	out android.ModuleOutPath, flags StripFlags) android.OptionalPath {
+	return stripper.strip(actx, in, out, flags, false)
 }
 
 // StripStaticLib strips a static library from its debug symbols and other
@@ -68,6 +68,10 @@ type TestOptions struct {
 	// Add MinApiLevelModuleController with ro.vndk.version property. If ro.vndk.version has an
 	// integer value and the value is less than the min_vndk_version, skip this module.
 	Min_vndk_version *int64
+
+	// A list of KEY=VALUE environment variables that the test harness sets when running the
+	// test. Values may reference $(MODULE_NAME), $(ARCH) and $(PLATFORM_SDK_VERSION).
+	Environment []string `android:"arch_variant"`
 }
 
 type TestBinaryProperties struct {
@@ -80,8 +84,9 @@ type TestBinaryProperties struct {
 	// directory, but test_per_src doesn't work.
 	No_named_install_directory *bool
 
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the test.  A module with multiple output files must select one with a
+	// tag, like ":module{tag}".
 	Data []string `android:"path,arch_variant"`
 
 	// list of shared library modules that should be installed alongside the test
@@ -378,7 +383,7 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 		testInstallBase = "/data/local/tests/vendor"
 	}
 
-	dataSrcPaths := android.PathsForModuleSrc(ctx, test.Properties.Data)
+	dataSrcPaths := android.PathsForModuleData(ctx, test.Properties.Data)
 
 	for _, dataSrcPath := range dataSrcPaths {
 		test.data = append(test.data, android.DataPath{SrcPath: dataSrcPath})
@@ -452,6 +457,8 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 		options = append(options, tradefed.Option{Name: "api-level-prop", Value: "ro.vndk.version"})
 		configs = append(configs, tradefed.Object{"module_controller", "com.android.tradefed.testtype.suite.module.MinApiLevelModuleController", options})
 	}
+	configs = append(configs, tradefed.EnvironmentOptions(ctx, "test_options.environment",
+		test.Properties.Test_options.Environment)...)
 
 	test.testConfig = tradefed.AutoGenNativeTestConfig(ctx, test.Properties.Test_config,
 		test.Properties.Test_config_template, test.testDecorator.InstallerProperties.Test_suites, configs, test.Properties.Auto_gen_config, testInstallBase)
@@ -540,8 +547,9 @@ func NewTestLibrary(hod android.HostOrDeviceSupported) *Module {
 }
 
 type BenchmarkProperties struct {
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the test.  A module with multiple output files must select one with a
+	// tag, like ":module{tag}".
 	Data []string `android:"path"`
 
 	// list of compatibility suites (for example "cts", "vts") that the module should be
@@ -599,7 +607,7 @@ func (benchmark *benchmarkDecorator) linkerDeps(ctx DepsContext, deps Deps) Deps
 }
 
 func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Path) {
-	benchmark.data = android.PathsForModuleSrc(ctx, benchmark.Properties.Data)
+	benchmark.data = android.PathsForModuleData(ctx, benchmark.Properties.Data)
 
 	var configs []tradefed.Config
 	if Bool(benchmark.Properties.Require_root) {
@@ -83,12 +83,17 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		tidyFlags:     strings.Join(in.TidyFlags, " "),
 		sAbiFlags:     strings.Join(in.SAbiFlags, " "),
 		toolchain:     in.Toolchain,
+		clangBin:      in.ClangBin,
+		ldRule:        in.ldRule,
 		gcovCoverage:  in.GcovCoverage,
 		tidy:          in.Tidy,
 		needTidyFiles: in.NeedTidyFiles,
 		sAbiDump:      in.SAbiDump,
 		emitXrefs:     in.EmitXrefs,
 
+		emitLLVMBitcode: in.EmitLLVMBitcode,
+		srcCFlags:       in.SrcCFlags,
+
 		systemIncludeFlags: strings.Join(in.SystemIncludeFlags, " "),
 
 		assemblerWithCpp: in.AssemblerWithCpp,
@@ -0,0 +1,841 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// dex_cache runs D8 on the classes of a jar, reusing the dex files of the classes that didn't
+// change since the previous build from a cache directory.
+//
+// The classes are grouped by their top-level class, since the nested classes and the lambdas of a
+// class are compiled from the same source file and D8 may desugar them together.  The groups that
+// are not in the cache are compiled with d8 --intermediate --file-per-class-file, with the other
+// classes of the jar on the classpath, and the dex files of each group are stored under a hash of
+// the classes of the group, of their superclasses and nest members in the jar, and of all the
+// interfaces in the jar, whose changes can affect the desugaring of the classes of the group.  The
+// cache is emptied whenever the dexer, its flags or the contents of the libraries change.  The dex
+// files of all the groups are then merged by D8 into the output directory, so the output of the
+// merge only depends on the classes and not on which of them were found in the cache.
+//
+// With --verify the jar is also dexed by running the dexer on it directly, like the build does
+// without the cache, and the outputs must be identical.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Bump to invalidate all the existing caches when the format or the key changes.
+	cacheVersion = "2"
+
+	// The access flag of interfaces in class files.
+	accInterface = 0x0200
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// errUncacheable is returned when D8 writes a dex file that doesn't belong to an input class,
+// which can't be stored with a group.
+var errUncacheable = errors.New("the intermediate dex files can't be cached")
+
+type dexCache struct {
+	dir string
+
+	// The files of the dexer, like its jar, whose contents are part of the cache key.
+	tools []string
+
+	// The dexer command and its flags, without the inputs and the output.
+	d8 []string
+
+	stdout, stderr io.Writer
+}
+
+// classGroup is a top-level class with its nested classes.
+type classGroup struct {
+	names []string
+	key   string
+}
+
+func newDexCache(dir string, tools []string, d8 []string) *dexCache {
+	return &dexCache{
+		dir:    dir,
+		tools:  tools,
+		d8:     d8,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+// run writes the dex files of the classes in jar to outDir.  If verify is set the classes are also
+// dexed without the cache, and an error is returned if the outputs differ.
+func (d *dexCache) run(jar, outDir string, verify bool) error {
+	classes, err := readClasses(jar)
+	if err != nil {
+		return err
+	}
+
+	if err := d.checkConfig(); err != nil {
+		return err
+	}
+
+	tmpDir := filepath.Join(d.dir, "tmp")
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	groups, err := groupClasses(classes)
+	if err != nil {
+		return err
+	}
+
+	entriesDir := filepath.Join(d.dir, "entries")
+	err = d.fillCache(classes, groups, entriesDir, filepath.Join(tmpDir, "intermediate"))
+	if err == errUncacheable {
+		// Dex everything without storing anything, later builds will fail to store the groups
+		// again and do the same, which is not slower than a build without the cache.
+		fmt.Fprintln(d.stderr, "dex_cache: not caching the intermediate dex files:", err)
+		if err := os.RemoveAll(entriesDir); err != nil {
+			return err
+		}
+		return d.dexAll(jar, outDir)
+	} else if err != nil {
+		return err
+	}
+
+	// The dex files are merged in the order of the class names, like in dexAll.
+	var names []string
+	entryOfClass := make(map[string]string)
+	used := make(map[string]bool)
+	for _, g := range groups {
+		used[g.key] = true
+		for _, name := range g.names {
+			names = append(names, name)
+			entryOfClass[name] = g.key
+		}
+	}
+	sort.Strings(names)
+	var dexFiles []string
+	for _, name := range names {
+		dexFiles = append(dexFiles, filepath.Join(entriesDir, entryOfClass[name], name+".dex"))
+	}
+	if err := d.merge(dexFiles, outDir, filepath.Join(tmpDir, "merge.zip")); err != nil {
+		return err
+	}
+
+	if err := pruneEntries(entriesDir, used); err != nil {
+		return err
+	}
+
+	if verify {
+		verifyOutDir := filepath.Join(tmpDir, "verify")
+		if err := d.dexAll(jar, verifyOutDir); err != nil {
+			return err
+		}
+		if err := compareDirs(outDir, verifyOutDir); err != nil {
+			return fmt.Errorf("the output with the cache differs from the output without it, "+
+				"unset INCREMENTAL_DEX to disable the cache: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// checkConfig empties the cache if it was filled by a different dexer or with different flags or
+// libraries.
+func (d *dexCache) checkConfig() error {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", cacheVersion)
+	for _, arg := range d.d8 {
+		fmt.Fprintf(h, "%s\x00", arg)
+	}
+	files := append([]string(nil), d.tools...)
+	files = append(files, libraries(d.d8)...)
+	for _, file := range files {
+		if err := hashFile(h, file); err != nil {
+			return err
+		}
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+
+	configFile := filepath.Join(d.dir, "config")
+	if old, err := ioutil.ReadFile(configFile); err != nil || string(old) != key {
+		if err := os.RemoveAll(d.dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(d.dir, 0777); err != nil {
+			return err
+		}
+	}
+	// The file is written even if it didn't change, since it is an output of the build rule.
+	return ioutil.WriteFile(configFile, []byte(key), 0666)
+}
+
+// fillCache dexes the groups that are not in the cache and stores them.
+func (d *dexCache) fillCache(classes map[string][]byte, groups map[string]*classGroup,
+	entriesDir, tmpDir string) error {
+
+	missing := make(map[string]bool)
+	var missingGroups []*classGroup
+	for _, g := range sortedGroups(groups) {
+		if _, err := os.Stat(filepath.Join(entriesDir, g.key)); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missingGroups = append(missingGroups, g)
+		for _, name := range g.names {
+			missing[name] = true
+		}
+	}
+	if len(missingGroups) == 0 {
+		return nil
+	}
+
+	outDir := filepath.Join(tmpDir, "out")
+	if err := d.dexIntermediate(classes, missing, outDir, tmpDir); err != nil {
+		return err
+	}
+
+	// Check that every dex file belongs to a class before storing any of them.
+	err := filepath.Walk(outDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(rel, ".dex") || !missing[strings.TrimSuffix(filepath.ToSlash(rel), ".dex")] {
+			return errUncacheable
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range missingGroups {
+		entryTmp := filepath.Join(tmpDir, g.key)
+		for _, name := range g.names {
+			dexFile := filepath.Join(outDir, name+".dex")
+			if _, err := os.Stat(dexFile); os.IsNotExist(err) {
+				// D8 writes an empty dex file for every class, a missing one would be dropped from
+				// the output by the merge.
+				return fmt.Errorf("d8 didn't write %s for %s", dexFile, name)
+			}
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(entryTmp, name)), 0777); err != nil {
+				return err
+			}
+			if err := os.Rename(dexFile, filepath.Join(entryTmp, name+".dex")); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(entriesDir, 0777); err != nil {
+			return err
+		}
+		// The entry is moved into place last, so that an interrupted build never leaves a partial
+		// entry that later builds would use.
+		if err := os.Rename(entryTmp, filepath.Join(entriesDir, g.key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dexAll dexes jar into outDir without the cache, with the same command as the build rule that
+// doesn't use the cache.
+func (d *dexCache) dexAll(jar, outDir string) error {
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return err
+	}
+	args := append(append([]string(nil), d.d8[1:]...), "--output", outDir, jar)
+	return d.runD8(args)
+}
+
+// dexIntermediate dexes the selected classes into one dex file per class in outDir, with the other
+// classes on the classpath.
+func (d *dexCache) dexIntermediate(classes map[string][]byte, selected map[string]bool,
+	outDir, tmpDir string) error {
+
+	var program, classpath []string
+	for _, name := range sortedKeys(classes) {
+		if selected[name] {
+			program = append(program, name)
+		} else {
+			classpath = append(classpath, name)
+		}
+	}
+
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return err
+	}
+	programJar := filepath.Join(tmpDir, "program.jar")
+	if err := writeClassesJar(programJar, classes, program); err != nil {
+		return err
+	}
+
+	args := append(intermediateFlags(d.d8), "--intermediate", "--file-per-class-file", "--output", outDir)
+	if len(classpath) > 0 {
+		classpathJar := filepath.Join(tmpDir, "classpath.jar")
+		if err := writeClassesJar(classpathJar, classes, classpath); err != nil {
+			return err
+		}
+		args = append(args, "--classpath", classpathJar)
+	}
+	args = append(args, programJar)
+	return d.runD8(args)
+}
+
+// merge merges the dex files into outDir.  The files are passed to D8 in a zip so that the command
+// line doesn't depend on the number of classes.
+func (d *dexCache) merge(dexFiles []string, outDir, zipFile string) error {
+	if err := os.MkdirAll(filepath.Dir(zipFile), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(zipFile)
+	if err != nil {
+		return err
+	}
+	w := zip.NewWriter(f)
+	for i, dexFile := range dexFiles {
+		data, err := ioutil.ReadFile(dexFile)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		entry, err := w.CreateHeader(&zip.FileHeader{
+			Name:   fmt.Sprintf("%08d.dex", i),
+			Method: zip.Store,
+		})
+		if err == nil {
+			_, err = entry.Write(data)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return err
+	}
+	args := append(append([]string(nil), d.d8[1:]...), "--output", outDir, zipFile)
+	return d.runD8(args)
+}
+
+func (d *dexCache) runD8(args []string) error {
+	cmd := exec.Command(d.d8[0], args...)
+	cmd.Stdout = d.stdout
+	cmd.Stderr = d.stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %s", d.d8[0], err)
+	}
+	return nil
+}
+
+// intermediateFlags returns the arguments of the dexer without the flags that are only used when
+// merging, since the main dex list is computed from all the classes.
+func intermediateFlags(d8 []string) []string {
+	var ret []string
+	for i := 1; i < len(d8); i++ {
+		switch d8[i] {
+		case "--main-dex-rules", "--main-dex-list":
+			i++
+		default:
+			ret = append(ret, d8[i])
+		}
+	}
+	return ret
+}
+
+// libraries returns the files passed with --lib or --classpath to the dexer.
+func libraries(d8 []string) []string {
+	var ret []string
+	for i := 0; i < len(d8)-1; i++ {
+		if d8[i] == "--lib" || d8[i] == "--classpath" {
+			ret = append(ret, d8[i+1])
+			i++
+		}
+	}
+	return ret
+}
+
+// readClasses returns the contents of the class files in jar by class name, like
+// "com/example/Foo".
+func readClasses(jar string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	classes := make(map[string][]byte)
+	for _, f := range r.File {
+		// D8 ignores the module descriptors and the classes of other Java versions in
+		// multi-release jars.
+		if !strings.HasSuffix(f.Name, ".class") || strings.HasPrefix(f.Name, "META-INF/") ||
+			filepath.Base(f.Name) == "module-info.class" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from %s: %s", f.Name, jar, err)
+		}
+		classes[strings.TrimSuffix(f.Name, ".class")] = data
+	}
+	return classes, nil
+}
+
+func writeClassesJar(jar string, classes map[string][]byte, names []string) error {
+	f, err := os.Create(jar)
+	if err != nil {
+		return err
+	}
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		entry, err := w.CreateHeader(&zip.FileHeader{
+			Name:   name + ".class",
+			Method: zip.Store,
+		})
+		if err == nil {
+			_, err = entry.Write(classes[name])
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// groupClasses groups the classes by top-level class and computes the cache key of each group.
+func groupClasses(classes map[string][]byte) (map[string]*classGroup, error) {
+	interfaces := sha256.New()
+	infos := make(map[string]*classInfo)
+	groups := make(map[string]*classGroup)
+	for _, name := range sortedKeys(classes) {
+		info, err := parseClass(classes[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s.class: %s", name, err)
+		}
+		infos[name] = info
+		if info.isInterface {
+			hashClass(interfaces, name, classes[name])
+		}
+
+		top := topLevelClass(name)
+		if groups[top] == nil {
+			groups[top] = &classGroup{}
+		}
+		groups[top].names = append(groups[top].names, name)
+	}
+	interfacesHash := interfaces.Sum(nil)
+
+	for _, g := range groups {
+		inGroup := make(map[string]bool)
+		for _, name := range g.names {
+			inGroup[name] = true
+		}
+		// The superclasses and the nest members of the classes that are in the jar but in other
+		// groups.
+		related := make(map[string]bool)
+		for _, name := range g.names {
+			info := infos[name]
+			for super := info.super; infos[super] != nil && !related[super]; super = infos[super].super {
+				related[super] = true
+			}
+			for _, member := range append([]string{info.nestHost}, info.nestMembers...) {
+				if infos[member] != nil {
+					related[member] = true
+				}
+			}
+		}
+
+		h := sha256.New()
+		h.Write(interfacesHash)
+		for _, name := range g.names {
+			hashClass(h, name, classes[name])
+		}
+		for _, name := range sortedBoolKeys(related) {
+			if !inGroup[name] {
+				hashClass(h, name, classes[name])
+			}
+		}
+		g.key = hex.EncodeToString(h.Sum(nil))
+	}
+	return groups, nil
+}
+
+// topLevelClass returns the name of the top-level class of a nested class.
+func topLevelClass(name string) string {
+	dir, base := "", name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		dir, base = name[:i+1], name[i+1:]
+	}
+	// A leading $ is part of the name of the top-level class.
+	leading := len(base) - len(strings.TrimLeft(base, "$"))
+	if i := strings.Index(base[leading:], "$"); i >= 0 {
+		base = base[:leading+i]
+	}
+	return dir + base
+}
+
+func hashClass(h io.Writer, name string, data []byte) {
+	fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+	h.Write(data)
+}
+
+// classInfo is the part of a class file that the cache key depends on.
+type classInfo struct {
+	isInterface bool
+
+	// The name of the superclass, "" for java/lang/Object.
+	super string
+
+	// The names of the classes of the NestHost and NestMembers attributes.
+	nestHost    string
+	nestMembers []string
+}
+
+// parseClass reads the access flags, the superclass and the nest of a class file.
+func parseClass(data []byte) (*classInfo, error) {
+	r := bytes.NewReader(data)
+	read := func(v interface{}) error {
+		return binary.Read(r, binary.BigEndian, v)
+	}
+	skip := func(size int64) error {
+		_, err := r.Seek(size, io.SeekCurrent)
+		return err
+	}
+
+	var header struct {
+		Magic        uint32
+		Minor, Major uint16
+		PoolCount    uint16
+	}
+	if err := read(&header); err != nil {
+		return nil, err
+	}
+	if header.Magic != 0xCAFEBABE {
+		return nil, fmt.Errorf("not a class file")
+	}
+
+	// The Utf8 constants and the name indexes of the Class constants.
+	utf8s := make(map[uint16]string)
+	classNames := make(map[uint16]uint16)
+	for i := uint16(1); i < header.PoolCount; i++ {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		var size int64
+		switch tag {
+		case 1: // Utf8
+			var length uint16
+			if err := read(&length); err != nil {
+				return nil, err
+			}
+			buf := make([]byte, length)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+			utf8s[i] = string(buf)
+		case 7: // Class
+			var nameIndex uint16
+			if err := read(&nameIndex); err != nil {
+				return nil, err
+			}
+			classNames[i] = nameIndex
+		case 8, 16, 19, 20: // String, MethodType, Module, Package
+			size = 2
+		case 15: // MethodHandle
+			size = 3
+		case 3, 4, 9, 10, 11, 12, 17, 18: // Integer, Float, the refs, NameAndType, the dynamics
+			size = 4
+		case 5, 6: // Long, Double
+			size = 8
+			// They take two entries.
+			i++
+		default:
+			return nil, fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+		if err := skip(size); err != nil {
+			return nil, err
+		}
+	}
+	className := func(index uint16) string {
+		return utf8s[classNames[index]]
+	}
+
+	var classHeader struct {
+		AccessFlags, ThisClass, SuperClass, InterfacesCount uint16
+	}
+	if err := read(&classHeader); err != nil {
+		return nil, err
+	}
+	info := &classInfo{
+		isInterface: classHeader.AccessFlags&accInterface != 0,
+		super:       className(classHeader.SuperClass),
+	}
+	if err := skip(2 * int64(classHeader.InterfacesCount)); err != nil {
+		return nil, err
+	}
+
+	// skipAttributes skips the attributes of a field or a method.
+	skipAttributes := func() error {
+		var count uint16
+		if err := read(&count); err != nil {
+			return err
+		}
+		for i := uint16(0); i < count; i++ {
+			var attr struct {
+				NameIndex uint16
+				Length    uint32
+			}
+			if err := read(&attr); err != nil {
+				return err
+			}
+			if err := skip(int64(attr.Length)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// The fields and then the methods.
+	for j := 0; j < 2; j++ {
+		var count uint16
+		if err := read(&count); err != nil {
+			return nil, err
+		}
+		for i := uint16(0); i < count; i++ {
+			// The access flags, the name and the descriptor.
+			if err := skip(6); err != nil {
+				return nil, err
+			}
+			if err := skipAttributes(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var attrCount uint16
+	if err := read(&attrCount); err != nil {
+		return nil, err
+	}
+	for i := uint16(0); i < attrCount; i++ {
+		var attr struct {
+			NameIndex uint16
+			Length    uint32
+		}
+		if err := read(&attr); err != nil {
+			return nil, err
+		}
+		switch utf8s[attr.NameIndex] {
+		case "NestHost":
+			var index uint16
+			if err := read(&index); err != nil {
+				return nil, err
+			}
+			info.nestHost = className(index)
+		case "NestMembers":
+			var count uint16
+			if err := read(&count); err != nil {
+				return nil, err
+			}
+			for k := uint16(0); k < count; k++ {
+				var index uint16
+				if err := read(&index); err != nil {
+					return nil, err
+				}
+				info.nestMembers = append(info.nestMembers, className(index))
+			}
+		default:
+			if err := skip(int64(attr.Length)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return info, nil
+}
+
+// pruneEntries removes the entries of the cache that were not used by this build.
+func pruneEntries(entriesDir string, used map[string]bool) error {
+	entries, err := ioutil.ReadDir(entriesDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !used[entry.Name()] {
+			if err := os.RemoveAll(filepath.Join(entriesDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compareDirs returns an error describing the first difference between the files in a and b.
+func compareDirs(a, b string) error {
+	files := func(dir string) (map[string]bool, error) {
+		ret := make(map[string]bool)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, err := filepath.Rel(dir, path)
+				ret[rel] = true
+				return err
+			}
+			return err
+		})
+		return ret, err
+	}
+
+	aFiles, err := files(a)
+	if err != nil {
+		return err
+	}
+	bFiles, err := files(b)
+	if err != nil {
+		return err
+	}
+	for _, rel := range sortedBoolKeys(aFiles) {
+		if !bFiles[rel] {
+			return fmt.Errorf("%s is only in the output with the cache", rel)
+		}
+		aData, err := ioutil.ReadFile(filepath.Join(a, rel))
+		if err != nil {
+			return err
+		}
+		bData, err := ioutil.ReadFile(filepath.Join(b, rel))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(aData, bData) {
+			return fmt.Errorf("%s is different", rel)
+		}
+	}
+	for _, rel := range sortedBoolKeys(bFiles) {
+		if !aFiles[rel] {
+			return fmt.Errorf("%s is missing from the output with the cache", rel)
+		}
+	}
+	return nil
+}
+
+func hashFile(h io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "%s\x00", file)
+	_, err = io.Copy(h, f)
+	return err
+}
+
+func sortedGroups(groups map[string]*classGroup) []*classGroup {
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*classGroup, len(names))
+	for i, name := range names {
+		ret[i] = groups[name]
+	}
+	return ret
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func sortedBoolKeys(m map[string]bool) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func main() {
+	cacheDir := flag.String("cache_dir", "", "directory to keep the intermediate dex files in")
+	outDir := flag.String("output", "", "directory to write the dex files to")
+	verify := flag.Bool("verify", false, "also dex all the classes without the cache and fail if the outputs differ")
+	var tools stringList
+	flag.Var(&tools, "tool", "a file of the dexer whose contents are part of the cache key, can be repeated")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dex_cache --cache_dir DIR --output DIR [--tool FILE]... [--verify] JAR -- D8 [ARG]...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if *cacheDir == "" || *outDir == "" || len(args) < 3 || args[1] != "--" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	d := newDexCache(*cacheDir, tools, args[2:])
+	if err := d.run(args[0], *outDir, *verify); err != nil {
+		fmt.Fprintln(os.Stderr, "dex_cache:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The test binary runs as a fake d8 when this is set to the file to log the dexed classes to.
+const fakeD8LogEnv = "DEX_CACHE_FAKE_D8_LOG"
+
+func TestMain(m *testing.M) {
+	if log := os.Getenv(fakeD8LogEnv); log != "" {
+		if err := fakeD8(log, os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeD8 writes "dex:" followed by the class file for each class with --intermediate, and the
+// concatenation of the input dex files, or of "dex:" followed by each input class file, to
+// classes.dex otherwise.
+func fakeD8(log string, args []string) error {
+	var outDir, input string
+	intermediate := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--output":
+			i++
+			outDir = args[i]
+		case "--classpath", "--lib", "--main-dex-rules":
+			i++
+		case "--intermediate":
+			intermediate = true
+		default:
+			input = args[i]
+		}
+	}
+
+	r, err := zip.OpenReader(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var dexed []string
+	merged := &bytes.Buffer{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if intermediate {
+			name := strings.TrimSuffix(f.Name, ".class")
+			dexed = append(dexed, name)
+			dexFile := filepath.Join(outDir, name+".dex")
+			if err := os.MkdirAll(filepath.Dir(dexFile), 0777); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(dexFile, append([]byte("dex:"), data...), 0666); err != nil {
+				return err
+			}
+		} else {
+			if strings.HasSuffix(f.Name, ".class") {
+				merged.WriteString("dex:")
+			}
+			merged.Write(data)
+		}
+	}
+
+	if !intermediate {
+		return ioutil.WriteFile(filepath.Join(outDir, "classes.dex"), merged.Bytes(), 0666)
+	}
+	f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, strings.Join(dexed, " "))
+	return err
+}
+
+// testClass describes a class file for the tests.
+type testClass struct {
+	name, body  string
+	isInterface bool
+	super       string
+	nestHost    string
+	nestMembers []string
+}
+
+// bytes returns a class file with a Utf8 constant that contains body, a Long constant, which takes
+// two constant pool entries, and a field with an attribute.
+func (c testClass) bytes() []byte {
+	pool := &bytes.Buffer{}
+	b := &bytes.Buffer{}
+	write := func(b *bytes.Buffer, v interface{}) { binary.Write(b, binary.BigEndian, v) }
+	next := uint16(1)
+	addUtf8 := func(s string) uint16 {
+		write(pool, uint8(1))
+		write(pool, uint16(len(s)))
+		pool.WriteString(s)
+		next++
+		return next - 1
+	}
+	addClass := func(name string) uint16 {
+		nameIndex := addUtf8(name)
+		write(pool, uint8(7))
+		write(pool, nameIndex)
+		next++
+		return next - 1
+	}
+
+	thisClass := addClass(c.name)
+	write(pool, uint8(5))
+	write(pool, uint64(42))
+	next += 2
+	bodyIndex := addUtf8(c.body)
+	var superClass uint16
+	if c.super != "" {
+		superClass = addClass(c.super)
+	}
+	attrs := &bytes.Buffer{}
+	var attrCount uint16
+	if c.nestHost != "" {
+		write(attrs, addUtf8("NestHost"))
+		write(attrs, uint32(2))
+		write(attrs, addClass(c.nestHost))
+		attrCount++
+	}
+	if len(c.nestMembers) > 0 {
+		nameIndex := addUtf8("NestMembers")
+		var members []uint16
+		for _, member := range c.nestMembers {
+			members = append(members, addClass(member))
+		}
+		write(attrs, nameIndex)
+		write(attrs, uint32(2+2*len(members)))
+		write(attrs, uint16(len(members)))
+		for _, member := range members {
+			write(attrs, member)
+		}
+		attrCount++
+	}
+
+	write(b, uint32(0xCAFEBABE))
+	write(b, uint16(0))
+	write(b, uint16(55))
+	write(b, next)
+	b.Write(pool.Bytes())
+	var accessFlags uint16 = 0x0001
+	if c.isInterface {
+		accessFlags |= accInterface | 0x0400
+	}
+	write(b, accessFlags)
+	write(b, thisClass)
+	write(b, superClass)
+	// No interfaces.
+	write(b, uint16(0))
+	// A field whose attribute is the index of the body.
+	write(b, uint16(1))
+	write(b, uint16(0x0001))
+	write(b, bodyIndex)
+	write(b, bodyIndex)
+	write(b, uint16(1))
+	write(b, bodyIndex)
+	write(b, uint32(2))
+	write(b, bodyIndex)
+	// No methods.
+	write(b, uint16(0))
+	write(b, attrCount)
+	b.Write(attrs.Bytes())
+	return b.Bytes()
+}
+
+// classFile returns the class file of a class without a superclass or a nest.
+func classFile(name, body string, isInterface bool) []byte {
+	return testClass{name: name, body: body, isInterface: isInterface}.bytes()
+}
+
+func writeJar(t *testing.T, jar string, classes map[string][]byte) {
+	t.Helper()
+	if err := writeClassesJar(jar, classes, sortedKeys(classes)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, tc := range []testClass{
+		{name: "a/B", body: "body"},
+		{name: "a/I", body: "body", isInterface: true},
+		{name: "a/B", body: "body", super: "a/Base", nestMembers: []string{"a/B$1", "a/Other"}},
+		{name: "a/Other", body: "body", nestHost: "a/B"},
+	} {
+		got, err := parseClass(tc.bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &classInfo{
+			isInterface: tc.isInterface,
+			super:       tc.super,
+			nestHost:    tc.nestHost,
+			nestMembers: tc.nestMembers,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %+v, got %+v", tc.name, want, got)
+		}
+	}
+	if _, err := parseClass([]byte("not a class")); err == nil {
+		t.Errorf("expected an error for an invalid class file")
+	}
+}
+
+func TestTopLevelClass(t *testing.T) {
+	for in, want := range map[string]string{
+		"Foo":                "Foo",
+		"a/b/Foo":            "a/b/Foo",
+		"a/b/Foo$Bar":        "a/b/Foo",
+		"a/b/Foo$Bar$1":      "a/b/Foo",
+		"a/b/Foo$$Lambda$1":  "a/b/Foo",
+		"a/b$c/Foo$Bar":      "a/b$c/Foo",
+		"a/b/$Foo":           "a/b/$Foo",
+		"a/b/$Foo$Bar":       "a/b/$Foo",
+		"a/b/Foo$$External1": "a/b/Foo",
+	} {
+		if got := topLevelClass(in); got != want {
+			t.Errorf("topLevelClass(%q): want %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestIntermediateFlags(t *testing.T) {
+	got := intermediateFlags([]string{"d8", "--min-api", "21", "--main-dex-rules", "rules", "--lib", "a.jar"})
+	want := []string{"--min-api", "21", "--lib", "a.jar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestDexCache(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	os.Setenv(fakeD8LogEnv, log)
+	defer os.Unsetenv(fakeD8LogEnv)
+
+	tool := filepath.Join(dir, "d8.jar")
+	lib := filepath.Join(dir, "lib.jar")
+	writeFile := func(file, content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(file, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(tool, "d8 v1")
+	writeFile(lib, "lib v1")
+
+	classes := map[string][]byte{
+		"a/A":    classFile("a/A", "A v1", false),
+		"a/A$1":  classFile("a/A$1", "A$1 v1", false),
+		"a/B":    classFile("a/B", "B v1", false),
+		"a/I":    classFile("a/I", "I v1", true),
+		"a/C$In": classFile("a/C$In", "C$In v1", false),
+	}
+
+	// run dexes the classes and returns the classes compiled by d8 and the output.
+	run := func(verify bool) ([]string, string) {
+		t.Helper()
+		os.Remove(log)
+		jar := filepath.Join(dir, "classes.jar")
+		writeJar(t, jar, classes)
+		outDir := filepath.Join(dir, "out")
+		os.RemoveAll(outDir)
+		d := newDexCache(filepath.Join(dir, "cache"), []string{tool},
+			[]string{os.Args[0], "--min-api", "21", "--lib", lib})
+		if err := d.run(jar, outDir, verify); err != nil {
+			t.Fatal(err)
+		}
+
+		var dexed []string
+		if data, err := ioutil.ReadFile(log); err == nil {
+			dexed = strings.Fields(string(data))
+		}
+		sort.Strings(dexed)
+		out, err := ioutil.ReadFile(filepath.Join(outDir, "classes.dex"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dexed, string(out)
+	}
+
+	// want returns the expected output of fakeD8 for the current classes.
+	want := func() string {
+		var out []byte
+		for _, name := range sortedKeys(classes) {
+			out = append(out, "dex:"...)
+			out = append(out, classes[name]...)
+		}
+		return string(out)
+	}
+
+	check := func(what string, wantDexed []string, verify bool) {
+		t.Helper()
+		dexed, out := run(verify)
+		if !reflect.DeepEqual(dexed, wantDexed) {
+			t.Errorf("%s: want dexed classes %q, got %q", what, wantDexed, dexed)
+		}
+		if out != want() {
+			t.Errorf("%s: want output %q, got %q", what, want(), out)
+		}
+	}
+
+	all := []string{"a/A", "a/A$1", "a/B", "a/C$In", "a/I"}
+	check("first build", all, false)
+	check("no change", nil, false)
+
+	classes["a/A$1"] = classFile("a/A$1", "A$1 v2", false)
+	check("nested class changed", []string{"a/A", "a/A$1"}, false)
+
+	delete(classes, "a/B")
+	check("class removed", nil, false)
+	if entries, err := ioutil.ReadDir(filepath.Join(dir, "cache", "entries")); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 3 {
+		t.Errorf("expected the entry of the removed class to be pruned, got %d entries", len(entries))
+	}
+
+	// A class is dexed again when its superclass or a member of its nest changes.
+	classes["a/Base"] = classFile("a/Base", "Base v1", false)
+	classes["a/Sub"] = testClass{name: "a/Sub", body: "Sub v1", super: "a/Base"}.bytes()
+	classes["a/N"] = testClass{name: "a/N", body: "N v1", nestMembers: []string{"a/Other"}}.bytes()
+	classes["a/Other"] = testClass{name: "a/Other", body: "Other v1", nestHost: "a/N"}.bytes()
+	check("classes added", []string{"a/Base", "a/N", "a/Other", "a/Sub"}, false)
+
+	classes["a/Base"] = classFile("a/Base", "Base v2", false)
+	check("superclass changed", []string{"a/Base", "a/Sub"}, false)
+
+	classes["a/Other"] = testClass{name: "a/Other", body: "Other v2", nestHost: "a/N"}.bytes()
+	check("nest member changed", []string{"a/N", "a/Other"}, false)
+
+	classes["a/I"] = classFile("a/I", "I v2", true)
+	all = []string{"a/A", "a/A$1", "a/Base", "a/C$In", "a/I", "a/N", "a/Other", "a/Sub"}
+	check("interface changed", all, false)
+
+	writeFile(lib, "lib v2")
+	check("library changed", all, false)
+
+	writeFile(tool, "d8 v2")
+	check("tool changed", all, false)
+
+	// The verification dexes the jar directly, which fakeD8 doesn't log.
+	check("verify", nil, true)
+}
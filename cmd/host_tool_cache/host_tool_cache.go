@@ -0,0 +1,549 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// host_tool_cache runs a command that compiles a source file of a host module, sharing its
+// outputs through a cache directory with the builds in other output directories.
+//
+// Results are looked up in two steps, like ccache's direct mode.  The command line, the implicit
+// inputs passed with -i and the names of the outputs, with the output directory replaced by a
+// placeholder, are hashed into the key of a manifest.  The manifest lists the results stored for
+// the key, each with the hashes of the inputs read by the command according to its depfile
+// (the source file and the headers).  A result is only used if all of those inputs still have the
+// same contents.  The compiler is passed with -i like the other implicit inputs, so a compiler
+// updated in place doesn't reuse the results of the old one.  The hashes of the -i inputs are
+// remembered by path, size and modification time, so the compiler is only hashed again when it
+// changes.
+//
+// Results that haven't been used for the longest time are deleted when the results take more than
+// -max_size bytes.  This is checked at most once per gcInterval, after storing a result.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"android/soong/makedeps"
+)
+
+const (
+	// Bump to invalidate all the existing entries when the format or the key changes.
+	cacheVersion = "1"
+
+	// The placeholder for the output directory in keys and stored depfiles.
+	outDirPlaceholder = "${OUT_DIR}"
+
+	// The number of results kept in a manifest, the oldest ones are dropped first.
+	maxManifestEntries = 8
+
+	// The default size of the results above which the least recently used ones are deleted.
+	defaultMaxSize = 10 << 30
+
+	// The minimum time between two collections of the cache.
+	gcInterval = time.Hour
+
+	// Manifests that haven't been used for this long are deleted when the cache is collected.
+	maxManifestAge = 30 * 24 * time.Hour
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// manifestEntry is a result stored for a manifest key.
+type manifestEntry struct {
+	// The hashes of the inputs read by the command, with the output directory replaced by
+	// outDirPlaceholder.
+	Inputs map[string]string
+
+	// The name of the directory that contains the outputs.
+	Result string
+}
+
+type hostToolCache struct {
+	dir     string
+	outDir  string
+	outputs []string
+	depFile string
+	inputs  []string
+	command []string
+
+	// The size of the results above which the least recently used ones are deleted.
+	maxSize int64
+
+	outDirRegexp *regexp.Regexp
+}
+
+func newHostToolCache(dir, outDir string, outputs []string, depFile string, inputs []string,
+	command []string) *hostToolCache {
+
+	outDir = filepath.Clean(outDir)
+	return &hostToolCache{
+		dir:     dir,
+		outDir:  outDir,
+		outputs: outputs,
+		depFile: depFile,
+		inputs:  inputs,
+		command: command,
+		maxSize: defaultMaxSize,
+		// Only match the output directory at the start of a path, e.g. in "-Iout/soong" or
+		// "-fprofile-use=out/x" but not in "src/out/soong".
+		outDirRegexp: regexp.MustCompile(`(^-[A-Za-z_]*|^|[=:,\s])` + regexp.QuoteMeta(outDir) + `/`),
+	}
+}
+
+// normalize replaces the output directory in s with outDirPlaceholder.
+func (c *hostToolCache) normalize(s string) string {
+	return c.outDirRegexp.ReplaceAllString(s, "${1}"+strings.ReplaceAll(outDirPlaceholder, "$", "$$")+"/")
+}
+
+// denormalize replaces outDirPlaceholder in s with the output directory.
+func (c *hostToolCache) denormalize(s string) string {
+	return strings.ReplaceAll(s, outDirPlaceholder+"/", c.outDir+"/")
+}
+
+// manifestKey returns the key of the manifest for the command.
+func (c *hostToolCache) manifestKey() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version %s\n", cacheVersion)
+	for _, arg := range c.command {
+		fmt.Fprintf(h, "arg %q\n", c.normalize(arg))
+	}
+	for _, output := range c.outputs {
+		fmt.Fprintf(h, "output %q\n", c.normalize(output))
+	}
+	fmt.Fprintf(h, "depfile %q\n", c.normalize(c.depFile))
+
+	inputs := append([]string(nil), c.inputs...)
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		hash, err := c.hashInput(input)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "input %q %s\n", c.normalize(input), hash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashInput returns the hash of an input passed with -i.  These are hashed for every command, and
+// include the compiler, so their hashes are stored in the cache keyed by their path, size and
+// modification time.
+func (c *hostToolCache) hashInput(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %d %d\n", abs, info.Size(), info.ModTime().UnixNano())
+	key := hex.EncodeToString(h.Sum(nil))
+	hashPath := filepath.Join(c.dir, "hashes", key[:2], key)
+
+	if data, err := ioutil.ReadFile(hashPath); err == nil {
+		return string(data), nil
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Failing to remember the hash only means it is computed again.
+	writeFileAtomically(hashPath, []byte(hash))
+	return hash, nil
+}
+
+func (c *hostToolCache) manifestPath(key string) string {
+	return filepath.Join(c.dir, "manifests", key[:2], key+".json")
+}
+
+func (c *hostToolCache) resultDir(result string) string {
+	return filepath.Join(c.dir, "results", result[:2], result)
+}
+
+func (c *hostToolCache) readManifest(key string) []manifestEntry {
+	var entries []manifestEntry
+	data, err := ioutil.ReadFile(c.manifestPath(key))
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// A corrupt manifest is only a cache miss, it will be replaced.
+		return nil
+	}
+	return entries
+}
+
+// lookup restores the outputs of a result stored for key whose inputs are unchanged.  It returns
+// false if there is no such result.
+func (c *hostToolCache) lookup(key string) bool {
+	for _, entry := range c.readManifest(key) {
+		if !c.inputsMatch(entry.Inputs) {
+			continue
+		}
+		if err := c.restore(c.resultDir(entry.Result)); err != nil {
+			continue
+		}
+		// The modification times record when the result and the manifest were last used, for gc.
+		now := time.Now()
+		os.Chtimes(c.resultDir(entry.Result), now, now)
+		os.Chtimes(c.manifestPath(key), now, now)
+		return true
+	}
+	return false
+}
+
+func (c *hostToolCache) inputsMatch(inputs map[string]string) bool {
+	for input, want := range inputs {
+		if hash, err := hashFile(c.denormalize(input)); err != nil || hash != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *hostToolCache) restore(dir string) error {
+	for i, output := range c.outputs {
+		if err := copyFile(filepath.Join(dir, fmt.Sprint(i)), output); err != nil {
+			return err
+		}
+	}
+	if c.depFile != "" {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "depfile"))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(c.depFile, []byte(c.denormalize(string(data))), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store adds the outputs of the command that just ran as a result for key.
+func (c *hostToolCache) store(key string) error {
+	inputs := make(map[string]string)
+	var depFileData []byte
+	if c.depFile != "" {
+		data, err := ioutil.ReadFile(c.depFile)
+		if err != nil {
+			return err
+		}
+		deps, err := makedeps.Parse(c.depFile, bytes.NewBuffer(append([]byte(nil), data...)))
+		if err != nil {
+			return err
+		}
+		for _, input := range deps.Inputs {
+			hash, err := hashFile(input)
+			if err != nil {
+				return err
+			}
+			inputs[c.normalize(input)] = hash
+		}
+		depFileData = []byte(c.normalize(string(data)))
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "manifest %s\n", key)
+	var sortedInputs []string
+	for input := range inputs {
+		sortedInputs = append(sortedInputs, input)
+	}
+	sort.Strings(sortedInputs)
+	for _, input := range sortedInputs {
+		fmt.Fprintf(h, "input %q %s\n", input, inputs[input])
+	}
+	result := hex.EncodeToString(h.Sum(nil))
+
+	if err := c.storeResult(result, depFileData); err != nil {
+		return err
+	}
+
+	entries := []manifestEntry{{Inputs: inputs, Result: result}}
+	for _, entry := range c.readManifest(key) {
+		if entry.Result != result && len(entries) < maxManifestEntries {
+			entries = append(entries, entry)
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.manifestPath(key), data)
+}
+
+// storeResult copies the outputs into the directory for result, unless it already exists.
+func (c *hostToolCache) storeResult(result string, depFileData []byte) error {
+	dir := c.resultDir(result)
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), result+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, output := range c.outputs {
+		if err := copyFile(output, filepath.Join(tmp, fmt.Sprint(i))); err != nil {
+			return err
+		}
+	}
+	if c.depFile != "" {
+		if err := ioutil.WriteFile(filepath.Join(tmp, "depfile"), depFileData, 0666); err != nil {
+			return err
+		}
+	}
+
+	// Move the complete result into place so that a concurrent lookup never sees a partial one.
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			// Another build stored the same result first.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// run looks up the outputs of the command in the cache, and runs the command and stores its
+// outputs if they are not found.  Failures to use the cache are reported to stderr but only make
+// the command run uncached.
+func (c *hostToolCache) run(stdout, stderr io.Writer) error {
+	key, err := c.manifestKey()
+	if err != nil {
+		fmt.Fprintf(stderr, "host_tool_cache: not caching: %s\n", err)
+	} else if c.lookup(key) {
+		return nil
+	}
+
+	cmd := exec.Command(c.command[0], c.command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if key != "" {
+		if err := c.store(key); err != nil {
+			fmt.Fprintf(stderr, "host_tool_cache: failed to store outputs: %s\n", err)
+		} else if err := c.maybeGC(time.Now()); err != nil {
+			fmt.Fprintf(stderr, "host_tool_cache: failed to collect the cache: %s\n", err)
+		}
+	}
+	return nil
+}
+
+// maybeGC collects the cache if it hasn't been collected for gcInterval.  The time of the last
+// collection is the modification time of the gc stamp file, which is updated first so that the
+// concurrent commands don't all collect the cache.
+func (c *hostToolCache) maybeGC(now time.Time) error {
+	stamp := filepath.Join(c.dir, "gc.stamp")
+	if info, err := os.Stat(stamp); err == nil && now.Sub(info.ModTime()) < gcInterval {
+		return nil
+	} else if os.IsNotExist(err) {
+		if err := ioutil.WriteFile(stamp, nil, 0666); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := os.Chtimes(stamp, now, now); err != nil {
+		return err
+	}
+	return c.gc(now)
+}
+
+// gc deletes the least recently used results until they take at most maxSize bytes, the manifests
+// that haven't been used for maxManifestAge and the temporary directories left by interrupted
+// commands.  A manifest entry whose result was deleted is only a cache miss.
+func (c *hostToolCache) gc(now time.Time) error {
+	type result struct {
+		dir  string
+		size int64
+		used time.Time
+	}
+	var results []result
+	var total int64
+
+	resultsDir := filepath.Join(c.dir, "results")
+	prefixes, err := ioutil.ReadDir(resultsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, prefix := range prefixes {
+		entries, err := ioutil.ReadDir(filepath.Join(resultsDir, prefix.Name()))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			dir := filepath.Join(resultsDir, prefix.Name(), entry.Name())
+			if strings.Contains(entry.Name(), ".tmp") {
+				if now.Sub(entry.ModTime()) > gcInterval {
+					os.RemoveAll(dir)
+				}
+				continue
+			}
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			r := result{dir: dir, used: entry.ModTime()}
+			for _, file := range files {
+				r.size += file.Size()
+			}
+			results = append(results, r)
+			total += r.size
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].used.Before(results[j].used) })
+	for _, r := range results {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.RemoveAll(r.dir); err != nil {
+			return err
+		}
+		total -= r.size
+	}
+
+	return filepath.Walk(filepath.Join(c.dir, "manifests"), func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !info.IsDir() && now.Sub(info.ModTime()) > maxManifestAge {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	// Remove the output first so that a hard link or a read-only output is replaced instead of
+	// modified.
+	os.Remove(to)
+	return ioutil.WriteFile(to, data, info.Mode().Perm())
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func main() {
+	var outputs, inputs stringList
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -cache_dir <dir> -out_dir <dir> -o <output> [-o <output>...] "+
+			"[-depfile <depfile>] [-i <input>...] [-max_size <bytes>] -- <command>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	cacheDir := flag.String("cache_dir", "", "the shared cache directory")
+	outDir := flag.String("out_dir", "", "the output directory of the build")
+	flag.Var(&outputs, "o", "an output of the command")
+	depFile := flag.String("depfile", "", "the depfile written by the command")
+	flag.Var(&inputs, "i", "an implicit input of the command that is not listed in its depfile")
+	maxSize := flag.Int64("max_size", defaultMaxSize,
+		"the size in bytes of the results above which the least recently used ones are deleted")
+	flag.Parse()
+
+	if *cacheDir == "" || *outDir == "" || len(outputs) == 0 || *maxSize < 0 || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cache := newHostToolCache(*cacheDir, *outDir, outputs, *depFile, inputs, flag.Args())
+	cache.maxSize = *maxSize
+	if err := cache.run(os.Stdout, os.Stderr); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, "host_tool_cache:", err)
+		os.Exit(1)
+	}
+}
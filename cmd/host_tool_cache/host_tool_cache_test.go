@@ -0,0 +1,195 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	c := newHostToolCache("cache", "out", []string{"out/foo.o"}, "", nil, nil)
+	for in, want := range map[string]string{
+		"out/soong/foo.o":         "${OUT_DIR}/soong/foo.o",
+		"-Iout/soong/gen":         "-I${OUT_DIR}/soong/gen",
+		"-isystemout/gen":         "-isystem${OUT_DIR}/gen",
+		"-Isrc/out/gen":           "-Isrc/out/gen",
+		"src/out/foo.c":           "src/out/foo.c",
+		"out.c":                   "out.c",
+		"a.o: out/gen.h src/a.c":  "a.o: ${OUT_DIR}/gen.h src/a.c",
+		"-fprofile-use=out/x.pgo": "-fprofile-use=${OUT_DIR}/x.pgo",
+	} {
+		if got := c.normalize(in); got != want {
+			t.Errorf("normalize(%q): want %q, got %q", in, want, got)
+		}
+		if got := c.denormalize(c.normalize(in)); got != in {
+			t.Errorf("denormalize(normalize(%q)): got %q", in, got)
+		}
+	}
+}
+
+func TestHostToolCache(t *testing.T) {
+	top := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(top); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	writeFile("src/foo.c", "foo")
+	writeFile("src/foo.h", "header")
+
+	// run runs a fake compiler that concatenates the source and header with its flag, and returns
+	// whether it ran or the outputs were restored from the cache.
+	run := func(outDir, flag string) bool {
+		t.Helper()
+		obj := filepath.Join(outDir, "obj/foo.o")
+		depFile := obj + ".d"
+		os.Remove(filepath.Join(top, "ran"))
+		script := `mkdir -p $(dirname $2) && cat src/foo.c src/foo.h > $2 && echo $1 >> $2 && ` +
+			`echo "$2: src/foo.c src/foo.h" > $2.d && touch ran`
+		c := newHostToolCache(filepath.Join(top, "cache"), outDir, []string{obj}, depFile, nil,
+			[]string{"sh", "-c", script, "sh", flag, obj})
+		if err := c.run(ioutil.Discard, os.Stderr); err != nil {
+			t.Fatal(err)
+		}
+		if g, w := readFile(depFile), obj+": src/foo.c src/foo.h\n"; g != w {
+			t.Errorf("want depfile %q, got %q", w, g)
+		}
+		if g, w := readFile(obj), "foo"+readFile("src/foo.h")+flag+"\n"; g != w {
+			t.Errorf("want output %q, got %q", w, g)
+		}
+		_, err := os.Stat(filepath.Join(top, "ran"))
+		return err == nil
+	}
+
+	if !run("out_a", "-O2") {
+		t.Errorf("expected the first build to run the command")
+	}
+	if run("out_b", "-O2") {
+		t.Errorf("expected the second output directory to reuse the cached outputs")
+	}
+	if !run("out_b", "-O0") {
+		t.Errorf("expected different flags to run the command")
+	}
+
+	writeFile("src/foo.h", "changed")
+	if !run("out_c", "-O2") {
+		// The outputs are checked by run, a stale result would have the old header.
+		t.Errorf("expected a changed header to run the command")
+	}
+}
+
+func TestHostToolCacheFailingCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out/foo.o")
+	c := newHostToolCache(filepath.Join(dir, "cache"), filepath.Join(dir, "out"), []string{out}, "", nil,
+		[]string{"sh", "-c", "echo error >&2; exit 3"})
+	stderr := &strings.Builder{}
+	if err := c.run(ioutil.Discard, stderr); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cache", "results")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be stored for a failing command")
+	}
+}
+
+func TestHostToolCacheGC(t *testing.T) {
+	dir := t.TempDir()
+	c := newHostToolCache(dir, filepath.Join(dir, "out"), nil, "", nil, nil)
+	c.maxSize = 10
+
+	now := time.Now()
+	writeResult := func(name string, size int, used time.Time) string {
+		t.Helper()
+		result := c.resultDir(name)
+		if err := os.MkdirAll(result, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(result, "0"), make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(result, used, used); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	oldest := writeResult("aa01", 6, now.Add(-3*time.Hour))
+	older := writeResult("aa02", 6, now.Add(-2*time.Hour))
+	newest := writeResult("bb01", 4, now.Add(-time.Hour))
+
+	manifest := c.manifestPath("cc01")
+	if err := writeFileAtomically(manifest, []byte("[]")); err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-2 * maxManifestAge)
+	if err := os.Chtimes(manifest, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.maybeGC(now); err != nil {
+		t.Fatal(err)
+	}
+	for _, deleted := range []string{oldest, manifest} {
+		if _, err := os.Stat(deleted); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be deleted", deleted)
+		}
+	}
+	for _, kept := range []string{older, newest} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("expected %s to be kept: %s", kept, err)
+		}
+	}
+
+	// The cache is not collected again before gcInterval.
+	writeResult("dd01", 6, now)
+	if err := c.maybeGC(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(older); err != nil {
+		t.Errorf("expected %s to be kept until the next collection: %s", older, err)
+	}
+	if err := c.maybeGC(now.Add(gcInterval + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(older); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be deleted by the next collection", older)
+	}
+}
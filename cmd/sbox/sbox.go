@@ -270,8 +270,9 @@ func runCommand(command *sbox_proto.Command, tempDir string, commandIndex int) (
 	}
 
 	if strings.Contains(rawCommand, depFilePlaceholder) {
-		depFile = filepath.Join(pathToTempDirInSbox, "deps.d")
-		rawCommand = strings.Replace(rawCommand, depFilePlaceholder, depFile, -1)
+		depFile = filepath.Join(tempDir, "deps.d")
+		rawCommand = strings.Replace(rawCommand, depFilePlaceholder,
+			filepath.Join(pathToTempDirInSbox, "deps.d"), -1)
 	}
 
 	if strings.Contains(rawCommand, sandboxDirPlaceholder) {
@@ -343,6 +344,13 @@ func runCommand(command *sbox_proto.Command, tempDir string, commandIndex int) (
 		return "", err
 	}
 
+	if depFile != "" {
+		err = validateDepFile(depFile, rawCommand)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// the created files match the declared files; now move them
 	err = moveFiles(command.CopyAfter, tempDir, "", writeType(writeIfChanged))
 	if err != nil {
@@ -352,6 +360,21 @@ func runCommand(command *sbox_proto.Command, tempDir string, commandIndex int) (
 	return depFile, nil
 }
 
+// validateDepFile checks that a command that was passed a depfile with the __SBOX_DEPFILE__
+// placeholder actually wrote it.
+func validateDepFile(depFile, rawCommand string) error {
+	fileInfo, err := os.Stat(depFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("sbox command(%s) did not write the depfile %s that it was passed as %s,\n"+
+			"the command must write a depfile when it is given one", rawCommand, depFile, depFilePlaceholder)
+	} else if err != nil {
+		return err
+	} else if fileInfo.IsDir() {
+		return fmt.Errorf("sbox command(%s) created a directory instead of the depfile %s", rawCommand, depFile)
+	}
+	return nil
+}
+
 // makeOutputDirs creates directories in the sandbox dir for every file that has a rule to be copied
 // out of the sandbox.  This emulate's Ninja's behavior of creating directories for output files
 // so that the tools don't have to.
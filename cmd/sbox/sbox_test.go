@@ -20,6 +20,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/proto"
 )
 
 func Test_filesHaveSameContents(t *testing.T) {
@@ -125,3 +129,81 @@ func Test_filesHaveSameContents(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateDepFile(t *testing.T) {
+	dir := t.TempDir()
+	depFile := filepath.Join(dir, "deps.d")
+
+	err := validateDepFile(depFile, "tool")
+	if err == nil || !strings.Contains(err.Error(), "did not write the depfile") {
+		t.Errorf("expected missing depfile error, got %v", err)
+	}
+
+	if err := os.Mkdir(depFile, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateDepFile(depFile, "tool"); err == nil {
+		t.Errorf("expected error for depfile that is a directory")
+	}
+	if err := os.Remove(depFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(depFile, []byte("out: in\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateDepFile(depFile, "tool"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func Test_runCommandSandboxInputs(t *testing.T) {
+	srcDir := t.TempDir()
+	for _, file := range []string{"declared.txt", "undeclared.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(srcDir, file), []byte(file+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// sbox runs from the top of the source tree.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(srcDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	savedOutputDir := outputDir
+	defer func() { outputDir = savedOutputDir }()
+	outputDir = filepath.Join(t.TempDir(), "gen")
+
+	// command returns a command that reads a declared and an undeclared input, copying only the
+	// declared input into the sandbox if sandboxInputs is true.
+	command := func(sandboxInputs bool) *sbox_proto.Command {
+		cmd := &sbox_proto.Command{
+			Command: proto.String("cat declared.txt undeclared.txt > __SBOX_SANDBOX_DIR__/out/out.txt"),
+			CopyAfter: []*sbox_proto.Copy{{
+				From: proto.String("out/out.txt"),
+				To:   proto.String(filepath.Join(outputDir, "out.txt")),
+			}},
+		}
+		if sandboxInputs {
+			cmd.CopyBefore = []*sbox_proto.Copy{{
+				From: proto.String("declared.txt"),
+				To:   proto.String("declared.txt"),
+			}}
+			cmd.Chdir = proto.Bool(true)
+		}
+		return cmd
+	}
+
+	if _, err := runCommand(command(false), filepath.Join(t.TempDir(), "sbox"), 0); err != nil {
+		t.Errorf("expected reading an undeclared input to pass without sandboxed inputs, got %s", err)
+	}
+
+	if _, err := runCommand(command(true), filepath.Join(t.TempDir(), "sbox"), 0); err == nil {
+		t.Errorf("expected reading an undeclared input to fail with sandboxed inputs")
+	}
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -48,11 +49,19 @@ var (
 	delveListen string
 	delvePath   string
 
-	moduleGraphFile   string
-	moduleActionsFile string
-	docFile           string
-	bazelQueryViewDir string
-	bp2buildMarker    string
+	moduleGraphFile           string
+	moduleActionsFile         string
+	dependencyGraphFile       string
+	mutatorProfileFile        string
+	singleThreadedMutators    bool
+	moduleVariantsName        string
+	moduleVariantProperties   bool
+	moduleVariantsFile        string
+	analysisCacheManifestFile string
+	docFile                   string
+	bazelQueryViewDir         string
+	bp2buildMarker            string
+	checkOnlyMarker           string
 
 	cmdlineArgs bootstrap.Args
 )
@@ -75,6 +84,8 @@ func init() {
 	flag.StringVar(&cmdlineArgs.TraceFile, "trace", "", "write trace to file")
 	flag.StringVar(&cmdlineArgs.Memprofile, "memprofile", "", "write memory profile to file")
 	flag.BoolVar(&cmdlineArgs.NoGC, "nogc", false, "turn off GC for debugging")
+	flag.StringVar(&mutatorProfileFile, "mutator_profile", "", "write the time spent in every mutator to file as a Chrome trace")
+	flag.BoolVar(&singleThreadedMutators, "single_threaded_mutators", false, "run the parallel mutators on one module at a time for debugging")
 
 	// Flags representing various modes soong_build can run in
 	flag.StringVar(&moduleGraphFile, "module_graph_file", "", "JSON module graph file to output")
@@ -82,8 +93,14 @@ func init() {
 	flag.StringVar(&docFile, "soong_docs", "", "build documentation file to output")
 	flag.StringVar(&bazelQueryViewDir, "bazel_queryview_dir", "", "path to the bazel queryview directory relative to --top")
 	flag.StringVar(&bp2buildMarker, "bp2build_marker", "", "If set, run bp2build, touch the specified marker file then exit")
+	flag.StringVar(&checkOnlyMarker, "check_only_marker", "", "If set, only parse and analyze the Android.bp files, touch the specified marker file then exit")
 	flag.StringVar(&cmdlineArgs.OutFile, "o", "build.ninja", "the Ninja file to output")
 	flag.BoolVar(&cmdlineArgs.EmptyNinjaFile, "empty-ninja-file", false, "write out a 0-byte ninja file")
+	flag.StringVar(&dependencyGraphFile, "dependency_graph_file", "", "JSON file to output the module dependency graph to")
+	flag.StringVar(&moduleVariantsName, "module_variants", "", "name of the module whose variants are written to --module_variants_file")
+	flag.StringVar(&moduleVariantsFile, "module_variants_file", "", "JSON file to output the variants of the --module_variants module to")
+	flag.BoolVar(&moduleVariantProperties, "module_variant_properties", false, "include the resolved properties of the variants in --module_variants_file")
+	flag.StringVar(&analysisCacheManifestFile, "analysis_cache_manifest", "", "JSON file to output the globs and directly written files to, for the analysis cache")
 
 	// Flags that probably shouldn't be flags of soong_build but we haven't found
 	// the time to remove them yet
@@ -126,6 +143,21 @@ func newConfig(availableEnv map[string]string) android.Config {
 		fmt.Fprintf(os.Stderr, "%s", err)
 		os.Exit(1)
 	}
+	if dependencyGraphFile != "" {
+		configuration.EnableDependencyGraph()
+	}
+	if mutatorProfileFile != "" {
+		configuration.EnableMutatorProfile()
+	}
+	if singleThreadedMutators {
+		configuration.EnableSingleThreadedMutators()
+	}
+	if moduleVariantsFile != "" {
+		configuration.EnableModuleVariants(moduleVariantsName)
+		if moduleVariantProperties {
+			configuration.EnableModuleVariantProperties()
+		}
+	}
 	return configuration
 }
 
@@ -167,6 +199,8 @@ func runMixedModeBuild(configuration android.Config, firstCtx *android.Context,
 	ninjaDeps = append(ninjaDeps, globListFiles...)
 
 	writeDepFile(cmdlineArgs.OutFile, *secondCtx.EventHandler, ninjaDeps)
+	writeDependencyGraph(secondConfig)
+	writeMutatorProfile(secondConfig)
 }
 
 // Run the code-generation phase to convert BazelTargetModules to BUILD files.
@@ -210,6 +244,67 @@ func writeJsonModuleGraphAndActions(ctx *android.Context, graphPath string, acti
 	ctx.Context.PrintJSONGraphAndActions(graphFile, actionsFile)
 }
 
+func writeModuleVariants(configuration android.Config) {
+	if moduleVariantsFile == "" {
+		return
+	}
+	err := android.WriteModuleVariants(configuration, shared.JoinPath(topDir, moduleVariantsFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing module variants %s: %s\n", moduleVariantsFile, err)
+		os.Exit(1)
+	}
+}
+
+func writeDependencyGraph(configuration android.Config) {
+	if dependencyGraphFile == "" {
+		return
+	}
+	err := android.WriteDependencyGraph(configuration, shared.JoinPath(topDir, dependencyGraphFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing dependency graph %s: %s\n", dependencyGraphFile, err)
+		os.Exit(1)
+	}
+}
+
+func writeMutatorProfile(configuration android.Config) {
+	if mutatorProfileFile == "" {
+		return
+	}
+	err := android.WriteMutatorProfile(configuration, shared.JoinPath(topDir, mutatorProfileFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing mutator profile %s: %s\n", mutatorProfileFile, err)
+		os.Exit(1)
+	}
+}
+
+// writeAnalysisCacheManifest writes the globs evaluated by ctx and the files written directly to
+// the output directory so that soong_ui can validate and restore a cached build.ninja.
+func writeAnalysisCacheManifest(ctx *android.Context) {
+	if analysisCacheManifestFile == "" {
+		return
+	}
+
+	manifest := shared.AnalysisCacheManifest{
+		Outputs: android.OutputDirFilesWritten(),
+	}
+	for _, glob := range ctx.Globs() {
+		manifest.Globs = append(manifest.Globs, shared.AnalysisCacheGlob{
+			Pattern:  glob.Pattern,
+			Excludes: glob.Excludes,
+			Matches:  glob.Matches,
+		})
+	}
+
+	data, err := json.Marshal(manifest)
+	if err == nil {
+		err = ioutil.WriteFile(shared.JoinPath(topDir, analysisCacheManifestFile), data, 0666)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing analysis cache manifest %s: %s\n", analysisCacheManifestFile, err)
+		os.Exit(1)
+	}
+}
+
 func writeBuildGlobsNinjaFile(ctx *android.Context, buildDir string, config interface{}) []string {
 	ctx.EventHandler.Begin("globs_ninja_file")
 	defer ctx.EventHandler.End("globs_ninja_file")
@@ -244,6 +339,7 @@ func doChosenActivity(configuration android.Config, extraNinjaDeps []string) str
 	generateQueryView := bazelQueryViewDir != ""
 	generateModuleGraphFile := moduleGraphFile != ""
 	generateDocFile := docFile != ""
+	checkOnly := checkOnlyMarker != ""
 
 	if generateBazelWorkspace {
 		// Run the alternate pipeline of bp2build mutators and singleton to convert
@@ -265,6 +361,10 @@ func doChosenActivity(configuration android.Config, extraNinjaDeps []string) str
 			stopBefore = bootstrap.StopBeforePrepareBuildActions
 		} else if generateDocFile {
 			stopBefore = bootstrap.StopBeforePrepareBuildActions
+		} else if checkOnly {
+			// The mutators, including the visibility and neverallow checks, report their errors
+			// before the build actions are generated, which is most of the time spent in soong_build.
+			stopBefore = bootstrap.StopBeforePrepareBuildActions
 		} else {
 			stopBefore = bootstrap.DoEverything
 		}
@@ -295,10 +395,20 @@ func doChosenActivity(configuration android.Config, extraNinjaDeps []string) str
 			}
 			writeDepFile(docFile, *ctx.EventHandler, ninjaDeps)
 			return docFile
+		} else if checkOnly {
+			// RunBlueprint exits with the errors if there were any, so reaching here means the
+			// Android.bp files are fine.
+			touch(shared.JoinPath(topDir, checkOnlyMarker))
+			writeDepFile(checkOnlyMarker, *ctx.EventHandler, ninjaDeps)
+			return checkOnlyMarker
 		} else {
 			// The actual output (build.ninja) was written in the RunBlueprint() call
 			// above
 			writeDepFile(cmdlineArgs.OutFile, *ctx.EventHandler, ninjaDeps)
+			writeDependencyGraph(configuration)
+			writeModuleVariants(configuration)
+			writeMutatorProfile(configuration)
+			writeAnalysisCacheManifest(ctx)
 		}
 	}
 
@@ -135,7 +135,7 @@ func main() {
 		build.OsEnvironment().IsEnvTrue("SOONG_UI_ANSI_OUTPUT"))
 
 	// Attach a new logger instance to the terminal output.
-	log := logger.New(output)
+	log := logger.New(output).SetJSON(build.OsEnvironment().IsEnvTrue("SOONG_UI_JSON_LOG"))
 	defer log.Cleanup()
 
 	// Create a context to simplify the program termination process.
@@ -149,7 +149,6 @@ func main() {
 	// Create and start a new metric record.
 	met := metrics.New()
 	met.SetBuildDateTime(buildStarted)
-	met.SetBuildCommand(os.Args)
 
 	// Create a new Status instance, which manages action counts and event output channels.
 	stat := &status.Status{}
@@ -174,6 +173,10 @@ func main() {
 		Status:  stat,
 	}}
 
+	// Record the build command before the config is created, so that it is in the metrics of
+	// builds that fail to parse their arguments or environment.
+	met.SetBuildCommand(build.RedactCommandLine(os.Args, build.MetricsRedactPatternsFromEnv(build.OsEnvironment())))
+
 	config := c.config(buildCtx, args...)
 
 	build.SetupOutDir(buildCtx, config)
@@ -212,13 +215,16 @@ func main() {
 		// is the first one that is executed to save the rbe metrics to a protobuf
 		// file. The soong metrics file is then next. Bazel profiles are written
 		// before the uploadMetrics is invoked. The written files are then uploaded
-		// if the uploading of the metrics is enabled.
+		// if the uploading of the metrics is enabled. The soong metrics file is
+		// saved once more after the upload so that the local copy includes the
+		// time spent uploading the metrics.
 		files := []string{
 			buildErrorFile,           // build error strings
 			rbeMetricsFile,           // high level metrics related to remote build execution.
 			soongMetricsFile,         // high level metrics related to this build system.
 			config.BazelMetricsDir(), // directory that contains a set of bazel metrics.
 		}
+		defer met.Dump(soongMetricsFile)
 		defer build.UploadMetrics(buildCtx, config, c.simpleOutput, buildStarted, files...)
 		defer met.Dump(soongMetricsFile)
 	}
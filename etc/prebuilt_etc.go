@@ -31,6 +31,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/google/blueprint/proptools"
@@ -115,8 +116,15 @@ type prebuiltSubdirProperties struct {
 	// Optional subdirectory under which this file is installed into, cannot be specified with
 	// sub_dir.
 	Relative_install_path *string `android:"arch_variant"`
+
+	// Optional version of this file, appended to the install subdirectory so that, for example,
+	// relative_install_path: "foo" and version: "2" install into etc/foo/2. May only contain
+	// letters, digits, and '.', '_' or '-' between them.
+	Version *string `android:"arch_variant"`
 }
 
+var prebuiltEtcVersionRegexp = regexp.MustCompile(`^[0-9A-Za-z]+([._-][0-9A-Za-z]+)*$`)
+
 type PrebuiltEtcModule interface {
 	android.Module
 
@@ -268,10 +276,14 @@ func (p *PrebuiltEtc) OutputFiles(tag string) (android.Paths, error) {
 }
 
 func (p *PrebuiltEtc) SubDir() string {
-	if subDir := proptools.String(p.subdirProperties.Sub_dir); subDir != "" {
-		return subDir
+	subDir := proptools.String(p.subdirProperties.Sub_dir)
+	if subDir == "" {
+		subDir = proptools.String(p.subdirProperties.Relative_install_path)
 	}
-	return proptools.String(p.subdirProperties.Relative_install_path)
+	if version := proptools.String(p.subdirProperties.Version); version != "" {
+		subDir = filepath.Join(subDir, version)
+	}
+	return subDir
 }
 
 func (p *PrebuiltEtc) BaseDir() string {
@@ -329,6 +341,24 @@ func (p *PrebuiltEtc) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		ctx.PropertyErrorf("sub_dir", "relative_install_path is set. Cannot set sub_dir")
 	}
 
+	// Check that the version is a single path component and that the versioned subdirectory
+	// stays inside the install directory.
+	if version := p.subdirProperties.Version; version != nil {
+		if !prebuiltEtcVersionRegexp.MatchString(*version) {
+			ctx.PropertyErrorf("version", "invalid version %q, it may only contain letters, digits, "+
+				"and '.', '_' or '-' between them", *version)
+			return
+		}
+		if subDir := p.SubDir(); filepath.IsAbs(subDir) || subDir == ".." || strings.HasPrefix(subDir, "../") {
+			property := "relative_install_path"
+			if p.subdirProperties.Sub_dir != nil {
+				property = "sub_dir"
+			}
+			ctx.PropertyErrorf(property, "install subdirectory %q is outside of the install directory", subDir)
+			return
+		}
+	}
+
 	// If soc install dir was specified and SOC specific is set, set the installDirPath to the
 	// specified socInstallDirBase.
 	installBaseDir := p.installDirBase
@@ -632,6 +662,9 @@ func generatePrebuiltSnapshot(s snapshot.SnapshotSingleton, ctx android.Singleto
 		if m.subdirProperties.Relative_install_path != nil {
 			prop.RelativeInstallPath = *m.subdirProperties.Relative_install_path
 		}
+		if m.subdirProperties.Version != nil {
+			prop.RelativeInstallPath = filepath.Join(prop.RelativeInstallPath, *m.subdirProperties.Version)
+		}
 
 		if m.properties.Filename != nil {
 			prop.Filename = *m.properties.Filename
@@ -703,6 +736,10 @@ func (module *PrebuiltEtc) ConvertWithBp2build(ctx android.TopDownMutatorContext
 	if !(dir == "etc" || dir == "usr/share") {
 		return
 	}
+	// prebuilt_file doesn't support versioned subdirectories yet
+	if module.subdirProperties.Version != nil {
+		return
+	}
 	if subDir := module.subdirProperties.Sub_dir; subDir != nil {
 		dir = dir + "/" + *subDir
 	}
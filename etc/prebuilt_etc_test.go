@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/google/blueprint/proptools"
@@ -180,6 +181,85 @@ func TestPrebuiltEtcCannotSetRelativeInstallPathAndSubDir(t *testing.T) {
 		`)
 }
 
+func TestPrebuiltEtcVersionInstallDirPath(t *testing.T) {
+	result := prepareForPrebuiltEtcTest.RunTestWithBp(t, `
+		prebuilt_etc {
+			name: "foo.conf",
+			src: "foo.conf",
+			relative_install_path: "foo",
+			version: "1.2",
+		}
+
+		prebuilt_etc {
+			name: "bar.conf",
+			src: "bar.conf",
+			version: "3",
+		}
+	`)
+
+	foo := result.Module("foo.conf", "android_arm64_armv8-a").(*PrebuiltEtc)
+	android.AssertPathRelativeToTopEquals(t, "install dir",
+		"out/soong/target/product/test_device/system/etc/foo/1.2", foo.installDirPath)
+	android.AssertStringEquals(t, "sub dir", "foo/1.2", foo.SubDir())
+
+	bar := result.Module("bar.conf", "android_arm64_armv8-a").(*PrebuiltEtc)
+	android.AssertPathRelativeToTopEquals(t, "install dir",
+		"out/soong/target/product/test_device/system/etc/3", bar.installDirPath)
+}
+
+func TestPrebuiltEtcVersionErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name: "invalid version",
+			bp: `
+				prebuilt_etc {
+					name: "foo.conf",
+					src: "foo.conf",
+					relative_install_path: "foo",
+					version: "../1",
+				}
+			`,
+			error: `version: invalid version "../1"`,
+		},
+		{
+			name: "traversal",
+			bp: `
+				prebuilt_etc {
+					name: "foo.conf",
+					src: "foo.conf",
+					relative_install_path: "foo/../..",
+					version: "1",
+				}
+			`,
+			error: `relative_install_path: install subdirectory "../1" is outside of the install directory`,
+		},
+		{
+			name: "absolute",
+			bp: `
+				prebuilt_etc {
+					name: "foo.conf",
+					src: "foo.conf",
+					sub_dir: "/foo",
+					version: "1",
+				}
+			`,
+			error: `sub_dir: install subdirectory "/foo/1" is outside of the install directory`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prepareForPrebuiltEtcTest.
+				ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.error))).
+				RunTestWithBp(t, tc.bp)
+		})
+	}
+}
+
 func TestPrebuiltEtcHost(t *testing.T) {
 	result := prepareForPrebuiltEtcTest.RunTestWithBp(t, `
 		prebuilt_etc_host {
@@ -0,0 +1,176 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterModuleType("composite_zip", compositeZipFactory)
+}
+
+type compositeZip struct {
+	android.ModuleBase
+
+	properties compositeZipProperties
+
+	output android.OutputPath
+}
+
+type compositeZipProperties struct {
+	// Set the name of the output. Defaults to <module_name>.zip.
+	Stem *string
+
+	// List of the files to place in the zip, each with the directory they are placed in.
+	Entries []compositeZipEntryProperties
+}
+
+type compositeZipEntryProperties struct {
+	// Directory in the zip where the files are placed. Defaults to the root of the zip.
+	Dir *string
+
+	// List of the files placed in dir. They can be source files, or the outputs of other modules
+	// using the syntax ":module" or ":module{tag}". The files keep their path relative to the
+	// directory of their module, or to the output directory of the module that generates them.
+	Srcs []string `android:"path"`
+
+	// List of the outputs of host modules placed in dir, using the syntax ":module" or
+	// ":module{tag}". Unlike srcs, which resolve to the default variant of the modules, these are
+	// the variants built for the build machine, like the host tools. The files keep their path
+	// relative to the output directory of their module.
+	Host_srcs []string
+}
+
+type compositeZipHostSrcTag struct {
+	blueprint.BaseDependencyTag
+	label string
+}
+
+// composite_zip is a zip of the outputs of other modules and of source files, each placed under a
+// directory of the zip.  Two files with the same path in the zip are an error.
+func compositeZipFactory() android.Module {
+	module := &compositeZip{}
+	module.AddProperties(&module.properties)
+	android.InitAndroidModule(module)
+	return module
+}
+
+func (z *compositeZip) DepsMutator(ctx android.BottomUpMutatorContext) {
+	// The srcs of the entries are added as dependencies by the path properties mutator.
+	for _, entry := range z.properties.Entries {
+		for _, src := range entry.Host_srcs {
+			module, _ := android.SrcIsModuleWithTag(src)
+			if module == "" {
+				ctx.PropertyErrorf("entries", "host_srcs %q is not a module reference", src)
+				continue
+			}
+			ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(),
+				compositeZipHostSrcTag{label: src}, module)
+		}
+	}
+}
+
+// hostSrcs returns the output files of the host_srcs dependencies, keyed by their label.
+func (z *compositeZip) hostSrcs(ctx android.ModuleContext) map[string]android.Paths {
+	srcs := make(map[string]android.Paths)
+	ctx.VisitDirectDeps(func(module android.Module) {
+		if tag, ok := ctx.OtherModuleDependencyTag(module).(compositeZipHostSrcTag); ok {
+			_, outputTag := android.SrcIsModuleWithTag(tag.label)
+			srcs[tag.label] = android.OutputFilesForModule(ctx, module, outputTag)
+		}
+	})
+	return srcs
+}
+
+func (z *compositeZip) installFileName() string {
+	return proptools.StringDefault(z.properties.Stem, z.BaseModuleName()+".zip")
+}
+
+func (z *compositeZip) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	// Map the paths in the zip to the files placed there, so that collisions are reported
+	// before anything is built.
+	files := make(map[string]android.Path)
+	hostSrcs := z.hostSrcs(ctx)
+	for _, entry := range z.properties.Entries {
+		dir := filepath.Clean(proptools.String(entry.Dir))
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			ctx.PropertyErrorf("entries", "dir %q must be a relative path inside the zip", proptools.String(entry.Dir))
+			continue
+		}
+		srcs := android.PathsForModuleSrc(ctx, entry.Srcs)
+		for _, label := range entry.Host_srcs {
+			paths, ok := hostSrcs[label]
+			if !ok {
+				// The dependency is missing, which has already been reported unless missing
+				// dependencies are allowed.
+				if module, _ := android.SrcIsModuleWithTag(label); module != "" {
+					ctx.AddMissingDependencies([]string{module})
+				}
+				continue
+			}
+			srcs = append(srcs, paths...)
+		}
+		for _, src := range srcs {
+			dest := filepath.Join(dir, src.Rel())
+			if other, exists := files[dest]; exists {
+				if other.String() != src.String() {
+					ctx.PropertyErrorf("entries", "%q is the destination of both %s and %s", dest, other, src)
+				}
+				continue
+			}
+			files[dest] = src
+		}
+	}
+	if ctx.Failed() {
+		return
+	}
+
+	dests := make([]string, 0, len(files))
+	for dest := range files {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+
+	// Each file is zipped directly from where it was built, at the path given by -e.
+	z.output = android.PathForModuleOut(ctx, z.installFileName()).OutputPath
+	builder := android.NewRuleBuilder(pctx, ctx)
+	cmd := builder.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", z.output)
+	for _, dest := range dests {
+		cmd.FlagWithArg("-e ", dest).FlagWithInput("-f ", files[dest])
+	}
+
+	builder.Build("composite_zip", fmt.Sprintf("Creating %s", z.installFileName()))
+}
+
+// Implements android.OutputFileProducer
+func (z *compositeZip) OutputFiles(tag string) (android.Paths, error) {
+	if tag == "" {
+		return android.Paths{z.output}, nil
+	}
+	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+}
+
+var _ android.OutputFileProducer = (*compositeZip)(nil)
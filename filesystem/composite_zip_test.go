@@ -0,0 +1,174 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestCompositeZip(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		fixture,
+		android.FixtureMergeMockFs(android.MockFS{
+			"docs/README.md":  nil,
+			"docs/sub/foo.md": nil,
+		}),
+	).RunTestWithBp(t, `
+		genrule {
+			name: "gen",
+			cmd: "touch $(out)",
+			out: ["bin/tool", "lib/libtool.so"],
+		}
+		filegroup {
+			name: "docs",
+			srcs: ["docs/**/*.md"],
+		}
+		composite_zip {
+			name: "dist",
+			entries: [
+				{
+					srcs: [":gen"],
+				},
+				{
+					dir: "share/doc",
+					srcs: [":docs", "docs/README.md"],
+				},
+			],
+		}
+	`)
+
+	module := result.ModuleForTests("dist", "")
+	rule := module.Rule("composite_zip")
+	android.AssertPathsRelativeToTopEquals(t, "inputs", []string{
+		"docs/README.md",
+		"docs/sub/foo.md",
+		"out/soong/.intermediates/gen/gen/bin/tool",
+		"out/soong/.intermediates/gen/gen/lib/libtool.so",
+	}, rule.Implicits)
+
+	command := android.StringRelativeToTop(result.Config, rule.RuleParams.Command)
+	for _, file := range []string{
+		"-e bin/tool -f out/soong/.intermediates/gen/gen/bin/tool",
+		"-e lib/libtool.so -f out/soong/.intermediates/gen/gen/lib/libtool.so",
+		"-e share/doc/docs/README.md -f docs/README.md",
+		"-e share/doc/docs/sub/foo.md -f docs/sub/foo.md",
+	} {
+		android.AssertStringDoesContain(t, "command", command, file)
+	}
+	android.AssertStringEquals(t, "output", "dist.zip", module.Output("dist.zip").Output.Base())
+}
+
+func TestCompositeZipHostSrcs(t *testing.T) {
+	result := fixture.RunTestWithBp(t, `
+		cc_binary_host {
+			name: "tool",
+			stl: "none",
+			system_shared_libs: [],
+		}
+		composite_zip {
+			name: "dist",
+			entries: [
+				{
+					dir: "bin",
+					host_srcs: [":tool"],
+				},
+			],
+		}
+	`)
+
+	rule := result.ModuleForTests("dist", "").Rule("composite_zip")
+	android.AssertPathsRelativeToTopEquals(t, "inputs", []string{
+		"out/soong/.intermediates/tool/linux_glibc_x86_64/tool",
+	}, rule.Implicits)
+
+	command := android.StringRelativeToTop(result.Config, rule.RuleParams.Command)
+	android.AssertStringDoesContain(t, "command", command,
+		"-e bin/tool -f out/soong/.intermediates/tool/linux_glibc_x86_64/tool")
+}
+
+func TestCompositeZipErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name: "collision",
+			bp: `
+				genrule {
+					name: "gen_a",
+					cmd: "touch $(out)",
+					out: ["tool"],
+				}
+				genrule {
+					name: "gen_b",
+					cmd: "touch $(out)",
+					out: ["tool"],
+				}
+				composite_zip {
+					name: "dist",
+					entries: [
+						{
+							dir: "bin",
+							srcs: [":gen_a"],
+						},
+						{
+							dir: "bin/",
+							srcs: [":gen_b"],
+						},
+					],
+				}
+			`,
+			error: `"bin/tool" is the destination of both .*gen_a/gen/tool and .*gen_b/gen/tool`,
+		},
+		{
+			name: "outside of the zip",
+			bp: `
+				composite_zip {
+					name: "dist",
+					entries: [
+						{
+							dir: "../bin",
+						},
+					],
+				}
+			`,
+			error: `dir "../bin" must be a relative path inside the zip`,
+		},
+		{
+			name: "host_srcs not a module",
+			bp: `
+				composite_zip {
+					name: "dist",
+					entries: [
+						{
+							host_srcs: ["tool"],
+						},
+					],
+				}
+			`,
+			error: `host_srcs "tool" is not a module reference`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fixture.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.error)).
+				RunTestWithBp(t, tc.bp)
+		})
+	}
+}
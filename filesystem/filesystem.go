@@ -88,6 +88,13 @@ type filesystemProperties struct {
 
 	// Symbolic links to be created under root with "ln -sf <target> <name>".
 	Symlinks []symlinkDefinition
+
+	// Manifest that sets the uid, gid and mode of files in the image. Each line has the form
+	// "<pattern> <uid> <gid> <mode>", where pattern is a glob relative to the root of the image,
+	// e.g. "bin/** 0 2000 0755". When several lines match a file the last one wins, and files that
+	// no line matches get the default fs_config. Lines starting with # are ignored. Currently, only
+	// ext4 is supported.
+	Permissions *string
 }
 
 // android_filesystem packages a set of modules and their transitive dependencies into a filesystem
@@ -162,17 +169,21 @@ func (f *filesystem) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	ctx.InstallFile(f.installDir, f.installFileName(), f.output)
 }
 
-// root zip will contain extra files/dirs that are not from the `deps` property.
-func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath {
+// root zip will contain extra files/dirs that are not from the `deps` property. Returns the zip
+// and the paths of the files/dirs in it.
+func (f *filesystem) buildRootZip(ctx android.ModuleContext) (android.OutputPath, []string) {
 	rootDir := android.PathForModuleGen(ctx, "root").OutputPath
 	builder := android.NewRuleBuilder(pctx, ctx)
 	builder.Command().Text("rm -rf").Text(rootDir.String())
 	builder.Command().Text("mkdir -p").Text(rootDir.String())
 
+	var entries []string
+
 	// create dirs and symlinks
 	for _, dir := range f.properties.Dirs {
 		// OutputPath.Join verifies dir
 		builder.Command().Text("mkdir -p").Text(rootDir.Join(ctx, dir).String())
+		entries = append(entries, strings.TrimPrefix(dir, "/"))
 	}
 
 	for _, symlink := range f.properties.Symlinks {
@@ -194,6 +205,7 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 
 		builder.Command().Text("mkdir -p").Text(filepath.Dir(dst.String()))
 		builder.Command().Text("ln -sf").Text(proptools.ShellEscape(target)).Text(dst.String())
+		entries = append(entries, strings.TrimPrefix(name, "/"))
 	}
 
 	// create extra files if there's any
@@ -206,6 +218,7 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 			if strings.HasPrefix(rel, "..") {
 				panic(fmt.Errorf("%q is not under %q\n", f, rootForExtraFiles))
 			}
+			entries = append(entries, rel)
 		}
 	}
 
@@ -227,7 +240,7 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 	builder.Command().Text("rm -rf").Text(rootDir.String())
 
 	builder.Build("zip_root", fmt.Sprintf("zipping root contents for %s", ctx.ModuleName()))
-	return zipOut
+	return zipOut, entries
 }
 
 func (f *filesystem) buildImageUsingBuildImage(ctx android.ModuleContext) android.OutputPath {
@@ -244,14 +257,23 @@ func (f *filesystem) buildImageUsingBuildImage(ctx android.ModuleContext) androi
 		Text("**/*:" + proptools.ShellEscape(depsBase)) // zip2zip verifies depsBase
 
 	rootDir := android.PathForModuleOut(ctx, "root").OutputPath
-	rootZip := f.buildRootZip(ctx)
+	rootZip, rootEntries := f.buildRootZip(ctx)
 	builder.Command().
 		BuiltTool("zipsync").
 		FlagWithArg("-d ", rootDir.String()). // zipsync wipes this. No need to clear.
 		Input(rootZip).
 		Input(rebasedDepsZip)
 
-	propFile, toolDeps := f.buildPropFile(ctx)
+	var fsConfig android.Path
+	if f.properties.Permissions != nil {
+		paths := rootEntries
+		for _, e := range f.entries {
+			paths = append(paths, filepath.Join(depsBase, e))
+		}
+		fsConfig = f.buildFsConfig(ctx, builder, rootDir, paths)
+	}
+
+	propFile, toolDeps := f.buildPropFile(ctx, fsConfig)
 	output := android.PathForModuleOut(ctx, f.installFileName()).OutputPath
 	builder.Command().BuiltTool("build_image").
 		Text(rootDir.String()). // input directory
@@ -276,7 +298,7 @@ func (f *filesystem) buildFileContexts(ctx android.ModuleContext) android.Output
 	return fcBin.OutputPath
 }
 
-func (f *filesystem) buildPropFile(ctx android.ModuleContext) (propFile android.OutputPath, toolDeps android.Paths) {
+func (f *filesystem) buildPropFile(ctx android.ModuleContext, fsConfig android.Path) (propFile android.OutputPath, toolDeps android.Paths) {
 	type prop struct {
 		name  string
 		value string
@@ -327,6 +349,10 @@ func (f *filesystem) buildPropFile(ctx android.ModuleContext) (propFile android.
 		addPath("selinux_fc", f.buildFileContexts(ctx))
 	}
 
+	if fsConfig != nil {
+		addPath("fs_config", fsConfig)
+	}
+
 	propFile = android.PathForModuleOut(ctx, "prop").OutputPath
 	builder := android.NewRuleBuilder(pctx, ctx)
 	builder.Command().Text("rm").Flag("-rf").Output(propFile)
@@ -350,6 +376,10 @@ func (f *filesystem) buildCpioImage(ctx android.ModuleContext, compressed bool)
 		ctx.PropertyErrorf("file_contexts", "file_contexts is not supported for compressed cpio image.")
 	}
 
+	if proptools.String(f.properties.Permissions) != "" {
+		ctx.PropertyErrorf("permissions", "permissions is not supported for cpio image.")
+	}
+
 	depsZipFile := android.PathForModuleOut(ctx, "deps.zip").OutputPath
 	f.entries = f.CopyDepsToZip(ctx, f.gatherFilteredPackagingSpecs(ctx), depsZipFile)
 
@@ -363,7 +393,7 @@ func (f *filesystem) buildCpioImage(ctx android.ModuleContext, compressed bool)
 		Text("**/*:" + proptools.ShellEscape(depsBase)) // zip2zip verifies depsBase
 
 	rootDir := android.PathForModuleOut(ctx, "root").OutputPath
-	rootZip := f.buildRootZip(ctx)
+	rootZip, _ := f.buildRootZip(ctx)
 	builder.Command().
 		BuiltTool("zipsync").
 		FlagWithArg("-d ", rootDir.String()). // zipsync wipes this. No need to clear.
@@ -125,3 +125,58 @@ func TestFileSystemGathersItemsOnlyInSystemPartition(t *testing.T) {
 	module := result.ModuleForTests("myfilesystem", "android_common").Module().(*systemImage)
 	android.AssertDeepEquals(t, "entries should have foo only", []string{"components/foo"}, module.entries)
 }
+
+func TestFileSystemPermissions(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		fixture,
+		android.FixtureRegisterWithContext(registerComponent),
+		android.FixtureAddTextFile("permissions.txt", `
+			# defaults for everything
+			** 0 0 0644
+			components 0 2000 0751
+			components/* 1000 1000 0600
+		`),
+	).RunTestWithBp(t, `
+		android_filesystem {
+			name: "myfilesystem",
+			multilib: {
+				common: {
+					deps: ["foo"],
+				},
+			},
+			dirs: ["dev"],
+			permissions: "permissions.txt",
+		}
+		component {
+			name: "foo",
+		}
+	`)
+
+	module := result.ModuleForTests("myfilesystem", "android_common")
+	overrides := module.Output("fs_config_overrides.txt")
+	android.AssertStringEquals(t, "overrides",
+		"components 0 2000 751\n"+
+			"components/foo 1000 1000 600\n"+
+			"dev 0 0 644\n",
+		android.ContentFromFileRuleForTests(t, overrides))
+
+	prop := module.Output("prop")
+	android.AssertStringDoesContain(t, "prop should set fs_config",
+		prop.RuleParams.Command, "fs_config=")
+	android.AssertStringDoesContain(t, "fs_config should be generated",
+		module.Output("fs_config").RuleParams.Command, "fs_config_overrides.txt")
+}
+
+func TestFileSystemPermissionsErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		fixture,
+		android.FixtureAddTextFile("permissions.txt", "** 0 0 0644\nbin/* 0 2000 999\n"),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`permissions: permissions.txt:2: invalid mode "999"`)).
+		RunTestWithBp(t, `
+		android_filesystem {
+			name: "myfilesystem",
+			permissions: "permissions.txt",
+		}
+	`)
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint/pathtools"
+	"github.com/google/blueprint/proptools"
+)
+
+// permissionsRule is a line of the permissions manifest, setting the owner and mode of the files
+// in the image that match pattern.
+type permissionsRule struct {
+	pattern string
+	uid     uint64
+	gid     uint64
+	mode    uint64
+}
+
+// parsePermissionsManifest parses a permissions manifest.  Each line that is not empty or a
+// comment has the form "<pattern> <uid> <gid> <mode>", where pattern is a glob relative to the
+// root of the image and mode is in octal.  Errors are reported with the name of the manifest and
+// the offending line.
+func parsePermissionsManifest(filename string, content string) ([]permissionsRule, error) {
+	var rules []permissionsRule
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", filename, i+1, fmt.Sprintf(format, args...))
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, errorf("expected \"<pattern> <uid> <gid> <mode>\", got %q", line)
+		}
+
+		pattern := fields[0]
+		if filepath.IsAbs(pattern) || pattern != filepath.Clean(pattern) || strings.HasPrefix(pattern, "../") {
+			return nil, errorf("pattern %q must be a clean path relative to the root of the image", pattern)
+		}
+		if _, err := pathtools.Match(pattern, ""); err != nil {
+			return nil, errorf("invalid pattern %q: %s", pattern, err)
+		}
+
+		uid, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, errorf("invalid uid %q", fields[1])
+		}
+		gid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, errorf("invalid gid %q", fields[2])
+		}
+		mode, err := strconv.ParseUint(fields[3], 8, 32)
+		if err != nil || mode > 07777 {
+			return nil, errorf("invalid mode %q, expected an octal mode like 0644", fields[3])
+		}
+
+		rules = append(rules, permissionsRule{pattern, uid, gid, mode})
+	}
+	return rules, nil
+}
+
+// permissionsOverrides returns the "<path> <uid> <gid> <mode>" lines for each of paths and their
+// parent directories that match a rule.  When several rules match a path the last one wins.
+func permissionsOverrides(rules []permissionsRule, paths []string) ([]string, error) {
+	all := make(map[string]bool)
+	for _, p := range paths {
+		for p = filepath.Clean(p); p != "." && p != "/"; p = filepath.Dir(p) {
+			all[p] = true
+		}
+	}
+	var lines []string
+	for _, p := range android.SortedStringKeys(all) {
+		for i := len(rules) - 1; i >= 0; i-- {
+			rule := rules[i]
+			match, err := pathtools.Match(rule.pattern, p)
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				lines = append(lines, fmt.Sprintf("%s %d %d %o", p, rule.uid, rule.gid, rule.mode))
+				break
+			}
+		}
+	}
+	return lines, nil
+}
+
+// buildFsConfig adds commands to builder that write a canned fs_config file for the files staged
+// in rootDir.  The owners and modes come from the fs_config tool, overridden by the rules of the
+// permissions manifest for the paths in paths.  It returns nil if the manifest has errors.
+func (f *filesystem) buildFsConfig(ctx android.ModuleContext, builder *android.RuleBuilder,
+	rootDir android.OutputPath, paths []string) android.Path {
+
+	manifest := android.PathForModuleSrc(ctx, proptools.String(f.properties.Permissions))
+	content, err := android.ReadSourceFile(ctx, manifest)
+	if err != nil {
+		ctx.PropertyErrorf("permissions", "failed to read %s: %s", manifest, err)
+		return nil
+	}
+	rules, err := parsePermissionsManifest(manifest.String(), string(content))
+	if err != nil {
+		ctx.PropertyErrorf("permissions", "%s", err)
+		return nil
+	}
+	overrides, err := permissionsOverrides(rules, paths)
+	if err != nil {
+		ctx.PropertyErrorf("permissions", "%s", err)
+		return nil
+	}
+
+	overridesFile := android.PathForModuleOut(ctx, "fs_config_overrides.txt").OutputPath
+	android.WriteFileRule(ctx, overridesFile, strings.Join(overrides, "\n")+"\n")
+
+	fsConfig := android.PathForModuleOut(ctx, "fs_config").OutputPath
+	builder.Command().
+		Text("(cd").Text(rootDir.String()).
+		Text("&& { find . -mindepth 1 -type d | sed 's,$,/,'; find . -mindepth 1 ! -type d; })").
+		Text("| cut -c 3- | sort |").
+		BuiltTool("fs_config").
+		Flag("-C").
+		FlagWithArg("-D ", rootDir.String()).
+		Text("|").
+		Text("awk").
+		FlagWithInput("-v overrides=", overridesFile).
+		Text(`'BEGIN { while ((getline line < overrides) > 0) { split(line, o, " "); uid[o[1]] = o[2]; gid[o[1]] = o[3]; mode[o[1]] = o[4] } }`).
+		Text(`{ p = $1; sub("/$", "", p) } (p in uid) { $2 = uid[p]; $3 = gid[p]; $4 = mode[p] } { print }'`).
+		FlagWithOutput("> ", fsConfig)
+
+	return fsConfig
+}
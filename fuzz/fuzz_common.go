@@ -86,7 +86,8 @@ type FuzzConfig struct {
 
 type FuzzProperties struct {
 	// Optional list of seed files to be installed to the fuzz target's output
-	// directory.
+	// directory. The files are installed by their base name, so no two of them
+	// may have the same name.
 	Corpus []string `android:"path"`
 	// Optional list of data files to be installed to the fuzz target's output
 	// directory. Directory structure relative to the module is preserved.
@@ -122,6 +123,21 @@ func IsValid(fuzzModule FuzzModule) bool {
 	return true
 }
 
+// CheckCorpus reports an error on the corpus property for every file of the corpus that has the
+// same name as an earlier one, as the corpus is flattened into a single directory when it is
+// installed and packaged.
+func (fuzzModule *FuzzPackagedModule) CheckCorpus(ctx android.ModuleContext) {
+	seen := make(map[string]android.Path)
+	for _, entry := range fuzzModule.Corpus {
+		if prev, exists := seen[entry.Base()]; exists {
+			ctx.PropertyErrorf("corpus", "%q and %q are both installed as corpus/%s",
+				prev.String(), entry.String(), entry.Base())
+			continue
+		}
+		seen[entry.Base()] = entry
+	}
+}
+
 func (s *FuzzPackager) PackageArtifacts(ctx android.SingletonContext, module android.Module, fuzzModule FuzzPackagedModule, archDir android.OutputPath, builder *android.RuleBuilder) []FileToZip {
 	// Package the corpora into a zipfile.
 	var files []FileToZip
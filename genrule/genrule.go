@@ -128,10 +128,12 @@ type generatorProperties struct {
 	//  $(out): a single output file.
 	//  $(depfile): a file to which dependencies will be written, if the depfile property is set to true.
 	//  $(genDir): the sandbox directory for this tool; contains $(out).
+	//  $(build_number_file): the file that contains the build number.  It changes in every build, so it is not an input: the command reads the current build number when it runs because of other changes.
 	//  $$: a literal $
 	Cmd *string
 
-	// Enable reading a file containing dependencies in gcc format after the command completes
+	// Enable reading a file containing dependencies in gcc format after the command completes.
+	// The command must write the file passed to it as $(depfile), the build fails if it does not.
 	Depfile *bool
 
 	// name of the modules (if any) that produces the host executable.   Leave empty for
@@ -141,6 +143,13 @@ type generatorProperties struct {
 	// Local file that is used as the tool
 	Tool_files []string `android:"path"`
 
+	// Tools and tool files that are only used when the build runs on the given host OS, for
+	// prebuilt tools that differ between host OSes.  They are added after tools and tool_files.
+	// It is an error if any OS has entries but the OS of the build host does not.
+	Target struct {
+		Linux_glibc, Linux_musl, Darwin hostOsToolProperties
+	}
+
 	// List of directories to export generated headers from
 	Export_include_dirs []string
 
@@ -149,6 +158,42 @@ type generatorProperties struct {
 
 	// input files to exclude
 	Exclude_srcs []string `android:"path,arch_variant"`
+
+	// Run the command in a sandbox directory that only contains the srcs and tools, so that it
+	// fails if it reads any file that was not declared. Defaults to true if GENRULE_SANDBOX_INPUTS
+	// is set, otherwise false.
+	Sandbox_inputs *bool
+}
+
+type hostOsToolProperties struct {
+	// name of the modules that produce the host executable when building on this host OS.
+	Tools []string
+
+	// Local files that are used as the tool when building on this host OS.
+	Tool_files []string `android:"path"`
+}
+
+// hostOsTools returns the tools and tool_files properties followed by the entries for the OS of
+// the build host.  It returns false if other host OSes have entries but the build host OS doesn't.
+func (p *generatorProperties) hostOsTools(buildOS android.OsType) (tools, toolFiles []string, ok bool) {
+	targets := map[android.OsType]*hostOsToolProperties{
+		android.Linux:     &p.Target.Linux_glibc,
+		android.LinuxMusl: &p.Target.Linux_musl,
+		android.Darwin:    &p.Target.Darwin,
+	}
+	tools = append(tools, p.Tools...)
+	toolFiles = append(toolFiles, p.Tool_files...)
+
+	hasEntries := func(t *hostOsToolProperties) bool { return len(t.Tools) > 0 || len(t.Tool_files) > 0 }
+	if t := targets[buildOS]; t != nil && hasEntries(t) {
+		return append(tools, t.Tools...), append(toolFiles, t.Tool_files...), true
+	}
+	for _, t := range targets {
+		if hasEntries(t) {
+			return tools, toolFiles, false
+		}
+	}
+	return tools, toolFiles, true
 }
 
 type Module struct {
@@ -231,6 +276,20 @@ func (g *Module) OutputFiles(tag string) (android.Paths, error) {
 			return android.Paths{outputFile}, nil
 		}
 	}
+	// or be an extension that selects the outputs ending with it, like {.java}, so that the sources
+	// generated for several languages can be passed to the module of each language.
+	if strings.HasPrefix(tag, ".") {
+		var outputs android.Paths
+		for _, outputFile := range g.outputFiles {
+			if strings.HasSuffix(outputFile.Rel(), tag) {
+				outputs = append(outputs, outputFile)
+			}
+		}
+		if len(outputs) > 0 {
+			return outputs, nil
+		}
+		return nil, fmt.Errorf("no outputs with the extension %q", tag)
+	}
 	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 }
 
@@ -239,7 +298,12 @@ var _ android.OutputFileProducer = (*Module)(nil)
 
 func toolDepsMutator(ctx android.BottomUpMutatorContext) {
 	if g, ok := ctx.Module().(*Module); ok {
-		for _, tool := range g.properties.Tools {
+		buildOS := ctx.Config().BuildOS
+		tools, _, ok := g.properties.hostOsTools(buildOS)
+		if !ok {
+			ctx.PropertyErrorf("target", "no tools or tool_files for the build host OS %q", buildOS.Name)
+		}
+		for _, tool := range tools {
 			tag := hostToolDependencyTag{label: tool}
 			if m := android.SrcIsModule(tool); m != "" {
 				tool = m
@@ -299,9 +363,11 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		}
 	}
 
+	toolProps, toolFileProps, _ := g.properties.hostOsTools(ctx.Config().BuildOS)
+
 	var tools android.Paths
 	var packagedTools []android.PackagingSpec
-	if len(g.properties.Tools) > 0 {
+	if len(toolProps) > 0 {
 		seenTools := make(map[string]bool)
 
 		ctx.VisitDirectDepsBlueprint(func(module blueprint.Module) {
@@ -364,7 +430,7 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		// The command that uses this placeholder file will never be executed because the rule will be
 		// replaced with an android.Error rule reporting the missing dependencies.
 		if ctx.Config().AllowMissingDependencies() {
-			for _, tool := range g.properties.Tools {
+			for _, tool := range toolProps {
 				if !seenTools[tool] {
 					addLocationLabel(tool, errorLocation{"***missing tool " + tool + "***"})
 				}
@@ -376,7 +442,7 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		return
 	}
 
-	for _, toolFile := range g.properties.Tool_files {
+	for _, toolFile := range toolFileProps {
 		paths := android.PathsForModuleSrc(ctx, []string{toolFile})
 		tools = append(tools, paths...)
 		addLocationLabel(toolFile, toolLocation{paths})
@@ -439,7 +505,12 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		manifestPath := android.PathForModuleOut(ctx, manifestName)
 
 		// Use a RuleBuilder to create a rule that runs the command inside an sbox sandbox.
-		rule := android.NewRuleBuilder(pctx, ctx).Sbox(task.genDir, manifestPath).SandboxTools()
+		rule := android.NewRuleBuilder(pctx, ctx).Sbox(task.genDir, manifestPath)
+		if proptools.BoolDefault(g.properties.Sandbox_inputs, ctx.Config().GenruleSandboxInputs()) {
+			rule.SandboxInputs()
+		} else {
+			rule.SandboxTools()
+		}
 		cmd := rule.Command()
 
 		for _, out := range task.out {
@@ -458,7 +529,7 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 			switch name {
 			case "location":
-				if len(g.properties.Tools) == 0 && len(g.properties.Tool_files) == 0 {
+				if len(toolProps) == 0 && len(toolFileProps) == 0 {
 					return reportError("at least one `tools` or `tool_files` is required if $(location) is used")
 				}
 				loc := locationLabels[firstLabel]
@@ -486,6 +557,8 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 				return "__SBOX_DEPFILE__", nil
 			case "genDir":
 				return cmd.PathForOutput(task.genDir), nil
+			case "build_number_file":
+				return cmd.PathForVolatileInput(ctx.Config().BuildNumberFile(ctx)), nil
 			default:
 				if strings.HasPrefix(name, "location ") {
 					label := strings.TrimSpace(strings.TrimPrefix(name, "location "))
@@ -834,7 +907,9 @@ func GenRuleFactory() android.Module {
 }
 
 type genRuleProperties struct {
-	// names of the output files that will be generated
+	// names of the output files that will be generated.  Other modules can use a single output
+	// with ":name{out}", or the outputs with an extension with ":name{.ext}", for example to pass
+	// the .cpp outputs to a cc module and the .java outputs to a java module.
 	Out []string `android:"arch_variant"`
 }
 
@@ -15,8 +15,10 @@
 package genrule
 
 import (
+	"fmt"
 	"os"
 	"regexp"
+	"runtime"
 	"testing"
 
 	"android/soong/android"
@@ -471,6 +473,66 @@ func TestGenruleCmd(t *testing.T) {
 	}
 }
 
+func TestGenruleHostOsTools(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			out: ["out"],
+			cmd: "$(location) > $(out)",
+			target: {
+				linux_glibc: {
+					tool_files: ["tool_file1"],
+				},
+				linux_musl: {
+					tool_files: ["tool_file1"],
+				},
+				darwin: {
+					tool_files: ["tool_file2"],
+				},
+			},
+		}
+	`
+
+	result := prepareForGenRuleTest.RunTestWithBp(t, testGenruleBp()+bp)
+
+	tool := "tool_file1"
+	if result.Config.BuildOS == android.Darwin {
+		tool = "tool_file2"
+	}
+	gen := result.ModuleForTests("gen", "")
+	android.AssertStringEquals(t, "raw commands", "__SBOX_SANDBOX_DIR__/tools/src/"+tool+" > __SBOX_SANDBOX_DIR__/out/out",
+		gen.Module().(*Module).rawCommands[0])
+
+	// The selected tool is an input so that changing it reruns the command.
+	out := gen.Output("out")
+	android.AssertStringListContains(t, "inputs", append(out.Inputs.Strings(), out.Implicits.Strings()...), tool)
+}
+
+func TestGenruleHostOsToolsMissing(t *testing.T) {
+	// Only give a tool for a host OS that the test isn't running on.
+	otherOS := "darwin"
+	if runtime.GOOS == "darwin" {
+		otherOS = "linux_glibc"
+	}
+	bp := fmt.Sprintf(`
+		genrule {
+			name: "gen",
+			out: ["out"],
+			cmd: "$(location) > $(out)",
+			target: {
+				%s: {
+					tool_files: ["tool_file1"],
+				},
+			},
+		}
+	`, otherOS)
+
+	prepareForGenRuleTest.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`target: no tools or tool_files for the build host OS`)).
+		RunTestWithBp(t, testGenruleBp()+bp)
+}
+
 func TestGenruleHashInputs(t *testing.T) {
 
 	// The basic idea here is to verify that the sbox command (which is
@@ -539,6 +601,36 @@ func TestGenruleHashInputs(t *testing.T) {
 	}
 }
 
+func TestGenruleBuildNumberFile(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			out: ["out"],
+			sandbox_inputs: true,
+			cmd: "cat $(build_number_file) > $(out)",
+		}
+	`
+
+	result := prepareForGenRuleTest.RunTestWithBp(t, testGenruleBp()+bp)
+
+	gen := result.ModuleForTests("gen", "")
+	android.AssertStringEquals(t, "raw commands", "cat __SBOX_SANDBOX_DIR__/out/build_number.txt > __SBOX_SANDBOX_DIR__/out/out",
+		gen.Module().(*Module).rawCommands[0])
+
+	// The build number changes in every build, the rule only reads it when it runs for other reasons.
+	rule := gen.Output("out")
+	android.AssertPathsRelativeToTopEquals(t, "order only", []string{"out/soong/build_number.txt"}, rule.OrderOnly)
+	android.AssertStringListDoesNotContain(t, "implicits", rule.Implicits.Strings(),
+		result.Config.BuildNumberFile(android.PathContextForTesting(result.Config)).String())
+
+	manifest := android.RuleBuilderSboxProtoForTests(t, gen.Output("genrule.sbox.textproto"))
+	var copied []string
+	for _, c := range manifest.Commands[0].CopyBefore {
+		copied = append(copied, c.GetTo())
+	}
+	android.AssertStringListContains(t, "copied into the sandbox", copied, "out/build_number.txt")
+}
+
 func TestGenSrcs(t *testing.T) {
 	testcases := []struct {
 		name string
@@ -656,6 +748,83 @@ func TestGenruleDefaults(t *testing.T) {
 	android.AssertDeepEquals(t, "srcs", expectedSrcs, gen.properties.Srcs)
 }
 
+func TestGenruleSandboxInputs(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "cat $(in) > $(out)",
+		}
+
+		genrule {
+			name: "gen_sandboxed",
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "cat $(in) > $(out)",
+			sandbox_inputs: true,
+		}
+
+		genrule {
+			name: "gen_not_sandboxed",
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "cat $(in) > $(out)",
+			sandbox_inputs: false,
+		}
+	`
+
+	testcases := []struct {
+		name      string
+		env       map[string]string
+		sandboxed map[string]bool
+	}{
+		{
+			name: "default",
+			sandboxed: map[string]bool{
+				"gen":               false,
+				"gen_sandboxed":     true,
+				"gen_not_sandboxed": false,
+			},
+		},
+		{
+			name: "GENRULE_SANDBOX_INPUTS",
+			env:  map[string]string{"GENRULE_SANDBOX_INPUTS": "true"},
+			sandboxed: map[string]bool{
+				"gen":               true,
+				"gen_sandboxed":     true,
+				"gen_not_sandboxed": false,
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			result := android.GroupFixturePreparers(
+				prepareForGenRuleTest,
+				android.FixtureMergeEnv(test.env),
+			).RunTestWithBp(t, bp)
+
+			for name, sandboxed := range test.sandboxed {
+				gen := result.ModuleForTests(name, "")
+				manifest := android.RuleBuilderSboxProtoForTests(t, gen.Output("genrule.sbox.textproto"))
+				command := manifest.Commands[0]
+
+				var copiedInputs []string
+				for _, copy := range command.CopyBefore {
+					copiedInputs = append(copiedInputs, copy.GetFrom())
+				}
+
+				android.AssertBoolEquals(t, name+" chdir", sandboxed, command.GetChdir())
+				android.AssertBoolEquals(t, name+" copies in1", sandboxed, android.InList("in1", copiedInputs))
+				if sandboxed {
+					android.AssertStringEquals(t, name+" cmd", "cat in1 > __SBOX_SANDBOX_DIR__/out/out", command.GetCommand())
+				}
+			}
+		})
+	}
+}
+
 func TestGenruleAllowMissingDependencies(t *testing.T) {
 	bp := `
 		output {
@@ -722,6 +891,43 @@ func TestGenruleOutputFiles(t *testing.T) {
 		result.ModuleForTests("gen_all", "").Module().(*useSource).srcs)
 }
 
+func TestGenruleOutputFilesByExtension(t *testing.T) {
+	bp := `
+				genrule {
+					name: "gen",
+					out: ["foo.cpp", "foo.h", "sub/Foo.java", "bar.cpp"],
+					cmd: "touch $(out)",
+				}
+				use_source {
+					name: "gen_cpp",
+					srcs: [":gen{.cpp}"],
+				}
+				use_source {
+					name: "gen_java",
+					srcs: [":gen{.java}"],
+				}
+			`
+
+	result := prepareForGenRuleTest.RunTestWithBp(t, testGenruleBp()+bp)
+	android.AssertPathsRelativeToTopEquals(t,
+		"genrule.tag with cpp extension",
+		[]string{"out/soong/.intermediates/gen/gen/foo.cpp", "out/soong/.intermediates/gen/gen/bar.cpp"},
+		result.ModuleForTests("gen_cpp", "").Module().(*useSource).srcs)
+	android.AssertPathsRelativeToTopEquals(t,
+		"genrule.tag with java extension",
+		[]string{"out/soong/.intermediates/gen/gen/sub/Foo.java"},
+		result.ModuleForTests("gen_java", "").Module().(*useSource).srcs)
+
+	prepareForGenRuleTest.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`no outputs with the extension ".rs"`,
+	)).RunTestWithBp(t, testGenruleBp()+bp+`
+				use_source {
+					name: "gen_rs",
+					srcs: [":gen{.rs}"],
+				}
+			`)
+}
+
 func TestPrebuiltTool(t *testing.T) {
 	testcases := []struct {
 		name             string
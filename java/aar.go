@@ -17,6 +17,7 @@ package java
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -88,6 +89,18 @@ type aaptProperties struct {
 	// do not include AndroidManifest from dependent libraries
 	Dont_merge_manifests *bool
 
+	// options of the manifest merger.  Setting any of them runs the manifest merger even if there
+	// are no other manifests to merge.  The options of an android_library are applied to its own
+	// manifests, before they are merged into the manifests of the apps that use it.
+	Manifest_merger struct {
+		// placeholders to substitute in the manifests, as name=value pairs.  ${name} in the
+		// manifests is replaced with value.
+		Placeholders []string
+
+		// extra arguments passed to the manifest merger, like "--property" options.
+		Args []string
+	}
+
 	// true if RRO is enforced for any of the dependent modules
 	RROEnforcedForDependent bool `blueprint:"mutated"`
 }
@@ -266,6 +279,47 @@ var extractAssetsRule = pctx.AndroidStaticRule("extractAssets",
 		CommandDeps: []string{"${config.Zip2ZipCmd}"},
 	})
 
+var manifestPlaceholderNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// manifestMergerArgs returns the manifest merger arguments for the manifest_merger properties, with
+// the placeholders sorted by name so that the command doesn't depend on their order.
+func (a *aapt) manifestMergerArgs(ctx android.ModuleContext) []string {
+	props := a.aaptProperties.Manifest_merger
+	if len(props.Placeholders) == 0 && len(props.Args) == 0 {
+		return nil
+	}
+	if Bool(a.aaptProperties.Dont_merge_manifests) {
+		ctx.PropertyErrorf("manifest_merger", "cannot be used with dont_merge_manifests")
+		return nil
+	}
+
+	placeholders := make(map[string]string)
+	for _, placeholder := range props.Placeholders {
+		i := strings.IndexByte(placeholder, '=')
+		if i < 0 || !manifestPlaceholderNameRegexp.MatchString(placeholder[:i]) {
+			ctx.PropertyErrorf("manifest_merger.placeholders", "%q must be name=value with a valid name", placeholder)
+			continue
+		}
+		name, value := placeholder[:i], placeholder[i+1:]
+		// The manifest merger splits the pairs on "=", and rejects empty values.
+		if value == "" || strings.Contains(value, "=") {
+			ctx.PropertyErrorf("manifest_merger.placeholders", "the value of %q must not be empty or contain \"=\"", name)
+			continue
+		}
+		if _, exists := placeholders[name]; exists {
+			ctx.PropertyErrorf("manifest_merger.placeholders", "duplicate placeholder %q", name)
+			continue
+		}
+		placeholders[name] = value
+	}
+
+	var args []string
+	for _, name := range android.SortedStringKeys(placeholders) {
+		args = append(args, "--placeholder", name+"="+placeholders[name])
+	}
+	return append(args, props.Args...)
+}
+
 func (a *aapt) buildActions(ctx android.ModuleContext, sdkContext android.SdkContext,
 	classLoaderContexts dexpreopt.ClassLoaderContextMap, excludedLibs []string,
 	extraLinkFlags ...string) {
@@ -293,11 +347,19 @@ func (a *aapt) buildActions(ctx android.ModuleContext, sdkContext android.SdkCon
 
 	// Add additional manifest files to transitive manifests.
 	additionalManifests := android.PathsForModuleSrc(ctx, a.aaptProperties.Additional_manifests)
+	mergerArgs := a.manifestMergerArgs(ctx)
+	if a.isLibrary && len(mergerArgs) > 0 {
+		manifestPath = manifestMergerToPath(ctx, android.PathForModuleOut(ctx, "manifest_merger_own", "AndroidManifest.xml"),
+			manifestPath, additionalManifests, a.isLibrary, mergerArgs)
+		additionalManifests = nil
+		mergerArgs = nil
+	}
 	a.transitiveManifestPaths = append(android.Paths{manifestPath}, additionalManifests...)
 	a.transitiveManifestPaths = append(a.transitiveManifestPaths, transitiveStaticLibManifests...)
 
-	if len(a.transitiveManifestPaths) > 1 && !Bool(a.aaptProperties.Dont_merge_manifests) {
-		a.mergedManifestFile = manifestMerger(ctx, a.transitiveManifestPaths[0], a.transitiveManifestPaths[1:], a.isLibrary)
+	if (len(a.transitiveManifestPaths) > 1 || len(mergerArgs) > 0) && !Bool(a.aaptProperties.Dont_merge_manifests) {
+		a.mergedManifestFile = manifestMerger(ctx, a.transitiveManifestPaths[0], a.transitiveManifestPaths[1:],
+			a.isLibrary, mergerArgs)
 		if !a.isLibrary {
 			// Only use the merged manifest for applications.  For libraries, the transitive closure of manifests
 			// will be propagated to the final application and merged there.  The merged manifest for libraries is
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/dexpreopt"
@@ -164,15 +165,24 @@ func ManifestFixer(ctx android.ModuleContext, manifest android.Path,
 }
 
 func manifestMerger(ctx android.ModuleContext, manifest android.Path, staticLibManifests android.Paths,
-	isLibrary bool) android.Path {
+	isLibrary bool, extraArgs []string) android.Path {
 
-	var args string
+	mergedManifest := android.PathForModuleOut(ctx, "manifest_merger", "AndroidManifest.xml")
+	return manifestMergerToPath(ctx, mergedManifest, manifest, staticLibManifests, isLibrary, extraArgs).WithoutRel()
+}
+
+// manifestMergerToPath merges the manifests into mergedManifest, passing extraArgs to the manifest
+// merger.
+func manifestMergerToPath(ctx android.ModuleContext, mergedManifest android.ModuleOutPath, manifest android.Path,
+	staticLibManifests android.Paths, isLibrary bool, extraArgs []string) android.ModuleOutPath {
+
+	var args []string
 	if !isLibrary {
 		// Follow Gradle's behavior, only pass --remove-tools-declarations when merging app manifests.
-		args = "--remove-tools-declarations"
+		args = append(args, "--remove-tools-declarations")
 	}
+	args = append(args, proptools.ShellEscapeList(extraArgs)...)
 
-	mergedManifest := android.PathForModuleOut(ctx, "manifest_merger", "AndroidManifest.xml")
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        manifestMergerRule,
 		Description: "merge manifest",
@@ -181,9 +191,9 @@ func manifestMerger(ctx android.ModuleContext, manifest android.Path, staticLibM
 		Output:      mergedManifest,
 		Args: map[string]string{
 			"libs": android.JoinWithPrefix(staticLibManifests.Strings(), "--libs "),
-			"args": args,
+			"args": strings.Join(args, " "),
 		},
 	})
 
-	return mergedManifest.WithoutRel()
+	return mergedManifest
 }
@@ -514,7 +514,7 @@ func (a *AndroidTestImport) GenerateAndroidBuildActions(ctx android.ModuleContex
 
 	a.generateAndroidBuildActions(ctx)
 
-	a.data = android.PathsForModuleSrc(ctx, a.testProperties.Data)
+	a.data = android.PathsForModuleData(ctx, a.testProperties.Data)
 }
 
 func (a *AndroidTestImport) InstallInTestcases() bool {
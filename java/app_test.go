@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -672,6 +673,89 @@ func TestLibraryAssets(t *testing.T) {
 	}
 }
 
+func TestManifestMergerOptions(t *testing.T) {
+	ctx := testApp(t, `
+		android_app {
+			name: "foo",
+			sdk_version: "current",
+			static_libs: ["lib"],
+			manifest_merger: {
+				placeholders: ["suffix=.foo", "authority=com.foo.provider"],
+				args: ["--property", "MIN_SDK_VERSION=21"],
+			},
+		}
+
+		android_app {
+			name: "bar",
+			sdk_version: "current",
+			manifest_merger: {
+				placeholders: ["label=Bar App"],
+			},
+		}
+
+		android_library {
+			name: "lib",
+			sdk_version: "current",
+			manifest_merger: {
+				placeholders: ["libName=lib"],
+			},
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Output("manifest_merger/AndroidManifest.xml")
+	android.AssertStringEquals(t, "foo manifest merger args",
+		"--remove-tools-declarations --placeholder authority=com.foo.provider --placeholder suffix=.foo --property MIN_SDK_VERSION=21",
+		foo.Args["args"])
+	// The placeholders of the library are applied to its own manifest.
+	android.AssertStringEquals(t, "foo manifest merger libs",
+		"--libs out/soong/.intermediates/lib/android_common/manifest_merger_own/AndroidManifest.xml",
+		foo.Args["libs"])
+
+	// The manifest merger runs for a single manifest with options.
+	bar := ctx.ModuleForTests("bar", "android_common").Output("manifest_merger/AndroidManifest.xml")
+	android.AssertStringEquals(t, "bar manifest merger args",
+		"--remove-tools-declarations --placeholder 'label=Bar App'", bar.Args["args"])
+	android.AssertStringEquals(t, "bar manifest merger libs", "", bar.Args["libs"])
+
+	lib := ctx.ModuleForTests("lib", "android_common").Output("manifest_merger_own/AndroidManifest.xml")
+	android.AssertStringEquals(t, "lib manifest merger args", "--placeholder libName=lib", lib.Args["args"])
+}
+
+func TestManifestMergerOptionsErrors(t *testing.T) {
+	testCases := []struct {
+		placeholder string
+		err         string
+	}{
+		{"noValue", `"noValue" must be name=value with a valid name`},
+		{"1name=value", `"1name=value" must be name=value with a valid name`},
+		{"name=", `the value of "name" must not be empty or contain "="`},
+		{"name=a=b", `the value of "name" must not be empty or contain "="`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.placeholder, func(t *testing.T) {
+			testJavaError(t, regexp.QuoteMeta(testCase.err), fmt.Sprintf(`
+				android_app {
+					name: "foo",
+					sdk_version: "current",
+					manifest_merger: {
+						placeholders: [%q],
+					},
+				}
+			`, testCase.placeholder))
+		})
+	}
+
+	testJavaError(t, `duplicate placeholder "name"`, `
+		android_app {
+			name: "foo",
+			sdk_version: "current",
+			manifest_merger: {
+				placeholders: ["name=a", "name=b"],
+			},
+		}
+	`)
+}
+
 func TestAppJavaResources(t *testing.T) {
 	bp := `
 			android_app {
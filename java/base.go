@@ -85,6 +85,11 @@ type CommonProperties struct {
 	// if not blank, run jarjar using the specified rules file
 	Jarjar_rules *string `android:"path,arch_variant"`
 
+	// list of jarjar rules of the form "<pattern> -> <result>" to run in addition to the rules in
+	// jarjar_rules, for example "com.google.common.** -> com.android.internal.guava.@1".  A rule in
+	// jarjar_rules with the same pattern as one of these rules is an error.
+	Jarjar_rename_rules []string `android:"arch_variant"`
+
 	// If not blank, set the java version passed to javac as -source and -target
 	Java_version *string
 
@@ -151,6 +156,9 @@ type CommonProperties struct {
 
 	Errorprone struct {
 		// List of javac flags that should only be used when running errorprone.
+		// They are passed after the global Error Prone checks, so they can change
+		// the severity of a check set by the global config, but enabling and
+		// disabling the same check here is an error.
 		Javacflags []string
 
 		// List of java_plugin modules that provide extra errorprone checks.
@@ -238,6 +246,11 @@ type DeviceProperties struct {
 
 		// list of flags that will be passed to the AIDL compiler
 		Flags []string
+
+		// the AIDL backend used to generate code from the aidl sources, one of "java", "cpp",
+		// "ndk" or "rust".  Java modules can only compile the sources generated by the "java"
+		// backend, which is the default.
+		Backend *string
 	}
 
 	// If true, export a copy of the module as a -hostdex module for host testing.
@@ -810,6 +823,14 @@ func (j *Module) aidlFlags(ctx android.ModuleContext, aidlPreprocess android.Opt
 
 	flags = append(flags, j.deviceProperties.Aidl.Flags...)
 
+	if backend := j.deviceProperties.Aidl.Backend; backend != nil {
+		if err := checkJavaAidlBackend(*backend); err != nil {
+			ctx.PropertyErrorf("aidl.backend", "%s", err)
+		} else {
+			flags = append(flags, "--lang="+*backend)
+		}
+	}
+
 	if aidlPreprocess.Valid() {
 		flags = append(flags, "-p"+aidlPreprocess.String())
 		deps = append(deps, aidlPreprocess.Path())
@@ -866,6 +887,9 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 			"-Xplugin:ErrorProne",
 			"${config.ErrorProneChecks}",
 		}
+		if err := checkErrorproneCheckConflicts(j.properties.Errorprone.Javacflags); err != nil {
+			ctx.PropertyErrorf("errorprone.javacflags", "%s", err)
+		}
 		errorProneFlags = append(errorProneFlags, j.properties.Errorprone.Javacflags...)
 
 		flags.errorProneExtraJavacFlags = "${config.ErrorProneHeapFlags} ${config.ErrorProneFlags} " +
@@ -1036,9 +1060,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 	}
 	srcFiles = srcFiles.FilterOutByExt(".srcjar")
 
-	if j.properties.Jarjar_rules != nil {
-		j.expandJarjarRules = android.PathForModuleSrc(ctx, *j.properties.Jarjar_rules)
-	}
+	j.expandJarjarRules = j.jarjarRules(ctx)
 
 	jarName := ctx.ModuleName() + ".jar"
 
@@ -1549,6 +1571,35 @@ func enableErrorproneFlags(flags javaBuilderFlags) javaBuilderFlags {
 	return flags
 }
 
+// checkErrorproneCheckConflicts returns an error if the -Xep:<check>[:<severity>] flags in flags
+// both enable and disable the same check.  The flags are passed after the global Error Prone
+// checks, so a module may still override the severity of a check set by the global config.
+func checkErrorproneCheckConflicts(flags []string) error {
+	enabled := make(map[string]string)
+	disabled := make(map[string]string)
+	for _, flag := range flags {
+		if !strings.HasPrefix(flag, "-Xep:") {
+			continue
+		}
+		check, severity := strings.TrimPrefix(flag, "-Xep:"), ""
+		if i := strings.Index(check, ":"); i != -1 {
+			check, severity = check[:i], check[i+1:]
+		}
+		if severity == "OFF" {
+			if other, ok := enabled[check]; ok {
+				return fmt.Errorf("conflicting flags %q and %q for Error Prone check %q", other, flag, check)
+			}
+			disabled[check] = flag
+		} else {
+			if other, ok := disabled[check]; ok {
+				return fmt.Errorf("conflicting flags %q and %q for Error Prone check %q", other, flag, check)
+			}
+			enabled[check] = flag
+		}
+	}
+	return nil
+}
+
 func (j *Module) compileJavaClasses(ctx android.ModuleContext, jarName string, idx int,
 	srcFiles, srcJars android.Paths, flags javaBuilderFlags, extraJarDeps android.Paths) android.WritablePath {
 
@@ -1950,7 +2001,7 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				if _, ok := module.(*Plugin); ok {
 					deps.errorProneProcessorPath = append(deps.errorProneProcessorPath, dep.ImplementationAndResourcesJars...)
 				} else {
-					ctx.PropertyErrorf("plugins", "%q is not a java_plugin module", otherName)
+					ctx.PropertyErrorf("errorprone.extra_check_modules", "%q is not a java_plugin module", otherName)
 				}
 			case exportedPluginTag:
 				if plugin, ok := module.(*Plugin); ok {
@@ -49,6 +49,12 @@ func init() {
 		"-J--add-opens=java.base/java.util=ALL-UNNAMED", // https://youtrack.jetbrains.com/issue/KT-43704
 	}, " "))
 
+	// Flags passed to kotlinc when compiling a module incrementally, together with the
+	// directory that holds the incremental caches of the module.
+	pctx.StaticVariable("KotlincIncrementalFlags", strings.Join([]string{
+		"-Xenable-incremental-compilation",
+	}, " "))
+
 	pctx.StaticVariable("KotlincGlobalFlags", strings.Join([]string{
 		// b/222162908: prevent kotlinc from reading /tmp/build.txt
 		"-Didea.plugins.compatible.build=999.SNAPSHOT",
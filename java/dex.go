@@ -121,6 +121,27 @@ var d8, d8RE = pctx.MultiCommandRemoteStaticRules("d8",
 		},
 	}, []string{"outDir", "d8Flags", "zipFlags", "tmpJar", "mergeZipsFlags"}, nil)
 
+// d8Incremental is like d8, but runs D8 through dex_cache, which keeps the intermediate dex files
+// of the classes in $cacheDir between builds and only dexes the classes that changed.
+var d8Incremental = pctx.AndroidStaticRule("d8Incremental",
+	blueprint.RuleParams{
+		Command: `rm -rf "$outDir" && mkdir -p "$outDir" && ` +
+			`mkdir -p $$(dirname $tmpJar) && ` +
+			`${config.Zip2ZipCmd} -i $in -o $tmpJar -x '**/*.dex' && ` +
+			`${config.DexCacheCmd} --cache_dir $cacheDir --tool ${config.D8Jar} --output $outDir ` +
+			`$dexCacheFlags $tmpJar -- ${config.D8Cmd} ${config.DexFlags} $d8Flags && ` +
+			`${config.SoongZipCmd} $zipFlags -o $outDir/classes.dex.jar -C $outDir -f "$outDir/classes*.dex" && ` +
+			`${config.MergeZipsCmd} -D -stripFile "**/*.class" $mergeZipsFlags $out $outDir/classes.dex.jar $in`,
+		CommandDeps: []string{
+			"${config.D8Cmd}",
+			"${config.D8Jar}",
+			"${config.DexCacheCmd}",
+			"${config.Zip2ZipCmd}",
+			"${config.SoongZipCmd}",
+			"${config.MergeZipsCmd}",
+		},
+	}, "outDir", "d8Flags", "zipFlags", "tmpJar", "mergeZipsFlags", "cacheDir", "dexCacheFlags")
+
 var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 	blueprint.RuleParams{
 		Command: `rm -rf "$outDir" && mkdir -p "$outDir" && ` +
@@ -359,22 +380,36 @@ func (d *dexer) compileDex(ctx android.ModuleContext, flags javaBuilderFlags, mi
 		d8Flags, d8Deps := d8Flags(flags)
 		d8Deps = append(d8Deps, commonDeps...)
 		rule := d8
+		args := map[string]string{
+			"d8Flags":        strings.Join(append(commonFlags, d8Flags...), " "),
+			"zipFlags":       zipFlags,
+			"outDir":         outDir.String(),
+			"tmpJar":         tmpJar.String(),
+			"mergeZipsFlags": mergeZipsFlags,
+		}
+		var implicitOutputs android.WritablePaths
 		if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_D8") {
 			rule = d8RE
+		} else if ctx.Config().IsEnvTrue("INCREMENTAL_DEX") {
+			// R8 optimizes the whole program, only D8 can reuse the dex files of unchanged classes.
+			rule = d8Incremental
+			cacheDir := android.PathForModuleOut(ctx, "dex_cache")
+			args["cacheDir"] = cacheDir.String()
+			if ctx.Config().IsEnvTrue("INCREMENTAL_DEX_VERIFY") {
+				args["dexCacheFlags"] = "--verify"
+			}
+			// The cache key is the only file of the cache that is always written, it makes the
+			// cache a declared output of the rule.
+			implicitOutputs = append(implicitOutputs, cacheDir.Join(ctx, "config"))
 		}
 		ctx.Build(pctx, android.BuildParams{
-			Rule:        rule,
-			Description: "d8",
-			Output:      javalibJar,
-			Input:       classesJar,
-			Implicits:   d8Deps,
-			Args: map[string]string{
-				"d8Flags":        strings.Join(append(commonFlags, d8Flags...), " "),
-				"zipFlags":       zipFlags,
-				"outDir":         outDir.String(),
-				"tmpJar":         tmpJar.String(),
-				"mergeZipsFlags": mergeZipsFlags,
-			},
+			Rule:            rule,
+			Description:     "d8",
+			Output:          javalibJar,
+			ImplicitOutputs: implicitOutputs,
+			Input:           classesJar,
+			Implicits:       d8Deps,
+			Args:            args,
 		})
 	}
 	if proptools.Bool(d.dexProperties.Uncompress_dex) {
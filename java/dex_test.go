@@ -101,3 +101,42 @@ func TestD8(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "expected no  static_lib header jar in foo javac classpath",
 		fooD8.Args["d8Flags"], staticLibHeader.String())
 }
+
+func TestD8Incremental(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["foo.java"],
+			installable: true,
+		}
+	`
+
+	// Incremental dexing is opt-in.
+	result := PrepareForTestWithJavaDefaultModulesWithoutFakeDex2oatd.RunTestWithBp(t, bp)
+	foo := result.ModuleForTests("foo", "android_common")
+	if rule := foo.MaybeRule("d8Incremental"); rule.Rule != nil {
+		t.Errorf("expected no incremental dexing without INCREMENTAL_DEX=true")
+	}
+	android.AssertStringEquals(t, "rule", "android/soong/java.d8", foo.Rule("d8").Rule.String())
+
+	result = android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModulesWithoutFakeDex2oatd,
+		android.FixtureMergeEnv(map[string]string{"INCREMENTAL_DEX": "true"}),
+	).RunTestWithBp(t, bp)
+	fooD8 := result.ModuleForTests("foo", "android_common").Rule("d8Incremental")
+	android.AssertStringEquals(t, "cache directory", "out/soong/.intermediates/foo/android_common/dex_cache",
+		android.StringRelativeToTop(result.Config, fooD8.Args["cacheDir"]))
+	android.AssertPathsRelativeToTopEquals(t, "implicit outputs",
+		[]string{"out/soong/.intermediates/foo/android_common/dex_cache/config"}, fooD8.ImplicitOutputs.Paths())
+	android.AssertStringEquals(t, "dex_cache flags", "", fooD8.Args["dexCacheFlags"])
+
+	result = android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModulesWithoutFakeDex2oatd,
+		android.FixtureMergeEnv(map[string]string{
+			"INCREMENTAL_DEX":        "true",
+			"INCREMENTAL_DEX_VERIFY": "true",
+		}),
+	).RunTestWithBp(t, bp)
+	fooD8 = result.ModuleForTests("foo", "android_common").Rule("d8Incremental")
+	android.AssertStringEquals(t, "dex_cache flags", "--verify", fooD8.Args["dexCacheFlags"])
+}
@@ -15,6 +15,7 @@
 package java
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -116,6 +117,26 @@ func genLogtags(ctx android.ModuleContext, logtagsFile android.Path) android.Pat
 	return javaFile
 }
 
+// aidlBackendModuleTypes maps the AIDL backends that java modules can't compile to the kinds of
+// modules that can.
+var aidlBackendModuleTypes = map[string]string{
+	"cpp":  "cc modules or aidl_interface",
+	"ndk":  "cc modules or aidl_interface",
+	"rust": "rust modules or aidl_interface",
+}
+
+// checkJavaAidlBackend returns an error if a java module can't use the AIDL backend.
+func checkJavaAidlBackend(backend string) error {
+	if backend == "java" {
+		return nil
+	}
+	if moduleTypes, ok := aidlBackendModuleTypes[backend]; ok {
+		return fmt.Errorf("the %q backend generates sources that java modules can't compile, use it from %s",
+			backend, moduleTypes)
+	}
+	return fmt.Errorf("unknown backend %q, expected one of \"java\", \"cpp\", \"ndk\" or \"rust\"", backend)
+}
+
 func genAidlIncludeFlags(srcFiles android.Paths) string {
 	var baseDirs []string
 	for _, srcFile := range srcFiles {
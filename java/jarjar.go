@@ -0,0 +1,117 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	// jarjarPatternRegexp matches the class name patterns that jarjar accepts, for example
+	// "com.google.common.**".
+	jarjarPatternRegexp = regexp.MustCompile(`^[A-Za-z_$*][A-Za-z0-9_$.*]*$`)
+
+	// jarjarResultRegexp matches the results of jarjar rules, which can refer to the parts of the
+	// class name matched by the wildcards of the pattern with @1, @2, etc.
+	jarjarResultRegexp = regexp.MustCompile(`^[A-Za-z_$@][A-Za-z0-9_$.@]*$`)
+)
+
+const jarjarRenameRuleExample = "com.google.common.** -> com.android.internal.guava.@1"
+
+// jarjarRenameRule is a rule from the jarjar_rename_rules property.
+type jarjarRenameRule struct {
+	pattern string
+	result  string
+}
+
+func (r jarjarRenameRule) String() string {
+	return "rule " + r.pattern + " " + r.result
+}
+
+// parseJarjarRenameRules parses rules of the form "<pattern> -> <result>".  It returns an error
+// for rules that are malformed or that use the same pattern as an earlier rule.
+func parseJarjarRenameRules(rules []string) ([]jarjarRenameRule, error) {
+	var ret []jarjarRenameRule
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "->", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule %q, expected a rule like %q", rule, jarjarRenameRuleExample)
+		}
+		pattern := strings.TrimSpace(parts[0])
+		result := strings.TrimSpace(parts[1])
+		if !jarjarPatternRegexp.MatchString(pattern) || !jarjarResultRegexp.MatchString(result) {
+			return nil, fmt.Errorf("invalid rule %q, expected a rule like %q", rule, jarjarRenameRuleExample)
+		}
+		if seen[pattern] {
+			return nil, fmt.Errorf("pattern %q is renamed by more than one rule", pattern)
+		}
+		seen[pattern] = true
+		ret = append(ret, jarjarRenameRule{pattern, result})
+	}
+	return ret, nil
+}
+
+// jarjarRules returns the jarjar rules file for the module, combining jarjar_rules and
+// jarjar_rename_rules, or nil if neither is set.
+func (j *Module) jarjarRules(ctx android.ModuleContext) android.Path {
+	var rulesFile android.Path
+	if j.properties.Jarjar_rules != nil {
+		rulesFile = android.PathForModuleSrc(ctx, *j.properties.Jarjar_rules)
+	}
+
+	if len(j.properties.Jarjar_rename_rules) == 0 {
+		return rulesFile
+	}
+
+	renameRules, err := parseJarjarRenameRules(j.properties.Jarjar_rename_rules)
+	if err != nil {
+		ctx.PropertyErrorf("jarjar_rename_rules", "%s", err)
+		return rulesFile
+	}
+
+	// The rules are written in the order they were listed, as the first matching rule wins.
+	var content strings.Builder
+	for _, rule := range renameRules {
+		fmt.Fprintln(&content, rule)
+	}
+	renameRulesFile := android.PathForModuleOut(ctx, "jarjar", "rename_rules.txt")
+	android.WriteFileRule(ctx, renameRulesFile, content.String())
+
+	if rulesFile == nil {
+		return renameRulesFile
+	}
+
+	// The jarjar_rules file may be generated, so the rules can only be merged when building.
+	// The rules from jarjar_rename_rules come first, and rules in the file that use one of
+	// their patterns are an error.
+	mergedRulesFile := android.PathForModuleOut(ctx, "jarjar", "merged_rules.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("awk").
+		Text(`'FNR == NR { if ($1 == "rule") inline[$2] = 1; print; next }`).
+		Text(`$1 == "rule" && ($2 in inline) { printf "%s: rule for %s conflicts with jarjar_rename_rules\n", FILENAME, $2 > "/dev/stderr"; err = 1 }`).
+		Text(`{ print } END { exit err }'`).
+		Input(renameRulesFile).
+		Input(rulesFile).
+		FlagWithOutput("> ", mergedRulesFile)
+	rule.Build("jarjar_merge_rules", "merge jarjar rules")
+
+	return mergedRulesFile
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestParseJarjarRenameRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rules    []string
+		expected []jarjarRenameRule
+		err      string
+	}{
+		{
+			name:  "wildcards",
+			rules: []string{"com.google.common.** -> com.android.internal.guava.@1", "org.foo.*Impl->org.bar.@1Impl"},
+			expected: []jarjarRenameRule{
+				{"com.google.common.**", "com.android.internal.guava.@1"},
+				{"org.foo.*Impl", "org.bar.@1Impl"},
+			},
+		},
+		{
+			name:  "missing arrow",
+			rules: []string{"com.google.common.** com.android.internal.guava.@1"},
+			err:   `invalid rule "com.google.common.** com.android.internal.guava.@1"`,
+		},
+		{
+			name:  "invalid result",
+			rules: []string{"com.google.** -> com.android.**"},
+			err:   `invalid rule "com.google.** -> com.android.**"`,
+		},
+		{
+			name:  "duplicate pattern",
+			rules: []string{"com.google.** -> com.android.@1", "com.google.** -> com.android.internal.@1"},
+			err:   `pattern "com.google.**" is renamed by more than one rule`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := parseJarjarRenameRules(tc.rules)
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected error %q", tc.err)
+				}
+				android.AssertStringDoesContain(t, "error", err.Error(), tc.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			android.AssertDeepEquals(t, "rules", tc.expected, rules)
+		})
+	}
+}
+
+func TestJarjarRenameRules(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureAddFile("jarjar-rules.txt", nil),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "inline",
+			srcs: ["a.java"],
+			jarjar_rename_rules: [
+				"com.google.common.** -> com.android.internal.guava.@1",
+				"com.google.protobuf.** -> com.android.internal.protobuf.@1",
+			],
+		}
+
+		java_library {
+			name: "merged",
+			srcs: ["a.java"],
+			jarjar_rules: "jarjar-rules.txt",
+			jarjar_rename_rules: ["com.google.common.** -> com.android.internal.guava.@1"],
+		}
+	`)
+
+	inline := result.ModuleForTests("inline", "android_common")
+	renameRules := inline.Output("jarjar/rename_rules.txt")
+	android.AssertStringEquals(t, "rename rules",
+		"rule com.google.common.** com.android.internal.guava.@1\n"+
+			"rule com.google.protobuf.** com.android.internal.protobuf.@1\n",
+		android.ContentFromFileRuleForTests(t, renameRules))
+	android.AssertPathRelativeToTopEquals(t, "inline jarjar rules",
+		"out/soong/.intermediates/inline/android_common/jarjar/rename_rules.txt",
+		inline.Output("jarjar/inline.jar").Implicit)
+
+	merged := result.ModuleForTests("merged", "android_common")
+	mergeRule := merged.Rule("jarjar_merge_rules")
+	android.AssertPathsRelativeToTopEquals(t, "merge inputs", []string{
+		"jarjar-rules.txt",
+		"out/soong/.intermediates/merged/android_common/jarjar/rename_rules.txt",
+	}, mergeRule.Implicits)
+	android.AssertPathRelativeToTopEquals(t, "merged jarjar rules",
+		"out/soong/.intermediates/merged/android_common/jarjar/merged_rules.txt",
+		merged.Output("jarjar/merged.jar").Implicit)
+}
+
+func TestJarjarRenameRulesError(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`jarjar_rename_rules: invalid rule "com.google.common.\*\*"`)).
+		RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			jarjar_rename_rules: ["com.google.common.**"],
+		}
+	`)
+}
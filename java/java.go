@@ -812,6 +812,10 @@ type TestOptions struct {
 
 	// If the test is a hostside(no device required) unittest that shall be run during presubmit check.
 	Unit_test *bool
+
+	// A list of KEY=VALUE environment variables that the test harness sets when running the
+	// test. Values may reference $(MODULE_NAME), $(ARCH) and $(PLATFORM_SDK_VERSION).
+	Environment []string
 }
 
 type testProperties struct {
@@ -827,8 +831,9 @@ type testProperties struct {
 	// should be installed with the module.
 	Test_config_template *string `android:"path,arch_variant"`
 
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the test.  A module with multiple output files must select one with a
+	// tag, like ":module{tag}".
 	Data []string `android:"path"`
 
 	// Flag to indicate whether or not to create test config automatically. If AndroidTest.xml
@@ -1090,10 +1095,13 @@ func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext,
 		j.testProperties.Test_options.Unit_test = proptools.BoolPtr(defaultUnitTest)
 	}
 
+	configs = append(configs, tradefed.EnvironmentOptions(ctx, "test_options.environment",
+		j.testProperties.Test_options.Environment)...)
+
 	j.testConfig = tradefed.AutoGenJavaTestConfig(ctx, j.testProperties.Test_config, j.testProperties.Test_config_template,
 		j.testProperties.Test_suites, configs, j.testProperties.Auto_gen_config, j.testProperties.Test_options.Unit_test)
 
-	j.data = android.PathsForModuleSrc(ctx, j.testProperties.Data)
+	j.data = android.PathsForModuleData(ctx, j.testProperties.Data)
 
 	j.extraTestConfigs = android.PathsForModuleSrc(ctx, j.testProperties.Test_options.Extra_test_configs)
 
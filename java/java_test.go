@@ -1303,6 +1303,45 @@ func TestAidlFlagsArePassedToTheAidlCompiler(t *testing.T) {
 	}
 }
 
+func TestAidlBackend(t *testing.T) {
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["aidl/foo/IFoo.aidl"],
+			aidl: { backend: "java" },
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["aidl/foo/IFoo.aidl"],
+		}
+	`)
+
+	aidlCommand := ctx.ModuleForTests("foo", "android_common").Rule("aidl").RuleParams.Command
+	android.AssertStringDoesContain(t, "foo aidl command", aidlCommand, "--lang=java")
+
+	aidlCommand = ctx.ModuleForTests("bar", "android_common").Rule("aidl").RuleParams.Command
+	android.AssertStringDoesNotContain(t, "bar aidl command", aidlCommand, "--lang=")
+}
+
+func TestAidlBackendErrors(t *testing.T) {
+	testJavaError(t, `aidl.backend: the "ndk" backend generates sources that java modules can't compile`, `
+		java_library {
+			name: "foo",
+			srcs: ["aidl/foo/IFoo.aidl"],
+			aidl: { backend: "ndk" },
+		}
+	`)
+
+	testJavaError(t, `aidl.backend: unknown backend "kotlin"`, `
+		java_library {
+			name: "foo",
+			srcs: ["aidl/foo/IFoo.aidl"],
+			aidl: { backend: "kotlin" },
+		}
+	`)
+}
+
 func TestAidlFlagsWithMinSdkVersion(t *testing.T) {
 	fixture := android.GroupFixturePreparers(
 		prepareForJavaTest, FixtureWithPrebuiltApis(map[string][]string{"14": {"foo"}}))
@@ -1497,6 +1536,74 @@ func TestErrorproneEnabledOnlyByEnvironmentVariable(t *testing.T) {
 	}
 }
 
+func TestErrorproneCustomChecks(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				extra_check_modules: ["checks"],
+				javacflags: ["-Xep:CustomCheck:ERROR", "-Xep:GlobalCheck:OFF"],
+			},
+		}
+
+		java_plugin {
+			name: "checks",
+			srcs: ["b.java"],
+		}
+	`
+	ctx := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"RUN_ERROR_PRONE": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	javac := foo.Description("javac")
+	errorprone := foo.Description("errorprone")
+
+	// The custom checks are only added to the errorprone compilation, after the global checks.
+	android.AssertStringDoesContain(t, "errorprone javacFlags", errorprone.Args["javacFlags"],
+		"${config.ErrorProneChecks} -Xep:CustomCheck:ERROR -Xep:GlobalCheck:OFF")
+	android.AssertStringDoesContain(t, "errorprone processorpath", errorprone.Args["processorpath"], "/checks.jar")
+	android.AssertStringDoesNotContain(t, "javac javacFlags", javac.Args["javacFlags"], "-Xep:CustomCheck:ERROR")
+	android.AssertStringDoesNotContain(t, "javac processorpath", javac.Args["processorpath"], "/checks.jar")
+}
+
+func TestErrorproneConflictingChecks(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`errorprone.javacflags: conflicting flags "-Xep:CustomCheck:WARN" and "-Xep:CustomCheck:OFF"`)).
+		RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				enabled: true,
+				javacflags: ["-Xep:CustomCheck:WARN", "-Xep:OtherCheck:ERROR", "-Xep:CustomCheck:OFF"],
+			},
+		}
+	`)
+}
+
+func TestTestOptionsEnvironment(t *testing.T) {
+	result := PrepareForIntegrationTestWithJava.RunTestWithBp(t, `
+		java_test_host {
+			name: "foo",
+			srcs: ["test.java"],
+			test_options: {
+				environment: ["FOO_CONFIG=$(MODULE_NAME).cfg"],
+			},
+		}`)
+
+	buildOS := result.Config.BuildOS.String()
+	autogen := result.ModuleForTests("foo", buildOS+"_common").Rule("autogen")
+	android.AssertStringDoesContain(t, "extraConfigs", autogen.Args["extraConfigs"],
+		`<option name="environment" key="FOO_CONFIG" value="foo.cfg" />`)
+}
+
 func TestDataDeviceBinsBuildsDeviceBinary(t *testing.T) {
 	testCases := []struct {
 		dataDeviceBinType  string
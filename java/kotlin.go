@@ -30,39 +30,73 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 	blueprint.RuleParams{
 		Command: `rm -rf "$classesDir" "$headerClassesDir" "$srcJarDir" "$kotlinBuildFile" "$emptyDir" && ` +
 			`mkdir -p "$classesDir" "$headerClassesDir" "$srcJarDir" "$emptyDir" && ` +
-			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
-			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
-			` --out_dir "$classesDir" --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
-			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
-			`${config.KotlincCmd} ${config.KotlincGlobalFlags} ` +
-			` ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} ` +
-			` $kotlincFlags -jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile ` +
-			` -kotlin-home $emptyDir ` +
-			` -Xplugin=${config.KotlinAbiGenPluginJar} ` +
-			` -P plugin:org.jetbrains.kotlin.jvm.abi:outputDir=$headerClassesDir && ` +
-			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir -write_if_changed && ` +
-			`${config.SoongZipCmd} -jar -o $headerJar -C $headerClassesDir -D $headerClassesDir -write_if_changed && ` +
-			`rm -rf "$srcJarDir"`,
-		CommandDeps: []string{
-			"${config.KotlincCmd}",
-			"${config.KotlinCompilerJar}",
-			"${config.KotlinPreloaderJar}",
-			"${config.KotlinReflectJar}",
-			"${config.KotlinScriptRuntimeJar}",
-			"${config.KotlinStdlibJar}",
-			"${config.KotlinTrove4jJar}",
-			"${config.KotlinAnnotationJar}",
-			"${config.KotlinAbiGenPluginJar}",
-			"${config.GenKotlinBuildFileCmd}",
-			"${config.SoongZipCmd}",
-			"${config.ZipSyncCmd}",
-		},
+			kotlincCommand(""),
+		CommandDeps:    kotlincCommandDeps,
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+		Restat:         true,
+	},
+	kotlincArgs...)
+
+// kotlincIncremental is the kotlinc rule used when incremental compilation is enabled.  It keeps
+// the classes, header classes and incremental caches of the previous build of the module, and
+// deletes them when the classpath, the flags or the list of sources change, so that the classes of
+// deleted or renamed sources don't end up in the jar, or when the compiler is newer than the
+// previous build.  The key describing the previous build is removed while kotlinc runs and only
+// written back after it succeeds, so a failed compilation starts from scratch the next time.
+var kotlincIncremental = pctx.AndroidRemoteStaticRule("kotlincIncremental", android.RemoteRuleSupports{Goma: true},
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" "$emptyDir" && ` +
+			`(echo "$classpath $kotlincFlags $kotlinJvmTarget" && cat "$out.rsp") > "$incrementalDir.key.new" && ` +
+			`if ! cmp -s "$incrementalDir.key.new" "$incrementalDir.key" || ` +
+			`[ ${config.KotlinCompilerJar} -nt "$incrementalDir.key" ]; then ` +
+			`rm -rf "$classesDir" "$headerClassesDir" "$incrementalDir"; fi && ` +
+			`rm -f "$incrementalDir.key" && ` +
+			`mkdir -p "$classesDir" "$headerClassesDir" "$srcJarDir" "$emptyDir" "$incrementalDir" && ` +
+			kotlincCommand(`${config.KotlincIncrementalFlags} -Xic-cache-dir="$incrementalDir" `) +
+			` && mv "$incrementalDir.key.new" "$incrementalDir.key"`,
+		CommandDeps:    kotlincCommandDeps,
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
 		Restat:         true,
 	},
-	"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
-	"headerClassesDir", "headerJar", "kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
+	append(kotlincArgs, "incrementalDir")...)
+
+var kotlincArgs = []string{"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
+	"headerClassesDir", "headerJar", "kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name"}
+
+// kotlincCommand returns the part of the kotlinc command that runs after the output directories
+// have been created, passing extraFlags to kotlinc.
+func kotlincCommand(extraFlags string) string {
+	return `${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+		`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
+		` --out_dir "$classesDir" --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
+		` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
+		`${config.KotlincCmd} ${config.KotlincGlobalFlags} ` +
+		` ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} ` +
+		` $kotlincFlags -jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile ` +
+		` -kotlin-home $emptyDir ` + extraFlags +
+		` -Xplugin=${config.KotlinAbiGenPluginJar} ` +
+		` -P plugin:org.jetbrains.kotlin.jvm.abi:outputDir=$headerClassesDir && ` +
+		`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir -write_if_changed && ` +
+		`${config.SoongZipCmd} -jar -o $headerJar -C $headerClassesDir -D $headerClassesDir -write_if_changed && ` +
+		`rm -rf "$srcJarDir"`
+}
+
+var kotlincCommandDeps = []string{
+	"${config.KotlincCmd}",
+	"${config.KotlinCompilerJar}",
+	"${config.KotlinPreloaderJar}",
+	"${config.KotlinReflectJar}",
+	"${config.KotlinScriptRuntimeJar}",
+	"${config.KotlinStdlibJar}",
+	"${config.KotlinTrove4jJar}",
+	"${config.KotlinAnnotationJar}",
+	"${config.KotlinAbiGenPluginJar}",
+	"${config.GenKotlinBuildFileCmd}",
+	"${config.SoongZipCmd}",
+	"${config.ZipSyncCmd}",
+}
 
 func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Paths) android.OptionalPath {
 	if len(commonSrcFiles) > 0 {
@@ -101,31 +135,46 @@ func kotlinCompile(ctx android.ModuleContext, outputFile, headerOutputFile andro
 		commonSrcFilesArg = "--common_srcs " + commonSrcsList.String()
 	}
 
+	args := map[string]string{
+		"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
+		"kotlincFlags":      flags.kotlincFlags,
+		"commonSrcFilesArg": commonSrcFilesArg,
+		"srcJars":           strings.Join(srcJars.Strings(), " "),
+		"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+		"headerClassesDir":  android.PathForModuleOut(ctx, "kotlinc", "header_classes").String(),
+		"headerJar":         headerOutputFile.String(),
+		"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+		"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+		"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
+		// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
+		"kotlinJvmTarget": "1.8",
+		"name":            kotlinName,
+	}
+
+	rule := kotlinc
+	if kotlinIncrementalCompilationEnabled(ctx) {
+		rule = kotlincIncremental
+		args["incrementalDir"] = android.PathForModuleOut(ctx, "kotlinc", "incremental").String()
+	}
+
 	ctx.Build(pctx, android.BuildParams{
-		Rule:           kotlinc,
+		Rule:           rule,
 		Description:    "kotlinc",
 		Output:         outputFile,
 		ImplicitOutput: headerOutputFile,
 		Inputs:         srcFiles,
 		Implicits:      deps,
-		Args: map[string]string{
-			"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
-			"kotlincFlags":      flags.kotlincFlags,
-			"commonSrcFilesArg": commonSrcFilesArg,
-			"srcJars":           strings.Join(srcJars.Strings(), " "),
-			"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
-			"headerClassesDir":  android.PathForModuleOut(ctx, "kotlinc", "header_classes").String(),
-			"headerJar":         headerOutputFile.String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
-			"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
-			"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
-			// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
-			"kotlinJvmTarget": "1.8",
-			"name":            kotlinName,
-		},
+		Args:           args,
 	})
 }
 
+// kotlinIncrementalCompilationEnabled returns true if kotlinc should reuse the results of the
+// previous compilation of the module.  Incremental compilation is opt-in with
+// KOTLIN_INCREMENTAL_COMPILATION=true, as it makes the outputs depend on the previous build.
+func kotlinIncrementalCompilationEnabled(ctx android.ModuleContext) bool {
+	return ctx.Config().IsEnvTrue("KOTLIN_INCREMENTAL_COMPILATION")
+}
+
 var kaptStubs = pctx.AndroidRemoteStaticRule("kaptStubs", android.RemoteRuleSupports{Goma: true},
 	blueprint.RuleParams{
 		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" "$kaptDir" && ` +
@@ -92,6 +92,41 @@ func TestKotlin(t *testing.T) {
 	}
 }
 
+func TestKotlinIncrementalCompilation(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+		}
+	`
+
+	t.Run("default", func(t *testing.T) {
+		result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, bp)
+
+		kotlinc := result.ModuleForTests("foo", "android_common").Description("kotlinc")
+		android.AssertStringDoesNotContain(t, "kotlinc rule", kotlinc.Rule.String(), "kotlincIncremental")
+		android.AssertStringDoesContain(t, "kotlinc command", kotlinc.RuleParams.Command, `rm -rf "$classesDir"`)
+		android.AssertStringEquals(t, "incrementalDir", "", kotlinc.Args["incrementalDir"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			PrepareForTestWithJavaDefaultModules,
+			android.FixtureMergeEnv(map[string]string{
+				"KOTLIN_INCREMENTAL_COMPILATION": "true",
+			}),
+		).RunTestWithBp(t, bp)
+
+		kotlinc := result.ModuleForTests("foo", "android_common").Description("kotlinc")
+		android.AssertStringDoesContain(t, "kotlinc rule", kotlinc.Rule.String(), "kotlincIncremental")
+		android.AssertStringPathRelativeToTopEquals(t, "incrementalDir", result.Config,
+			"out/soong/.intermediates/foo/android_common/kotlinc/incremental", kotlinc.Args["incrementalDir"])
+		// The sources are part of the key, so removing a source deletes the stale classes.
+		android.AssertStringDoesContain(t, "kotlinc command", kotlinc.RuleParams.Command, `cat "$out.rsp"`)
+		android.AssertStringDoesNotContain(t, "kotlinc command", kotlinc.RuleParams.Command, "cksum")
+	})
+}
+
 func TestKapt(t *testing.T) {
 	bp := `
 		java_library {
@@ -18,6 +18,8 @@ package python
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"android/soong/android"
 	"android/soong/bazel"
@@ -114,6 +116,16 @@ type BinaryProperties struct {
 	// doesn't exist next to the Android.bp, this attribute doesn't need to be set to true
 	// explicitly.
 	Auto_gen_config *bool
+
+	// the absolute path of the Python interpreter that runs the binary, for example
+	// "/usr/bin/python3". It is used in the shebang line of the launcher instead of finding
+	// python3 or python2.7 in PATH. It is ignored when embedded_launcher is true.
+	Interpreter *string `android:"arch_variant"`
+
+	// a checked-in file that lists the python library modules embedded into the binary through
+	// libs and their transitive dependencies, one module name per line in sorted order.  The build
+	// fails when the embedded modules differ from the list, and prints the command that updates it.
+	Lockfile *string `android:"path"`
 }
 
 type binaryDecorator struct {
@@ -128,6 +140,10 @@ type IntermPathProvider interface {
 
 var (
 	StubTemplateHost = "build/soong/python/scripts/stub_template_host.txt"
+
+	// interpreterRegexp matches the absolute interpreter paths that can be substituted into the
+	// launcher stub and its shebang line.
+	interpreterRegexp = regexp.MustCompile(`^(/[A-Za-z0-9._+-]+)+$`)
 )
 
 func NewBinary(hod android.HostOrDeviceSupported) (*Module, *binaryDecorator) {
@@ -158,7 +174,7 @@ func (binary *binaryDecorator) bootstrapperProps() []interface{} {
 
 func (binary *binaryDecorator) bootstrap(ctx android.ModuleContext, actualVersion string,
 	embeddedLauncher bool, srcsPathMappings []pathMapping, srcsZip android.Path,
-	depsSrcsZips android.Paths) android.OptionalPath {
+	depsSrcsZips android.Paths, depsModules []string) android.OptionalPath {
 
 	main := ""
 	if binary.autorun() {
@@ -178,16 +194,63 @@ func (binary *binaryDecorator) bootstrap(ctx android.ModuleContext, actualVersio
 		})
 	}
 
+	var interpreter string
+	if !embeddedLauncher {
+		interpreter = binary.getHostInterpreterName(ctx, actualVersion)
+	}
+
+	validations := binary.checkLockfile(ctx, depsModules)
+
 	binFile := registerBuildActionForParFile(ctx, embeddedLauncher, launcherPath,
-		binary.getHostInterpreterName(ctx, actualVersion),
-		main, binary.getStem(ctx), append(android.Paths{srcsZip}, depsSrcsZips...))
+		interpreter, main, binary.getStem(ctx), append(android.Paths{srcsZip}, depsSrcsZips...),
+		validations)
 
 	return android.OptionalPathForPath(binFile)
 }
 
+// checkLockfile writes the sorted names of the embedded python library modules to
+// <module>.lockfile in the intermediates directory, and returns the timestamp of a rule that
+// compares it with the lockfile property, to be used as a validation of the par file.
+func (binary *binaryDecorator) checkLockfile(ctx android.ModuleContext, depsModules []string) android.Paths {
+	if binary.binaryProperties.Lockfile == nil {
+		return nil
+	}
+	lockfile := android.PathForModuleSrc(ctx, *binary.binaryProperties.Lockfile)
+
+	// Every line ends with a newline, like in a lockfile edited by hand, and a lockfile without
+	// modules is empty.
+	var content strings.Builder
+	for _, module := range android.SortedUniqueStrings(depsModules) {
+		content.WriteString(module + "\n")
+	}
+	generated := android.PathForModuleOut(ctx, ctx.ModuleName()+".lockfile")
+	android.WriteFileRuleVerbatim(ctx, generated, content.String())
+
+	timestamp := android.PathForModuleOut(ctx, "lockfile.timestamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        checkLockfile,
+		Description: "check python lockfile",
+		Input:       generated,
+		Implicit:    lockfile,
+		Output:      timestamp,
+		Args: map[string]string{
+			"lockfile": lockfile.String(),
+			"module":   ctx.ModuleName(),
+		},
+	})
+	return android.Paths{timestamp}
+}
+
 // get host interpreter name.
 func (binary *binaryDecorator) getHostInterpreterName(ctx android.ModuleContext,
 	actualVersion string) string {
+	if interpreter := String(binary.binaryProperties.Interpreter); interpreter != "" {
+		if !interpreterRegexp.MatchString(interpreter) {
+			ctx.PropertyErrorf("interpreter", "%q must be an absolute path to a Python interpreter", interpreter)
+		}
+		return interpreter
+	}
+
 	var interp string
 	switch actualVersion {
 	case pyVersion2:
@@ -45,12 +45,12 @@ var (
 	hostPar = pctx.AndroidStaticRule("hostPar",
 		blueprint.RuleParams{
 			Command: `sed -e 's/%interpreter%/$interp/g' -e 's/%main%/$main/g' $template > $stub && ` +
-				`echo "#!/usr/bin/env $interp" >${out}.prefix &&` +
+				`echo "#!$shebang" >${out}.prefix &&` +
 				`$mergeParCmd -p --prefix ${out}.prefix -pm $stub $out $srcsZips && ` +
 				`chmod +x $out && (rm -f $stub; rm -f ${out}.prefix)`,
 			CommandDeps: []string{"$mergeParCmd"},
 		},
-		"interp", "main", "template", "stub", "srcsZips")
+		"interp", "shebang", "main", "template", "stub", "srcsZips")
 
 	embeddedPar = pctx.AndroidStaticRule("embeddedPar",
 		blueprint.RuleParams{
@@ -69,6 +69,16 @@ var (
 			CommandDeps: []string{"$mergeParCmd"},
 		},
 		"srcsZips", "launcher")
+
+	checkLockfile = pctx.AndroidStaticRule("checkLockfile",
+		blueprint.RuleParams{
+			Command: `if ! diff -u $lockfile $in; then ` +
+				`echo "$lockfile does not match the python modules embedded into $module." && ` +
+				`echo "If the change is expected, update it with:" && ` +
+				`echo "  cp $in $lockfile" && ` +
+				`exit 1; fi && touch $out`,
+		},
+		"lockfile", "module")
 )
 
 func init() {
@@ -81,7 +91,7 @@ func init() {
 
 func registerBuildActionForParFile(ctx android.ModuleContext, embeddedLauncher bool,
 	launcherPath android.OptionalPath, interpreter, main, binName string,
-	srcsZips android.Paths) android.Path {
+	srcsZips, validations android.Paths) android.Path {
 
 	// .intermediate output path for bin executable.
 	binFile := android.PathForModuleOut(ctx, binName)
@@ -97,13 +107,21 @@ func registerBuildActionForParFile(ctx android.ModuleContext, embeddedLauncher b
 		// intermediate output path for __main__.py
 		stub := android.PathForModuleOut(ctx, mainFileName).String()
 
+		// interpreters given as an absolute path are run directly, others are found in PATH.
+		shebang := "/usr/bin/env " + interpreter
+		if strings.HasPrefix(interpreter, "/") {
+			shebang = interpreter
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:        hostPar,
 			Description: "host python archive",
 			Output:      binFile,
 			Implicits:   implicits,
+			Validations: validations,
 			Args: map[string]string{
 				"interp":   strings.Replace(interpreter, "/", `\/`, -1),
+				"shebang":  shebang,
 				"main":     strings.Replace(main, "/", `\/`, -1),
 				"template": template.String(),
 				"stub":     stub,
@@ -120,6 +138,7 @@ func registerBuildActionForParFile(ctx android.ModuleContext, embeddedLauncher b
 				Description: "embedded python archive",
 				Output:      binFile,
 				Implicits:   implicits,
+				Validations: validations,
 				Args: map[string]string{
 					"srcsZips": strings.Join(srcsZips.Strings(), " "),
 					"launcher": launcherPath.String(),
@@ -131,6 +150,7 @@ func registerBuildActionForParFile(ctx android.ModuleContext, embeddedLauncher b
 				Description: "embedded python archive",
 				Output:      binFile,
 				Implicits:   implicits,
+				Validations: validations,
 				Args: map[string]string{
 					"main":     strings.Replace(strings.TrimSuffix(main, pyExt), "/", ".", -1),
 					"srcsZips": strings.Join(srcsZips.Strings(), " "),
@@ -176,6 +176,9 @@ type Module struct {
 	// dependency modules' zip filepath for zipping current module source/data files.
 	depsSrcsZips android.Paths
 
+	// the names of the python library modules that are embedded through the dependencies.
+	depsModules []string
+
 	// (.intermediate) module output path as installation source.
 	installSource android.OptionalPath
 
@@ -215,7 +218,7 @@ type bootstrapper interface {
 	bootstrapperProps() []interface{}
 	bootstrap(ctx android.ModuleContext, ActualVersion string, embeddedLauncher bool,
 		srcsPathMappings []pathMapping, srcsZip android.Path,
-		depsSrcsZips android.Paths) android.OptionalPath
+		depsSrcsZips android.Paths, depsModules []string) android.OptionalPath
 
 	autorun() bool
 }
@@ -479,7 +482,7 @@ func (p *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		// registering actions to build the par file
 		// bootstrap returns the binary output path
 		p.installSource = p.bootstrapper.bootstrap(ctx, p.properties.Actual_version,
-			p.isEmbeddedLauncherEnabled(), p.srcsPathMappings, p.srcsZip, p.depsSrcsZips)
+			p.isEmbeddedLauncherEnabled(), p.srcsPathMappings, p.srcsZip, p.depsSrcsZips, p.depsModules)
 	}
 
 	// Only Python binary and test modules have non-empty installer.
@@ -740,6 +743,7 @@ func (p *Module) collectPathsFromTransitiveDeps(ctx android.ModuleContext) {
 					path.dest, path.src.String(), ctx.ModuleName(), ctx.OtherModuleName(child))
 			}
 			p.depsSrcsZips = append(p.depsSrcsZips, dep.getSrcsZip())
+			p.depsModules = append(p.depsModules, ctx.OtherModuleName(child))
 		}
 		return true
 	})
@@ -375,6 +375,123 @@ func expectModule(t *testing.T, ctx *android.TestContext, name, variant, expecte
 	android.AssertPathsRelativeToTopEquals(t, "depsSrcsZips", expectedDepsSrcsZips, base.depsSrcsZips)
 }
 
+func TestPythonBinaryInterpreter(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		interpreter     string
+		expectedInterp  string
+		expectedShebang string
+		err             string
+	}{
+		{
+			desc:            "default",
+			expectedInterp:  "python3",
+			expectedShebang: "/usr/bin/env python3",
+		},
+		{
+			desc:            "absolute interpreter",
+			interpreter:     `interpreter: "/usr/local/bin/python3.9",`,
+			expectedInterp:  `\/usr\/local\/bin\/python3.9`,
+			expectedShebang: "/usr/local/bin/python3.9",
+		},
+		{
+			desc:        "relative interpreter",
+			interpreter: `interpreter: "python3.9",`,
+			err:         `interpreter: "python3.9" must be an absolute path to a Python interpreter`,
+		},
+		{
+			desc:        "interpreter with arguments",
+			interpreter: `interpreter: "/usr/bin/python3 -E",`,
+			err:         `interpreter: "/usr/bin/python3 -E" must be an absolute path to a Python interpreter`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			errorHandler := android.FixtureExpectsNoErrors
+			if tc.err != "" {
+				errorHandler = android.FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(tc.err))
+			}
+
+			result := android.GroupFixturePreparers(
+				PrepareForTestWithPythonBuildComponents,
+				android.FixtureAddFile(StubTemplateHost, nil),
+				android.FixtureAddFile("dir/bin.py", nil),
+			).ExtendWithErrorHandler(errorHandler).RunTestWithBp(t, `
+				python_binary_host {
+					name: "bin",
+					srcs: ["dir/bin.py"],
+					main: "dir/bin.py",
+					`+tc.interpreter+`
+				}`)
+
+			if tc.err != "" {
+				return
+			}
+
+			hostPar := result.ModuleForTests("bin", "PY3").Rule("hostPar")
+			android.AssertStringEquals(t, "interp", tc.expectedInterp, hostPar.Args["interp"])
+			android.AssertStringEquals(t, "shebang", tc.expectedShebang, hostPar.Args["shebang"])
+		})
+	}
+}
+
+func TestPythonBinaryLockfile(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithPythonBuildComponents,
+		android.FixtureAddFile(StubTemplateHost, nil),
+		android.FixtureAddFile("dir/bin.py", nil),
+		android.FixtureAddFile("dir/bin.lock", nil),
+		android.FixtureAddFile("dir/a.py", nil),
+		android.FixtureAddFile("dir/b.py", nil),
+	).RunTestWithBp(t, `
+		python_library_host {
+			name: "lib_b",
+			srcs: ["dir/b.py"],
+		}
+
+		python_library_host {
+			name: "lib_a",
+			srcs: ["dir/a.py"],
+			libs: ["lib_b"],
+		}
+
+		python_binary_host {
+			name: "bin",
+			srcs: ["dir/bin.py"],
+			libs: [
+				"lib_b",
+				"lib_a",
+			],
+			lockfile: "dir/bin.lock",
+		}
+
+		python_binary_host {
+			name: "bin_nodeps",
+			srcs: ["dir/bin.py"],
+			lockfile: "dir/bin.lock",
+		}`)
+
+	bin := result.ModuleForTests("bin", "PY3")
+
+	generated := bin.Output("bin.lockfile")
+	android.AssertStringEquals(t, "lockfile content", "lib_a\nlib_b\n",
+		android.ContentFromFileRuleForTests(t, generated))
+
+	generated = result.ModuleForTests("bin_nodeps", "PY3").Output("bin_nodeps.lockfile")
+	android.AssertStringEquals(t, "lockfile without modules", "",
+		android.ContentFromFileRuleForTests(t, generated))
+
+	check := bin.Rule("checkLockfile")
+	android.AssertPathRelativeToTopEquals(t, "check input",
+		"out/soong/.intermediates/bin/PY3/bin.lockfile", check.Input)
+	android.AssertPathRelativeToTopEquals(t, "check lockfile", "dir/bin.lock", check.Implicit)
+
+	hostPar := bin.Rule("hostPar")
+	android.AssertPathsRelativeToTopEquals(t, "par validations",
+		[]string{"out/soong/.intermediates/bin/PY3/lockfile.timestamp"}, hostPar.Validations)
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
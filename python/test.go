@@ -47,8 +47,9 @@ type TestProperties struct {
 	// should be installed with the module.
 	Test_config_template *string `android:"path,arch_variant"`
 
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the test.  A module with multiple output files must select one with a
+	// tag, like ":module{tag}".
 	Data []string `android:"path,arch_variant"`
 
 	// list of java modules that provide data that should be installed alongside the test.
@@ -84,7 +85,7 @@ func (test *testDecorator) install(ctx android.ModuleContext, file android.Path)
 
 	test.binaryDecorator.pythonInstaller.install(ctx, file)
 
-	dataSrcPaths := android.PathsForModuleSrc(ctx, test.testProperties.Data)
+	dataSrcPaths := android.PathsForModuleData(ctx, test.testProperties.Data)
 
 	for _, dataSrcPath := range dataSrcPaths {
 		test.data = append(test.data, android.DataPath{SrcPath: dataSrcPath})
@@ -208,8 +208,14 @@ func transformSrctoCrate(ctx ModuleContext, main android.Path, deps PathDeps, fl
 	if crateName != "" {
 		rustcFlags = append(rustcFlags, "--crate-name="+crateName)
 	}
-	if targetTriple != "" {
+	if flags.TargetSpec != nil {
+		// rustc treats a target ending in .json as the path to a target spec.
+		rustcFlags = append(rustcFlags, "--target="+flags.TargetSpec.String())
+		implicits = append(implicits, flags.TargetSpec)
+	} else if targetTriple != "" {
 		rustcFlags = append(rustcFlags, "--target="+targetTriple)
+	}
+	if targetTriple != "" {
 		linkFlags = append(linkFlags, "-target "+targetTriple)
 	}
 
@@ -340,7 +346,11 @@ func Rustdoc(ctx ModuleContext, main android.Path, deps PathDeps,
 	targetTriple := ctx.toolchain().RustTriple()
 
 	// Collect rustc flags
-	if targetTriple != "" {
+	var implicits android.Paths
+	if flags.TargetSpec != nil {
+		rustdocFlags = append(rustdocFlags, "--target="+flags.TargetSpec.String())
+		implicits = append(implicits, flags.TargetSpec)
+	} else if targetTriple != "" {
 		rustdocFlags = append(rustdocFlags, "--target="+targetTriple)
 	}
 
@@ -369,6 +379,7 @@ func Rustdoc(ctx ModuleContext, main android.Path, deps PathDeps,
 		Output:      docTimestampFile,
 		Input:       main,
 		Implicit:    ctx.RustModule().UnstrippedOutputFile(),
+		Implicits:   implicits,
 		Args: map[string]string{
 			"rustdocFlags": strings.Join(rustdocFlags, " "),
 			"outDir":       docDir.String(),
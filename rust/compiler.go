@@ -154,6 +154,13 @@ type BaseCompilerProperties struct {
 	// whether to suppress inclusion of standard crates - defaults to false
 	No_stdlibs *bool
 
+	// path to a custom target spec JSON file to pass to rustc with --target instead of the triple
+	// of the Soong arch. The module is still linked with the toolchain of its arch, so the spec
+	// must be compatible with it. Only supported for device modules with no_stdlibs: true, as the
+	// standard crates are only built for the Soong targets. The rlibs and dylibs the module depends
+	// on must use the same target_spec.
+	Target_spec *string `android:"path,arch_variant"`
+
 	// Change the rustlibs linkage to select rlib linkage by default for device targets.
 	// Also link libstd as an rlib as well on device targets.
 	// Note: This is the default behavior for host targets.
@@ -197,6 +204,9 @@ type baseCompiler struct {
 	// If a crate has a source-generated dependency, a copy of the source file
 	// will be available in cargoOutDir (equivalent to Cargo OUT_DIR).
 	cargoOutDir android.ModuleOutPath
+
+	// custom target spec the crate is compiled for, if any.
+	targetSpecFile android.OptionalPath
 }
 
 func (compiler *baseCompiler) Disabled() bool {
@@ -305,6 +315,12 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags) Flag
 	flags.GlobalRustFlags = append(flags.GlobalRustFlags, ctx.toolchain().ToolchainRustFlags())
 	flags.GlobalLinkFlags = append(flags.GlobalLinkFlags, ctx.toolchain().ToolchainLinkFlags())
 
+	if compiler.Properties.Target_spec != nil {
+		flags.TargetSpec = compiler.targetSpec(ctx)
+		compiler.targetSpecFile = android.OptionalPathForPath(flags.TargetSpec)
+	}
+	compiler.checkTargetSpecDeps(ctx)
+
 	if ctx.Host() && !ctx.Windows() {
 		rpathPrefix := `\$$ORIGIN/`
 		if ctx.Darwin() {
@@ -324,6 +340,57 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags) Flag
 	return flags
 }
 
+// targetSpec returns the path to the target_spec file, or nil if it can't be used for this module.
+func (compiler *baseCompiler) targetSpec(ctx ModuleContext) android.Path {
+	if !ctx.Device() {
+		ctx.PropertyErrorf("target_spec", "custom target specs are only supported for device modules")
+		return nil
+	}
+	if ctx.toolchain().RustTriple() == "" {
+		ctx.PropertyErrorf("target_spec", "custom target specs are not supported for %s", ctx.Arch().ArchType)
+		return nil
+	}
+	if !Bool(compiler.Properties.No_stdlibs) {
+		ctx.PropertyErrorf("target_spec", "requires no_stdlibs: true, the standard crates are not built for custom targets")
+		return nil
+	}
+	spec := android.PathForModuleSrc(ctx, String(compiler.Properties.Target_spec))
+	if spec.Ext() != ".json" {
+		ctx.PropertyErrorf("target_spec", "%q must be a .json file", spec.Rel())
+		return nil
+	}
+	return spec
+}
+
+func (compiler *baseCompiler) targetSpecPath() android.OptionalPath {
+	return compiler.targetSpecFile
+}
+
+// checkTargetSpecDeps reports the rlib and dylib dependencies that are not compiled for the same
+// target spec as this crate, rustc can't link crates compiled for different targets.
+func (compiler *baseCompiler) checkTargetSpecDeps(ctx ModuleContext) {
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		depTag := ctx.OtherModuleDependencyTag(dep)
+		if depTag != rlibDepTag && depTag != dylibDepTag {
+			return
+		}
+		rustDep, ok := dep.(*Module)
+		if !ok || rustDep.compiler == nil {
+			return
+		}
+		spec, depSpec := compiler.targetSpecFile.String(), rustDep.compiler.targetSpecPath().String()
+		if spec != depSpec {
+			if depSpec == "" {
+				depSpec = ctx.toolchain().RustTriple()
+			}
+			if spec == "" {
+				spec = ctx.toolchain().RustTriple()
+			}
+			ctx.ModuleErrorf("dependency %q is compiled for target %s, not %s", ctx.OtherModuleName(dep), depSpec, spec)
+		}
+	})
+}
+
 func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathDeps) android.Path {
 	panic(fmt.Errorf("baseCrater doesn't know how to crate things!"))
 }
@@ -15,6 +15,7 @@
 package rust
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -337,3 +338,79 @@ func TestManualLinkageRejection(t *testing.T) {
 		}
 	`)
 }
+
+// Test that a custom target spec replaces the triple of the toolchain when compiling.
+func TestTargetSpec(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForRustTest,
+		rustMockedFiles.AddToFixture(),
+		android.FixtureAddFile("firmware.json", nil),
+	).RunTestWithBp(t, `
+		rust_binary {
+			name: "fizz",
+			srcs: ["foo.rs"],
+			no_stdlibs: true,
+			target_spec: "firmware.json",
+		}`)
+
+	fizz := result.ModuleForTests("fizz", "android_arm64_armv8-a").Rule("rustc")
+	android.AssertStringDoesContain(t, "rustcFlags", fizz.Args["rustcFlags"], "--target=firmware.json")
+	android.AssertStringDoesNotContain(t, "rustcFlags", fizz.Args["rustcFlags"], "--target=aarch64-linux-android")
+	android.AssertStringDoesContain(t, "linkFlags", fizz.Args["linkFlags"], "-target aarch64-linux-android")
+	android.AssertStringListContains(t, "implicits", android.PathsRelativeToTop(fizz.Implicits), "firmware.json")
+}
+
+func TestTargetSpecErrors(t *testing.T) {
+	testRustError(t, "target_spec: custom target specs are only supported for device modules", `
+		rust_binary_host {
+			name: "fizz",
+			srcs: ["foo.rs"],
+			no_stdlibs: true,
+			target_spec: "foo.rs",
+		}`)
+	testRustError(t, "target_spec: requires no_stdlibs: true", `
+		rust_binary {
+			name: "fizz",
+			srcs: ["foo.rs"],
+			target_spec: "foo.rs",
+		}`)
+	testRustError(t, `target_spec: "foo.rs" must be a .json file`, `
+		rust_binary {
+			name: "fizz",
+			srcs: ["foo.rs"],
+			no_stdlibs: true,
+			target_spec: "foo.rs",
+		}`)
+}
+
+func TestTargetSpecDeps(t *testing.T) {
+	bp := `
+		rust_binary {
+			name: "fizz",
+			srcs: ["foo.rs"],
+			no_stdlibs: true,
+			target_spec: "firmware.json",
+			rlibs: ["libbuzz"],
+		}
+		rust_library_rlib {
+			name: "libbuzz",
+			crate_name: "buzz",
+			srcs: ["foo.rs"],
+			no_stdlibs: true,
+			%s
+		}`
+
+	android.GroupFixturePreparers(
+		prepareForRustTest,
+		rustMockedFiles.AddToFixture(),
+		android.FixtureAddFile("firmware.json", nil),
+	).RunTestWithBp(t, fmt.Sprintf(bp, `target_spec: "firmware.json",`))
+
+	android.GroupFixturePreparers(
+		prepareForRustTest,
+		rustMockedFiles.AddToFixture(),
+		android.FixtureAddFile("firmware.json", nil),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`dependency "libbuzz" is compiled for target aarch64-linux-android, not firmware.json`)).
+		RunTestWithBp(t, fmt.Sprintf(bp, ""))
+}
@@ -183,6 +183,7 @@ func (fuzz *fuzzDecorator) install(ctx ModuleContext) {
 
 	if fuzz.fuzzPackagedModule.FuzzProperties.Corpus != nil {
 		fuzz.fuzzPackagedModule.Corpus = android.PathsForModuleSrc(ctx, fuzz.fuzzPackagedModule.FuzzProperties.Corpus)
+		fuzz.fuzzPackagedModule.CheckCorpus(ctx)
 	}
 	if fuzz.fuzzPackagedModule.FuzzProperties.Data != nil {
 		fuzz.fuzzPackagedModule.Data = android.PathsForModuleSrc(ctx, fuzz.fuzzPackagedModule.FuzzProperties.Data)
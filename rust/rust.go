@@ -62,6 +62,7 @@ type Flags struct {
 	ClippyFlags     []string // Flags that apply to clippy-driver, during the linting
 	RustdocFlags    []string // Flags that apply to rustdoc
 	Toolchain       config.Toolchain
+	TargetSpec      android.Path // Custom target spec JSON passed to --target instead of the toolchain triple
 	Coverage        bool
 	Clippy          bool
 }
@@ -484,6 +485,8 @@ type compiler interface {
 
 	stdLinkage(ctx *depsContext) RustLinkage
 
+	targetSpecPath() android.OptionalPath
+
 	unstrippedOutputFilePath() android.Path
 	strippedOutputFilePath() android.OptionalPath
 }
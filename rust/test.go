@@ -46,8 +46,9 @@ type TestProperties struct {
 	// installed into.
 	Test_suites []string `android:"arch_variant"`
 
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the test.  A module with multiple output files must select one with a
+	// tag, like ":module{tag}".
 	Data []string `android:"path,arch_variant"`
 
 	// list of shared library modules that should be installed alongside the test
@@ -142,7 +143,7 @@ func (test *testDecorator) install(ctx ModuleContext) {
 		test.Properties.Auto_gen_config,
 		testInstallBase)
 
-	dataSrcPaths := android.PathsForModuleSrc(ctx, test.Properties.Data)
+	dataSrcPaths := android.PathsForModuleData(ctx, test.Properties.Data)
 
 	ctx.VisitDirectDepsWithTag(dataLibDepTag, func(dep android.Module) {
 		depName := ctx.OtherModuleName(dep)
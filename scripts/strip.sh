@@ -24,24 +24,27 @@
 #   -o ${file}: output file (required)
 #   -d ${file}: deps file (required)
 #   -k symbols: Symbols to keep (optional)
+#   -s ${file}: file to write the debug info to, linked from the output file (optional)
 #   --add-gnu-debuglink
 #   --keep-mini-debug-info
 #   --keep-symbols
 #   --keep-symbols-and-debug-frame
+#   --keep-symbol-table
 #   --remove-build-id
 
 set -o pipefail
 
-OPTSTRING=d:i:o:k:-:
+OPTSTRING=d:i:o:k:s:-:
 
 usage() {
     cat <<EOF
-Usage: strip.sh [options] -k symbols -i in-file -o out-file -d deps-file
+Usage: strip.sh [options] -k symbols -s debug-file -i in-file -o out-file -d deps-file
 Options:
         --add-gnu-debuglink             Add a gnu-debuglink section to out-file
         --keep-mini-debug-info          Keep compressed debug info in out-file
         --keep-symbols                  Keep symbols in out-file
         --keep-symbols-and-debug-frame  Keep symbols and .debug_frame in out-file
+        --keep-symbol-table             Strip debug info but keep the symbol tables in out-file
         --remove-build-id               Remove the gnu build-id section in out-file
 EOF
     exit 1
@@ -63,6 +66,12 @@ do_strip_keep_symbols() {
     "${CLANG_BIN}/llvm-objcopy" "${infile}" "${outfile}.tmp" ${REMOVE_SECTIONS}
 }
 
+do_strip_keep_symbol_table() {
+    # --strip-debug removes the .debug_* and .zdebug_* sections and the symbols
+    # that refer to them, but keeps .symtab and .dynsym for symbolization.
+    "${CLANG_BIN}/llvm-strip" --strip-debug "${infile}" -o "${outfile}.tmp"
+}
+
 do_strip_keep_symbol_list() {
     echo "${symbols_to_keep}" | tr ',' '\n' > "${outfile}.symbolList"
 
@@ -122,6 +131,14 @@ do_add_gnu_debuglink() {
     "${CLANG_BIN}/llvm-objcopy" --add-gnu-debuglink="${infile}" "${outfile}.tmp"
 }
 
+do_split_debug() {
+    # The gnu-debuglink records the name of the file it is given, so the debug
+    # file is written under its final name.
+    rm -f "${split_debug_file}"
+    "${CLANG_BIN}/llvm-objcopy" --only-keep-debug "${infile}" "${split_debug_file}"
+    "${CLANG_BIN}/llvm-objcopy" --add-gnu-debuglink="${split_debug_file}" "${outfile}.tmp"
+}
+
 do_remove_build_id() {
     "${CLANG_BIN}/llvm-strip" --remove-section=.note.gnu.build-id "${outfile}.tmp" -o "${outfile}.tmp.no-build-id"
     rm -f "${outfile}.tmp"
@@ -134,12 +151,14 @@ while getopts $OPTSTRING opt; do
         i) infile="${OPTARG}" ;;
         o) outfile="${OPTARG}" ;;
         k) symbols_to_keep="${OPTARG}" ;;
+        s) split_debug_file="${OPTARG}" ;;
         -)
             case "${OPTARG}" in
                 add-gnu-debuglink) add_gnu_debuglink=true ;;
                 keep-mini-debug-info) keep_mini_debug_info=true ;;
                 keep-symbols) keep_symbols=true ;;
                 keep-symbols-and-debug-frame) keep_symbols_and_debug_frame=true ;;
+                keep-symbol-table) keep_symbol_table=true ;;
                 remove-build-id) remove_build_id=true ;;
                 *) echo "Unknown option --${OPTARG}"; usage ;;
             esac;;
@@ -183,11 +202,25 @@ if [ ! -z "${symbols_to_keep}" -a ! -z "${keep_symbols}" ]; then
     usage
 fi
 
+if [ ! -z "${keep_symbol_table}" ]; then
+    if [ ! -z "${keep_symbols}" -o ! -z "${keep_symbols_and_debug_frame}" -o ! -z "${keep_mini_debug_info}" -o ! -z "${symbols_to_keep}" ]; then
+        echo "--keep-symbol-table cannot be used with --keep-symbols, --keep-symbols-and-debug-frame, --keep-mini-debug-info or -k"
+        usage
+    fi
+fi
+
 if [ ! -z "${add_gnu_debuglink}" -a ! -z "${keep_mini_debug_info}" ]; then
     echo "--add-gnu-debuglink cannot be used with --keep-mini-debug-info"
     usage
 fi
 
+if [ ! -z "${split_debug_file}" ]; then
+    if [ ! -z "${add_gnu_debuglink}" -o ! -z "${keep_mini_debug_info}" ]; then
+        echo "-s cannot be used with --add-gnu-debuglink or --keep-mini-debug-info"
+        usage
+    fi
+fi
+
 rm -f "${outfile}.tmp"
 
 if [ ! -z "${keep_symbols}" ]; then
@@ -198,6 +231,8 @@ elif [ ! -z "${keep_mini_debug_info}" ]; then
     do_strip_keep_mini_debug_info
 elif [ ! -z "${keep_symbols_and_debug_frame}" ]; then
     do_strip_keep_symbols_and_debug_frame
+elif [ ! -z "${keep_symbol_table}" ]; then
+    do_strip_keep_symbol_table
 else
     do_strip
 fi
@@ -206,6 +241,10 @@ if [ ! -z "${add_gnu_debuglink}" ]; then
     do_add_gnu_debuglink
 fi
 
+if [ ! -z "${split_debug_file}" ]; then
+    do_split_debug
+fi
+
 if [ ! -z "${remove_build_id}" ]; then
     do_remove_build_id
 fi
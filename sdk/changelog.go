@@ -0,0 +1,221 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint/parser"
+
+	"android/soong/android"
+)
+
+// snapshotChangelog is the format of the changelog generated when the previous_snapshot property
+// is set. Every list is sorted by member name.
+type snapshotChangelog struct {
+	// The members that are in the new snapshot but not in the previous one.
+	Added []string `json:"added"`
+
+	// The members that are in the previous snapshot but not in the new one.
+	Removed []string `json:"removed"`
+
+	// The members whose prebuilt modules have a different version.
+	VersionChanged []snapshotVersionChange `json:"version_changed"`
+
+	// The members whose prebuilt modules have different properties.
+	Changed []string `json:"changed"`
+}
+
+type snapshotVersionChange struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// snapshotMember is what is compared between two snapshots for a single member.
+type snapshotMember struct {
+	// The version of the versioned prebuilt of the member, empty for unversioned snapshots.
+	version string
+
+	// The canonical form of the properties of the unversioned and versioned prebuilts of the
+	// member, empty if the snapshot has no such prebuilt.
+	unversioned string
+	versioned   string
+}
+
+// Properties that identify a prebuilt rather than describe the member, so they are ignored when
+// comparing snapshots.
+var snapshotChangelogIgnoredProperties = map[string]bool{
+	"name":                  true,
+	"prefer":                true,
+	"sdk_member_name":       true,
+	"use_source_config_var": true,
+}
+
+// snapshotMembers returns the members of the sdk called sdkName in the snapshot Android.bp file.
+func snapshotMembers(sdkName string, filename string, contents string) (map[string]*snapshotMember, []error) {
+	file, errs := parser.ParseAndEval(filename, strings.NewReader(contents), parser.NewScope(nil))
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	members := make(map[string]*snapshotMember)
+	for _, def := range file.Defs {
+		module, ok := def.(*parser.Module)
+		if !ok || module.Type == "package" || strings.HasSuffix(module.Type, "_snapshot") {
+			continue
+		}
+
+		var name string
+		if prop, ok := module.GetProperty("name"); ok {
+			if s, ok := prop.Value.Eval().(*parser.String); ok {
+				name = s.Value
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		version := ""
+		if i := strings.LastIndex(name, "@"); i != -1 {
+			name, version = strings.TrimPrefix(name[:i], sdkName+"_"), name[i+1:]
+		}
+
+		member := members[name]
+		if member == nil {
+			member = &snapshotMember{}
+			members[name] = member
+		}
+		properties := canonicalSnapshotValue(&module.Map, version)
+		if version != "" {
+			member.version = version
+			member.versioned = properties
+		} else {
+			member.unversioned = properties
+		}
+	}
+	return members, nil
+}
+
+// canonicalSnapshotValue returns a string form of value that doesn't depend on the order of the
+// properties of maps or the elements of lists. References to other versioned prebuilts have the
+// version removed so that a version change isn't also reported as a change to every member.
+func canonicalSnapshotValue(value parser.Expression, version string) string {
+	switch v := value.Eval().(type) {
+	case *parser.String:
+		s := v.Value
+		if version != "" {
+			s = strings.ReplaceAll(s, "@"+version, "")
+		}
+		return strconv.Quote(s)
+	case *parser.Bool:
+		return strconv.FormatBool(v.Value)
+	case *parser.Int64:
+		return strconv.FormatInt(v.Value, 10)
+	case *parser.List:
+		var values []string
+		for _, e := range v.Values {
+			values = append(values, canonicalSnapshotValue(e, version))
+		}
+		sort.Strings(values)
+		return "[" + strings.Join(values, ",") + "]"
+	case *parser.Map:
+		var properties []string
+		for _, p := range v.Properties {
+			if snapshotChangelogIgnoredProperties[p.Name] {
+				continue
+			}
+			properties = append(properties, p.Name+":"+canonicalSnapshotValue(p.Value, version))
+		}
+		sort.Strings(properties)
+		return "{" + strings.Join(properties, ",") + "}"
+	default:
+		return v.String()
+	}
+}
+
+// diffSnapshotMembers compares the members of two snapshots.
+func diffSnapshotMembers(previous, current map[string]*snapshotMember) snapshotChangelog {
+	changelog := snapshotChangelog{
+		Added:          []string{},
+		Removed:        []string{},
+		VersionChanged: []snapshotVersionChange{},
+		Changed:        []string{},
+	}
+
+	for _, name := range android.SortedStringKeys(current) {
+		c := current[name]
+		p, ok := previous[name]
+		if !ok {
+			changelog.Added = append(changelog.Added, name)
+			continue
+		}
+		if p.version != "" && c.version != "" && p.version != c.version {
+			changelog.VersionChanged = append(changelog.VersionChanged, snapshotVersionChange{name, p.version, c.version})
+		}
+		// Compare the unversioned prebuilts if both snapshots have them, as they are what is used by
+		// default, otherwise fall back to the versioned prebuilts.
+		if p.unversioned != "" && c.unversioned != "" {
+			if p.unversioned != c.unversioned {
+				changelog.Changed = append(changelog.Changed, name)
+			}
+		} else if p.versioned != c.versioned {
+			changelog.Changed = append(changelog.Changed, name)
+		}
+	}
+
+	for _, name := range android.SortedStringKeys(previous) {
+		if _, ok := current[name]; !ok {
+			changelog.Removed = append(changelog.Removed, name)
+		}
+	}
+
+	return changelog
+}
+
+// buildSnapshotChangelog generates a JSON file that lists the differences between the members of
+// the snapshot Android.bp contents and the previous snapshot set by the previous_snapshot
+// property.
+func (s *sdk) buildSnapshotChangelog(ctx android.ModuleContext, contents string, path android.WritablePath) bool {
+	previousPath := android.PathForSource(ctx, *s.properties.Previous_snapshot)
+	previousContents, err := android.ReadSourceFile(ctx, previousPath)
+	if err != nil {
+		ctx.PropertyErrorf("previous_snapshot", "failed to read %s: %s", previousPath, err)
+		return false
+	}
+
+	previous, errs := snapshotMembers(ctx.ModuleName(), previousPath.String(), string(previousContents))
+	if len(errs) > 0 {
+		for _, err := range errs {
+			ctx.PropertyErrorf("previous_snapshot", "%s", err)
+		}
+		return false
+	}
+	current, errs := snapshotMembers(ctx.ModuleName(), "Android.bp", contents)
+	if len(errs) > 0 {
+		panic(fmt.Errorf("failed to parse generated snapshot: %v", errs))
+	}
+
+	data, err := json.MarshalIndent(diffSnapshotMembers(previous, current), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	android.WriteFileRule(ctx, path, string(data))
+	return true
+}
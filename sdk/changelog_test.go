@@ -0,0 +1,155 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestDiffSnapshotMembers(t *testing.T) {
+	previous, errs := snapshotMembers("mysdk", "previous/Android.bp", `
+java_import {
+    name: "mysdk_reordered@1",
+    sdk_member_name: "reordered",
+    jars: ["java/reordered.jar"],
+    apex_available: ["com.android.foo", "//apex_available:platform"],
+}
+
+java_import {
+    name: "mysdk_changed@1",
+    sdk_member_name: "changed",
+    jars: ["java/changed.jar"],
+    libs: ["mysdk_reordered@1"],
+}
+
+java_import {
+    name: "mysdk_removed@1",
+    sdk_member_name: "removed",
+    jars: ["java/removed.jar"],
+}
+
+sdk_snapshot {
+    name: "mysdk@1",
+    java_header_libs: ["mysdk_reordered@1", "mysdk_changed@1", "mysdk_removed@1"],
+}
+`)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	current, errs := snapshotMembers("mysdk", "Android.bp", `
+java_import {
+    name: "mysdk_added@2",
+    sdk_member_name: "added",
+    jars: ["java/added.jar"],
+}
+
+java_import {
+    name: "mysdk_changed@2",
+    sdk_member_name: "changed",
+    jars: ["java/changed.jar"],
+    libs: ["mysdk_reordered@2"],
+    permitted_packages: ["pkg.changed"],
+}
+
+java_import {
+    name: "mysdk_reordered@2",
+    sdk_member_name: "reordered",
+    apex_available: ["//apex_available:platform", "com.android.foo"],
+    jars: ["java/reordered.jar"],
+}
+
+sdk_snapshot {
+    name: "mysdk@2",
+    java_header_libs: ["mysdk_added@2", "mysdk_changed@2", "mysdk_reordered@2"],
+}
+`)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	android.AssertDeepEquals(t, "changelog", snapshotChangelog{
+		Added:   []string{"added"},
+		Removed: []string{"removed"},
+		VersionChanged: []snapshotVersionChange{
+			{"changed", "1", "2"},
+			{"reordered", "1", "2"},
+		},
+		Changed: []string{"changed"},
+	}, diffSnapshotMembers(previous, current))
+}
+
+func TestSnapshotChangelog(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForSdkTestWithJava,
+		android.FixtureAddTextFile("previous/mysdk.bp", `
+java_import {
+    name: "myjavalib",
+    prefer: true,
+    jars: ["java/myjavalib.jar"],
+    permitted_packages: ["pkg.myjavalib"],
+    apex_available: ["//apex_available:platform"],
+    visibility: ["//visibility:public"],
+}
+
+java_import {
+    name: "oldlib",
+    prefer: true,
+    jars: ["java/oldlib.jar"],
+}
+`),
+	).RunTestWithBp(t, `
+		sdk {
+			name: "mysdk",
+			java_header_libs: ["myjavalib"],
+			previous_snapshot: "previous/mysdk.bp",
+		}
+
+		java_library {
+			name: "myjavalib",
+			srcs: ["Test.java"],
+			system_modules: "none",
+			sdk_version: "none",
+			compile_dex: true,
+			permitted_packages: ["pkg.myjavalib"],
+		}
+	`)
+
+	changelog := result.ModuleForTests("mysdk", "common_os").Output("mysdk-current-changelog.json")
+	android.AssertStringEquals(t, "changelog", `{
+  "added": [],
+  "removed": [
+    "oldlib"
+  ],
+  "version_changed": [],
+  "changed": []
+}`, android.ContentFromFileRuleForTests(t, changelog))
+}
+
+func TestSnapshotChangelogErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForSdkTestWithJava,
+		android.FixtureAddTextFile("previous/mysdk.bp", `java_import {`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`previous_snapshot: previous/mysdk.bp:1:`)).
+		RunTestWithBp(t, `
+		sdk {
+			name: "mysdk",
+			previous_snapshot: "previous/mysdk.bp",
+		}
+	`)
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -75,6 +76,9 @@ type sdk struct {
 
 	snapshotFile android.OptionalPath
 
+	// The changelog of the snapshot, only valid when previous_snapshot is set.
+	snapshotChangelogFile android.OptionalPath
+
 	// The builder, preserved for testing.
 	builderForTests *snapshotBuilder
 }
@@ -98,6 +102,12 @@ type sdkProperties struct {
 	//   dropped. Adding a rule to members that have //visibility:private will
 	//   cause the //visibility:private to be discarded.
 	Prebuilt_visibility []string
+
+	// Path, relative to the root of the source tree, to the Android.bp file of a previously
+	// generated snapshot of this sdk, e.g. prebuilts/module_sdk/art/current/sdk/Android.bp.
+	// When set, a <snapshot>-changelog.json file listing the members that were added, removed or
+	// changed since that snapshot is generated next to the snapshot zip.
+	Previous_snapshot *string
 }
 
 // sdk defines an SDK which is a logical group of modules (e.g. native libs, headers, java libs, etc.)
@@ -194,6 +204,12 @@ func (s *sdk) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		p := s.buildSnapshot(ctx, sdkVariants)
 		zip := ctx.InstallFile(android.PathForMainlineSdksInstall(ctx), p.Base(), p)
 		s.snapshotFile = android.OptionalPathForPath(zip)
+
+		if s.snapshotChangelogFile.Valid() {
+			changelog := s.snapshotChangelogFile.Path()
+			installed := ctx.InstallFile(android.PathForMainlineSdksInstall(ctx), changelog.Base(), changelog)
+			s.snapshotChangelogFile = android.OptionalPathForPath(installed)
+		}
 	}
 }
 
@@ -202,16 +218,21 @@ func (s *sdk) AndroidMkEntries() []android.AndroidMkEntries {
 		return []android.AndroidMkEntries{}
 	}
 
+	distFiles := android.Paths{s.snapshotFile.Path()}
+	if s.snapshotChangelogFile.Valid() {
+		distFiles = append(distFiles, s.snapshotChangelogFile.Path())
+	}
+
 	return []android.AndroidMkEntries{android.AndroidMkEntries{
 		Class:      "FAKE",
 		OutputFile: s.snapshotFile,
-		DistFiles:  android.MakeDefaultDistFiles(s.snapshotFile.Path()),
+		DistFiles:  android.MakeDefaultDistFiles(distFiles...),
 		Include:    "$(BUILD_PHONY_PACKAGE)",
 		ExtraFooters: []android.AndroidMkExtraFootersFunc{
 			func(w io.Writer, name, prefix, moduleDir string) {
 				// Allow the sdk to be built by simply passing its name on the command line.
 				fmt.Fprintln(w, ".PHONY:", s.Name())
-				fmt.Fprintln(w, s.Name()+":", s.snapshotFile.String())
+				fmt.Fprintln(w, s.Name()+":", strings.Join(distFiles.Strings(), " "))
 			},
 		},
 	}}
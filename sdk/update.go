@@ -483,6 +483,14 @@ be unnecessary as every module in the sdk already has its own licenses property.
 
 	bp.build(pctx, ctx, nil)
 
+	if s.properties.Previous_snapshot != nil {
+		changelogPath := fmt.Sprintf("%s%s-changelog.json", ctx.ModuleName(), snapshotZipFileSuffix)
+		changelog := android.PathForModuleOut(ctx, changelogPath)
+		if s.buildSnapshotChangelog(ctx, contents, changelog) {
+			s.snapshotChangelogFile = android.OptionalPathForPath(changelog)
+		}
+	}
+
 	// Copy the build number file into the snapshot.
 	builder.CopyToSnapshot(ctx.Config().BuildNumberFile(ctx), BUILD_NUMBER_FILE)
 
@@ -85,6 +85,12 @@ type shBinaryProperties struct {
 	// install symlinks to the binary
 	Symlinks []string `android:"arch_variant"`
 
+	// list of files, filegroup modules or outputs of other modules that provide data that should
+	// be installed alongside the binary or test. The files keep their path relative to the module
+	// directory, so the script can find them relative to its own location.  A module with multiple
+	// output files must select one with a tag, like ":module{tag}".
+	Data []string `android:"path,arch_variant"`
+
 	// Make this module available when building for ramdisk.
 	// On device without a dedicated recovery partition, the module is only
 	// available after switching root into
@@ -118,10 +124,6 @@ type TestProperties struct {
 	// installed with the module.
 	Test_config *string `android:"path,arch_variant"`
 
-	// list of files or filegroup modules that provide data that should be installed alongside
-	// the test.
-	Data []string `android:"path,arch_variant"`
-
 	// Add RootTargetPreparer to auto generated test config. This guarantees the test to run
 	// with root permission.
 	Require_root *bool
@@ -165,6 +167,8 @@ type ShBinary struct {
 	sourceFilePath android.Path
 	outputFilePath android.OutputPath
 	installedFile  android.InstallPath
+
+	data android.Paths
 }
 
 var _ android.HostToolProvider = (*ShBinary)(nil)
@@ -176,7 +180,6 @@ type ShTest struct {
 
 	installDir android.InstallPath
 
-	data       android.Paths
 	testConfig android.Path
 
 	dataModules map[string]android.Path
@@ -259,6 +262,8 @@ func (s *ShBinary) generateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	s.outputFilePath = android.PathForModuleOut(ctx, filename).OutputPath
 
+	s.data = android.PathsForModuleData(ctx, s.properties.Data)
+
 	// This ensures that outputFilePath has the correct name for others to
 	// use, as the source file may have a different name.
 	ctx.Build(pctx, android.BuildParams{
@@ -274,6 +279,11 @@ func (s *ShBinary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	if !s.Installable() {
 		s.SkipInstall()
 	}
+	// The data is installed before the binary, as the last installed file is the one that Make
+	// considers to be the module.
+	for _, d := range s.data {
+		ctx.InstallFile(installDir, d.Rel(), d)
+	}
 	s.installedFile = ctx.InstallExecutable(installDir, s.outputFilePath.Base(), s.outputFilePath)
 	for _, symlink := range s.Symlinks() {
 		ctx.InstallSymlink(installDir, symlink, s.installedFile)
@@ -367,8 +377,6 @@ func (s *ShTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	s.installedFile = ctx.InstallExecutable(s.installDir, s.outputFilePath.Base(), s.outputFilePath)
 
-	s.data = android.PathsForModuleSrc(ctx, s.testProperties.Data)
-
 	var configs []tradefed.Config
 	if Bool(s.testProperties.Require_root) {
 		configs = append(configs, tradefed.Object{"target_preparer", "com.android.tradefed.targetprep.RootTargetPreparer", nil})
@@ -255,3 +255,38 @@ func TestShTestHost_dataDeviceModulesAutogenTradefedConfig(t *testing.T) {
 		t.Errorf("foo extraConfings %v does not contain %q", autogen.Args["extraConfigs"], expectedBinAutogenConfig)
 	}
 }
+
+func TestShBinaryData(t *testing.T) {
+	result := prepareForShTest.RunTestWithBp(t, `
+		sh_binary {
+			name: "foo",
+			src: "test.sh",
+			data: [
+				"testdata/data1",
+				"testdata/sub/data2",
+			],
+		}
+	`)
+
+	mod := result.ModuleForTests("foo", "android_arm64_armv8-a").Module()
+	android.AssertPathsRelativeToTopEquals(t, "installed files", []string{
+		"out/soong/target/product/test_device/system/bin/testdata/data1",
+		"out/soong/target/product/test_device/system/bin/testdata/sub/data2",
+		"out/soong/target/product/test_device/system/bin/foo",
+	}, mod.FilesToInstall().Paths())
+}
+
+func TestShBinaryDataMissing(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForShTest,
+		android.PrepareForTestDisallowNonExistentPaths,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`module source path "testdata/missing" does not exist`)).
+		RunTestWithBp(t, `
+		sh_binary {
+			name: "foo",
+			src: "test.sh",
+			data: ["testdata/missing"],
+		}
+	`)
+}
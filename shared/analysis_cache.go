@@ -0,0 +1,35 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+// AnalysisCacheManifest is written by soong_build when the experimental analysis cache is enabled
+// in soong_ui. It records what soong_build depended on and produced that isn't recorded in its
+// depfile or by its well known outputs, so that soong_ui can check whether a cached result is still
+// valid and restore all of it.
+type AnalysisCacheManifest struct {
+	// The globs that soong_build evaluated, along with their results.
+	Globs []AnalysisCacheGlob
+
+	// The files that soong_build wrote directly instead of through a build rule, relative to the
+	// top of the source tree.
+	Outputs []string
+}
+
+// AnalysisCacheGlob is a glob evaluated by soong_build.
+type AnalysisCacheGlob struct {
+	Pattern  string
+	Excludes []string
+	Matches  []string
+}
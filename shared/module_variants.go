@@ -0,0 +1,35 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+// ModuleVariant describes a single variant of a module. A list of them is written by soong_build
+// when soong_ui is run with --variants, so that soong_ui can print them.
+type ModuleVariant struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Type    string `json:"type"`
+	Variant string `json:"variant"`
+
+	// The value of each variation dimension of the variant, for example "arch" or "image".
+	Variations map[string]string `json:"variations"`
+
+	// The values of properties common to all modules that commonly differ between variants.
+	Properties map[string]string `json:"properties"`
+
+	// The values of all the properties of the variant by their names in Android.bp files, after
+	// defaults, arch and product variable properties were merged by the mutators.  It is only set
+	// when soong_ui is run with --properties.
+	ResolvedProperties map[string]interface{} `json:"resolved_properties,omitempty"`
+}
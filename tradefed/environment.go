@@ -0,0 +1,71 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tradefed
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"android/soong/android"
+)
+
+var environmentKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvironmentOptions returns the test config options that make the test harness set the
+// environment variables in env, a list of "KEY=VALUE" strings from the named property.  Values may
+// reference the $(MODULE_NAME), $(ARCH) and $(PLATFORM_SDK_VERSION) build variables, and "$$" is
+// a literal "$".  Errors for malformed or duplicated entries are reported on the property.
+func EnvironmentOptions(ctx android.ModuleContext, property string, env []string) []Config {
+	vars := map[string]string{
+		"MODULE_NAME":          ctx.ModuleName(),
+		"ARCH":                 ctx.Arch().ArchType.Name,
+		"PLATFORM_SDK_VERSION": ctx.Config().PlatformSdkVersion().String(),
+	}
+
+	var configs []Config
+	seen := make(map[string]bool)
+	for _, entry := range env {
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 {
+			ctx.PropertyErrorf(property, "expected KEY=VALUE, got %q", entry)
+			continue
+		}
+		key, value := split[0], split[1]
+		if !environmentKeyRegexp.MatchString(key) {
+			ctx.PropertyErrorf(property, "invalid environment variable name %q", key)
+			continue
+		}
+		if seen[key] {
+			ctx.PropertyErrorf(property, "duplicate environment variable %q", key)
+			continue
+		}
+		seen[key] = true
+
+		expanded, err := android.Expand(value, func(name string) (string, error) {
+			if v, ok := vars[name]; ok {
+				return v, nil
+			}
+			return "", fmt.Errorf("unknown variable $(%s)", name)
+		})
+		if err != nil {
+			ctx.PropertyErrorf(property, "%s: %s", key, err)
+			continue
+		}
+		configs = append(configs, Option{Name: "environment", Key: key, Value: html.EscapeString(expanded)})
+	}
+	return configs
+}
@@ -0,0 +1,490 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements the experimental analysis cache, which stores the results of soong_build
+// so that they can be restored instead of running soong_build again when none of its inputs have
+// changed, e.g. for a clean build of a checkout that has already been built on another machine.
+//
+// A cache entry is keyed by a hash of the soong_build binary, the bootstrap Ninja file (which
+// contains the soong_build command line), the list of Android.bp files and the product
+// configuration. An entry that is found is only used if, in addition:
+// * every file in the depfile of build.ninja, including all the Android.bp files and the files
+//   written to the output directory by Kati, still has the same contents,
+// * the environment variables read by soong_build still have the same values, and
+// * the globs evaluated by soong_build still have the same results.
+//
+// The cache is only consulted when the bootstrap Ninja file would run soong_build, so incremental
+// builds where nothing has changed don't pay for checking the entry.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/makedeps"
+	"android/soong/shared"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+const (
+	// The location of the analysis cache, either a directory or a URL whose scheme is one of
+	// analysisCacheBackends, e.g. file:///path/to/cache.
+	analysisCacheEnvVar = "SOONG_ANALYSIS_CACHE"
+
+	// Set to "disabled" to not use the analysis cache even if SOONG_ANALYSIS_CACHE is set, or to
+	// "validate" to always run soong_build and fail the build if its results differ from those of
+	// a valid cache entry.
+	analysisCacheModeEnvVar = "SOONG_ANALYSIS_CACHE_MODE"
+
+	// The manifest written by soong_build, see shared.AnalysisCacheManifest.
+	analysisCacheManifestFile = "soong.analysis_cache.json"
+
+	// The hashes of the source files that soong_build read, stored in each cache entry.
+	analysisCacheInputsFile = "soong.analysis_cache.inputs.json"
+)
+
+// analysisCacheBackend stores the analysis cache entries. An entry is a set of files relative to
+// the output directory.
+type analysisCacheBackend interface {
+	// fetch copies the files of the entry for key into dir. It returns false if there is no such
+	// entry.
+	fetch(key string, dir string) (bool, error)
+
+	// store adds an entry for key that contains files, which are relative to dir. It does nothing if
+	// there is already an entry for key.
+	store(key string, dir string, files []string) error
+}
+
+// analysisCacheBackends maps the URL schemes supported in SOONG_ANALYSIS_CACHE to the functions
+// that create their backends.
+var analysisCacheBackends = map[string]func(location string) analysisCacheBackend{
+	"file": newLocalAnalysisCacheBackend,
+}
+
+// localAnalysisCacheBackend stores each entry in a directory named after its key.
+type localAnalysisCacheBackend struct {
+	dir string
+}
+
+func newLocalAnalysisCacheBackend(location string) analysisCacheBackend {
+	return &localAnalysisCacheBackend{dir: location}
+}
+
+func (l *localAnalysisCacheBackend) fetch(key string, dir string) (bool, error) {
+	entry := filepath.Join(l.dir, key)
+	if _, err := os.Stat(entry); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	err := filepath.Walk(entry, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(entry, path)
+		if err != nil {
+			return err
+		}
+		return copyFileMakingDirs(path, filepath.Join(dir, rel))
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *localAnalysisCacheBackend) store(key string, dir string, files []string) error {
+	entry := filepath.Join(l.dir, key)
+	if _, err := os.Stat(entry); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(l.dir, 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(l.dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, file := range files {
+		if err := copyFileMakingDirs(filepath.Join(dir, file), filepath.Join(tmp, file)); err != nil {
+			return err
+		}
+	}
+
+	// Move the complete entry into place so that a concurrent fetch never sees a partial entry.
+	if err := os.Rename(tmp, entry); err != nil {
+		if _, statErr := os.Stat(entry); statErr == nil {
+			// Another build stored the same entry first.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func copyFileMakingDirs(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+	_, err := copyFile(src, dst)
+	return err
+}
+
+type analysisCache struct {
+	backend  analysisCacheBackend
+	validate bool
+	key      string
+}
+
+// analysisCacheEnabled returns true if SOONG_ANALYSIS_CACHE is set and the cache has not been
+// disabled with SOONG_ANALYSIS_CACHE_MODE.
+func analysisCacheEnabled(config Config) bool {
+	location, _ := config.Environment().Get(analysisCacheEnvVar)
+	mode, _ := config.Environment().Get(analysisCacheModeEnvVar)
+	return location != "" && mode != "disabled"
+}
+
+// newAnalysisCache returns the analysis cache to use for this build, or nil if it is not enabled
+// or not supported for the kind of build.
+func newAnalysisCache(ctx Context, config Config) *analysisCache {
+	if !analysisCacheEnabled(config) {
+		return nil
+	}
+
+	mode, _ := config.Environment().Get(analysisCacheModeEnvVar)
+	if mode != "" && mode != "validate" {
+		ctx.Fatalf("Unknown %s value %q, expected \"disabled\" or \"validate\"", analysisCacheModeEnvVar, mode)
+	}
+
+	// Only plain soong_build runs are cached.
+	if config.bazelBuildMode() == mixedBuild || config.Bp2Build() || config.JsonModuleGraph() ||
+		config.Queryview() || config.SoongDocs() || config.ModuleVariants() != "" ||
+		!config.SoongBuildInvocationNeeded() || shared.IsDebugging() {
+		return nil
+	}
+
+	location, _ := config.Environment().Get(analysisCacheEnvVar)
+	scheme := "file"
+	if i := strings.Index(location, "://"); i != -1 {
+		scheme, location = location[:i], location[i+len("://"):]
+	}
+	newBackend, ok := analysisCacheBackends[scheme]
+	if !ok {
+		ctx.Fatalf("Unsupported %s scheme %q", analysisCacheEnvVar, scheme)
+	}
+
+	return &analysisCache{
+		backend:  newBackend(location),
+		validate: mode == "validate",
+	}
+}
+
+// soongBuildNeeded returns true if the bootstrap Ninja file would run soong_build to regenerate
+// build.ninja. The soong_build binary must already be up to date.
+func (c *analysisCache) soongBuildNeeded(ctx Context, config Config) bool {
+	if _, err := os.Stat(config.SoongNinjaFile()); err != nil {
+		return true
+	}
+
+	cmd := Command(ctx, config, "soong analysis cache dry run", config.PrebuiltBuildTool("ninja"),
+		"-n",
+		"-o", "usesphonyoutputs=yes",
+		"-f", filepath.Join(config.SoongOutDir(), "bootstrap.ninja"),
+		config.SoongNinjaFile())
+	var ninjaEnv Environment
+	ninjaEnv.Set("TOP", os.Getenv("TOP"))
+	cmd.Environment = &ninjaEnv
+	cmd.Sandbox = soongSandbox
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		ctx.Verbosef("Failed to check whether soong_build needs to run: %s\n%s", err, output)
+		return true
+	}
+	return !bytes.Contains(output, []byte("ninja: no work to do."))
+}
+
+// soongBuildBinary returns the path of the soong_build binary, which has to be built before the
+// cache key can be computed.
+func (c *analysisCache) soongBuildBinary(config Config) string {
+	return filepath.Join(config.HostToolDir(), "soong_build")
+}
+
+// computeKey sets the key of the cache entry for this build.
+func (c *analysisCache) computeKey(ctx Context, config Config) {
+	h := sha256.New()
+	for _, input := range []struct{ name, path string }{
+		{"soong_build", c.soongBuildBinary(config)},
+		{"bootstrap.ninja", shared.JoinPath(config.SoongOutDir(), "bootstrap.ninja")},
+		{"Android.bp.list", filepath.Join(config.FileListDir(), "Android.bp.list")},
+		{"soong.variables", filepath.Join(config.SoongOutDir(), "soong.variables")},
+	} {
+		hash, err := hashFile(input.path)
+		if err != nil {
+			ctx.Fatalf("Failed to compute the analysis cache key: %s", err)
+		}
+		fmt.Fprintf(h, "%s %s\n", input.name, hash)
+	}
+	c.key = hex.EncodeToString(h.Sum(nil))
+}
+
+// outputs returns the files of a cache entry, relative to the output directory.
+func (c *analysisCache) outputs(ctx Context, config Config, manifest *shared.AnalysisCacheManifest) []string {
+	files := []string{
+		config.SoongNinjaFile(),
+		config.SoongNinjaFile() + ".d",
+		config.UsedEnvFile(soongBuildTag),
+		config.NamedGlobFile(soongBuildTag),
+		filepath.Join(config.SoongOutDir(), analysisCacheManifestFile),
+		filepath.Join(config.SoongOutDir(), analysisCacheInputsFile),
+	}
+	for _, optional := range []string{config.SoongAndroidMk(), config.SoongMakeVarsMk(), config.DependencyGraphFile()} {
+		if _, err := os.Stat(optional); err == nil {
+			files = append(files, optional)
+		}
+	}
+	files = append(files, manifest.Outputs...)
+
+	outDir := absPath(ctx, config.OutDir())
+	var rels []string
+	for _, file := range files {
+		rel, err := filepath.Rel(outDir, absPath(ctx, file))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			ctx.Fatalf("Analysis cache output %s is not in the output directory %s", file, outDir)
+		}
+		rels = append(rels, rel)
+	}
+	return rels
+}
+
+// invalidReason returns why the entry that has been fetched into dir can't be used for this build,
+// or "" if it can.
+func (c *analysisCache) invalidReason(ctx Context, config Config, env *Environment, dir string) string {
+	rel := func(path string) string {
+		r, err := filepath.Rel(absPath(ctx, config.OutDir()), absPath(ctx, path))
+		if err != nil {
+			ctx.Fatalf("Failed to make %s relative to the output directory: %s", path, err)
+		}
+		return filepath.Join(dir, r)
+	}
+
+	var inputs map[string]string
+	if err := readJson(rel(filepath.Join(config.SoongOutDir(), analysisCacheInputsFile)), &inputs); err != nil {
+		return err.Error()
+	}
+	var sortedInputs []string
+	for input := range inputs {
+		sortedInputs = append(sortedInputs, input)
+	}
+	sort.Strings(sortedInputs)
+	for _, input := range sortedInputs {
+		if hash, err := hashFile(input); err != nil || hash != inputs[input] {
+			return fmt.Sprintf("%s has changed", input)
+		}
+	}
+
+	getenv := func(k string) string {
+		v, _ := env.Get(k)
+		return v
+	}
+	if stale, err := shared.StaleEnvFile(rel(config.UsedEnvFile(soongBuildTag)), getenv); err != nil {
+		return err.Error()
+	} else if stale {
+		return "the environment has changed"
+	}
+
+	var manifest shared.AnalysisCacheManifest
+	if err := readJson(rel(filepath.Join(config.SoongOutDir(), analysisCacheManifestFile)), &manifest); err != nil {
+		return err.Error()
+	}
+	for _, glob := range manifest.Globs {
+		result, err := pathtools.Glob(glob.Pattern, glob.Excludes, pathtools.FollowSymlinks)
+		if err != nil || strings.Join(result.Matches, "\n") != strings.Join(glob.Matches, "\n") {
+			return fmt.Sprintf("the results of the glob %q have changed", glob.Pattern)
+		}
+	}
+
+	return ""
+}
+
+// fetchValid fetches the entry for this build into a new temporary directory. It returns "" if
+// there is no valid entry, in which case there is nothing to clean up.
+func (c *analysisCache) fetchValid(ctx Context, config Config, env *Environment) string {
+	dir, err := ioutil.TempDir(config.SoongOutDir(), ".analysis_cache")
+	if err != nil {
+		ctx.Fatalf("Failed to create a directory for the analysis cache: %s", err)
+	}
+
+	if found, err := c.backend.fetch(c.key, dir); err != nil {
+		ctx.Println("Failed to fetch analysis cache entry", c.key+":", err)
+	} else if !found {
+		ctx.Verbosef("No analysis cache entry %s", c.key)
+	} else if reason := c.invalidReason(ctx, config, env, dir); reason != "" {
+		ctx.Verbosef("Not using analysis cache entry %s: %s", c.key, reason)
+	} else {
+		return dir
+	}
+
+	os.RemoveAll(dir)
+	return ""
+}
+
+// restore replaces the outputs of soong_build with those of a valid cache entry. It returns false
+// if there is no valid entry, in which case soong_build needs to run.
+func (c *analysisCache) restore(ctx Context, config Config, env *Environment) bool {
+	dir := c.fetchValid(ctx, config, env)
+	if dir == "" {
+		return false
+	}
+	defer os.RemoveAll(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(config.OutDir(), rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		return os.Rename(path, dst)
+	})
+	if err != nil {
+		// Some of the outputs may have been replaced, make sure soong_build runs again.
+		os.Remove(config.SoongNinjaFile())
+		ctx.Fatalf("Failed to restore analysis cache entry %s: %s", c.key, err)
+	}
+
+	ctx.Verbosef("Restored analysis cache entry %s", c.key)
+	return true
+}
+
+// check fails the build if there is a valid cache entry whose files differ from the outputs of
+// soong_build.
+func (c *analysisCache) check(ctx Context, config Config, env *Environment) {
+	dir := c.fetchValid(ctx, config, env)
+	if dir == "" {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		cached, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		actual, err := ioutil.ReadFile(filepath.Join(config.OutDir(), rel))
+		if err != nil || !bytes.Equal(cached, actual) {
+			return fmt.Errorf("%s differs from the output of soong_build", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.Fatalf("Analysis cache entry %s is invalid: %s", c.key, err)
+	}
+}
+
+// store adds the outputs of soong_build to the cache.
+func (c *analysisCache) store(ctx Context, config Config) {
+	var manifest shared.AnalysisCacheManifest
+	if err := readJson(filepath.Join(config.SoongOutDir(), analysisCacheManifestFile), &manifest); err != nil {
+		ctx.Println("Not storing analysis cache entry:", err)
+		return
+	}
+
+	depFile := config.SoongNinjaFile() + ".d"
+	data, err := ioutil.ReadFile(depFile)
+	if err != nil {
+		ctx.Println("Not storing analysis cache entry:", err)
+		return
+	}
+	deps, err := makedeps.Parse(depFile, bytes.NewBuffer(data))
+	if err != nil {
+		ctx.Println("Not storing analysis cache entry:", err)
+		return
+	}
+
+	// The inputs in the output directory are hashed too, as some of them, e.g. dexpreopt.config,
+	// are written by Kati and are not part of the key.
+	inputs := make(map[string]string)
+	for _, input := range deps.Inputs {
+		hash, err := hashFile(input)
+		if err != nil {
+			ctx.Println("Not storing analysis cache entry:", err)
+			return
+		}
+		inputs[input] = hash
+	}
+	inputsData, err := json.Marshal(inputs)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(config.SoongOutDir(), analysisCacheInputsFile), inputsData, 0666)
+	}
+	if err != nil {
+		ctx.Println("Not storing analysis cache entry:", err)
+		return
+	}
+
+	if err := c.backend.store(c.key, config.OutDir(), c.outputs(ctx, config, &manifest)); err != nil {
+		ctx.Println("Failed to store analysis cache entry", c.key+":", err)
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readJson(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	return nil
+}
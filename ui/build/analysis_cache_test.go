@@ -0,0 +1,81 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalAnalysisCacheBackend(t *testing.T) {
+	cacheDir := t.TempDir()
+	outDir := t.TempDir()
+	backend := newLocalAnalysisCacheBackend(cacheDir)
+
+	files := map[string]string{
+		"soong/build.ninja":   "build.ninja contents",
+		"soong/build.ninja.d": "build.ninja.d contents",
+	}
+	for name, contents := range files {
+		path := filepath.Join(outDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if found, err := backend.fetch("key", t.TempDir()); err != nil || found {
+		t.Fatalf("fetch of a missing entry returned %t, %v; expected false, nil", found, err)
+	}
+
+	if err := backend.store("key", outDir, []string{"soong/build.ninja", "soong/build.ninja.d"}); err != nil {
+		t.Fatalf("store failed: %s", err)
+	}
+
+	// Storing an existing entry does nothing.
+	if err := ioutil.WriteFile(filepath.Join(outDir, "soong/build.ninja"), []byte("changed"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.store("key", outDir, []string{"soong/build.ninja"}); err != nil {
+		t.Fatalf("store of an existing entry failed: %s", err)
+	}
+
+	fetchDir := t.TempDir()
+	if found, err := backend.fetch("key", fetchDir); err != nil || !found {
+		t.Fatalf("fetch returned %t, %v; expected true, nil", found, err)
+	}
+	for name, contents := range files {
+		got, err := ioutil.ReadFile(filepath.Join(fetchDir, name))
+		if err != nil {
+			t.Fatalf("fetch did not restore %s: %s", name, err)
+		}
+		if string(got) != contents {
+			t.Errorf("expected %s to contain %q, got %q", name, contents, string(got))
+		}
+	}
+
+	// No temporary directories are left behind.
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "key" {
+		t.Errorf("expected the cache directory to only contain the entry, got %v", entries)
+	}
+}
@@ -65,6 +65,9 @@ builddir = {{.OutDir}}
 {{end -}}
 pool highmem_pool
  depth = {{.HighmemParallel}}
+{{range .NinjaPools}}pool {{.Name}}
+ depth = {{.Depth}}
+{{end -}}
 {{if and (not .SkipKatiNinja) .HasKatiSuffix}}subninja {{.KatiBuildNinjaFile}}
 subninja {{.KatiPackageNinjaFile}}
 {{end -}}
@@ -183,6 +186,34 @@ func checkRAM(ctx Context, config Config) {
 	}
 }
 
+// checkDiskSpace aborts the build if the filesystem hosting the out directory
+// has less free space than config.MinFreeDiskSpace(), rather than letting the
+// build fail later with confusing errors.
+func checkDiskSpace(ctx Context, config Config) {
+	minFree := config.MinFreeDiskSpace()
+	if minFree == 0 {
+		return
+	}
+
+	free, err := freeSpaceForPath(config.OutDir())
+	if err != nil {
+		ctx.Verbosef("Failed to get free disk space of %s: %v", config.OutDir(), err)
+		return
+	}
+	ctx.Verbosef("Free disk space: %.3vGB", float64(free)/(1024*1024*1024))
+
+	if free < minFree {
+		ctx.Println("************************************************************")
+		ctx.Printf("Only %.3vGB of disk space is free on the filesystem hosting\n", float64(free)/(1024*1024*1024))
+		ctx.Printf("%s, but at least %.3vGB are required.\n", config.OutDir(), float64(minFree)/(1024*1024*1024))
+		ctx.Println("")
+		ctx.Println("Free up some disk space, or set BUILD_MIN_FREE_DISK_SPACE_GB")
+		ctx.Println("to lower the limit (0 disables this check).")
+		ctx.Println("************************************************************")
+		ctx.Fatalln("Not enough free disk space")
+	}
+}
+
 // Build the tree. The 'what' argument can be used to chose which components of
 // the build to run, via checking various bitmasks.
 func Build(ctx Context, config Config) {
@@ -215,6 +246,8 @@ func Build(ctx Context, config Config) {
 
 	SetupOutDir(ctx, config)
 
+	checkDiskSpace(ctx, config)
+
 	// checkCaseSensitivity issues a warning if a case-insensitive file system is being used.
 	checkCaseSensitivity(ctx, config)
 
@@ -294,6 +327,12 @@ func Build(ctx Context, config Config) {
 		runSoong(ctx, config)
 	}
 
+	if config.ModuleVariants() != "" {
+		// --variants and --properties only query soong_build, don't build anything.
+		printModuleVariants(ctx, config)
+		return
+	}
+
 	if what&RunKati != 0 {
 		genKatiSuffix(ctx, config)
 		runKatiCleanSpec(ctx, config)
@@ -312,6 +351,18 @@ func Build(ctx Context, config Config) {
 	// Write combined ninja file
 	createCombinedBuildNinjaFile(ctx, config)
 
+	if config.ExplainOutput() != "" {
+		// --explain only reports why the output would be rebuilt, don't build anything.
+		explainRebuild(ctx, config)
+		return
+	}
+
+	if config.NinjaSubgraph() != "" {
+		// --ninja-subgraph only writes the ninja file of the target, don't build anything.
+		writeNinjaSubgraph(ctx, config)
+		return
+	}
+
 	distGzipFile(ctx, config, config.CombinedNinjaFile())
 
 	if what&RunBuildTests != 0 {
@@ -323,7 +374,9 @@ func Build(ctx Context, config Config) {
 			installCleanIfNecessary(ctx, config)
 		}
 
+		warnings := startWarningCounter(ctx, config)
 		runNinjaForBuild(ctx, config)
+		checkWarningBudget(ctx, config, warnings)
 	}
 
 	// Currently, using Bazel requires Kati and Soong to run first, so check whether to run Bazel last.
@@ -0,0 +1,117 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// When SOONG_COMPRESS_JSON_OUTPUTS=true, each of the JSON files returned by compressibleJsonOutputs
+// is replaced by a gzipped copy at the same path with a ".gz" suffix after soong_build runs, for
+// example out/soong/development/ide/compdb/compile_commands.json.gz. Code in soong_ui that reads
+// them must use readMaybeCompressedFile, other tools have to decompress the ".gz" file.
+//
+// Some large files are deliberately left alone:
+//   - .ninja_log, as Ninja reads and appends to it on every build.
+//   - module-graph.json, as it is the output Ninja checks to decide whether to rerun soong_build.
+//   - the compilation database when SOONG_LINK_COMPDB_TO is set, as the IDEs that read it through
+//     the link can't decompress it.
+func compressibleJsonOutputs(config Config) []string {
+	outputs := []string{
+		config.ModuleActionsFile(),
+		config.DependencyGraphFile(),
+		config.ModuleVariantsFile(),
+	}
+	if _, linked := config.Environment().Get("SOONG_LINK_COMPDB_TO"); !linked {
+		outputs = append(outputs, config.CompdbFile())
+	}
+	return outputs
+}
+
+// compressJsonOutputs gzips the JSON files written by soong_build that exist.
+func compressJsonOutputs(ctx Context, config Config) {
+	for _, path := range compressibleJsonOutputs(config) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := gzipFileInPlace(path); err != nil {
+			ctx.Fatalf("Failed to compress %s: %s", path, err)
+		}
+	}
+}
+
+// gzipFileInPlace replaces path with a gzipped copy at path + ".gz".
+func gzipFileInPlace(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Write to a temporary file first so that an interrupted build never leaves a truncated
+	// ".gz" file behind.
+	out, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".gz.")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(out.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// readMaybeCompressedFile returns the contents of path, or the decompressed contents of
+// path + ".gz" if path doesn't exist because it was compressed by compressJsonOutputs.
+func readMaybeCompressedFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if !os.IsNotExist(err) {
+		return data, err
+	}
+
+	f, gzErr := os.Open(path + ".gz")
+	if os.IsNotExist(gzErr) {
+		// Report the uncompressed path, it is the one callers know about.
+		return nil, err
+	} else if gzErr != nil {
+		return nil, gzErr
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %s", f.Name(), err)
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressedJsonOutputs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compile_commands.json")
+	contents := `[{"directory": "/src", "file": "foo.cpp"}]`
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipFileInPlace(path); err != nil {
+		t.Fatalf("failed to compress %s: %s", path, err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", path, err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 || entries[0].Name() != "compile_commands.json.gz" {
+		t.Errorf("expected only compile_commands.json.gz in %s, got %v", dir, entries)
+	}
+
+	got, err := readMaybeCompressedFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", path, err)
+	}
+	if string(got) != contents {
+		t.Errorf("got %q, want %q", string(got), contents)
+	}
+
+	// A newer uncompressed file is preferred.
+	if err := ioutil.WriteFile(path, []byte("[]"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readMaybeCompressedFile(path); err != nil || string(got) != "[]" {
+		t.Errorf("got %q, %v, want %q", string(got), err, "[]")
+	}
+}
+
+func TestReadMaybeCompressedFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := readMaybeCompressedFile(path); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
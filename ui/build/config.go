@@ -40,6 +40,12 @@ const (
 
 	configFetcher         = "vendor/google/tools/soong/expconfigfetcher"
 	envConfigFetchTimeout = 10 * time.Second
+
+	defaultMetricsUploadRetries    = 3
+	defaultMetricsUploadRetryDelay = time.Second
+	defaultMetricsUploadTimeout    = 5 * time.Minute
+
+	defaultMinFreeDiskSpaceGB = 5
 )
 
 type Config struct{ *configImpl }
@@ -60,10 +66,20 @@ type configImpl struct {
 	checkbuild      bool
 	dist            bool
 	jsonModuleGraph bool
+	dependencyGraph bool
+	profileSoong    bool
+	singleThreaded  bool
+	moduleVariants  string
+	explainOutput   string
+	ninjaSubgraph   string
+	maxDuration     time.Duration
+	buildDeadline   time.Time
+	variantsJson    bool
 	bp2build        bool
 	queryview       bool
 	reportMkMetrics bool // Collect and report mk2bp migration progress metrics.
 	soongDocs       bool
+	checkOnly       bool
 	skipConfig      bool
 	skipKati        bool
 	skipKatiNinja   bool
@@ -71,6 +87,17 @@ type configImpl struct {
 	skipNinja       bool
 	skipSoongTests  bool
 
+	// Set by --properties=<module>[:<variant>], the variant selected by modulePropertiesFilter, all
+	// of them if it is empty.
+	moduleProperties       bool
+	modulePropertiesFilter string
+
+	// Set by --no-host-tool-cache to ignore SOONG_HOST_TOOL_CACHE for this build.
+	noHostToolCache bool
+
+	// Set by --lint-empty-globs to make soong_build warn about globs that match no files.
+	lintEmptyGlobs bool
+
 	// From the product config
 	katiArgs        []string
 	ninjaArgs       []string
@@ -82,6 +109,10 @@ type configImpl struct {
 	// Autodetected
 	totalRAM uint64
 
+	// The minimum number of bytes that must be free on the filesystem hosting
+	// the out directory for the build to start. Zero disables the check.
+	minFreeDiskSpace uint64
+
 	brokenDupRules     bool
 	brokenUsesNetwork  bool
 	brokenNinjaEnvVars []string
@@ -98,12 +129,53 @@ type configImpl struct {
 	emptyNinjaFile bool
 
 	metricsUploader string
+
+	// Additional metrics uploaders that are run with the same metrics files
+	// as metricsUploader.
+	metricsUploaders []string
+
+	// Number of times a failed metrics upload is retried and the delay
+	// before the first retry. The delay doubles after every attempt.
+	metricsUploadRetries    int
+	metricsUploadRetryDelay time.Duration
+
+	// Whether large metrics files are gzipped before they are uploaded.
+	compressMetrics bool
+
+	// Glob patterns of environment variable names whose values are redacted
+	// before they are recorded in the metrics.
+	metricsRedactPatterns []string
+
+	// Log the metrics uploader command instead of executing it.
+	metricsUploadDryRun bool
+
+	// Whether symlinks are followed when looking for metrics files to upload.
+	metricsFollowSymlinks bool
+
+	// Whether the large JSON files written by soong_build are gzipped, see compressJsonOutputs.
+	compressJsonOutputs bool
+
+	// How long the metrics uploader may run before it is killed. Zero means
+	// no time limit.
+	metricsUploadTimeout time.Duration
 }
 
 const srcDirFileCheck = "build/soong/root.bp"
 
 var buildFiles = []string{"Android.mk", "Android.bp"}
 
+// defaultMetricsRedactPatterns match the names of environment variables that
+// commonly hold secrets or identify the user.
+var defaultMetricsRedactPatterns = []string{
+	"*_TOKEN",
+	"*_SECRET",
+	"*PASSWORD*",
+	"*CREDENTIAL*",
+	"*SIGNING*",
+	"LOGNAME",
+	"USER",
+}
+
 type BuildAction uint
 
 const (
@@ -245,6 +317,11 @@ func NewConfig(ctx Context, args ...string) Config {
 
 	ret.totalRAM = detectTotalRAM(ctx)
 
+	ret.minFreeDiskSpace = defaultMinFreeDiskSpaceGB * 1024 * 1024 * 1024
+	if minFreeGB, ok := ret.environ.GetInt("BUILD_MIN_FREE_DISK_SPACE_GB"); ok && minFreeGB >= 0 {
+		ret.minFreeDiskSpace = uint64(minFreeGB) * 1024 * 1024 * 1024
+	}
+
 	ret.parseArgs(ctx, args)
 
 	// Make sure OUT_DIR is set appropriately
@@ -366,6 +443,48 @@ func NewConfig(ctx Context, args ...string) Config {
 	}
 
 	ret.metricsUploader = GetMetricsUploader(srcDir, ret.environ)
+	ret.metricsUploaders = GetMetricsUploaders(srcDir, ret.environ)
+
+	ret.metricsUploadRetries = defaultMetricsUploadRetries
+	if retries, ok := ret.environ.GetInt("METRICS_UPLOADER_RETRIES"); ok && retries >= 0 {
+		ret.metricsUploadRetries = retries
+	}
+	ret.metricsUploadRetryDelay = defaultMetricsUploadRetryDelay
+	if delayMs, ok := ret.environ.GetInt("METRICS_UPLOADER_RETRY_DELAY_MS"); ok && delayMs >= 0 {
+		ret.metricsUploadRetryDelay = time.Duration(delayMs) * time.Millisecond
+	}
+	ret.compressMetrics = ret.environ.IsEnvTrue("METRICS_UPLOADER_COMPRESS")
+	ret.metricsUploadDryRun = ret.environ.IsEnvTrue("METRICS_UPLOADER_DRY_RUN")
+	ret.metricsFollowSymlinks = ret.environ.IsEnvTrue("METRICS_UPLOADER_FOLLOW_SYMLINKS")
+
+	ret.metricsUploadTimeout = defaultMetricsUploadTimeout
+	if timeoutText, ok := ret.environ.Get("METRICS_UPLOADER_TIMEOUT"); ok {
+		// For example, "1m"
+		if timeout, err := time.ParseDuration(timeoutText); err == nil && timeout >= 0 {
+			ret.metricsUploadTimeout = timeout
+		}
+	}
+
+	ret.metricsRedactPatterns = MetricsRedactPatternsFromEnv(ret.environ)
+
+	ret.compressJsonOutputs = ret.environ.IsEnvTrue("SOONG_COMPRESS_JSON_OUTPUTS")
+
+	if ret.lintEmptyGlobs {
+		ret.environ.Set("SOONG_LINT_EMPTY_GLOBS", "true")
+	}
+
+	if cacheDir, ok := ret.environ.Get("SOONG_HOST_TOOL_CACHE"); ok {
+		if ret.noHostToolCache || cacheDir == "" {
+			ret.environ.Unset("SOONG_HOST_TOOL_CACHE")
+		} else {
+			// The cache is shared between output directories, so it can't live in one of them.
+			cacheDir = absPath(ctx, cacheDir)
+			if rel, err := filepath.Rel(ret.OutDir(), cacheDir); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+				ctx.Fatalf("SOONG_HOST_TOOL_CACHE (%s) must not be inside the output directory (%s)", cacheDir, ret.OutDir())
+			}
+			ret.environ.Set("SOONG_HOST_TOOL_CACHE", cacheDir)
+		}
+	}
 
 	if outDir := ret.OutDir(); strings.ContainsRune(outDir, ' ') {
 		ctx.Println("The absolute path of your output directory ($OUT_DIR) contains a space character:")
@@ -735,8 +854,102 @@ func (c *configImpl) parseArgs(ctx Context, args []string) {
 			c.skipConfig = true
 		} else if arg == "--skip-soong-tests" {
 			c.skipSoongTests = true
+		} else if arg == "--no-host-tool-cache" {
+			c.noHostToolCache = true
+		} else if arg == "--lint-empty-globs" {
+			c.lintEmptyGlobs = true
+		} else if arg == "--check-only" {
+			c.checkOnly = true
 		} else if arg == "--mk-metrics" {
 			c.reportMkMetrics = true
+		} else if arg == "--dependency-graph" {
+			c.dependencyGraph = true
+		} else if arg == "--profile-soong" {
+			c.profileSoong = true
+		} else if arg == "--soong-single-threaded" {
+			c.singleThreaded = true
+		} else if arg == "--variants" || strings.HasPrefix(arg, "--variants=") {
+			if arg == "--variants" {
+				if i+1 >= len(args) {
+					ctx.Fatalln("--variants requires the name of a module")
+				}
+				i++
+				c.moduleVariants = strings.TrimSpace(args[i])
+			} else {
+				c.moduleVariants = strings.TrimPrefix(arg, "--variants=")
+			}
+			if c.moduleVariants == "" {
+				ctx.Fatalln("--variants requires the name of a module")
+			}
+		} else if arg == "--properties" || strings.HasPrefix(arg, "--properties=") {
+			var value string
+			if arg == "--properties" {
+				if i+1 >= len(args) {
+					ctx.Fatalln("--properties requires the name of a module")
+				}
+				i++
+				value = strings.TrimSpace(args[i])
+			} else {
+				value = strings.TrimPrefix(arg, "--properties=")
+			}
+			module, variant := value, ""
+			if i := strings.Index(value, ":"); i >= 0 {
+				module, variant = value[:i], value[i+1:]
+			}
+			if module == "" {
+				ctx.Fatalln("--properties requires the name of a module")
+			}
+			c.moduleVariants = module
+			c.moduleProperties = true
+			c.modulePropertiesFilter = variant
+		} else if arg == "--explain" || strings.HasPrefix(arg, "--explain=") {
+			if arg == "--explain" {
+				if i+1 >= len(args) {
+					ctx.Fatalln("--explain requires the path of an output")
+				}
+				i++
+				c.explainOutput = strings.TrimSpace(args[i])
+			} else {
+				c.explainOutput = strings.TrimPrefix(arg, "--explain=")
+			}
+			if c.explainOutput == "" {
+				ctx.Fatalln("--explain requires the path of an output")
+			}
+		} else if arg == "--ninja-subgraph" || strings.HasPrefix(arg, "--ninja-subgraph=") {
+			if arg == "--ninja-subgraph" {
+				if i+1 >= len(args) {
+					ctx.Fatalln("--ninja-subgraph requires the name of a module")
+				}
+				i++
+				c.ninjaSubgraph = strings.TrimSpace(args[i])
+			} else {
+				c.ninjaSubgraph = strings.TrimPrefix(arg, "--ninja-subgraph=")
+			}
+			if c.ninjaSubgraph == "" {
+				ctx.Fatalln("--ninja-subgraph requires the name of a module")
+			}
+		} else if arg == "--max-duration" || strings.HasPrefix(arg, "--max-duration=") {
+			var value string
+			if arg == "--max-duration" {
+				if i+1 >= len(args) {
+					ctx.Fatalln("--max-duration requires a duration")
+				}
+				i++
+				value = strings.TrimSpace(args[i])
+			} else {
+				value = strings.TrimPrefix(arg, "--max-duration=")
+			}
+			c.maxDuration = parseMaxDuration(ctx, value)
+			c.buildDeadline = time.Now().Add(c.maxDuration)
+		} else if strings.HasPrefix(arg, "--variants-format=") {
+			switch format := strings.TrimPrefix(arg, "--variants-format="); format {
+			case "text":
+				c.variantsJson = false
+			case "json":
+				c.variantsJson = true
+			default:
+				ctx.Fatalf("Unknown --variants-format %q, expected \"text\" or \"json\"", format)
+			}
 		} else if len(arg) > 0 && arg[0] == '-' {
 			parseArgNum := func(def int) int {
 				if len(arg) > 2 {
@@ -844,6 +1057,11 @@ func (c *configImpl) Arguments() []string {
 }
 
 func (c *configImpl) SoongBuildInvocationNeeded() bool {
+	if c.CheckOnly() {
+		// Only the Android.bp files are checked, the targets aren't built
+		return false
+	}
+
 	if len(c.Arguments()) > 0 {
 		// Explicit targets requested that are not special targets like b2pbuild
 		// or the JSON module graph
@@ -933,6 +1151,12 @@ func (c *configImpl) SoongDocsHtml() string {
 	return shared.JoinPath(c.SoongOutDir(), "docs/soong_build.html")
 }
 
+// CheckOnlyMarkerFile returns the path of the file that soong_build writes after it checked the
+// Android.bp files for --check-only.
+func (c *configImpl) CheckOnlyMarkerFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "check_only.marker")
+}
+
 func (c *configImpl) QueryviewMarkerFile() string {
 	return shared.JoinPath(c.SoongOutDir(), "queryview.marker")
 }
@@ -945,6 +1169,24 @@ func (c *configImpl) ModuleActionsFile() string {
 	return shared.JoinPath(c.SoongOutDir(), "module-actions.json")
 }
 
+// DependencyGraphFile returns the path that soong_build writes the module dependency graph to
+// when DependencyGraph is true.
+func (c *configImpl) DependencyGraphFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "dependency-graph.json")
+}
+
+// CompdbFile returns the path of the compilation database that soong_build writes when
+// SOONG_GEN_COMPDB=true.
+func (c *configImpl) CompdbFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "development/ide/compdb/compile_commands.json")
+}
+
+// ModuleVariantsFile returns the path that soong_build writes the variants of the ModuleVariants
+// module to.
+func (c *configImpl) ModuleVariantsFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "module-variants.json")
+}
+
 func (c *configImpl) TempDir() string {
 	return shared.TempDirForOutDir(c.SoongOutDir())
 }
@@ -974,6 +1216,119 @@ func (c *configImpl) JsonModuleGraph() bool {
 	return c.jsonModuleGraph
 }
 
+// DependencyGraph returns true if --dependency-graph was passed, which makes the main soong_build
+// invocation also write the module dependency graph to DependencyGraphFile.
+func (c *configImpl) DependencyGraph() bool {
+	return c.dependencyGraph
+}
+
+// ProfileSoong returns true if --profile-soong was passed, which makes the main soong_build
+// invocation write a CPU profile to SoongCpuProfileFile and the time spent in every mutator to
+// SoongMutatorProfileFile.
+func (c *configImpl) ProfileSoong() bool {
+	return c.profileSoong
+}
+
+// SoongSingleThreaded returns true if --soong-single-threaded was passed, which makes the main
+// soong_build invocation run its parallel mutators on one module at a time.
+func (c *configImpl) SoongSingleThreaded() bool {
+	return c.singleThreaded
+}
+
+// SoongCpuProfileFile returns the path of the pprof CPU profile of soong_build written for
+// --profile-soong.
+func (c *configImpl) SoongCpuProfileFile() string {
+	return filepath.Join(c.OutDir(), "soong_build.pprof")
+}
+
+// SoongMutatorProfileFile returns the path of the Chrome trace of the mutators of soong_build
+// written for --profile-soong.
+func (c *configImpl) SoongMutatorProfileFile() string {
+	return filepath.Join(c.OutDir(), "soong_build_mutators.trace.json")
+}
+
+// ModuleVariants returns the name of the module passed to --variants, or "" if it wasn't passed.
+// When it is set soong_ui prints the variants of the module after running soong_build instead of
+// building anything.
+func (c *configImpl) ModuleVariants() string {
+	return c.moduleVariants
+}
+
+// ModuleVariantsJson returns true if --variants-format=json was passed, which makes soong_ui print
+// the variants of the ModuleVariants module as JSON instead of text.
+func (c *configImpl) ModuleVariantsJson() bool {
+	return c.variantsJson
+}
+
+// ModuleProperties returns true if --properties=<module>[:<variant>] was passed, which makes
+// soong_ui print the resolved properties of the variants of the ModuleVariants module as JSON.
+func (c *configImpl) ModuleProperties() bool {
+	return c.moduleProperties
+}
+
+// ModulePropertiesVariant returns the variant passed to --properties=<module>:<variant>, or "" to
+// print the properties of all the variants.
+func (c *configImpl) ModulePropertiesVariant() string {
+	return c.modulePropertiesFilter
+}
+
+// parseMaxDuration parses the value of --max-duration, either a number of minutes or a duration
+// like "1h30m".
+func parseMaxDuration(ctx Context, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if minutes, atoiErr := strconv.Atoi(value); atoiErr == nil {
+		d, err = time.Duration(minutes)*time.Minute, nil
+	}
+	if err != nil || d <= 0 {
+		ctx.Fatalf("--max-duration requires a positive number of minutes or a duration like 1h30m, got %q", value)
+	}
+	return d
+}
+
+// MaxDuration returns the time budget passed to --max-duration, or 0 if there is none.
+func (c *configImpl) MaxDuration() time.Duration {
+	return c.maxDuration
+}
+
+// BuildDeadline returns the time at which the budget passed to --max-duration runs out, and
+// false if there is no budget.  Ninja is stopped at that time, see runNinjaForBuild.
+func (c *configImpl) BuildDeadline() (time.Time, bool) {
+	return c.buildDeadline, c.maxDuration > 0
+}
+
+// ExplainOutput returns the output path passed to --explain, or "" if it wasn't passed.  When it
+// is set soong_ui prints why ninja would rebuild the output instead of building anything.
+func (c *configImpl) ExplainOutput() string {
+	return c.explainOutput
+}
+
+// NinjaSubgraph returns the module or ninja target passed to --ninja-subgraph, or "" if it wasn't
+// passed.  When it is set soong_ui writes the part of the ninja graph needed to build the target to
+// NinjaSubgraphFile instead of building anything.
+func (c *configImpl) NinjaSubgraph() string {
+	return c.ninjaSubgraph
+}
+
+// NinjaSubgraphFile returns the path of the ninja file written for --ninja-subgraph.
+func (c *configImpl) NinjaSubgraphFile() string {
+	return filepath.Join(c.OutDir(), "ninja_subgraph", c.ninjaSubgraph+".ninja")
+}
+
+// HostToolCacheDir returns the absolute path of the cache shared between output directories for
+// the outputs of host compile actions, or "" if SOONG_HOST_TOOL_CACHE is unset or
+// --no-host-tool-cache was passed.
+func (c *configImpl) HostToolCacheDir() string {
+	cacheDir, _ := c.environ.Get("SOONG_HOST_TOOL_CACHE")
+	return cacheDir
+}
+
+// WarningBaseline returns the baseline file that limits the number of compiler warnings of each
+// category, or "" if SOONG_WARNING_BASELINE is unset.
+func (c *configImpl) WarningBaseline() string {
+	baseline, _ := c.environ.Get("SOONG_WARNING_BASELINE")
+	return baseline
+}
+
 func (c *configImpl) Bp2Build() bool {
 	return c.bp2build
 }
@@ -986,6 +1341,12 @@ func (c *configImpl) SoongDocs() bool {
 	return c.soongDocs
 }
 
+// CheckOnly returns true if --check-only was passed to only parse and analyze the Android.bp
+// files, without generating the Ninja file or building anything.
+func (c *configImpl) CheckOnly() bool {
+	return c.checkOnly
+}
+
 func (c *configImpl) IsVerbose() bool {
 	return c.verbose
 }
@@ -1078,6 +1439,12 @@ func (c *configImpl) TotalRAM() uint64 {
 	return c.totalRAM
 }
 
+// MinFreeDiskSpace returns the minimum number of bytes that must be free on
+// the filesystem hosting the out directory. Zero disables the check.
+func (c *configImpl) MinFreeDiskSpace() uint64 {
+	return c.minFreeDiskSpace
+}
+
 // ForceUseGoma determines whether we should override Goma deprecation
 // and use Goma for the current build or not.
 func (c *configImpl) ForceUseGoma() bool {
@@ -1278,6 +1645,12 @@ func (c *configImpl) KatiPackageNinjaFile() string {
 	return filepath.Join(c.OutDir(), "build"+c.KatiSuffix()+katiPackageSuffix+".ninja")
 }
 
+// BuildEnvironmentFile returns the path of the file recording the environment
+// variables the build was run with.
+func (c *configImpl) BuildEnvironmentFile() string {
+	return filepath.Join(c.SoongOutDir(), "build.environment.json")
+}
+
 func (c *configImpl) SoongNinjaFile() string {
 	return filepath.Join(c.SoongOutDir(), "build.ninja")
 }
@@ -1388,6 +1761,79 @@ func (c *configImpl) MetricsUploaderApp() string {
 	return c.metricsUploader
 }
 
+// MetricsUploaderApps returns all the metrics uploaders to run, starting with
+// MetricsUploaderApp if it is set.
+func (c *configImpl) MetricsUploaderApps() []string {
+	var uploaders []string
+	if c.metricsUploader != "" {
+		uploaders = append(uploaders, c.metricsUploader)
+	}
+	for _, uploader := range c.metricsUploaders {
+		if !inList(uploader, uploaders) {
+			uploaders = append(uploaders, uploader)
+		}
+	}
+	return uploaders
+}
+
+// MetricsUploadRetries returns the number of times a failed metrics upload is
+// retried. Zero means the uploader is only run once.
+func (c *configImpl) MetricsUploadRetries() int {
+	return c.metricsUploadRetries
+}
+
+// MetricsUploadRetryDelay returns the delay before the first retry of a failed
+// metrics upload.
+func (c *configImpl) MetricsUploadRetryDelay() time.Duration {
+	return c.metricsUploadRetryDelay
+}
+
+// CompressMetrics returns true if metrics files larger than
+// metricsCompressionThreshold are gzipped before they are uploaded.
+func (c *configImpl) CompressMetrics() bool {
+	return c.compressMetrics
+}
+
+// CompressJsonOutputs returns true if SOONG_COMPRESS_JSON_OUTPUTS=true, which makes soong_ui
+// replace the large JSON files written by soong_build with gzipped copies.
+func (c *configImpl) CompressJsonOutputs() bool {
+	return c.compressJsonOutputs
+}
+
+// MetricsUploadDryRun returns true if UploadMetrics should prepare the upload
+// and log the uploader command without executing it.
+func (c *configImpl) MetricsUploadDryRun() bool {
+	return c.metricsUploadDryRun
+}
+
+// MetricsUploadTimeout returns how long the metrics uploader may run before it
+// is killed. Zero means the uploader is never timed out.
+func (c *configImpl) MetricsUploadTimeout() time.Duration {
+	return c.metricsUploadTimeout
+}
+
+// MetricsFollowSymlinks returns true if symlinks found while looking for
+// metrics files to upload are followed instead of skipped.
+func (c *configImpl) MetricsFollowSymlinks() bool {
+	return c.metricsFollowSymlinks
+}
+
+// MetricsRedactPatterns returns the glob patterns of environment variable
+// names whose values must not be recorded in the metrics.
+func (c *configImpl) MetricsRedactPatterns() []string {
+	return c.metricsRedactPatterns
+}
+
+// MetricsRedactPatternsFromEnv returns the patterns of MetricsRedactPatterns
+// for env, which can be used before the config is created.
+func MetricsRedactPatternsFromEnv(env *Environment) []string {
+	patterns := append([]string{}, defaultMetricsRedactPatterns...)
+	if extra, ok := env.Get("METRICS_REDACT_ENV_PATTERNS"); ok {
+		patterns = append(patterns, strings.Fields(extra)...)
+	}
+	return patterns
+}
+
 // LogsDir returns the absolute path to the logs directory where build log and
 // metrics files are located. By default, the logs directory is the out
 // directory. If the argument dist is specified, the logs directory
@@ -1435,3 +1881,18 @@ func GetMetricsUploader(topDir string, env *Environment) string {
 
 	return ""
 }
+
+// GetMetricsUploaders returns the existing metrics uploaders listed in the
+// space separated METRICS_UPLOADERS environment variable.
+func GetMetricsUploaders(topDir string, env *Environment) []string {
+	var metricsUploaders []string
+	if v, ok := env.Get("METRICS_UPLOADERS"); ok {
+		for _, p := range strings.Fields(v) {
+			metricsUploader := filepath.Join(topDir, p)
+			if _, err := os.Stat(metricsUploader); err == nil {
+				metricsUploaders = append(metricsUploaders, metricsUploader)
+			}
+		}
+	}
+	return metricsUploaders
+}
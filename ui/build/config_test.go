@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"android/soong/ui/logger"
 	smpb "android/soong/ui/metrics/metrics_proto"
@@ -113,6 +114,124 @@ func TestConfigParseArgsJK(t *testing.T) {
 	}
 }
 
+func TestConfigParseArgsMaxDuration(t *testing.T) {
+	ctx := testContext()
+
+	testCases := []struct {
+		args []string
+
+		maxDuration time.Duration
+		err         string
+	}{
+		{args: nil},
+		{args: []string{"--max-duration=30"}, maxDuration: 30 * time.Minute},
+		{args: []string{"--max-duration", "90"}, maxDuration: 90 * time.Minute},
+		{args: []string{"--max-duration=1h30m"}, maxDuration: 90 * time.Minute},
+		{args: []string{"--max-duration=0"}, err: "--max-duration requires a positive number of minutes"},
+		{args: []string{"--max-duration=abc"}, err: "--max-duration requires a positive number of minutes"},
+		{args: []string{"--max-duration"}, err: "--max-duration requires a duration"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			defer logger.Recover(func(err error) {
+				if tc.err == "" {
+					t.Fatal(err)
+				} else if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %q", tc.err, err)
+				}
+			})
+
+			c := &configImpl{}
+			start := time.Now()
+			c.parseArgs(ctx, tc.args)
+			if tc.err != "" {
+				t.Fatalf("expected error containing %q", tc.err)
+			}
+
+			if c.MaxDuration() != tc.maxDuration {
+				t.Errorf("want max duration %s, got %s", tc.maxDuration, c.MaxDuration())
+			}
+			deadline, ok := c.BuildDeadline()
+			if ok != (tc.maxDuration > 0) {
+				t.Errorf("want deadline %v, got %v", tc.maxDuration > 0, ok)
+			}
+			if ok && (deadline.Before(start.Add(tc.maxDuration)) || deadline.After(time.Now().Add(tc.maxDuration))) {
+				t.Errorf("want deadline %s after the arguments were parsed, got %s", tc.maxDuration, deadline.Sub(start))
+			}
+		})
+	}
+}
+
+func TestConfigParseArgsProperties(t *testing.T) {
+	ctx := testContext()
+
+	testCases := []struct {
+		args []string
+
+		module  string
+		variant string
+		err     string
+	}{
+		{args: []string{"--properties=libfoo"}, module: "libfoo"},
+		{args: []string{"--properties=libfoo:android_arm64_armv8-a_shared"}, module: "libfoo", variant: "android_arm64_armv8-a_shared"},
+		{args: []string{"--properties", "libfoo"}, module: "libfoo"},
+		{args: []string{"--properties", "libfoo:android_arm64_armv8-a_shared"}, module: "libfoo", variant: "android_arm64_armv8-a_shared"},
+		{args: []string{"--properties"}, err: "--properties requires the name of a module"},
+		{args: []string{"--properties=:android_arm64_armv8-a_shared"}, err: "--properties requires the name of a module"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			defer logger.Recover(func(err error) {
+				if tc.err == "" {
+					t.Fatal(err)
+				} else if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %q", tc.err, err)
+				}
+			})
+
+			c := &configImpl{}
+			c.parseArgs(ctx, tc.args)
+			if tc.err != "" {
+				t.Fatalf("expected error containing %q", tc.err)
+			}
+
+			if !c.ModuleProperties() {
+				t.Errorf("expected the module properties to be printed")
+			}
+			if c.ModuleVariants() != tc.module {
+				t.Errorf("want module %q, got %q", tc.module, c.ModuleVariants())
+			}
+			if c.ModulePropertiesVariant() != tc.variant {
+				t.Errorf("want variant %q, got %q", tc.variant, c.ModulePropertiesVariant())
+			}
+		})
+	}
+}
+
+func TestConfigParseArgsCheckOnly(t *testing.T) {
+	ctx := testContext()
+
+	c := &configImpl{}
+	c.parseArgs(ctx, []string{"--check-only", "droid"})
+	if !c.CheckOnly() {
+		t.Errorf("expected --check-only to be set")
+	}
+	if c.SoongBuildInvocationNeeded() {
+		t.Errorf("expected --check-only to not need the soong_build invocation for build.ninja")
+	}
+
+	c = &configImpl{}
+	c.parseArgs(ctx, []string{"droid"})
+	if c.CheckOnly() {
+		t.Errorf("expected --check-only to not be set")
+	}
+	if !c.SoongBuildInvocationNeeded() {
+		t.Errorf("expected droid to need the soong_build invocation for build.ninja")
+	}
+}
+
 func TestConfigParseArgsVars(t *testing.T) {
 	ctx := testContext()
 
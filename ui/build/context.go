@@ -23,6 +23,8 @@ import (
 	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
 	"android/soong/ui/status"
 	"android/soong/ui/tracer"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // Context combines a context.Context, logger.Logger, and terminal.Writer.
@@ -62,6 +64,19 @@ func (c ContextImpl) EndTrace() {
 	}
 }
 
+// EndTraceWithResult is like EndTrace, but also records whether the traced
+// operation succeeded in the time metrics.
+func (c ContextImpl) EndTraceWithResult(succeeded bool) {
+	if c.Tracer != nil {
+		c.Tracer.End(c.Thread)
+	}
+	if c.Metrics != nil {
+		perf := c.Metrics.EventTracer.End(c.Thread)
+		perf.Succeeded = proto.Bool(succeeded)
+		c.Metrics.SetTimeMetrics(perf)
+	}
+}
+
 // CompleteTrace writes a trace with a beginning and end times.
 func (c ContextImpl) CompleteTrace(name, desc string, begin, end uint64) {
 	if c.Tracer != nil {
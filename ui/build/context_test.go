@@ -0,0 +1,62 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"android/soong/ui/metrics"
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEndTraceWithResult(t *testing.T) {
+	ctx := testContext()
+	ctx.Metrics = metrics.New()
+
+	ctx.BeginTrace(metrics.RunSetupTool, "succeeded")
+	ctx.EndTraceWithResult(true)
+	ctx.BeginTrace(metrics.RunSetupTool, "failed")
+	ctx.EndTraceWithResult(false)
+	ctx.BeginTrace(metrics.RunSetupTool, "no_result")
+	ctx.EndTrace()
+
+	metricsFile := filepath.Join(t.TempDir(), "soong_metrics")
+	if err := ctx.Metrics.Dump(metricsFile); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got soong_metrics_proto.MetricsBase
+	if err := proto.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	setupTools := got.GetSetupTools()
+	if len(setupTools) != 3 {
+		t.Fatalf("got %d setup tool metrics, want 3", len(setupTools))
+	}
+	for i, want := range []*bool{proto.Bool(true), proto.Bool(false), nil} {
+		perf := setupTools[i]
+		if (perf.Succeeded == nil) != (want == nil) || (want != nil && *perf.Succeeded != *want) {
+			t.Errorf("%s: got succeeded %v, want %v", perf.GetDescription(), perf.Succeeded, want)
+		}
+	}
+}
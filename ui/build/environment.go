@@ -16,9 +16,13 @@ package build
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -144,6 +148,85 @@ func (e *Environment) IsFalse(key string) bool {
 	return false
 }
 
+// redactedValue replaces the values of environment variables that are redacted
+// by redactEnvironment.
+const redactedValue = "<redacted>"
+
+// redactEnvironment replaces the value of every environment variable whose key
+// matches one of the glob patterns with redactedValue. The keys are kept so
+// that consumers of the environment can still tell the variable was set.
+func redactEnvironment(env *Environment, patterns []string) {
+	for i, envVar := range *env {
+		key, _, ok := decodeKeyValue(envVar)
+		if !ok {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, key); matched {
+				(*env)[i] = key + "=" + redactedValue
+				break
+			}
+		}
+	}
+}
+
+// RedactCommandLine returns a copy of args with the value of every KEY=VALUE,
+// --flag=value or --flag value argument redacted if its key matches one of the
+// glob patterns passed to redactEnvironment.  Flag names are matched as
+// environment variable names, so --signing-key=... matches the *SIGNING*
+// pattern.  The argument after a matching flag is only taken as its value if it
+// is not another flag.  The other arguments, including the targets, are kept as
+// they are.
+func RedactCommandLine(args []string, patterns []string) []string {
+	matches := func(key string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, key); matched {
+				return true
+			}
+		}
+		return false
+	}
+	flagKey := func(flag string) string {
+		return strings.ToUpper(strings.ReplaceAll(strings.TrimLeft(flag, "-"), "-", "_"))
+	}
+
+	ret := make([]string, len(args))
+	copy(ret, args)
+	for i := 0; i < len(ret); i++ {
+		arg := ret[i]
+		if split := strings.SplitN(arg, "=", 2); len(split) == 2 {
+			key := split[0]
+			if strings.HasPrefix(key, "-") {
+				key = flagKey(key)
+			}
+			if matches(key) {
+				ret[i] = split[0] + "=" + redactedValue
+			}
+		} else if strings.HasPrefix(arg, "-") && matches(flagKey(arg)) &&
+			i+1 < len(ret) && !strings.HasPrefix(ret[i+1], "-") {
+			i++
+			ret[i] = redactedValue
+		}
+	}
+	return ret
+}
+
+// writeBuildEnvironmentFile writes env to path as a JSON object. The keys are
+// sorted so that the files of two builds can be diffed.
+func writeBuildEnvironmentFile(path string, env *Environment) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(env.AsMap()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
+
 // AppendFromKati reads a shell script written by Kati that exports or unsets
 // environment variables, and applies those to the local Environment.
 func (e *Environment) AppendFromKati(filename string) error {
@@ -15,6 +15,8 @@
 package build
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -87,3 +89,89 @@ func TestEnvAppendFromKati(t *testing.T) {
 		t.Errorf("     got: %v", got)
 	}
 }
+
+func TestRedactEnvironment(t *testing.T) {
+	env := &Environment{
+		"API_TOKEN=abc123",
+		"DB_PASSWORD_FILE=/secret/path",
+		"HOME=/home/user",
+		"RELEASE_SECRET=hunter2",
+		"TOKEN_CACHE=/tmp/cache",
+		"USER=someone",
+	}
+	redactEnvironment(env, defaultMetricsRedactPatterns)
+
+	got := env.Environ()
+	expected := []string{
+		"API_TOKEN=" + redactedValue,
+		"DB_PASSWORD_FILE=" + redactedValue,
+		"HOME=/home/user",
+		"RELEASE_SECRET=" + redactedValue,
+		"TOKEN_CACHE=/tmp/cache",
+		"USER=" + redactedValue,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Environment list does not match")
+		t.Errorf("expected: %v", expected)
+		t.Errorf("     got: %v", got)
+	}
+}
+
+func TestRedactCommandLine(t *testing.T) {
+	args := []string{
+		"soong_ui",
+		"--make-mode",
+		"API_TOKEN=abc123",
+		"TARGET_PRODUCT=aosp_arm",
+		"--signing-key=/keys/release",
+		"--signing-key", "/keys/release",
+		"--signing", "--max-duration", "30",
+		"--max-duration=30",
+		"droid",
+		"dist",
+	}
+	got := RedactCommandLine(args, defaultMetricsRedactPatterns)
+	expected := []string{
+		"soong_ui",
+		"--make-mode",
+		"API_TOKEN=" + redactedValue,
+		"TARGET_PRODUCT=aosp_arm",
+		"--signing-key=" + redactedValue,
+		"--signing-key", redactedValue,
+		"--signing", "--max-duration", "30",
+		"--max-duration=30",
+		"droid",
+		"dist",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected: %q", expected)
+		t.Errorf("     got: %q", got)
+	}
+	if args[2] != "API_TOKEN=abc123" {
+		t.Errorf("expected the arguments to be left unchanged, got %q", args)
+	}
+}
+
+func TestWriteBuildEnvironmentFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "soong", "build.environment.json")
+	env := &Environment{"TEST2=0", "TEST=1", "API_TOKEN=" + redactedValue}
+	if err := writeBuildEnvironmentFile(path, env); err != nil {
+		t.Fatalf("Unexpected error from %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	expected := `{
+  "API_TOKEN": "<redacted>",
+  "TEST": "1",
+  "TEST2": "0"
+}
+`
+	if string(got) != expected {
+		t.Errorf("Environment file does not match")
+		t.Errorf("expected: %s", expected)
+		t.Errorf("     got: %s", got)
+	}
+}
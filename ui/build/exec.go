@@ -17,8 +17,10 @@ package build
 import (
 	"bufio"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -32,11 +34,18 @@ type Cmd struct {
 	Environment *Environment
 	Sandbox     Sandbox
 
+	// If set, the process is sent os.Interrupt if it is still running at this time.  It is only
+	// honored when the process is started with Start, including by RunAndStream.  A sandboxed
+	// process gets the signal from its sandbox.
+	Deadline time.Time
+
 	ctx    Context
 	config Config
 	name   string
 
-	started time.Time
+	started          time.Time
+	deadlineTimer    *time.Timer
+	deadlineExceeded int32
 }
 
 func Command(ctx Context, config Config, name string, executable string, args ...string) *Cmd {
@@ -82,7 +91,23 @@ func (c *Cmd) report() {
 
 func (c *Cmd) Start() error {
 	c.prepare()
-	return c.Cmd.Start()
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+	if !c.Deadline.IsZero() {
+		c.deadlineTimer = time.AfterFunc(time.Until(c.Deadline), func() {
+			atomic.StoreInt32(&c.deadlineExceeded, 1)
+			c.ctx.Verbosef("%q is still running at its deadline, interrupting it", c.name)
+			c.Process.Signal(os.Interrupt)
+		})
+	}
+	return nil
+}
+
+// DeadlineExceeded returns true if the process was interrupted because it was still running at
+// Deadline.
+func (c *Cmd) DeadlineExceeded() bool {
+	return atomic.LoadInt32(&c.deadlineExceeded) != 0
 }
 
 func (c *Cmd) Run() error {
@@ -108,6 +133,9 @@ func (c *Cmd) CombinedOutput() ([]byte, error) {
 
 func (c *Cmd) Wait() error {
 	err := c.Cmd.Wait()
+	if c.deadlineTimer != nil {
+		c.deadlineTimer.Stop()
+	}
 	c.report()
 	return err
 }
@@ -175,15 +203,24 @@ func (c *Cmd) RunAndPrintOrFatal() {
 // RunAndStreamOrFatal will run the command, while running print
 // any output, then handle any errors with a call to ctx.Fatal
 func (c *Cmd) RunAndStreamOrFatal() {
+	c.reportError(c.RunAndStream())
+}
+
+// RunAndStream is like RunAndStreamOrFatal, but returns the error instead of
+// reporting it to the logger.
+func (c *Cmd) RunAndStream() error {
 	out, err := c.StdoutPipe()
 	if err != nil {
-		c.ctx.Fatal(err)
+		return err
 	}
 	c.Stderr = c.Stdout
 
 	st := c.ctx.Status.StartTool()
 
-	c.StartOrFatal()
+	if err := c.Start(); err != nil {
+		st.Finish()
+		return err
+	}
 
 	buf := bufio.NewReaderSize(out, 2*1024*1024)
 	for {
@@ -194,11 +231,12 @@ func (c *Cmd) RunAndStreamOrFatal() {
 		} else if err == io.EOF {
 			break
 		} else if err != nil {
-			c.ctx.Fatal(err)
+			st.Finish()
+			return err
 		}
 	}
 
 	err = c.Wait()
 	st.Finish()
-	c.reportError(err)
+	return err
 }
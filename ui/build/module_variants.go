@@ -0,0 +1,125 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"android/soong/shared"
+)
+
+// printModuleVariants prints the variants of the module passed to --variants or --properties that
+// were written by soong_build.
+func printModuleVariants(ctx Context, config Config) {
+	data, err := readMaybeCompressedFile(config.ModuleVariantsFile())
+	if err != nil {
+		ctx.Fatalf("Failed to read module variants: %s", err)
+	}
+	var variants []shared.ModuleVariant
+	if err := json.Unmarshal(data, &variants); err != nil {
+		ctx.Fatalf("Failed to parse %s: %s", config.ModuleVariantsFile(), err)
+	}
+	if len(variants) == 0 {
+		ctx.Fatalf("No module named %q", config.ModuleVariants())
+	}
+
+	if config.ModuleProperties() {
+		if variant := config.ModulePropertiesVariant(); variant != "" {
+			selected := selectModuleVariant(variants, variant)
+			if len(selected) == 0 {
+				var names []string
+				for _, v := range variants {
+					names = append(names, v.Variant)
+				}
+				ctx.Fatalf("Module %q has no variant %q, the variants are:\n  %s",
+					config.ModuleVariants(), variant, strings.Join(names, "\n  "))
+			}
+			variants = selected
+		}
+		// The resolved properties are nested, so they are only printed as JSON.
+		if err := writeModuleVariants(ctx.Writer, variants, true); err != nil {
+			ctx.Fatalf("Failed to print module properties: %s", err)
+		}
+		return
+	}
+
+	if err := writeModuleVariants(ctx.Writer, variants, config.ModuleVariantsJson()); err != nil {
+		ctx.Fatalf("Failed to print module variants: %s", err)
+	}
+}
+
+// selectModuleVariant returns the variants called variant, one for each module with the requested
+// name.
+func selectModuleVariant(variants []shared.ModuleVariant, variant string) []shared.ModuleVariant {
+	var ret []shared.ModuleVariant
+	for _, v := range variants {
+		if v.Variant == variant {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// writeModuleVariants writes variants to w, either as indented JSON or as a list of the variants
+// of each module with their variations and properties.
+func writeModuleVariants(w io.Writer, variants []shared.ModuleVariant, asJson bool) error {
+	if asJson {
+		data, err := json.MarshalIndent(variants, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	module := ""
+	for _, variant := range variants {
+		if name := "//" + variant.Dir + ":" + variant.Name; name != module {
+			module = name
+			if _, err := fmt.Fprintf(w, "%s (%s)\n", module, variant.Type); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n    variations: %s\n    properties: %s\n", variant.Variant,
+			formatModuleVariantValues(variant.Variations), formatModuleVariantValues(variant.Properties)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatModuleVariantValues returns values as a sorted list of key=value pairs, quoting empty
+// values so that they are visible.
+func formatModuleVariantValues(values map[string]string) string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		v := values[k]
+		if v == "" {
+			v = `""`
+		}
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"android/soong/shared"
+)
+
+func TestWriteModuleVariants(t *testing.T) {
+	variants := []shared.ModuleVariant{
+		{
+			Name:       "libfoo",
+			Dir:        "foo",
+			Type:       "cc_library",
+			Variant:    "android_arm64_armv8-a_shared",
+			Variations: map[string]string{"os": "android", "image": "", "link": "shared"},
+			Properties: map[string]string{"enabled": "true", "arch": "arm64_armv8-a"},
+		},
+		{
+			Name:       "libfoo",
+			Dir:        "foo",
+			Type:       "cc_library",
+			Variant:    "android_arm64_armv8-a_static",
+			Variations: map[string]string{"os": "android", "image": "", "link": "static"},
+			Properties: map[string]string{"enabled": "false", "arch": "arm64_armv8-a"},
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := writeModuleVariants(buf, variants, false); err != nil {
+			t.Fatal(err)
+		}
+		want := `//foo:libfoo (cc_library)
+  android_arm64_armv8-a_shared
+    variations: image="" link=shared os=android
+    properties: arch=arm64_armv8-a enabled=true
+  android_arm64_armv8-a_static
+    variations: image="" link=static os=android
+    properties: arch=arm64_armv8-a enabled=false
+`
+		if got := buf.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := writeModuleVariants(buf, variants, true); err != nil {
+			t.Fatal(err)
+		}
+		var got []shared.ModuleVariant
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse output %q: %s", buf.String(), err)
+		}
+		if !reflect.DeepEqual(got, variants) {
+			t.Errorf("got %v, want %v", got, variants)
+		}
+	})
+}
+
+func TestSelectModuleVariant(t *testing.T) {
+	variants := []shared.ModuleVariant{
+		{Name: "libfoo", Dir: "foo", Variant: "android_arm64_armv8-a_shared"},
+		{Name: "libfoo", Dir: "foo", Variant: "android_arm64_armv8-a_static"},
+		{Name: "libfoo", Dir: "bar", Variant: "android_arm64_armv8-a_static"},
+	}
+
+	got := selectModuleVariant(variants, "android_arm64_armv8-a_static")
+	if want := variants[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := selectModuleVariant(variants, "linux_glibc_x86_64_static"); len(got) != 0 {
+		t.Errorf("expected no variants, got %v", got)
+	}
+}
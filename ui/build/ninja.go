@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"android/soong/ui/metrics"
@@ -39,7 +40,14 @@ func runNinjaForBuild(ctx Context, config Config) {
 	// progress of the build.
 	fifo := filepath.Join(config.OutDir(), ".ninja_fifo")
 	nr := status.NewNinjaReader(ctx, ctx.Status.StartTool(), fifo)
-	defer nr.Close()
+	readerClosed := false
+	closeReader := func() {
+		if !readerClosed {
+			readerClosed = true
+			nr.Close()
+		}
+	}
+	defer closeReader()
 
 	executable := config.PrebuiltBuildTool("ninja")
 	args := []string{
@@ -186,6 +194,17 @@ func runNinjaForBuild(ctx Context, config Config) {
 		ctx.Verbosef("  %s", envVar)
 	}
 
+	// Record the environment for reproducing the build later, without the
+	// values of sensitive variables.
+	redactedEnv := cmd.Environment.Copy()
+	redactEnvironment(redactedEnv, config.MetricsRedactPatterns())
+	if err := writeBuildEnvironmentFile(config.BuildEnvironmentFile(), redactedEnv); err != nil {
+		ctx.Fatalf("Failed to write %s: %v", config.BuildEnvironmentFile(), err)
+	}
+	if ctx.Metrics != nil {
+		ctx.Metrics.SetNinjaEnvironment(redactedEnv.AsMap())
+	}
+
 	// Poll the Ninja log for updates regularly based on the heartbeat
 	// frequency. If it isn't updated enough, then we want to surface the
 	// possibility that Ninja is stuck, to the user.
@@ -207,10 +226,65 @@ func runNinjaForBuild(ctx Context, config Config) {
 		}
 	}()
 
+	deadline, hasDeadline := config.BuildDeadline()
+	if !hasDeadline {
+		ctx.Status.Status("Starting ninja...")
+		cmd.RunAndStreamOrFatal()
+		return
+	}
+
+	if !time.Now().Before(deadline) {
+		ctx.Fatalf("The --max-duration=%s budget ran out before ninja started, nothing was built.", config.MaxDuration())
+	}
+
+	// When ninja is interrupted it interrupts the actions that are still running instead of
+	// waiting for them, and deletes the outputs they have already modified.  The actions that
+	// finished were recorded in .ninja_log as they finished, so the next build only reruns the
+	// interrupted and remaining ones.
+	cmd.Deadline = deadline
+	progress := &ninjaProgress{}
+	ctx.Status.AddOutput(progress)
+	before := ctx.Status.Counts()
+
 	ctx.Status.Status("Starting ninja...")
-	cmd.RunAndStreamOrFatal()
+	err := cmd.RunAndStream()
+	if err != nil && cmd.DeadlineExceeded() {
+		// Wait for the reader to process the last ninja status updates.
+		closeReader()
+		counts := progress.get()
+		finished := counts.FinishedActions - before.FinishedActions
+		total := counts.TotalActions - before.TotalActions
+		ctx.Fatalf("Stopped ninja after the --max-duration=%s budget ran out: %d of %d actions finished, "+
+			"%d remaining. Run the build again to continue.", config.MaxDuration(), finished, total, total-finished)
+	}
+	cmd.reportError(err)
 }
 
+// ninjaProgress is a status.StatusOutput that remembers the latest action counts, which
+// include the total number of actions ninja planned until ninja finishes.
+type ninjaProgress struct {
+	lock   sync.Mutex
+	counts status.Counts
+}
+
+func (p *ninjaProgress) set(counts status.Counts) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.counts = counts
+}
+
+func (p *ninjaProgress) get() status.Counts {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.counts
+}
+
+func (p *ninjaProgress) StartAction(_ *status.Action, counts status.Counts)       { p.set(counts) }
+func (p *ninjaProgress) FinishAction(_ status.ActionResult, counts status.Counts) { p.set(counts) }
+func (p *ninjaProgress) Message(status.MsgLevel, string)                          {}
+func (p *ninjaProgress) Flush()                                                   {}
+func (p *ninjaProgress) Write(b []byte) (int, error)                              { return len(b), nil }
+
 // A simple struct for checking if Ninja gets stuck, using timestamps.
 type ninjaStucknessChecker struct {
 	logPath     string
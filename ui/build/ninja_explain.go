@@ -0,0 +1,219 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"android/soong/ui/metrics"
+)
+
+// ninjaExplanation is the reason ninja's "-d explain" gives for a path being dirty.
+type ninjaExplanation struct {
+	// The reason, as printed by ninja.
+	message string
+
+	// The input that is newer than the path, if that is the reason.
+	input string
+
+	// The mtimes of the path and of the input, if ninja printed them.
+	oldMtime, newMtime string
+}
+
+// ninjaExplainPatterns match the "ninja explain:" messages that name the dirty path, and the
+// input that made it dirty if there is one.
+var ninjaExplainPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:restat of )?output (?P<path>.+?) (?:is )?older than most recent input (?P<input>.+) \((?P<old>-?\d+) vs (?P<new>-?\d+)\)$`),
+	regexp.MustCompile(`^recorded mtime of (?P<path>.+?) older than most recent input (?P<input>.+) \((?P<old>-?\d+) vs (?P<new>-?\d+)\)$`),
+	regexp.MustCompile(`^output (?P<path>.+) doesn't exist$`),
+	regexp.MustCompile(`^command line changed for (?P<path>.+)$`),
+	regexp.MustCompile(`^command line not found in log for (?P<path>.+)$`),
+	regexp.MustCompile(`^(?P<path>.+) has no in-edge and is missing$`),
+	regexp.MustCompile(`^deps for '(?P<path>.+)' are missing$`),
+	regexp.MustCompile(`^stored deps info out of date for '(?P<path>.+)' \((?P<old>-?\d+) vs (?P<new>-?\d+)\)$`),
+}
+
+var ninjaDirtyInputPattern = regexp.MustCompile(`^(.+) is dirty$`)
+
+// parseNinjaExplain reads the output of ninja -d explain and returns the explanation for each path
+// that ninja said why it was dirty, and the set of inputs that ninja only said were dirty.
+func parseNinjaExplain(r io.Reader) (map[string]ninjaExplanation, map[string]bool, error) {
+	explanations := make(map[string]ninjaExplanation)
+	dirty := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "ninja explain: ") {
+			continue
+		}
+		message := strings.TrimPrefix(line, "ninja explain: ")
+
+		matched := false
+		for _, pattern := range ninjaExplainPatterns {
+			match := pattern.FindStringSubmatch(message)
+			if match == nil {
+				continue
+			}
+			e := ninjaExplanation{message: message}
+			var path string
+			for i, name := range pattern.SubexpNames() {
+				switch name {
+				case "path":
+					path = match[i]
+				case "input":
+					e.input = match[i]
+				case "old":
+					e.oldMtime = match[i]
+				case "new":
+					e.newMtime = match[i]
+				}
+			}
+			// Keep the first reason, which is the one that made ninja consider the path dirty.
+			if _, ok := explanations[path]; !ok {
+				explanations[path] = e
+			}
+			matched = true
+			break
+		}
+		if !matched {
+			if match := ninjaDirtyInputPattern.FindStringSubmatch(message); match != nil {
+				dirty[match[1]] = true
+			}
+		}
+	}
+	return explanations, dirty, scanner.Err()
+}
+
+// parseNinjaQueryInputs returns the explicit and implicit inputs from the output of
+// ninja -t query.  Order-only inputs are skipped as they never make a path dirty.
+func parseNinjaQueryInputs(r io.Reader) ([]string, error) {
+	var inputs []string
+	inInputs := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "  input:"):
+			inInputs = true
+		case strings.HasPrefix(line, "    ") && inInputs:
+			input := strings.TrimSpace(line)
+			if strings.HasPrefix(input, "|| ") || strings.HasPrefix(input, "|@ ") {
+				continue
+			}
+			inputs = append(inputs, strings.TrimPrefix(input, "| "))
+		default:
+			inInputs = false
+		}
+	}
+	return inputs, scanner.Err()
+}
+
+// writeRebuildExplanation writes to w the chain of dirty paths from target to the input that
+// changed, using the explanations and dirty inputs parsed from ninja -d explain.  inputsOf returns
+// the inputs of a path, and is used to find the dirty input of a path that ninja didn't explain.
+func writeRebuildExplanation(w io.Writer, target string, explanations map[string]ninjaExplanation,
+	dirty map[string]bool, inputsOf func(string) ([]string, error)) error {
+
+	isDirty := func(path string) bool {
+		_, explained := explanations[path]
+		return explained || dirty[path]
+	}
+
+	if !isDirty(target) {
+		_, err := fmt.Fprintf(w, "%s is up to date\n", target)
+		return err
+	}
+
+	fmt.Fprintf(w, "%s is rebuilt because:\n", target)
+	seen := make(map[string]bool)
+	for path := target; path != "" && !seen[path]; {
+		seen[path] = true
+		next := ""
+		if e, ok := explanations[path]; ok {
+			if e.input != "" {
+				fmt.Fprintf(w, "  %s: input %s is newer than the output (mtime %s vs %s)\n", path, e.input, e.oldMtime, e.newMtime)
+				next = e.input
+			} else if e.oldMtime != "" {
+				fmt.Fprintf(w, "  %s: %s (old %s, new %s)\n", path, e.message, e.oldMtime, e.newMtime)
+			} else {
+				fmt.Fprintf(w, "  %s: %s\n", path, e.message)
+			}
+		} else {
+			inputs, err := inputsOf(path)
+			if err != nil {
+				return err
+			}
+			for _, input := range inputs {
+				if isDirty(input) {
+					fmt.Fprintf(w, "  %s: input %s is dirty\n", path, input)
+					next = input
+					break
+				}
+			}
+			if next == "" {
+				fmt.Fprintf(w, "  %s: is dirty\n", path)
+			}
+		}
+
+		if next != "" && !isDirty(next) {
+			// The input isn't dirty itself, so it is the file that changed.
+			fmt.Fprintf(w, "The changed input is %s\n", next)
+			break
+		}
+		path = next
+	}
+	return nil
+}
+
+// explainRebuild runs a dry run of ninja with explanations for the output passed to --explain,
+// and prints why it would be rebuilt instead of building it.
+func explainRebuild(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.PrimaryNinja, "explain")
+	defer ctx.EndTrace()
+
+	executable := config.PrebuiltBuildTool("ninja")
+	commonArgs := []string{"-f", config.CombinedNinjaFile()}
+	target := config.ExplainOutput()
+
+	cmd := Command(ctx, config, "ninja", executable,
+		append(commonArgs, "-d", "explain", "-n", "-j", "1", target)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		ctx.Fatalf("Failed to explain %s: %s\n%s", target, err, output)
+	}
+	explanations, dirty, err := parseNinjaExplain(strings.NewReader(string(output)))
+	if err != nil {
+		ctx.Fatalf("Failed to parse ninja explanations: %s", err)
+	}
+
+	inputsOf := func(path string) ([]string, error) {
+		queryCmd := Command(ctx, config, "ninja", executable,
+			append(commonArgs, "-t", "query", path)...)
+		output, err := queryCmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("ninja -t query %s failed: %s", path, err)
+		}
+		return parseNinjaQueryInputs(strings.NewReader(string(output)))
+	}
+
+	if err := writeRebuildExplanation(ctx.Writer, target, explanations, dirty, inputsOf); err != nil {
+		ctx.Fatalf("Failed to explain %s: %s", target, err)
+	}
+}
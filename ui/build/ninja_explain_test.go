@@ -0,0 +1,127 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testNinjaExplainOutput = `ninja explain: out/lib.a is dirty
+ninja explain: output out/foo.o older than most recent input foo/foo.h (100 vs 200)
+ninja explain: out/foo.o is dirty
+ninja explain: command line changed for out/bar.o
+[1/3] cc out/foo.o
+ninja explain: output out/gen.h doesn't exist
+`
+
+const testNinjaQueryOutput = `out/lib.a:
+  input: ar
+    out/bar.o
+    out/foo.o
+    | out/ar_tool
+    || out/gen.h
+  outputs:
+    out/lib.so
+`
+
+func TestParseNinjaExplain(t *testing.T) {
+	explanations, dirty, err := parseNinjaExplain(strings.NewReader(testNinjaExplainOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantExplanations := map[string]ninjaExplanation{
+		"out/foo.o": {
+			message:  "output out/foo.o older than most recent input foo/foo.h (100 vs 200)",
+			input:    "foo/foo.h",
+			oldMtime: "100",
+			newMtime: "200",
+		},
+		"out/bar.o": {message: "command line changed for out/bar.o"},
+		"out/gen.h": {message: "output out/gen.h doesn't exist"},
+	}
+	if !reflect.DeepEqual(explanations, wantExplanations) {
+		t.Errorf("want explanations:\n%#v\ngot:\n%#v", wantExplanations, explanations)
+	}
+
+	wantDirty := map[string]bool{"out/lib.a": true, "out/foo.o": true}
+	if !reflect.DeepEqual(dirty, wantDirty) {
+		t.Errorf("want dirty %v, got %v", wantDirty, dirty)
+	}
+}
+
+func TestParseNinjaQueryInputs(t *testing.T) {
+	inputs, err := parseNinjaQueryInputs(strings.NewReader(testNinjaQueryOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"out/bar.o", "out/foo.o", "out/ar_tool"}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("want inputs %q, got %q", want, inputs)
+	}
+}
+
+func TestWriteRebuildExplanation(t *testing.T) {
+	explanations, dirty, err := parseNinjaExplain(strings.NewReader(`ninja explain: out/lib.a is dirty
+ninja explain: output out/foo.o older than most recent input foo/foo.h (100 vs 200)
+ninja explain: out/foo.o is dirty
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputsOf := func(path string) ([]string, error) {
+		if path == "out/lib.a" {
+			return []string{"out/bar.o", "out/foo.o"}, nil
+		}
+		return nil, fmt.Errorf("unexpected query for %s", path)
+	}
+
+	testCases := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{
+			name:   "changed source",
+			target: "out/lib.a",
+			want: `out/lib.a is rebuilt because:
+  out/lib.a: input out/foo.o is dirty
+  out/foo.o: input foo/foo.h is newer than the output (mtime 100 vs 200)
+The changed input is foo/foo.h
+`,
+		},
+		{
+			name:   "up to date",
+			target: "out/bar.o",
+			want:   "out/bar.o is up to date\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := writeRebuildExplanation(buf, tc.target, explanations, dirty, inputsOf); err != nil {
+				t.Fatal(err)
+			}
+			if g, w := buf.String(), tc.want; g != w {
+				t.Errorf("want:\n%s\ngot:\n%s", w, g)
+			}
+		})
+	}
+}
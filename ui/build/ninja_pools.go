@@ -0,0 +1,83 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NinjaPool is a pool declared by the NinjaPools product variable, that modules can run their
+// memory-hungry actions in.
+type NinjaPool struct {
+	Name  string
+	Depth int
+}
+
+var ninjaPoolNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// The pools that are declared by soong_ui or ninja itself.
+var reservedNinjaPools = []string{"console", "highmem_pool", "local_pool"}
+
+// NinjaPools returns the pools declared by the NinjaPools product variable in soong.variables,
+// which are added to the combined ninja file.  There are none before the product config ran.
+func (c *configImpl) NinjaPools() ([]NinjaPool, error) {
+	file := filepath.Join(c.SoongOutDir(), "soong.variables")
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var variables struct {
+		NinjaPools []string
+	}
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return parseNinjaPools(variables.NinjaPools)
+}
+
+// parseNinjaPools parses pools in the <name>:<depth> format.
+func parseNinjaPools(pools []string) ([]NinjaPool, error) {
+	var ret []NinjaPool
+	seen := make(map[string]bool)
+	for _, pool := range pools {
+		s := strings.SplitN(pool, ":", 2)
+		if len(s) != 2 || !ninjaPoolNameRe.MatchString(s[0]) {
+			return nil, fmt.Errorf("invalid ninja pool %q, expected <name>:<depth>", pool)
+		}
+		name := s[0]
+		depth, err := strconv.Atoi(s[1])
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid depth of ninja pool %q, expected a positive number", pool)
+		}
+		if inList(name, reservedNinjaPools) {
+			return nil, fmt.Errorf("ninja pool %q is reserved", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("ninja pool %q is declared more than once", name)
+		}
+		seen[name] = true
+		ret = append(ret, NinjaPool{Name: name, Depth: depth})
+	}
+	return ret, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNinjaPools(t *testing.T) {
+	testCases := []struct {
+		pools []string
+		want  []NinjaPool
+		err   string
+	}{
+		{
+			pools: nil,
+			want:  nil,
+		},
+		{
+			pools: []string{"link_pool:4", "lto.pool:1"},
+			want:  []NinjaPool{{Name: "link_pool", Depth: 4}, {Name: "lto.pool", Depth: 1}},
+		},
+		{
+			pools: []string{"link_pool"},
+			err:   `invalid ninja pool "link_pool", expected <name>:<depth>`,
+		},
+		{
+			pools: []string{"link pool:4"},
+			err:   `invalid ninja pool "link pool:4", expected <name>:<depth>`,
+		},
+		{
+			pools: []string{"link_pool:0"},
+			err:   `invalid depth of ninja pool "link_pool:0", expected a positive number`,
+		},
+		{
+			pools: []string{"highmem_pool:2"},
+			err:   `ninja pool "highmem_pool" is reserved`,
+		},
+		{
+			pools: []string{"link_pool:2", "link_pool:4"},
+			err:   `ninja pool "link_pool" is declared more than once`,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseNinjaPools(tc.pools)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%q: want error %q, got %v", tc.pools, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tc.pools, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: want %v, got %v", tc.pools, tc.want, got)
+		}
+	}
+}
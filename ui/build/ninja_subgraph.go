@@ -0,0 +1,657 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/ui/metrics"
+)
+
+// The variables that ninja reads from a rule or a build statement.  Only these are written to the
+// subgraph, after expanding all the other variables that they reference.
+var ninjaRuleVariables = []string{
+	"command",
+	"depfile",
+	"deps",
+	"description",
+	"generator",
+	"msvc_deps_prefix",
+	"pool",
+	"restat",
+	"rspfile",
+	"rspfile_content",
+}
+
+// ninjaEvalString is a value or a path of a ninja file, with the variable references not expanded.
+type ninjaEvalString []ninjaEvalPiece
+
+type ninjaEvalPiece struct {
+	// The literal text, or the name of the variable if isVariable is set.
+	text       string
+	isVariable bool
+}
+
+func (e ninjaEvalString) evaluate(lookup func(string) string) string {
+	b := &strings.Builder{}
+	for _, piece := range e {
+		if piece.isVariable {
+			b.WriteString(lookup(piece.text))
+		} else {
+			b.WriteString(piece.text)
+		}
+	}
+	return b.String()
+}
+
+// ninjaScope holds the variables and rules of a ninja file.  Files read with subninja get a scope
+// of their own, files read with include share the scope of the file that includes them.
+type ninjaScope struct {
+	parent *ninjaScope
+	vars   map[string]string
+	rules  map[string]*ninjaRule
+}
+
+func newNinjaScope(parent *ninjaScope) *ninjaScope {
+	return &ninjaScope{
+		parent: parent,
+		vars:   make(map[string]string),
+		rules:  make(map[string]*ninjaRule),
+	}
+}
+
+func (s *ninjaScope) lookupVar(name string) string {
+	for ; s != nil; s = s.parent {
+		if value, ok := s.vars[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func (s *ninjaScope) lookupRule(name string) *ninjaRule {
+	for ; s != nil; s = s.parent {
+		if rule, ok := s.rules[name]; ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+type ninjaRule struct {
+	name  string
+	scope *ninjaScope
+
+	// The bindings of a rule are expanded when it is used, see ninjaGraph.ruleVariables.
+	bindings map[string]ninjaEvalString
+}
+
+type ninjaEdge struct {
+	// nil for phony edges.
+	rule  *ninjaRule
+	scope *ninjaScope
+
+	outputs, implicitOutputs                  []string
+	inputs, implicits, orderOnly, validations []string
+
+	// The bindings of a build statement are expanded when it is read, like the paths.
+	bindings map[string]string
+}
+
+// ninjaGraph is the graph of the build statements of a ninja file and the files it includes.
+type ninjaGraph struct {
+	edges     []*ninjaEdge
+	producers map[string]*ninjaEdge
+
+	// The depth of each pool.
+	pools map[string]string
+}
+
+// readNinjaGraph reads the ninja file and the files it includes and subninjas.  The paths of the
+// included files are relative to the working directory, like for ninja.
+func readNinjaGraph(file string) (*ninjaGraph, error) {
+	g := &ninjaGraph{
+		producers: make(map[string]*ninjaEdge),
+		pools:     make(map[string]string),
+	}
+	if err := g.readFile(file, newNinjaScope(nil)); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *ninjaGraph) readFile(file string, scope *ninjaScope) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	lines := ninjaLogicalLines(string(data))
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line.indented {
+			return fmt.Errorf("%s:%d: unexpected indentation", file, line.number)
+		}
+
+		// The bindings that follow a rule, build or pool statement.
+		var bindings []ninjaLine
+		for i+1 < len(lines) && lines[i+1].indented {
+			i++
+			bindings = append(bindings, lines[i])
+		}
+
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", file, line.number, fmt.Sprintf(format, args...))
+		}
+
+		p := &ninjaLineParser{s: line.text}
+		keyword := p.readIdent()
+		p.skipSpaces()
+		switch keyword {
+		case "rule":
+			rule := &ninjaRule{name: p.readIdent(), scope: scope, bindings: make(map[string]ninjaEvalString)}
+			for _, binding := range bindings {
+				key, value, err := parseNinjaBinding(binding.text)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %s", file, binding.number, err)
+				}
+				rule.bindings[key] = value
+			}
+			scope.rules[rule.name] = rule
+		case "build":
+			if err := g.readEdge(p, bindings, scope); err != nil {
+				return errorf("%s", err)
+			}
+		case "pool":
+			name := p.readIdent()
+			g.pools[name] = ""
+			for _, binding := range bindings {
+				key, value, err := parseNinjaBinding(binding.text)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %s", file, binding.number, err)
+				}
+				if key == "depth" {
+					g.pools[name] = value.evaluate(scope.lookupVar)
+				}
+			}
+		case "default":
+			// The subgraph has its own default target.
+		case "include", "subninja":
+			path, err := p.readEvalString(false)
+			if err != nil {
+				return errorf("%s", err)
+			}
+			childScope := scope
+			if keyword == "subninja" {
+				childScope = newNinjaScope(scope)
+			}
+			if err := g.readFile(strings.TrimSpace(path.evaluate(scope.lookupVar)), childScope); err != nil {
+				return err
+			}
+		default:
+			key, value, err := parseNinjaBinding(line.text)
+			if err != nil {
+				return errorf("%s", err)
+			}
+			scope.vars[key] = value.evaluate(scope.lookupVar)
+		}
+	}
+	return nil
+}
+
+// readEdge reads a build statement.  Its bindings are expanded first, so that they can be used in
+// its paths.
+func (g *ninjaGraph) readEdge(p *ninjaLineParser, bindings []ninjaLine, scope *ninjaScope) error {
+	edge := &ninjaEdge{scope: scope, bindings: make(map[string]string)}
+	lookup := func(name string) string {
+		if value, ok := edge.bindings[name]; ok {
+			return value
+		}
+		return scope.lookupVar(name)
+	}
+	for _, binding := range bindings {
+		key, value, err := parseNinjaBinding(binding.text)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", binding.number, err)
+		}
+		edge.bindings[key] = value.evaluate(lookup)
+	}
+
+	readPaths := func(list *[]string) error {
+		for {
+			p.skipSpaces()
+			if p.done() || p.peek() == ':' || p.peek() == '|' {
+				return nil
+			}
+			path, err := p.readEvalString(true)
+			if err != nil {
+				return err
+			}
+			*list = append(*list, filepath.Clean(path.evaluate(lookup)))
+		}
+	}
+
+	if err := readPaths(&edge.outputs); err != nil {
+		return err
+	}
+	if p.consume("|") {
+		if err := readPaths(&edge.implicitOutputs); err != nil {
+			return err
+		}
+	}
+	if !p.consume(":") {
+		return fmt.Errorf("expected ':' after the outputs")
+	}
+	p.skipSpaces()
+	ruleName := p.readIdent()
+	if ruleName != "phony" {
+		edge.rule = scope.lookupRule(ruleName)
+		if edge.rule == nil {
+			return fmt.Errorf("unknown rule %q", ruleName)
+		}
+	}
+
+	list := &edge.inputs
+	for {
+		if err := readPaths(list); err != nil {
+			return err
+		}
+		if p.done() {
+			break
+		}
+		switch {
+		case p.consume("||"):
+			list = &edge.orderOnly
+		case p.consume("|@"):
+			list = &edge.validations
+		case p.consume("|"):
+			list = &edge.implicits
+		default:
+			return fmt.Errorf("unexpected %q in the inputs", p.peek())
+		}
+	}
+
+	g.edges = append(g.edges, edge)
+	for _, output := range append(edge.outputs, edge.implicitOutputs...) {
+		g.producers[output] = edge
+	}
+	return nil
+}
+
+// subgraph returns the edges needed to build target, in the order that they were read.
+func (g *ninjaGraph) subgraph(target string) ([]*ninjaEdge, error) {
+	target = filepath.Clean(target)
+	if g.producers[target] == nil {
+		return nil, fmt.Errorf("no ninja target named %q", target)
+	}
+
+	needed := make(map[*ninjaEdge]bool)
+	queue := []string{target}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		edge := g.producers[path]
+		if edge == nil || needed[edge] {
+			continue
+		}
+		needed[edge] = true
+		for _, list := range [][]string{edge.inputs, edge.implicits, edge.orderOnly, edge.validations} {
+			queue = append(queue, list...)
+		}
+	}
+
+	var edges []*ninjaEdge
+	for _, edge := range g.edges {
+		if needed[edge] {
+			edges = append(edges, edge)
+		}
+	}
+	return edges, nil
+}
+
+// ruleVariables returns the variables that ninja reads for the edge, escaped for a ninja file.  All
+// the variables they reference are expanded, except $in, $in_newline and $out that ninja expands
+// from the paths of the edge.
+func (g *ninjaGraph) ruleVariables(edge *ninjaEdge) map[string]string {
+	var expand func(name string, expanding map[string]bool) string
+	expand = func(name string, expanding map[string]bool) string {
+		switch name {
+		case "in", "in_newline", "out":
+			return "${" + name + "}"
+		}
+		if value, ok := edge.bindings[name]; ok {
+			return escapeNinjaValue(value)
+		}
+		if value, ok := edge.rule.bindings[name]; ok && !expanding[name] {
+			expanding[name] = true
+			defer delete(expanding, name)
+			b := &strings.Builder{}
+			for _, piece := range value {
+				if piece.isVariable {
+					b.WriteString(expand(piece.text, expanding))
+				} else {
+					b.WriteString(escapeNinjaValue(piece.text))
+				}
+			}
+			return b.String()
+		}
+		return escapeNinjaValue(edge.scope.lookupVar(name))
+	}
+
+	vars := make(map[string]string)
+	for _, name := range ninjaRuleVariables {
+		_, inRule := edge.rule.bindings[name]
+		_, inEdge := edge.bindings[name]
+		if inRule || inEdge {
+			vars[name] = expand(name, make(map[string]bool))
+		}
+	}
+	return vars
+}
+
+// writeNinjaSubgraphFile writes a ninja file with the edges, with a rule for each set of expanded
+// rule variables, so that it doesn't depend on the variables of the files the edges were read from.
+func writeNinjaSubgraphFile(w io.Writer, g *ninjaGraph, edges []*ninjaEdge, target, builddir string) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# The part of the build graph needed to build %s.\n\n", target)
+	fmt.Fprintf(b, "builddir = %s\n\n", escapeNinjaValue(builddir))
+
+	// The rules for the edges, by their variables.
+	rules := make(map[string]string)
+	ruleNames := make(map[string]bool)
+	edgeRules := make([]string, len(edges))
+	pools := make(map[string]bool)
+	var rulesText strings.Builder
+	for i, edge := range edges {
+		if edge.rule == nil {
+			edgeRules[i] = "phony"
+			continue
+		}
+		vars := g.ruleVariables(edge)
+		var definition strings.Builder
+		for _, name := range ninjaRuleVariables {
+			if value, ok := vars[name]; ok {
+				fmt.Fprintf(&definition, "    %s = %s\n", name, value)
+			}
+		}
+		if pool := vars["pool"]; pool != "" {
+			pools[pool] = true
+		}
+
+		name, ok := rules[definition.String()]
+		if !ok {
+			name = edge.rule.name
+			for n := 2; ruleNames[name]; n++ {
+				name = fmt.Sprintf("%s_%d", edge.rule.name, n)
+			}
+			ruleNames[name] = true
+			rules[definition.String()] = name
+			fmt.Fprintf(&rulesText, "rule %s\n%s\n", name, definition.String())
+		}
+		edgeRules[i] = name
+	}
+
+	var poolNames []string
+	for pool := range pools {
+		poolNames = append(poolNames, pool)
+	}
+	sort.Strings(poolNames)
+	for _, pool := range poolNames {
+		if depth, ok := g.pools[pool]; ok {
+			fmt.Fprintf(b, "pool %s\n    depth = %s\n\n", pool, depth)
+		}
+	}
+	b.WriteString(rulesText.String())
+
+	writePaths := func(prefix string, paths []string) {
+		if len(paths) > 0 {
+			b.WriteString(prefix)
+			for _, path := range paths {
+				b.WriteString(" " + escapeNinjaPath(path))
+			}
+		}
+	}
+	for i, edge := range edges {
+		b.WriteString("build")
+		writePaths("", edge.outputs)
+		writePaths(" |", edge.implicitOutputs)
+		b.WriteString(": " + edgeRules[i])
+		writePaths("", edge.inputs)
+		writePaths(" |", edge.implicits)
+		writePaths(" ||", edge.orderOnly)
+		writePaths(" |@", edge.validations)
+		b.WriteString("\n")
+		if dyndep, ok := edge.bindings["dyndep"]; ok {
+			fmt.Fprintf(b, "    dyndep = %s\n", escapeNinjaValue(dyndep))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(b, "default %s\n", escapeNinjaPath(target))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeNinjaValue(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func escapeNinjaPath(s string) string {
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(s)
+}
+
+// ninjaLine is a line of a ninja file, with the escaped newlines joined.
+type ninjaLine struct {
+	text     string
+	number   int
+	indented bool
+}
+
+// ninjaLogicalLines splits a ninja file into lines, skipping the empty lines and the comments.
+func ninjaLogicalLines(data string) []ninjaLine {
+	var lines []ninjaLine
+	b := &strings.Builder{}
+	number, start := 1, 1
+	flush := func() {
+		text := b.String()
+		b.Reset()
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		lines = append(lines, ninjaLine{
+			text:     strings.TrimRight(trimmed, "\r"),
+			number:   start,
+			indented: len(trimmed) < len(text),
+		})
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '$' && i+1 < len(data) && (data[i+1] == '\n' ||
+			(data[i+1] == '\r' && i+2 < len(data) && data[i+2] == '\n')):
+			// An escaped newline continues the line, without the indentation of the next one.
+			if data[i+1] == '\r' {
+				i++
+			}
+			i++
+			number++
+			for i+1 < len(data) && data[i+1] == ' ' {
+				i++
+			}
+		case c == '$' && i+1 < len(data):
+			b.WriteByte(c)
+			b.WriteByte(data[i+1])
+			i++
+		case c == '\n':
+			flush()
+			number++
+			start = number
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return lines
+}
+
+// parseNinjaBinding parses a "name = value" line.
+func parseNinjaBinding(line string) (string, ninjaEvalString, error) {
+	p := &ninjaLineParser{s: line}
+	key := p.readIdent()
+	p.skipSpaces()
+	if key == "" || !p.consume("=") {
+		return "", nil, fmt.Errorf("expected a variable assignment, got %q", line)
+	}
+	p.skipSpaces()
+	value, err := p.readEvalString(false)
+	return key, value, err
+}
+
+type ninjaLineParser struct {
+	s   string
+	pos int
+}
+
+func (p *ninjaLineParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *ninjaLineParser) peek() byte {
+	return p.s[p.pos]
+}
+
+func (p *ninjaLineParser) consume(token string) bool {
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *ninjaLineParser) skipSpaces() {
+	for !p.done() && p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func isNinjaVarChar(c byte, allowDot bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || (allowDot && c == '.')
+}
+
+func (p *ninjaLineParser) readIdent() string {
+	start := p.pos
+	for !p.done() && isNinjaVarChar(p.peek(), true) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// readEvalString reads a value up to the end of the line, or a path up to the next unescaped
+// space, colon or pipe.
+func (p *ninjaLineParser) readEvalString(path bool) (ninjaEvalString, error) {
+	var e ninjaEvalString
+	literal := &strings.Builder{}
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			e = append(e, ninjaEvalPiece{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for !p.done() {
+		c := p.peek()
+		if path && (c == ' ' || c == ':' || c == '|') {
+			break
+		}
+		p.pos++
+		if c != '$' {
+			literal.WriteByte(c)
+			continue
+		}
+		if p.done() {
+			return nil, fmt.Errorf("unexpected '$' at the end of the line")
+		}
+		switch c := p.peek(); {
+		case c == '$' || c == ' ' || c == ':':
+			literal.WriteByte(c)
+			p.pos++
+		case c == '{':
+			end := strings.IndexByte(p.s[p.pos:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("missing '}' in %q", p.s)
+			}
+			flushLiteral()
+			e = append(e, ninjaEvalPiece{text: p.s[p.pos+1 : p.pos+end], isVariable: true})
+			p.pos += end + 1
+		case isNinjaVarChar(c, false):
+			start := p.pos
+			for !p.done() && isNinjaVarChar(p.peek(), false) {
+				p.pos++
+			}
+			flushLiteral()
+			e = append(e, ninjaEvalPiece{text: p.s[start:p.pos], isVariable: true})
+		default:
+			return nil, fmt.Errorf("bad $-escape %q in %q", "$"+string(c), p.s)
+		}
+	}
+	flushLiteral()
+	return e, nil
+}
+
+// writeNinjaSubgraph writes the part of the combined ninja file needed to build the module passed
+// to --ninja-subgraph to a ninja file that can be built on its own.  The subgraph is read from the
+// ninja files of the build, so it uses the same variants of the module and its dependencies.
+func writeNinjaSubgraph(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.PrimaryNinja, "ninja_subgraph")
+	defer ctx.EndTrace()
+
+	target := config.NinjaSubgraph()
+	g, err := readNinjaGraph(config.CombinedNinjaFile())
+	if err != nil {
+		ctx.Fatalf("Failed to read the ninja files: %s", err)
+	}
+	edges, err := g.subgraph(target)
+	if err != nil {
+		ctx.Fatalf("Failed to find the ninja subgraph: %s", err)
+	}
+
+	file := config.NinjaSubgraphFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		ctx.Fatalf("Failed to create the directory of the ninja subgraph: %s", err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		ctx.Fatalf("Failed to create the ninja subgraph: %s", err)
+	}
+	defer f.Close()
+
+	// A builddir of its own keeps the ninja log of the full build untouched.
+	builddir := strings.TrimSuffix(file, ".ninja")
+	if err := writeNinjaSubgraphFile(f, g, edges, target, builddir); err != nil {
+		ctx.Fatalf("Failed to write the ninja subgraph: %s", err)
+	}
+
+	ctx.Printf("Wrote the %d build statements needed to build %s to %s, build them with:\n  %s -f %s",
+		len(edges), target, file, config.PrebuiltBuildTool("ninja"), file)
+}
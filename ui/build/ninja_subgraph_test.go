@@ -0,0 +1,165 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCombinedNinja = `builddir = out
+cflags = -O2
+
+pool highmem_pool
+    depth = 2
+
+# The rules of the sub ninja file can use the variables of this one.
+rule cc
+    command = clang $cflags $extra -c $in -o $out
+    description = CC $out
+    depfile = $out.d
+    deps = gcc
+
+subninja $dir/soong.ninja
+include $dir/extra.ninja
+
+build m: phony lib
+default m
+`
+
+const testSoongNinja = `cflags = -O0
+
+rule link
+    command = ld.lld $
+        @$out.rsp -o $out
+    rspfile = $out.rsp
+    rspfile_content = $in_newline
+    pool = highmem_pool
+
+build out/foo.o: cc src/foo$ bar.c | out/gen.h || out/dir
+    extra = -DHOME=$$HOME
+build out/gen.h: phony
+build out/lib.so | out/lib.so.toc: link out/foo.o |@ out/check
+build out/check: cc src/check.c
+build lib: phony out/lib.so
+build out/unused.o: cc src/unused.c
+`
+
+const testExtraNinja = `build out/dir: phony
+`
+
+const testNinjaSubgraph = `# The part of the build graph needed to build lib.
+
+builddir = out/ninja_subgraph/lib
+
+pool highmem_pool
+    depth = 2
+
+rule cc
+    command = clang -O0 -DHOME=$$HOME -c ${in} -o ${out}
+    depfile = ${out}.d
+    deps = gcc
+    description = CC ${out}
+
+rule link
+    command = ld.lld @${out}.rsp -o ${out}
+    pool = highmem_pool
+    rspfile = ${out}.rsp
+    rspfile_content = ${in_newline}
+
+rule cc_2
+    command = clang -O0  -c ${in} -o ${out}
+    depfile = ${out}.d
+    deps = gcc
+    description = CC ${out}
+
+build out/foo.o: cc src/foo$ bar.c | out/gen.h || out/dir
+
+build out/gen.h: phony
+
+build out/lib.so | out/lib.so.toc: link out/foo.o |@ out/check
+
+build out/check: cc_2 src/check.c
+
+build lib: phony out/lib.so
+
+build out/dir: phony
+
+default lib
+`
+
+func TestNinjaSubgraph(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"combined.ninja": "dir = " + dir + "\n" + testCombinedNinja,
+		"soong.ninja":    testSoongNinja,
+		"extra.ninja":    testExtraNinja,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := readNinjaGraph(filepath.Join(dir, "combined.ninja"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges, err := g.subgraph("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	if err := writeNinjaSubgraphFile(b, g, edges, "lib", "out/ninja_subgraph/lib"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != testNinjaSubgraph {
+		t.Errorf("want:\n%s\ngot:\n%s", testNinjaSubgraph, b.String())
+	}
+
+	// The subgraph can be read again, and contains the same graph.
+	subgraphFile := filepath.Join(dir, "subgraph.ninja")
+	if err := ioutil.WriteFile(subgraphFile, []byte(b.String()), 0666); err != nil {
+		t.Fatal(err)
+	}
+	subgraph, err := readNinjaGraph(subgraphFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := len(subgraph.edges), len(edges); g != w {
+		t.Errorf("want %d edges in the subgraph, got %d", w, g)
+	}
+	if g, w := subgraph.producers["out/foo.o"].inputs, []string{"src/foo bar.c"}; len(g) != 1 || g[0] != w[0] {
+		t.Errorf("want inputs %q, got %q", w, g)
+	}
+
+	if _, err := g.subgraph("missing"); err == nil {
+		t.Errorf("expected an error for a missing target")
+	}
+}
+
+func TestNinjaLogicalLines(t *testing.T) {
+	lines := ninjaLogicalLines("a = b$\n    c\n# comment\n\n  d = $$$\n  e\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %#v", lines)
+	}
+	if g, w := lines[0], (ninjaLine{text: "a = bc", number: 1}); g != w {
+		t.Errorf("want %#v, got %#v", w, g)
+	}
+	if g, w := lines[1], (ninjaLine{text: "d = $$e", number: 5, indented: true}); g != w {
+		t.Errorf("want %#v, got %#v", w, g)
+	}
+}
@@ -239,6 +239,20 @@ func (c *Cmd) wrapSandbox() {
 		sandboxArgs = append(sandboxArgs, "-B", ccacheDir)
 	}
 
+	if cacheDir := c.config.HostToolCacheDir(); cacheDir != "" {
+		// nsjail can only bind mount directories that exist.
+		if err := os.MkdirAll(cacheDir, 0777); err != nil {
+			c.ctx.Fatalf("Failed to create SOONG_HOST_TOOL_CACHE %s: %s", cacheDir, err)
+		}
+		sandboxArgs = append(sandboxArgs, "-B", cacheDir)
+	}
+
+	if !c.Deadline.IsZero() {
+		// nsjail kills the sandboxed process when it receives a signal itself, pass the interrupt
+		// sent at the deadline on so that the process can stop cleanly.
+		sandboxArgs = append(sandboxArgs, "--forward_signals")
+	}
+
 	// Stop nsjail from parsing arguments
 	sandboxArgs = append(sandboxArgs, "--")
 
@@ -44,6 +44,7 @@ const (
 	jsonModuleGraphTag = "modulegraph"
 	queryviewTag       = "queryview"
 	soongDocsTag       = "soong_docs"
+	checkOnlyTag       = "check_only"
 
 	// bootstrapEpoch is used to determine if an incremental build is incompatible with the current
 	// version of bootstrap and needs cleaning before continuing the build.  Increment this for
@@ -237,6 +238,7 @@ func bootstrapGlobFileList(config Config) []string {
 		config.NamedGlobFile(jsonModuleGraphTag),
 		config.NamedGlobFile(queryviewTag),
 		config.NamedGlobFile(soongDocsTag),
+		config.NamedGlobFile(checkOnlyTag),
 	}
 }
 
@@ -251,6 +253,29 @@ func bootstrapBlueprint(ctx Context, config Config) {
 	if config.EmptyNinjaFile() {
 		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--empty-ninja-file")
 	}
+	if config.DependencyGraph() {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--dependency_graph_file", config.DependencyGraphFile())
+	}
+	if config.ProfileSoong() {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs,
+			"--cpuprofile", config.SoongCpuProfileFile(),
+			"--mutator_profile", config.SoongMutatorProfileFile())
+	}
+	if config.SoongSingleThreaded() {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--single_threaded_mutators")
+	}
+	if config.ModuleVariants() != "" {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs,
+			"--module_variants", config.ModuleVariants(),
+			"--module_variants_file", config.ModuleVariantsFile())
+		if config.ModuleProperties() {
+			mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--module_variant_properties")
+		}
+	}
+	if analysisCacheEnabled(config) {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--analysis_cache_manifest",
+			filepath.Join(config.SoongOutDir(), analysisCacheManifestFile))
+	}
 
 	mainSoongBuildInvocation := primaryBuilderInvocation(
 		config,
@@ -310,12 +335,23 @@ func bootstrapBlueprint(ctx Context, config Config) {
 		fmt.Sprintf("generating Soong docs at %s", config.SoongDocsHtml()),
 	)
 
+	checkOnlyInvocation := primaryBuilderInvocation(
+		config,
+		checkOnlyTag,
+		config.CheckOnlyMarkerFile(),
+		[]string{
+			"--check_only_marker", config.CheckOnlyMarkerFile(),
+		},
+		"checking Android.bp files",
+	)
+
 	globFiles := []string{
 		config.NamedGlobFile(soongBuildTag),
 		config.NamedGlobFile(bp2buildTag),
 		config.NamedGlobFile(jsonModuleGraphTag),
 		config.NamedGlobFile(queryviewTag),
 		config.NamedGlobFile(soongDocsTag),
+		config.NamedGlobFile(checkOnlyTag),
 	}
 
 	// The glob .ninja files are subninja'd. However, they are generated during
@@ -346,7 +382,8 @@ func bootstrapBlueprint(ctx Context, config Config) {
 			bp2buildInvocation,
 			jsonModuleGraphInvocation,
 			queryviewInvocation,
-			soongDocsInvocation},
+			soongDocsInvocation,
+			checkOnlyInvocation},
 	}
 
 	bootstrapDeps := bootstrap.RunBlueprint(blueprintArgs, bootstrap.DoEverything, blueprintCtx, blueprintConfig)
@@ -425,6 +462,10 @@ func runSoong(ctx Context, config Config) {
 		if config.SoongDocs() {
 			checkEnvironmentFile(soongBuildEnv, config.UsedEnvFile(soongDocsTag))
 		}
+
+		if config.CheckOnly() {
+			checkEnvironmentFile(soongBuildEnv, config.UsedEnvFile(checkOnlyTag))
+		}
 	}()
 
 	runMicrofactory(ctx, config, "bpglob", "github.com/google/blueprint/bootstrap/bpglob",
@@ -484,15 +525,51 @@ func runSoong(ctx Context, config Config) {
 		targets = append(targets, config.SoongDocsHtml())
 	}
 
-	if config.SoongBuildInvocationNeeded() {
+	if config.CheckOnly() {
+		targets = append(targets, config.CheckOnlyMarkerFile())
+	}
+
+	// The analysis cache is only used for builds that only need soong_build to generate
+	// build.ninja, so if it restores build.ninja there is nothing else to do.
+	cache := newAnalysisCache(ctx, config)
+	restored := false
+	if cache != nil {
+		func() {
+			ctx.BeginTrace(metrics.RunSoong, "analysis cache")
+			defer ctx.EndTrace()
+
+			// The cache key depends on the soong_build binary.
+			ninja("soong_build", "bootstrap.ninja", cache.soongBuildBinary(config))
+			if !cache.soongBuildNeeded(ctx, config) {
+				// build.ninja is up to date, there is nothing to restore or store.
+				cache = nil
+				return
+			}
+			cache.computeKey(ctx, config)
+			if !cache.validate {
+				restored = cache.restore(ctx, config, soongBuildEnv)
+			}
+		}()
+	}
+
+	if config.SoongBuildInvocationNeeded() && !restored {
 		// This build generates <builddir>/build.ninja, which is used later by build/soong/ui/build/build.go#Build().
 		targets = append(targets, config.SoongNinjaFile())
 	}
 
-	ninja("bootstrap", "bootstrap.ninja", targets...)
+	if !restored {
+		ninja("bootstrap", "bootstrap.ninja", targets...)
+	}
+
+	if cache != nil && !restored {
+		if cache.validate {
+			cache.check(ctx, config, soongBuildEnv)
+		}
+		cache.store(ctx, config)
+	}
 
 	var soongBuildMetrics *soong_metrics_proto.SoongBuildMetrics
-	if shouldCollectBuildSoongMetrics(config) {
+	if shouldCollectBuildSoongMetrics(config) && !restored {
 		soongBuildMetrics := loadSoongBuildMetrics(ctx, config)
 		logSoongBuildMetrics(ctx, soongBuildMetrics)
 	}
@@ -510,6 +587,10 @@ func runSoong(ctx Context, config Config) {
 	if config.JsonModuleGraph() {
 		distGzipFile(ctx, config, config.ModuleGraphFile(), "soong")
 	}
+
+	if config.CompressJsonOutputs() {
+		compressJsonOutputs(ctx, config)
+	}
 }
 
 func runMicrofactory(ctx Context, config Config, name string, pkg string, mapping map[string]string) {
@@ -18,11 +18,20 @@ package build
 // another.
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"android/soong/ui/logger"
 	"android/soong/ui/metrics"
 
 	"google.golang.org/protobuf/proto"
@@ -34,34 +43,52 @@ const (
 	// Used to generate a raw protobuf file that contains information
 	// of the list of metrics files from host to destination storage.
 	uploadPbFilename = ".uploader.pb"
+
+	// Metrics files smaller than this size are not worth compressing before
+	// they are uploaded.
+	metricsCompressionThreshold = 64 * 1024
+
+	// The maximum number of metrics files that are copied concurrently.
+	maxMetricsCopyWorkers = 8
 )
 
 var (
 	// For testing purpose.
 	tmpDir = ioutil.TempDir
+	sleep  = time.Sleep
 )
 
 // pruneMetricsFiles iterates the list of paths, checking if a path exist.
 // If a path is a file, it is added to the return list. If the path is a
 // directory, a recursive call is made to add the children files of the
-// path.
-func pruneMetricsFiles(paths []string) []string {
+// path. Symlinks are skipped unless followSymlinks is set, as they may point
+// to unrelated files outside of the out directory.
+func pruneMetricsFiles(paths []string, followSymlinks bool) []string {
+	stat := os.Lstat
+	if followSymlinks {
+		stat = os.Stat
+	}
+
 	var metricsFiles []string
 	for _, p := range paths {
-		fi, err := os.Stat(p)
+		fi, err := stat(p)
 		// Some paths passed may not exist. For example, build errors protobuf
 		// file may not exist since the build was successful.
 		if err != nil {
 			continue
 		}
 
+		if fi.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+
 		if fi.IsDir() {
 			if l, err := ioutil.ReadDir(p); err == nil {
 				files := make([]string, 0, len(l))
 				for _, fi := range l {
 					files = append(files, filepath.Join(p, fi.Name()))
 				}
-				metricsFiles = append(metricsFiles, pruneMetricsFiles(files)...)
+				metricsFiles = append(metricsFiles, pruneMetricsFiles(files, followSymlinks)...)
 			}
 		} else {
 			metricsFiles = append(metricsFiles, p)
@@ -70,41 +97,196 @@ func pruneMetricsFiles(paths []string) []string {
 	return metricsFiles
 }
 
+// sha256File returns the SHA-256 checksum and the size of the file at path.
+func sha256File(path string) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return h.Sum(nil), size, nil
+}
+
+// verifyMetricsFileCopy compares the checksums of src and its copy dst to
+// guard against truncated copies, e.g. when the disk is full. The check is
+// skipped for empty files.
+func verifyMetricsFileCopy(ctx Context, src, dst string, size int64) error {
+	if size == 0 {
+		return nil
+	}
+
+	srcSum, srcSize, err := sha256File(src)
+	if err != nil {
+		return err
+	}
+	dstSum, dstSize, err := sha256File(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcSum, dstSum) {
+		ctx.Printf("checksum mismatch between %q (%d bytes) and its copy %q (%d bytes)\n", src, srcSize, dst, dstSize)
+		return fmt.Errorf("checksum of %q does not match %q", dst, src)
+	}
+	return nil
+}
+
+// copyMetricsFile copies src to dst in dir, compressing the copy if configured
+// to. It returns the path of the file to upload.
+func copyMetricsFile(ctx Context, config Config, dir, src, dst string) (string, error) {
+	size, err := copyFile(src, dst)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy %q to %q: %v", src, dst, err)
+	}
+	if err := verifyMetricsFileCopy(ctx, src, dst, size); err != nil {
+		return "", fmt.Errorf("failed to copy %q to %q: %v", src, dst, err)
+	}
+	if config.CompressMetrics() && size >= metricsCompressionThreshold {
+		if err := gzipFileToDir(dst, dir); err != nil {
+			return "", fmt.Errorf("failed to compress %q: %v", dst, err)
+		}
+		dst += ".gz"
+	}
+	return dst, nil
+}
+
+// metricsFileCopyNames returns the paths in dir of the copies of files. The
+// copies keep the base names of files, and a file whose base name is already
+// taken by a previous file gets a numbered suffix before its extension, so
+// that the concurrent copies never write the same file.
+func metricsFileCopyNames(dir string, files []string) []string {
+	taken := make(map[string]bool, len(files))
+	names := make([]string, len(files))
+	for i, file := range files {
+		base := filepath.Base(file)
+		name := base
+		for n := 1; taken[name]; n++ {
+			ext := filepath.Ext(base)
+			name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(base, ext), n, ext)
+		}
+		taken[name] = true
+		names[i] = filepath.Join(dir, name)
+	}
+	return names
+}
+
+// copyMetricsFiles copies the metrics files into dir using a bounded pool of
+// workers. The returned paths are in the same order as files. If any copy
+// failed, the error of the first failed file in the list is returned.
+func copyMetricsFiles(ctx Context, config Config, dir string, files []string) ([]string, error) {
+	dsts := metricsFileCopyNames(dir, files)
+	copies := make([]string, len(files))
+	if len(files) == 1 {
+		var err error
+		copies[0], err = copyMetricsFile(ctx, config, dir, files[0], dsts[0])
+		return copies, err
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > maxMetricsCopyWorkers {
+		workers = maxMetricsCopyWorkers
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	errs := make([]error, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				copies[i], errs[i] = copyMetricsFile(ctx, config, dir, files[i], dsts[i])
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return copies, nil
+}
+
 // UploadMetrics uploads a set of metrics files to a server for analysis.
 // The metrics files are first copied to a temporary directory
 // and the uploader is then executed in the background to allow the user/system
 // to continue working. Soong communicates to the uploader through the
-// upload_proto raw protobuf file.
+// upload_proto raw protobuf file. The time spent uploading is recorded in the
+// metrics together with whether the upload succeeded.
 func UploadMetrics(ctx Context, config Config, simpleOutput bool, buildStarted time.Time, paths ...string) {
 	ctx.BeginTrace(metrics.RunSetupTool, "upload_metrics")
-	defer ctx.EndTrace()
+	// A failed upload calls ctx.Fatal, so succeeded is only set if
+	// uploadMetrics returns normally.
+	succeeded := false
+	defer func() { ctx.EndTraceWithResult(succeeded) }()
 
-	uploader := config.MetricsUploaderApp()
-	if uploader == "" {
+	uploadMetrics(ctx, config, simpleOutput, buildStarted, paths...)
+	succeeded = true
+}
+
+// uploadMetrics runs every configured metrics uploader with the same set of
+// metrics files. A failing uploader does not prevent the remaining ones from
+// running, the failures are reported together once all uploaders ran.
+func uploadMetrics(ctx Context, config Config, simpleOutput bool, buildStarted time.Time, paths ...string) {
+	uploaders := config.MetricsUploaderApps()
+	if len(uploaders) == 0 {
 		// If the uploader path was not specified, no metrics shall be uploaded.
 		return
 	}
 
 	// Several of the files might be directories.
-	metricsFiles := pruneMetricsFiles(paths)
+	metricsFiles := pruneMetricsFiles(paths, config.MetricsFollowSymlinks())
 	if len(metricsFiles) == 0 {
 		return
 	}
 
+	var errs []string
+	for _, uploader := range uploaders {
+		func() {
+			defer logger.Recover(func(err error) {
+				errs = append(errs, fmt.Sprintf("%s: %v", uploader, err))
+			})
+			uploadMetricsWith(ctx, config, simpleOutput, buildStarted, uploader, metricsFiles)
+		}()
+	}
+
+	succeeded := len(uploaders) - len(errs)
+	if len(errs) > 0 {
+		ctx.Fatalf("%d of %d metrics uploads succeeded, failures:\n%s", succeeded, len(uploaders), strings.Join(errs, "\n"))
+	}
+	ctx.Verbosef("%d of %d metrics uploads succeeded\n", succeeded, len(uploaders))
+}
+
+// uploadMetricsWith copies the metrics files to a temporary directory owned
+// by uploader and runs it.
+func uploadMetricsWith(ctx Context, config Config, simpleOutput bool, buildStarted time.Time, uploader string, metricsFiles []string) {
 	// The temporary directory cannot be deleted as the metrics uploader is started
 	// in the background and requires to exist until the operation is done. The
 	// uploader can delete the directory as it is specified in the upload proto.
+	// Every uploader gets its own directory so that one uploader deleting it
+	// does not affect the others.
 	tmpDir, err := tmpDir("", "upload_metrics")
 	if err != nil {
 		ctx.Fatalf("failed to create a temporary directory to store the list of metrics files: %v\n", err)
 	}
 
-	for i, src := range metricsFiles {
-		dst := filepath.Join(tmpDir, filepath.Base(src))
-		if _, err := copyFile(src, dst); err != nil {
-			ctx.Fatalf("failed to copy %q to %q: %v\n", src, dst, err)
-		}
-		metricsFiles[i] = dst
+	metricsFiles, err = copyMetricsFiles(ctx, config, tmpDir, metricsFiles)
+	if err != nil {
+		ctx.Fatalln(err)
 	}
 
 	// For platform builds, the branch and target name is hardcoded to specific
@@ -126,12 +308,73 @@ func UploadMetrics(ctx Context, config Config, simpleOutput bool, buildStarted t
 		ctx.Fatalf("failed to write the marshaled metrics upload protobuf to %q: %v\n", pbFile, err)
 	}
 
-	// Start the uploader in the background as it takes several milliseconds to start the uploader
-	// and prepare the metrics for upload. This affects small shell commands like "lunch".
-	cmd := Command(ctx, config, "upload metrics", uploader, "--upload-metrics", pbFile)
-	if simpleOutput {
-		cmd.RunOrFatal()
-	} else {
-		cmd.RunAndStreamOrFatal()
+	args := []string{"--upload-metrics", pbFile}
+	if config.MetricsUploadDryRun() {
+		logUploadDryRun(ctx, tmpDir, uploader, args)
+		return
+	}
+
+	uploadCtx, cancel := uploaderContext(ctx, config.MetricsUploadTimeout())
+	defer cancel()
+	if err := runUploader(uploadCtx, config, simpleOutput, uploader, args...); err != nil {
+		if uploadCtx.Err() == context.DeadlineExceeded {
+			// The uploader was killed before it could delete the temporary directory.
+			os.RemoveAll(tmpDir)
+			ctx.Fatalf("metrics uploader %q timed out after %s and was killed\n", uploader, config.MetricsUploadTimeout())
+		}
+		ctx.Fatalf("upload metrics failed with: %v\n", err)
+	}
+}
+
+// uploaderContext returns a copy of ctx whose context.Context is cancelled
+// after timeout, which kills the uploader subprocess. A zero timeout means the
+// uploader is never timed out.
+func uploaderContext(ctx Context, timeout time.Duration) (Context, context.CancelFunc) {
+	impl := *ctx.ContextImpl
+	cancel := func() {}
+	if timeout > 0 {
+		impl.Context, cancel = context.WithTimeout(ctx.Context, timeout)
+	}
+	return Context{&impl}, cancel
+}
+
+// logUploadDryRun logs the uploader command line and the files that would have
+// been uploaded instead of running the uploader. As nothing else deletes the
+// temporary directory in that case, it is removed here.
+func logUploadDryRun(ctx Context, tmpDir, uploader string, args []string) {
+	defer os.RemoveAll(tmpDir)
+
+	ctx.Printf("metrics upload dry run, not executing: %q\n", append([]string{uploader}, args...))
+	l, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		ctx.Printf("failed to list %q: %v\n", tmpDir, err)
+		return
+	}
+	for _, fi := range l {
+		ctx.Printf("  %s (%d bytes)\n", filepath.Join(tmpDir, fi.Name()), fi.Size())
+	}
+}
+
+// runUploader executes the metrics uploader. A failed upload is retried up to
+// config.MetricsUploadRetries() times, doubling the delay between every
+// attempt. It returns the error of the final attempt.
+func runUploader(ctx Context, config Config, simpleOutput bool, uploader string, args ...string) error {
+	retries := config.MetricsUploadRetries()
+	delay := config.MetricsUploadRetryDelay()
+	for attempt := 0; ; attempt++ {
+		// Each attempt runs to completion, or until ctx times out and the uploader is killed.
+		cmd := Command(ctx, config, "upload metrics", uploader, args...)
+		var err error
+		if simpleOutput {
+			err = cmd.Run()
+		} else {
+			err = cmd.RunAndStream()
+		}
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		ctx.Printf("metrics upload attempt %d of %d failed: %v, retrying in %s\n", attempt+1, retries+1, err, delay)
+		sleep(delay)
+		delay *= 2
 	}
 }
@@ -15,6 +15,8 @@
 package build
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -27,6 +29,10 @@ import (
 	"time"
 
 	"android/soong/ui/logger"
+
+	"google.golang.org/protobuf/proto"
+
+	upload_proto "android/soong/ui/metrics/upload_proto"
 )
 
 func TestPruneMetricsFiles(t *testing.T) {
@@ -56,13 +62,26 @@ func TestPruneMetricsFiles(t *testing.T) {
 		}
 	}
 
+	// Symlinks to a file and a directory outside of the pruned directory.
+	outsideDir := t.TempDir()
+	outsideFile := filepath.Join(outsideDir, "f1")
+	if err := ioutil.WriteFile(outsideFile, []byte{}, 0777); err != nil {
+		t.Fatalf("got %v, expecting nil error on writing file %q", err, outsideFile)
+	}
+	if err := os.Symlink(outsideFile, filepath.Join(rootDir, "d1", "link_f1")); err != nil {
+		t.Fatalf("got %v, expecting nil error on creating symlink", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(rootDir, "d1", "link_d1")); err != nil {
+		t.Fatalf("got %v, expecting nil error on creating symlink", err)
+	}
+
 	want := []string{
 		filepath.Join(rootDir, "d1", "f1"),
 		filepath.Join(rootDir, "d1", "d2", "f1"),
 		filepath.Join(rootDir, "d1", "d2", "d3", "f1"),
 	}
 
-	got := pruneMetricsFiles([]string{rootDir})
+	got := pruneMetricsFiles([]string{rootDir}, false)
 
 	sort.Strings(got)
 	sort.Strings(want)
@@ -70,6 +89,19 @@ func TestPruneMetricsFiles(t *testing.T) {
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("got %q, want %q after pruning metrics files", got, want)
 	}
+
+	want = append(want,
+		filepath.Join(rootDir, "d1", "link_d1", "f1"),
+		filepath.Join(rootDir, "d1", "link_f1"))
+
+	got = pruneMetricsFiles([]string{rootDir}, true)
+
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q after pruning metrics files following symlinks", got, want)
+	}
 }
 
 func TestUploadMetrics(t *testing.T) {
@@ -199,3 +231,478 @@ func TestUploadMetricsErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestUploadMetricsRetries(t *testing.T) {
+	ctx := testContext()
+	tests := []struct {
+		description string
+		retries     int
+		failures    int
+		wantErr     bool
+		wantDelays  []time.Duration
+	}{{
+		description: "no retries fails on first error",
+		retries:     0,
+		failures:    1,
+		wantErr:     true,
+	}, {
+		description: "succeeds after retrying",
+		retries:     3,
+		failures:    2,
+		wantDelays:  []time.Duration{time.Second, 2 * time.Second},
+	}, {
+		description: "retries exhausted",
+		retries:     2,
+		failures:    5,
+		wantErr:     true,
+		wantDelays:  []time.Duration{time.Second, 2 * time.Second},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			outDir := t.TempDir()
+
+			orgTmpDir := tmpDir
+			tmpDir = func(string, string) (string, error) {
+				retDir := filepath.Join(outDir, "tmp_upload_dir")
+				if err := os.Mkdir(retDir, 0755); err != nil {
+					t.Fatalf("failed to create temporary directory %q: %v", retDir, err)
+				}
+				return retDir, nil
+			}
+			defer func() { tmpDir = orgTmpDir }()
+
+			var gotDelays []time.Duration
+			orgSleep := sleep
+			sleep = func(d time.Duration) { gotDelays = append(gotDelays, d) }
+			defer func() { sleep = orgSleep }()
+
+			// The fake uploader records every attempt in a separate file and
+			// fails until it has been run more than tt.failures times.
+			attemptsDir := filepath.Join(outDir, "attempts")
+			if err := os.Mkdir(attemptsDir, 0755); err != nil {
+				t.Fatalf("failed to create %q: %v", attemptsDir, err)
+			}
+			uploader := filepath.Join(outDir, "uploader.sh")
+			script := "#!/bin/sh\n" +
+				"n=0\n" +
+				"while [ -f \"" + attemptsDir + "/$n\" ]; do n=$((n+1)); done\n" +
+				": > \"" + attemptsDir + "/$n\"\n" +
+				"[ $n -ge " + strconv.Itoa(tt.failures) + " ]\n"
+			if err := ioutil.WriteFile(uploader, []byte(script), 0755); err != nil {
+				t.Fatalf("failed to create fake uploader %q: %v", uploader, err)
+			}
+
+			metricsFile := filepath.Join(outDir, "metrics_file_1")
+			if err := ioutil.WriteFile(metricsFile, []byte("test file"), 0644); err != nil {
+				t.Fatalf("failed to create a fake metrics file %q for uploading: %v", metricsFile, err)
+			}
+
+			config := Config{&configImpl{
+				environ: &Environment{
+					"OUT_DIR=" + outDir,
+				},
+				metricsUploader:         uploader,
+				metricsUploadRetries:    tt.retries,
+				metricsUploadRetryDelay: time.Second,
+			}}
+
+			var gotErr error
+			func() {
+				defer logger.Recover(func(err error) {
+					gotErr = err
+				})
+				UploadMetrics(ctx, config, true, time.Now(), metricsFile)
+			}()
+
+			if tt.wantErr && gotErr == nil {
+				t.Errorf("got nil, expecting an upload failure")
+			} else if !tt.wantErr && gotErr != nil {
+				t.Errorf("got unexpected error: %v", gotErr)
+			}
+
+			wantAttempts := tt.failures + 1
+			if wantAttempts > tt.retries+1 {
+				wantAttempts = tt.retries + 1
+			}
+			attempts, err := ioutil.ReadDir(attemptsDir)
+			if err != nil {
+				t.Fatalf("failed to read %q: %v", attemptsDir, err)
+			}
+			if len(attempts) != wantAttempts {
+				t.Errorf("got %d uploader attempts, want %d", len(attempts), wantAttempts)
+			}
+			if !reflect.DeepEqual(gotDelays, tt.wantDelays) {
+				t.Errorf("got retry delays %v, want %v", gotDelays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestUploadMetricsCompression(t *testing.T) {
+	ctx := testContext()
+	defer logger.Recover(func(err error) {
+		t.Fatalf("got unexpected error: %v", err)
+	})
+
+	outDir := t.TempDir()
+	uploadDir := filepath.Join(outDir, "tmp_upload_dir")
+	orgTmpDir := tmpDir
+	tmpDir = func(string, string) (string, error) {
+		if err := os.Mkdir(uploadDir, 0755); err != nil {
+			t.Fatalf("failed to create temporary directory %q: %v", uploadDir, err)
+		}
+		return uploadDir, nil
+	}
+	defer func() { tmpDir = orgTmpDir }()
+
+	largeContents := bytes.Repeat([]byte("large metrics file"), metricsCompressionThreshold)
+	largeFile := filepath.Join(outDir, "large_metrics_file")
+	if err := ioutil.WriteFile(largeFile, largeContents, 0644); err != nil {
+		t.Fatalf("failed to create a fake metrics file %q for uploading: %v", largeFile, err)
+	}
+	smallFile := filepath.Join(outDir, "small_metrics_file")
+	if err := ioutil.WriteFile(smallFile, []byte("small metrics file"), 0644); err != nil {
+		t.Fatalf("failed to create a fake metrics file %q for uploading: %v", smallFile, err)
+	}
+
+	config := Config{&configImpl{
+		environ: &Environment{
+			"OUT_DIR=" + outDir,
+		},
+		metricsUploader: "echo",
+		compressMetrics: true,
+	}}
+
+	UploadMetrics(ctx, config, true, time.Now(), largeFile, smallFile)
+
+	data, err := ioutil.ReadFile(filepath.Join(uploadDir, uploadPbFilename))
+	if err != nil {
+		t.Fatalf("failed to read the upload proto: %v", err)
+	}
+	upload := &upload_proto.Upload{}
+	if err := proto.Unmarshal(data, upload); err != nil {
+		t.Fatalf("failed to unmarshal the upload proto: %v", err)
+	}
+	want := []string{
+		filepath.Join(uploadDir, "large_metrics_file.gz"),
+		filepath.Join(uploadDir, "small_metrics_file"),
+	}
+	if got := upload.GetMetricsFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q as the uploaded metrics files", got, want)
+	}
+
+	f, err := os.Open(want[0])
+	if err != nil {
+		t.Fatalf("failed to open the compressed metrics file: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("%q is not a gzip stream: %v", want[0], err)
+	}
+	got, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress %q: %v", want[0], err)
+	}
+	if !bytes.Equal(got, largeContents) {
+		t.Errorf("decompressed %q does not match the original metrics file", want[0])
+	}
+}
+
+func TestVerifyMetricsFileCopy(t *testing.T) {
+	ctx := testContext()
+	tests := []struct {
+		description string
+		src         string
+		dst         string
+		expectedErr string
+	}{{
+		description: "identical copy",
+		src:         "test file",
+		dst:         "test file",
+	}, {
+		description: "truncated copy",
+		src:         "test file",
+		dst:         "test",
+		expectedErr: "does not match",
+	}, {
+		description: "empty file is not checked",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			dst := filepath.Join(dir, "dst")
+			if err := ioutil.WriteFile(src, []byte(tt.src), 0644); err != nil {
+				t.Fatalf("failed to create %q: %v", src, err)
+			}
+			if err := ioutil.WriteFile(dst, []byte(tt.dst), 0644); err != nil {
+				t.Fatalf("failed to create %q: %v", dst, err)
+			}
+
+			err := verifyMetricsFileCopy(ctx, src, dst, int64(len(tt.src)))
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("got unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("got %v, want %q to be contained in error", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestUploadMetricsDryRun(t *testing.T) {
+	var log bytes.Buffer
+	ctx := testContext()
+	ctx.Logger = logger.New(&log)
+	defer logger.Recover(func(err error) {
+		t.Fatalf("got unexpected error: %v", err)
+	})
+
+	outDir := t.TempDir()
+	uploadDir := filepath.Join(outDir, "tmp_upload_dir")
+	orgTmpDir := tmpDir
+	tmpDir = func(string, string) (string, error) {
+		if err := os.Mkdir(uploadDir, 0755); err != nil {
+			t.Fatalf("failed to create temporary directory %q: %v", uploadDir, err)
+		}
+		return uploadDir, nil
+	}
+	defer func() { tmpDir = orgTmpDir }()
+
+	metricsFile := filepath.Join(outDir, "metrics_file_1")
+	if err := ioutil.WriteFile(metricsFile, []byte("test file"), 0644); err != nil {
+		t.Fatalf("failed to create a fake metrics file %q for uploading: %v", metricsFile, err)
+	}
+
+	// The uploader does not exist, so the upload fails if it is executed.
+	uploader := filepath.Join(outDir, "non_existent_uploader")
+	config := Config{&configImpl{
+		environ: &Environment{
+			"OUT_DIR=" + outDir,
+		},
+		metricsUploader:     uploader,
+		metricsUploadDryRun: true,
+	}}
+
+	UploadMetrics(ctx, config, true, time.Now(), metricsFile)
+
+	for _, want := range []string{uploader, "--upload-metrics", filepath.Join(uploadDir, "metrics_file_1")} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("got log %q, want %q to be logged", log.String(), want)
+		}
+	}
+	if _, err := os.Stat(uploadDir); !os.IsNotExist(err) {
+		t.Errorf("got %v, expecting %q to be removed", err, uploadDir)
+	}
+}
+
+func TestCopyMetricsFiles(t *testing.T) {
+	ctx := testContext()
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	var files, want []string
+	for i := 0; i < 3*maxMetricsCopyWorkers; i++ {
+		name := "metrics_file_" + strconv.Itoa(i)
+		src := filepath.Join(srcDir, name)
+		if err := ioutil.WriteFile(src, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create a fake metrics file %q: %v", src, err)
+		}
+		files = append(files, src)
+		want = append(want, filepath.Join(dstDir, name))
+	}
+
+	config := Config{&configImpl{}}
+	got, err := copyMetricsFiles(ctx, config, dstDir, files)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q as the copied metrics files", got, want)
+	}
+	for _, dst := range got {
+		contents, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", dst, err)
+		}
+		if string(contents) != filepath.Base(dst) {
+			t.Errorf("got %q, want %q as the contents of %q", contents, filepath.Base(dst), dst)
+		}
+	}
+
+	files = append(files, filepath.Join(srcDir, "non_existent_file"))
+	if _, err := copyMetricsFiles(ctx, config, dstDir, files); err == nil || !strings.Contains(err.Error(), "failed to copy") {
+		t.Errorf("got %v, want a copy failure", err)
+	}
+}
+
+func TestCopyMetricsFilesWithSameBaseName(t *testing.T) {
+	ctx := testContext()
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	var files []string
+	for _, dir := range []string{"a", "b", "c"} {
+		src := filepath.Join(srcDir, dir, "metrics.pb")
+		if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(src, []byte(dir), 0644); err != nil {
+			t.Fatalf("failed to create a fake metrics file %q: %v", src, err)
+		}
+		files = append(files, src)
+	}
+	files = append(files, filepath.Join(srcDir, "metrics_1.pb"))
+	if err := ioutil.WriteFile(files[3], []byte("d"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{&configImpl{}}
+	got, err := copyMetricsFiles(ctx, config, dstDir, files)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []string{
+		filepath.Join(dstDir, "metrics.pb"),
+		filepath.Join(dstDir, "metrics_1.pb"),
+		filepath.Join(dstDir, "metrics_2.pb"),
+		filepath.Join(dstDir, "metrics_1_1.pb"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q as the copied metrics files", got, want)
+	}
+	for i, dst := range got {
+		contents, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", dst, err)
+		}
+		if want := []string{"a", "b", "c", "d"}[i]; string(contents) != want {
+			t.Errorf("got %q, want %q as the contents of %q", contents, want, dst)
+		}
+	}
+}
+
+func TestUploadMetricsTimeout(t *testing.T) {
+	ctx := testContext()
+	outDir := t.TempDir()
+
+	uploadDir := filepath.Join(outDir, "tmp_upload_dir")
+	orgTmpDir := tmpDir
+	tmpDir = func(string, string) (string, error) {
+		if err := os.Mkdir(uploadDir, 0755); err != nil {
+			t.Fatalf("failed to create temporary directory %q: %v", uploadDir, err)
+		}
+		return uploadDir, nil
+	}
+	defer func() { tmpDir = orgTmpDir }()
+
+	// The fake uploader hangs much longer than the timeout.
+	uploader := filepath.Join(outDir, "uploader.sh")
+	if err := ioutil.WriteFile(uploader, []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatalf("failed to create fake uploader %q: %v", uploader, err)
+	}
+
+	metricsFile := filepath.Join(outDir, "metrics_file_1")
+	if err := ioutil.WriteFile(metricsFile, []byte("test file"), 0644); err != nil {
+		t.Fatalf("failed to create a fake metrics file %q for uploading: %v", metricsFile, err)
+	}
+
+	config := Config{&configImpl{
+		environ: &Environment{
+			"OUT_DIR=" + outDir,
+		},
+		metricsUploader:      uploader,
+		metricsUploadTimeout: 100 * time.Millisecond,
+	}}
+
+	start := time.Now()
+	var gotErr error
+	func() {
+		defer logger.Recover(func(err error) {
+			gotErr = err
+		})
+		UploadMetrics(ctx, config, true, time.Now(), metricsFile)
+	}()
+
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "timed out") {
+		t.Errorf("got %v, expecting the upload to time out", gotErr)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("upload took %s, expecting the uploader to be killed", elapsed)
+	}
+	if _, err := os.Stat(uploadDir); !os.IsNotExist(err) {
+		t.Errorf("got %v, expecting %q to be removed", err, uploadDir)
+	}
+}
+
+func TestUploadMetricsMultipleUploaders(t *testing.T) {
+	ctx := testContext()
+	outDir := t.TempDir()
+
+	orgTmpDir := tmpDir
+	tmpDir = func(string, string) (string, error) {
+		return ioutil.TempDir(outDir, "tmp_upload_dir")
+	}
+	defer func() { tmpDir = orgTmpDir }()
+
+	// Every fake uploader leaves a marker file behind when it is run.
+	markersDir := filepath.Join(outDir, "markers")
+	if err := os.Mkdir(markersDir, 0755); err != nil {
+		t.Fatalf("failed to create %q: %v", markersDir, err)
+	}
+	fakeUploader := func(name string, exitCode int) string {
+		uploader := filepath.Join(outDir, name)
+		script := "#!/bin/sh\n" +
+			": > \"" + filepath.Join(markersDir, name) + "\"\n" +
+			"exit " + strconv.Itoa(exitCode) + "\n"
+		if err := ioutil.WriteFile(uploader, []byte(script), 0755); err != nil {
+			t.Fatalf("failed to create fake uploader %q: %v", uploader, err)
+		}
+		return uploader
+	}
+
+	metricsFile := filepath.Join(outDir, "metrics_file_1")
+	if err := ioutil.WriteFile(metricsFile, []byte("test file"), 0644); err != nil {
+		t.Fatalf("failed to create a fake metrics file %q for uploading: %v", metricsFile, err)
+	}
+
+	config := Config{&configImpl{
+		environ: &Environment{
+			"OUT_DIR=" + outDir,
+		},
+		metricsUploader: fakeUploader("uploader_1", 0),
+		metricsUploaders: []string{
+			fakeUploader("uploader_2", 1),
+			fakeUploader("uploader_3", 0),
+		},
+	}}
+
+	var gotErr error
+	func() {
+		defer logger.Recover(func(err error) {
+			gotErr = err
+		})
+		UploadMetrics(ctx, config, true, time.Now(), metricsFile)
+	}()
+
+	if gotErr == nil {
+		t.Fatalf("got nil, expecting the failure of uploader_2")
+	}
+	for _, want := range []string{"2 of 3", "uploader_2"} {
+		if !strings.Contains(gotErr.Error(), want) {
+			t.Errorf("got %q, want %q to be contained in error", gotErr.Error(), want)
+		}
+	}
+
+	for _, name := range []string{"uploader_1", "uploader_2", "uploader_3"} {
+		if _, err := os.Stat(filepath.Join(markersDir, name)); err != nil {
+			t.Errorf("expecting %s to have run: %v", name, err)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
 func absPath(ctx Context, p string) string {
@@ -170,3 +171,13 @@ func gzipFileToDir(src, destDir string) error {
 
 	return nil
 }
+
+// freeSpaceForPath returns the number of bytes available to unprivileged users
+// on the filesystem containing path.
+func freeSpaceForPath(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -119,3 +119,17 @@ func TestCopyFileErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestFreeSpaceForPath(t *testing.T) {
+	free, err := freeSpaceForPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("freeSpaceForPath failed: %v", err)
+	}
+	if free == 0 {
+		t.Errorf("expected a nonzero amount of free space")
+	}
+
+	if _, err := freeSpaceForPath("/non/existent/path"); err == nil {
+		t.Errorf("expected an error for a non-existent path")
+	}
+}
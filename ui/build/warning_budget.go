@@ -0,0 +1,172 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"android/soong/ui/status"
+)
+
+const warningBaselineHeader = `# The number of compiler warnings allowed in each category, checked by soong_ui when
+# SOONG_WARNING_BASELINE points to this file.  Categories that are not listed allow no warnings.
+`
+
+// startWarningCounter starts counting the compiler warnings of the ninja actions if
+// SOONG_WARNING_BASELINE is set, and returns nil otherwise.
+func startWarningCounter(ctx Context, config Config) *status.WarningCounter {
+	if config.WarningBaseline() == "" {
+		return nil
+	}
+	counter := status.NewWarningCounter(ctx.Logger, filepath.Join(config.OutDir(), "warnings_by_action.json"))
+	ctx.Status.AddOutput(counter)
+	return counter
+}
+
+// checkWarningBudget fails the build if a category has more warnings than the baseline allows.
+// The current counts are written to $OUT_DIR/warning_counts.txt in the format of the baseline, so
+// that the baseline can be updated by copying it.
+func checkWarningBudget(ctx Context, config Config, counter *status.WarningCounter) {
+	if counter == nil {
+		return
+	}
+	ctx.BeginTrace("soong_ui", "warning_budget")
+	defer ctx.EndTrace()
+
+	baseline := config.WarningBaseline()
+	budget, err := readWarningBaseline(baseline)
+	if err != nil {
+		ctx.Fatalf("Failed to read the warning baseline: %s", err)
+	}
+
+	counter.Prune(ninjaGraphOutputs(ctx, config))
+
+	counts := counter.Counts()
+	countsFile := filepath.Join(config.OutDir(), "warning_counts.txt")
+	if err := ioutil.WriteFile(countsFile, []byte(formatWarningCounts(counts)), 0666); err != nil {
+		ctx.Fatalf("Failed to write the warning counts: %s", err)
+	}
+
+	exceeded, lowered := compareWarningBudget(counts, budget)
+	updateCommand := fmt.Sprintf("cp %s %s", countsFile, baseline)
+	if len(exceeded) > 0 {
+		ctx.Fatalf("The number of compiler warnings exceeds the budget in %s:\n  %s\n"+
+			"Fix the new warnings, or if they are expected, update the baseline with:\n  %s",
+			baseline, strings.Join(exceeded, "\n  "), updateCommand)
+	}
+	if len(lowered) > 0 {
+		ctx.Printf("Compiler warnings were fixed in %d categories, lower the budget with:\n  %s",
+			len(lowered), updateCommand)
+	}
+}
+
+// ninjaGraphOutputs returns the outputs of all the actions in the combined ninja file.
+func ninjaGraphOutputs(ctx Context, config Config) map[string]bool {
+	cmd := Command(ctx, config, "ninja", config.PrebuiltBuildTool("ninja"),
+		"-f", config.CombinedNinjaFile(), "-t", "targets", "all")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		ctx.Fatal(err)
+	}
+	cmd.StartOrFatal()
+
+	// Each line is "<output>: <rule>".
+	outputs := make(map[string]bool)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.LastIndex(line, ": "); i >= 0 {
+			outputs[line[:i]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		ctx.Fatalf("Failed to read the outputs of the build graph: %s", err)
+	}
+	cmd.WaitOrFatal()
+	return outputs
+}
+
+// compareWarningBudget returns the descriptions of the categories that have more warnings than the
+// budget, and the names of the ones that have less.
+func compareWarningBudget(counts, budget map[string]int) (exceeded, lowered []string) {
+	for _, category := range sortedWarningCategories(counts) {
+		if counts[category] > budget[category] {
+			exceeded = append(exceeded, fmt.Sprintf("%s: %d warnings, %d allowed",
+				category, counts[category], budget[category]))
+		}
+	}
+	for _, category := range sortedWarningCategories(budget) {
+		if counts[category] < budget[category] {
+			lowered = append(lowered, category)
+		}
+	}
+	return exceeded, lowered
+}
+
+// readWarningBaseline reads a baseline file with a category and its number of warnings on each
+// line.  A missing file allows no warnings.
+func readWarningBaseline(file string) (map[string]int, error) {
+	budget := make(map[string]int)
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return budget, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a category and a number of warnings", file, lineNumber)
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid number of warnings %q", file, lineNumber, fields[1])
+		}
+		budget[fields[0]] = count
+	}
+	return budget, scanner.Err()
+}
+
+func formatWarningCounts(counts map[string]int) string {
+	b := &strings.Builder{}
+	b.WriteString(warningBaselineHeader)
+	for _, category := range sortedWarningCategories(counts) {
+		fmt.Fprintf(b, "%s %d\n", category, counts[category])
+	}
+	return b.String()
+}
+
+func sortedWarningCategories(m map[string]int) []string {
+	var categories []string
+	for category := range m {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
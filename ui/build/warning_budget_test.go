@@ -0,0 +1,82 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWarningBaseline(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.txt")
+
+	budget, err := readWarningBaseline(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budget) != 0 {
+		t.Errorf("expected a missing baseline to allow no warnings, got %v", budget)
+	}
+
+	counts := map[string]int{"-Wunused-variable": 3, "javac:deprecation": 1}
+	if err := ioutil.WriteFile(baseline, []byte(formatWarningCounts(counts)), 0666); err != nil {
+		t.Fatal(err)
+	}
+	budget, err = readWarningBaseline(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(budget, counts) {
+		t.Errorf("want %v, got %v", counts, budget)
+	}
+
+	if err := ioutil.WriteFile(baseline, []byte("-Wunused-variable three\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readWarningBaseline(baseline); err == nil {
+		t.Errorf("expected an error for an invalid number of warnings")
+	}
+}
+
+func TestCompareWarningBudget(t *testing.T) {
+	budget := map[string]int{
+		"-Wunused-variable":  3,
+		"-Wformat":           2,
+		"javac:deprecation":  1,
+		"-Wdeprecated-enums": 4,
+	}
+	counts := map[string]int{
+		"-Wunused-variable":  4,
+		"-Wformat":           2,
+		"-Wnew-category":     1,
+		"-Wdeprecated-enums": 1,
+	}
+
+	exceeded, lowered := compareWarningBudget(counts, budget)
+	wantExceeded := []string{
+		"-Wnew-category: 1 warnings, 0 allowed",
+		"-Wunused-variable: 4 warnings, 3 allowed",
+	}
+	if !reflect.DeepEqual(exceeded, wantExceeded) {
+		t.Errorf("want exceeded %q, got %q", wantExceeded, exceeded)
+	}
+	wantLowered := []string{"-Wdeprecated-enums", "javac:deprecation"}
+	if !reflect.DeepEqual(lowered, wantLowered) {
+		t.Errorf("want lowered %q, got %q", wantLowered, lowered)
+	}
+}
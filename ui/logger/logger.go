@@ -20,7 +20,9 @@
 // the log file by default, unless SetVerbose(true) has been called.
 //
 // The log file also includes extended date/time/source information, which are
-// omitted from the stderr output for better readability.
+// omitted from the stderr output for better readability. After SetJSON(true),
+// the log file is written as newline-delimited JSON records instead, while the
+// stderr output is unchanged.
 //
 // In order to better handle resource cleanup after a Fatal error, the Fatal
 // functions panic instead of calling os.Exit(). To actually do the cleanup,
@@ -29,6 +31,7 @@
 package logger
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -36,9 +39,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 type Logger interface {
@@ -134,9 +140,26 @@ func Recover(fn func(err error)) {
 	}
 }
 
+// Levels of the records written to the log file in JSON mode.
+const (
+	levelInfo    = "info"
+	levelVerbose = "verbose"
+	levelFatal   = "fatal"
+	levelPanic   = "panic"
+)
+
+// jsonRecord is a single line of the log file in JSON mode.
+type jsonRecord struct {
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
 type stdLogger struct {
 	stderr  *log.Logger
 	verbose bool
+	json    bool
 
 	fileLogger *log.Logger
 	mutex      sync.Mutex
@@ -162,6 +185,13 @@ func (s *stdLogger) SetVerbose(v bool) *stdLogger {
 	return s
 }
 
+// SetJSON controls whether the file-backed log is written as
+// newline-delimited JSON records.
+func (s *stdLogger) SetJSON(v bool) *stdLogger {
+	s.json = v
+	return s
+}
+
 // SetOutput controls where the file-backed log will be saved. It will keep
 // some number of backups of old log files.
 func (s *stdLogger) SetOutput(path string) *stdLogger {
@@ -219,66 +249,89 @@ func (s *stdLogger) Cleanup() {
 
 // Output writes string to both stderr and the file log.
 func (s *stdLogger) Output(calldepth int, str string) error {
-	s.stderr.Output(calldepth+1, str)
-	return s.fileLogger.Output(calldepth+1, str)
+	return s.output(calldepth+1, levelInfo, str)
 }
 
 // VerboseOutput is equivalent to Output, but only goes to the file log
 // unless SetVerbose(true) has been called.
 func (s *stdLogger) VerboseOutput(calldepth int, str string) error {
-	if s.verbose {
+	return s.output(calldepth+1, levelVerbose, str)
+}
+
+func (s *stdLogger) output(calldepth int, level string, str string) error {
+	if level != levelVerbose || s.verbose {
 		s.stderr.Output(calldepth+1, str)
 	}
-	return s.fileLogger.Output(calldepth+1, str)
+	if !s.json {
+		return s.fileLogger.Output(calldepth+1, str)
+	}
+
+	record := jsonRecord{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Level:     level,
+		Message:   strings.TrimSuffix(str, "\n"),
+	}
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		record.Fields = map[string]string{"source": file + ":" + strconv.Itoa(line)}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.fileLogger.Writer().Write(append(data, '\n'))
+	return err
 }
 
 // Print prints to both stderr and the file log.
 // Arguments are handled in the manner of fmt.Print.
 func (s *stdLogger) Print(v ...interface{}) {
 	output := fmt.Sprint(v...)
-	s.Output(2, output)
+	s.output(2, levelInfo, output)
 }
 
 // Printf prints to both stderr and the file log.
 // Arguments are handled in the manner of fmt.Printf.
 func (s *stdLogger) Printf(format string, v ...interface{}) {
 	output := fmt.Sprintf(format, v...)
-	s.Output(2, output)
+	s.output(2, levelInfo, output)
 }
 
 // Println prints to both stderr and the file log.
 // Arguments are handled in the manner of fmt.Println.
 func (s *stdLogger) Println(v ...interface{}) {
 	output := fmt.Sprintln(v...)
-	s.Output(2, output)
+	s.output(2, levelInfo, output)
 }
 
 // Verbose is equivalent to Print, but only goes to the file log unless
 // SetVerbose(true) has been called.
 func (s *stdLogger) Verbose(v ...interface{}) {
 	output := fmt.Sprint(v...)
-	s.VerboseOutput(2, output)
+	s.output(2, levelVerbose, output)
 }
 
 // Verbosef is equivalent to Printf, but only goes to the file log unless
 // SetVerbose(true) has been called.
 func (s *stdLogger) Verbosef(format string, v ...interface{}) {
 	output := fmt.Sprintf(format, v...)
-	s.VerboseOutput(2, output)
+	s.output(2, levelVerbose, output)
 }
 
 // Verboseln is equivalent to Println, but only goes to the file log unless
 // SetVerbose(true) has been called.
 func (s *stdLogger) Verboseln(v ...interface{}) {
 	output := fmt.Sprintln(v...)
-	s.VerboseOutput(2, output)
+	s.output(2, levelVerbose, output)
 }
 
 // Fatal is equivalent to Print() followed by a call to panic() that
 // Cleanup will convert to a os.Exit(1).
 func (s *stdLogger) Fatal(v ...interface{}) {
 	output := fmt.Sprint(v...)
-	s.Output(2, output)
+	s.output(2, levelFatal, output)
 	panic(fatalLog{errors.New(output)})
 }
 
@@ -286,7 +339,7 @@ func (s *stdLogger) Fatal(v ...interface{}) {
 // Cleanup will convert to a os.Exit(1).
 func (s *stdLogger) Fatalf(format string, v ...interface{}) {
 	output := fmt.Sprintf(format, v...)
-	s.Output(2, output)
+	s.output(2, levelFatal, output)
 	panic(fatalLog{errors.New(output)})
 }
 
@@ -294,27 +347,27 @@ func (s *stdLogger) Fatalf(format string, v ...interface{}) {
 // Cleanup will convert to a os.Exit(1).
 func (s *stdLogger) Fatalln(v ...interface{}) {
 	output := fmt.Sprintln(v...)
-	s.Output(2, output)
+	s.output(2, levelFatal, output)
 	panic(fatalLog{errors.New(output)})
 }
 
 // Panic is equivalent to Print() followed by a call to panic().
 func (s *stdLogger) Panic(v ...interface{}) {
 	output := fmt.Sprint(v...)
-	s.Output(2, output)
+	s.output(2, levelPanic, output)
 	panic(output)
 }
 
 // Panicf is equivalent to Printf() followed by a call to panic().
 func (s *stdLogger) Panicf(format string, v ...interface{}) {
 	output := fmt.Sprintf(format, v...)
-	s.Output(2, output)
+	s.output(2, levelPanic, output)
 	panic(output)
 }
 
 // Panicln is equivalent to Println() followed by a call to panic().
 func (s *stdLogger) Panicln(v ...interface{}) {
 	output := fmt.Sprintln(v...)
-	s.Output(2, output)
+	s.output(2, levelPanic, output)
 	panic(output)
 }
@@ -16,6 +16,7 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,8 +25,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestCreateFileWithRotation(t *testing.T) {
@@ -210,3 +213,68 @@ func TestRuntimePanic(t *testing.T) {
 	*i = 0
 	t.Errorf("Should not get here")
 }
+
+func TestJSONOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	log := New(&bytes.Buffer{}).SetJSON(true)
+	log.SetOutput(path)
+	log.Printf("Test %d", 1)
+	log.Verboseln("Test", 2)
+	log.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	var records []jsonRecord
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		var record jsonRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("Failed to decode log record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	expected := []struct {
+		level   string
+		message string
+	}{
+		{levelInfo, "Test 1"},
+		{levelVerbose, "Test 2"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d log records, got %d: %#v", len(expected), len(records), records)
+	}
+	for i, e := range expected {
+		r := records[i]
+		if r.Level != e.level || r.Message != e.message {
+			t.Errorf("Expected level %q and message %q, got %q and %q", e.level, e.message, r.Level, r.Message)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, r.Timestamp); err != nil {
+			t.Errorf("Failed to parse timestamp %q: %v", r.Timestamp, err)
+		}
+		if !strings.Contains(r.Fields["source"], "logger_test.go") {
+			t.Errorf("Expected source in logger_test.go, got %q", r.Fields["source"])
+		}
+	}
+}
+
+func TestRecoverFatalJSON(t *testing.T) {
+	log := New(&bytes.Buffer{}).SetJSON(true)
+	defer func() {
+		if p := recover(); p != nil {
+			t.Errorf("Unexpected panic: %#v", p)
+		}
+	}()
+	defer Recover(func(err error) {
+		if err.Error() != "Test" {
+			t.Errorf("Expected %q, but got %q", "Test", err.Error())
+		}
+	})
+	log.Fatal("Test")
+	t.Errorf("Should not get here")
+}
@@ -34,6 +34,7 @@ package metrics
 import (
 	"os"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -214,6 +215,24 @@ func (m *Metrics) SetBuildCommand(cmd []string) {
 	m.metrics.BuildCommand = proto.String(strings.Join(cmd, " "))
 }
 
+// SetNinjaEnvironment records the environment variables that Ninja runs with.
+// Sensitive values are expected to have been redacted by the caller.
+func (m *Metrics) SetNinjaEnvironment(env map[string]string) {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	m.metrics.NinjaEnvironment = nil
+	for _, k := range keys {
+		m.metrics.NinjaEnvironment = append(m.metrics.NinjaEnvironment, &soong_metrics_proto.EnvironmentVariable{
+			Key:   proto.String(k),
+			Value: proto.String(env[k]),
+		})
+	}
+}
+
 // Dump exports the collected metrics from the executed build to the file at
 // out path.
 func (m *Metrics) Dump(out string) error {
@@ -258,6 +258,14 @@ func (s *Status) Finish() {
 	}
 }
 
+// Counts returns the current counters across all ToolStatus instances.
+func (s *Status) Counts() Counts {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.counts
+}
+
 func (s *Status) updateTotalActions(diff int) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -0,0 +1,160 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"android/soong/ui/logger"
+)
+
+var (
+	ansiEscapeRe = regexp.MustCompile("\x1b\\[[0-9;]*[mK]")
+
+	// file:line:column: warning: message [-Wcategory], printed by clang and gcc.
+	compilerWarningRe = regexp.MustCompile(`^\S+?:\d+:(?:\d+:)? warning: .* \[(-W[^\],]+)[^\]]*\]$`)
+
+	// file:line: warning: [category] message, printed by javac.
+	javacWarningRe = regexp.MustCompile(`^\S+?:\d+: warning: \[([\w-]+)\] `)
+)
+
+// Warning is a compiler warning printed by an action.
+type Warning struct {
+	// The category of the warning, like "-Wunused-variable" or "javac:deprecation".
+	Category string
+
+	// The line that contains the warning, without colors.
+	Line string
+}
+
+// ParseWarnings returns the compiler warnings in the output of an action.  Warnings without a
+// category are ignored.
+func ParseWarnings(output string) []Warning {
+	var warnings []Warning
+	for _, line := range strings.Split(ansiEscapeRe.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimSpace(line)
+		if match := compilerWarningRe.FindStringSubmatch(line); match != nil {
+			warnings = append(warnings, Warning{Category: match[1], Line: line})
+		} else if match := javacWarningRe.FindStringSubmatch(line); match != nil {
+			warnings = append(warnings, Warning{Category: "javac:" + match[1], Line: line})
+		}
+	}
+	return warnings
+}
+
+// WarningCounter is a StatusOutput that counts the compiler warnings printed by the actions of the
+// build.  Only the actions that run print their warnings, so the warnings of each action are kept
+// in a file and replaced when the action runs again, so that an incremental build counts the
+// warnings of the actions that were up to date too.
+type WarningCounter struct {
+	log  logger.Logger
+	file string
+
+	// The warnings printed by each action, by the outputs of the action.
+	actions map[string][]Warning
+}
+
+var _ StatusOutput = (*WarningCounter)(nil)
+
+// NewWarningCounter returns a WarningCounter that keeps the warnings of the actions in file.
+func NewWarningCounter(log logger.Logger, file string) *WarningCounter {
+	w := &WarningCounter{
+		log:     log,
+		file:    file,
+		actions: make(map[string][]Warning),
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		if err := json.Unmarshal(data, &w.actions); err != nil {
+			// Counting the warnings of the actions that run is still right after a clean build.
+			log.Printf("Ignoring the warnings of the previous builds in %s: %s", file, err)
+			w.actions = make(map[string][]Warning)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Printf("Failed to read the warnings of the previous builds: %s", err)
+	}
+	return w
+}
+
+// Counts returns the number of different warnings in each category.  A warning printed by several
+// actions, like one in a header included by many sources, is counted once.
+func (w *WarningCounter) Counts() map[string]int {
+	seen := make(map[Warning]bool)
+	counts := make(map[string]int)
+	for _, warnings := range w.actions {
+		for _, warning := range warnings {
+			if !seen[warning] {
+				seen[warning] = true
+				counts[warning.Category]++
+			}
+		}
+	}
+	return counts
+}
+
+// Prune forgets the warnings of the actions that have none of their outputs in outputs, the
+// outputs of the actions of the current build graph, so that the warnings of removed modules are
+// not counted anymore.
+func (w *WarningCounter) Prune(outputs map[string]bool) {
+	for key := range w.actions {
+		inGraph := false
+		for _, output := range strings.Fields(key) {
+			if outputs[output] {
+				inGraph = true
+				break
+			}
+		}
+		if !inGraph {
+			delete(w.actions, key)
+		}
+	}
+}
+
+func (w *WarningCounter) StartAction(action *Action, counts Counts) {}
+
+func (w *WarningCounter) FinishAction(result ActionResult, counts Counts) {
+	key := strings.Join(result.Outputs, " ")
+	if key == "" {
+		key = result.Description + " " + result.Command
+	}
+
+	if warnings := ParseWarnings(result.Output); len(warnings) > 0 {
+		w.actions[key] = warnings
+	} else {
+		delete(w.actions, key)
+	}
+}
+
+func (w *WarningCounter) Message(level MsgLevel, message string) {}
+
+func (w *WarningCounter) Flush() {
+	data, err := json.Marshal(w.actions)
+	if err == nil {
+		err = ioutil.WriteFile(w.file+".tmp", data, 0666)
+	}
+	if err == nil {
+		err = os.Rename(w.file+".tmp", w.file)
+	}
+	if err != nil {
+		w.log.Printf("Failed to write the warnings of the build: %s", err)
+	}
+}
+
+func (w *WarningCounter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"android/soong/ui/logger"
+)
+
+func TestParseWarnings(t *testing.T) {
+	output := "In file included from a.c:1:\n" +
+		"foo.h:3:5: warning: unused variable 'x' [-Wunused-variable]\n" +
+		"\x1b[1mbar.cpp:10:2: \x1b[0;1;35mwarning: \x1b[0m\x1b[1mfoo is deprecated [-Wdeprecated-declarations]\x1b[0m\n" +
+		"baz.c:1:1: warning: fallthrough [-Wimplicit-fallthrough,-Wextra]\n" +
+		"Foo.java:12: warning: [deprecation] bar() in Baz has been deprecated\n" +
+		"warning: a warning without a category\n" +
+		"baz.c:2:1: error: an error [-Wformat]\n" +
+		"1 warning generated.\n"
+
+	want := []Warning{
+		{"-Wunused-variable", "foo.h:3:5: warning: unused variable 'x' [-Wunused-variable]"},
+		{"-Wdeprecated-declarations", "bar.cpp:10:2: warning: foo is deprecated [-Wdeprecated-declarations]"},
+		{"-Wimplicit-fallthrough", "baz.c:1:1: warning: fallthrough [-Wimplicit-fallthrough,-Wextra]"},
+		{"javac:deprecation", "Foo.java:12: warning: [deprecation] bar() in Baz has been deprecated"},
+	}
+	if got := ParseWarnings(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWarningCounter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "warnings.json")
+	log := logger.New(ioutil.Discard)
+
+	finish := func(w *WarningCounter, output string, outputs ...string) {
+		w.FinishAction(ActionResult{Action: &Action{Outputs: outputs}, Output: output}, Counts{})
+	}
+	header := "foo.h:3:5: warning: unused variable 'x' [-Wunused-variable]\n"
+
+	w := NewWarningCounter(log, file)
+	finish(w, header, "a.o")
+	// The same warning in a header included by two sources is counted once.
+	finish(w, header, "b.o")
+	finish(w, "c.c:1:1: warning: unused variable 'y' [-Wunused-variable]\n", "c.o")
+	finish(w, "Foo.java:1: warning: [removal] foo\n", "foo.jar")
+	w.Flush()
+
+	want := map[string]int{"-Wunused-variable": 2, "javac:removal": 1}
+	if got := w.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// An incremental build keeps the warnings of the actions that didn't run, and replaces the ones
+	// of the actions that ran.
+	w = NewWarningCounter(log, file)
+	finish(w, "", "c.o")
+	finish(w, "Foo.java:1: warning: [removal] foo\nFoo.java:2: warning: [removal] bar\n", "foo.jar")
+
+	want = map[string]int{"-Wunused-variable": 1, "javac:removal": 2}
+	if got := w.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// The warnings of the actions that were removed from the build graph are forgotten.
+	w.Prune(map[string]bool{"b.o": true, "c.o": true})
+	want = map[string]int{"-Wunused-variable": 1}
+	if got := w.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after pruning: want %v, got %v", want, got)
+	}
+}
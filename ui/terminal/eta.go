@@ -0,0 +1,111 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"fmt"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+const (
+	// The completion rate is measured over the actions that finished in the last etaWindow, so that
+	// the estimate follows the build through phases that are faster or slower than average.
+	etaWindow = time.Minute
+
+	// The completion rate is sampled at most once every etaSampleInterval, which also limits how
+	// often the displayed estimate can change.
+	etaSampleInterval = time.Second
+
+	// No estimate is shown until the build has run for etaMinDuration and etaMinActions actions
+	// have finished, as the estimate is mostly noise before then.
+	etaMinDuration = 10 * time.Second
+	etaMinActions  = 20
+
+	// etaSmoothing is the weight of each new sample in the moving average of the completion rate.
+	etaSmoothing = 0.2
+)
+
+type etaSample struct {
+	time     time.Time
+	finished int
+}
+
+// etaEstimator estimates the time remaining in a build from the rate that actions have finished
+// recently.
+type etaEstimator struct {
+	start   time.Time
+	samples []etaSample
+
+	// The moving average of the completion rate in actions per second.
+	rate     float64
+	haveRate bool
+
+	text string
+}
+
+// update records the counts of a build at time now.
+func (e *etaEstimator) update(now time.Time, counts status.Counts) {
+	if e.start.IsZero() {
+		e.start = now
+		e.samples = []etaSample{{now, counts.FinishedActions}}
+		return
+	}
+
+	if now.Sub(e.samples[len(e.samples)-1].time) < etaSampleInterval {
+		return
+	}
+	e.samples = append(e.samples, etaSample{now, counts.FinishedActions})
+
+	// Drop the samples that have fallen out of the window, keeping at least one to measure from.
+	for len(e.samples) > 2 && now.Sub(e.samples[0].time) > etaWindow {
+		e.samples = e.samples[1:]
+	}
+
+	oldest := e.samples[0]
+	rate := float64(counts.FinishedActions-oldest.finished) / now.Sub(oldest.time).Seconds()
+	if e.haveRate {
+		e.rate = etaSmoothing*rate + (1-etaSmoothing)*e.rate
+	} else {
+		e.rate, e.haveRate = rate, true
+	}
+
+	e.text = ""
+	if now.Sub(e.start) >= etaMinDuration && counts.FinishedActions >= etaMinActions && e.rate > 0 {
+		remaining := float64(counts.TotalActions-counts.FinishedActions) / e.rate
+		e.text = "ETA " + formatETA(time.Duration(remaining*float64(time.Second)))
+	}
+}
+
+// String returns the estimate to display, or an empty string if there isn't one yet.
+func (e *etaEstimator) String() string {
+	return e.text
+}
+
+// formatETA formats d more coarsely the longer it is, so that the estimate doesn't change every
+// time it is displayed.
+func formatETA(d time.Duration) string {
+	switch {
+	case d >= time.Hour-30*time.Second:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute-5*time.Second:
+		d = d.Round(10 * time.Second)
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Round(time.Second).Seconds()))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+func TestEtaEstimator(t *testing.T) {
+	start := time.Unix(1000, 0)
+	counts := func(finished int) status.Counts {
+		return status.Counts{TotalActions: 1000, FinishedActions: finished}
+	}
+
+	e := &etaEstimator{}
+
+	// 10 actions per second for 5 seconds is too early for an estimate.
+	for i := 0; i <= 5; i++ {
+		e.update(start.Add(time.Duration(i)*time.Second), counts(10*i))
+	}
+	if got := e.String(); got != "" {
+		t.Errorf("expected no estimate early in the build, got %q", got)
+	}
+
+	for i := 6; i <= 20; i++ {
+		e.update(start.Add(time.Duration(i)*time.Second), counts(10*i))
+	}
+	if got, want := e.String(), "ETA 1m20s"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A burst of actions within the sample interval doesn't change the estimate.
+	e.update(start.Add(20*time.Second+500*time.Millisecond), counts(400))
+	if got, want := e.String(), "ETA 1m20s"; got != want {
+		t.Errorf("got %q within the sample interval, want %q", got, want)
+	}
+
+	// A slow couple of seconds barely moves the estimate.
+	e.update(start.Add(22*time.Second), counts(210))
+	if got, want := e.String(), "ETA 1m20s"; got != want {
+		t.Errorf("got %q after a slow second, want %q", got, want)
+	}
+}
+
+func TestEtaEstimatorNoProgress(t *testing.T) {
+	start := time.Unix(1000, 0)
+	e := &etaEstimator{}
+	for i := 0; i <= 20; i++ {
+		e.update(start.Add(time.Duration(i)*time.Second), status.Counts{TotalActions: 1000, FinishedActions: 100})
+	}
+	if got := e.String(); got != "" {
+		t.Errorf("expected no estimate when no actions are finishing, got %q", got)
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	testCases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{42*time.Second + 400*time.Millisecond, "42s"},
+		{57 * time.Second, "1m00s"},
+		{3*time.Minute + 24*time.Second, "3m20s"},
+		{59*time.Minute + 40*time.Second, "1h00m"},
+		{2*time.Hour + 5*time.Minute + 20*time.Second, "2h05m"},
+	}
+	for _, tc := range testCases {
+		if got := formatETA(tc.d); got != tc.want {
+			t.Errorf("formatETA(%s) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
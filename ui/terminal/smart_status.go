@@ -49,6 +49,7 @@ type smartStatusOutput struct {
 	termWidth, termHeight int
 
 	runningActions  []actionTableEntry
+	eta             etaEstimator
 	ticker          *time.Ticker
 	done            chan bool
 	sigwinch        chan os.Signal
@@ -142,7 +143,7 @@ func (s *smartStatusOutput) StartAction(action *status.Action, counts status.Cou
 		startTime: startTime,
 	})
 
-	s.statusLine(progress + str)
+	s.statusLine(progress + s.etaString() + str)
 }
 
 func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
@@ -151,13 +152,16 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 		str = result.Command
 	}
 
-	progress := s.formatter.progress(counts) + str
+	progress := s.formatter.progress(counts)
 
 	output := s.formatter.result(result)
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.eta.update(time.Now(), counts)
+	progress += s.etaString() + str
+
 	for i, runningAction := range s.runningActions {
 		if runningAction.action == result.Action {
 			s.runningActions = append(s.runningActions[:i], s.runningActions[i+1:]...)
@@ -174,6 +178,15 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 	}
 }
 
+// etaString returns the estimated time remaining in the build followed by a space, or an empty
+// string if there is no estimate yet.
+func (s *smartStatusOutput) etaString() string {
+	if eta := s.eta.String(); eta != "" {
+		return eta + " "
+	}
+	return ""
+}
+
 func (s *smartStatusOutput) Flush() {
 	if s.tableMode {
 		// Stop the action table tick outside of the lock to avoid lock ordering issues between s.done and
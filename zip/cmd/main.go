@@ -60,6 +60,15 @@ func (file) Set(s string) error {
 	return nil
 }
 
+type explicitFile struct{}
+
+func (explicitFile) String() string { return `""` }
+
+func (explicitFile) Set(s string) error {
+	fileArgsBuilder.ExplicitPathInZip(s)
+	return nil
+}
+
 type listFiles struct{}
 
 func (listFiles) String() string { return `""` }
@@ -145,7 +154,7 @@ func main() {
 
 	flags := flag.NewFlagSet("flags", flag.ExitOnError)
 	flags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: soong_zip -o zipfile [-m manifest] [-C dir] [-f|-l file] [-D dir]...\n")
+		fmt.Fprintf(os.Stderr, "usage: soong_zip -o zipfile [-m manifest] [-C dir] [-e path] [-f|-l file] [-D dir]...\n")
 		flags.PrintDefaults()
 		os.Exit(2)
 	}
@@ -159,6 +168,7 @@ func main() {
 	ignoreMissingFiles := flags.Bool("ignore_missing_files", false, "continue if a requested file does not exist")
 	symlinks := flags.Bool("symlinks", true, "store symbolic links in zip instead of following them")
 	srcJar := flags.Bool("srcjar", false, "move .java files to locations that match their package statement")
+	preserveTimestamps := flags.Bool("preserve_timestamps", false, "store the modification times of files instead of a fixed timestamp")
 
 	parallelJobs := flags.Int("parallel", runtime.NumCPU(), "number of parallel threads to use")
 	cpuProfile := flags.String("cpuprofile", "", "write cpu profile to file")
@@ -169,6 +179,7 @@ func main() {
 	flags.Var(&rspFiles{}, "r", "file containing list of files to zip with Ninja rsp file escaping")
 	flags.Var(&dir{}, "D", "directory to include in zip")
 	flags.Var(&file{}, "f", "file to include in zip")
+	flags.Var(&explicitFile{}, "e", "path in the zip of the file of the next -f argument")
 	flags.Var(&nonDeflatedFiles, "s", "file path to be stored within the zip without compression")
 	flags.Var(&relativeRoot{}, "C", "path to use as relative root of files in following -f, -l, or -D arguments")
 	flags.Var(&junkPaths{}, "j", "junk paths, zip files without directory names")
@@ -224,6 +235,7 @@ func main() {
 		WriteIfChanged:           *writeIfChanged,
 		StoreSymlinks:            *symlinks,
 		IgnoreMissingFiles:       *ignoreMissingFiles,
+		PreserveTimestamps:       *preserveTimestamps,
 	})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err.Error())
@@ -69,6 +69,7 @@ type pathMapping struct {
 
 type FileArg struct {
 	PathPrefixInZip, SourcePrefixToStrip string
+	ExplicitPathInZip                    string
 	SourceFiles                          []string
 	JunkPaths                            bool
 	GlobDir                              string
@@ -105,6 +106,13 @@ func (b *FileArgsBuilder) PathPrefixInZip(rootPrefix string) *FileArgsBuilder {
 	return b
 }
 
+// ExplicitPathInZip sets the path in the zip of the file of the next call to File, in place of its
+// path relative to the relative root.  The path prefix in the zip still applies to it.
+func (b *FileArgsBuilder) ExplicitPathInZip(s string) *FileArgsBuilder {
+	b.state.ExplicitPathInZip = s
+	return b
+}
+
 func (b *FileArgsBuilder) File(name string) *FileArgsBuilder {
 	if b.err != nil {
 		return b
@@ -113,10 +121,21 @@ func (b *FileArgsBuilder) File(name string) *FileArgsBuilder {
 	arg := b.state
 	arg.SourceFiles = []string{name}
 	b.fileArgs = append(b.fileArgs, arg)
+	b.state.ExplicitPathInZip = ""
 	return b
 }
 
+// checkNoExplicitPathInZip sets the error if the explicit path in the zip was set for a list of
+// files instead of a single file.
+func (b *FileArgsBuilder) checkNoExplicitPathInZip(arg string) {
+	if b.err == nil && b.state.ExplicitPathInZip != "" {
+		b.err = fmt.Errorf("the path in the zip %q must be followed by a single file, not %s",
+			b.state.ExplicitPathInZip, arg)
+	}
+}
+
 func (b *FileArgsBuilder) Dir(name string) *FileArgsBuilder {
+	b.checkNoExplicitPathInZip("the directory " + name)
 	if b.err != nil {
 		return b
 	}
@@ -129,6 +148,7 @@ func (b *FileArgsBuilder) Dir(name string) *FileArgsBuilder {
 
 // List reads the file names from the given file and adds them to the source files list.
 func (b *FileArgsBuilder) List(name string) *FileArgsBuilder {
+	b.checkNoExplicitPathInZip("the list " + name)
 	if b.err != nil {
 		return b
 	}
@@ -154,6 +174,7 @@ func (b *FileArgsBuilder) List(name string) *FileArgsBuilder {
 
 // RspFile reads the file names from given .rsp file and adds them to the source files list.
 func (b *FileArgsBuilder) RspFile(name string) *FileArgsBuilder {
+	b.checkNoExplicitPathInZip("the list " + name)
 	if b.err != nil {
 		return b
 	}
@@ -202,10 +223,11 @@ func (x IncorrectRelativeRootError) Error() string {
 }
 
 type ZipWriter struct {
-	time         time.Time
-	createdFiles map[string]string
-	createdDirs  map[string]string
-	directories  bool
+	time               time.Time
+	preserveTimestamps bool
+	createdFiles       map[string]string
+	createdDirs        map[string]string
+	directories        bool
 
 	errors   chan error
 	writeOps chan chan *zipEntry
@@ -248,6 +270,10 @@ type ZipArgs struct {
 	StoreSymlinks            bool
 	IgnoreMissingFiles       bool
 
+	// PreserveTimestamps stores the modification times of the files and symlinks in the zip.  By
+	// default every entry uses jar.DefaultTime so that the same inputs always produce the same zip.
+	PreserveTimestamps bool
+
 	Stderr     io.Writer
 	Filesystem pathtools.FileSystem
 }
@@ -262,6 +288,7 @@ func zipTo(args ZipArgs, w io.Writer) error {
 
 	z := &ZipWriter{
 		time:               jar.DefaultTime,
+		preserveTimestamps: args.PreserveTimestamps,
 		createdDirs:        make(map[string]string),
 		createdFiles:       make(map[string]string),
 		directories:        args.AddDirectoryEntriesToZip,
@@ -310,6 +337,10 @@ func zipTo(args ZipArgs, w io.Writer) error {
 			}
 			srcs = append(srcs, result.Matches...)
 		}
+		if fa.ExplicitPathInZip != "" && len(srcs) > 1 {
+			return fmt.Errorf("the path in the zip %q must be followed by a single file, %q matches %d files",
+				fa.ExplicitPathInZip, strings.Join(fa.SourceFiles, " "), len(srcs))
+		}
 		if fa.GlobDir != "" {
 			if exists, isDir, err := z.fs.Exists(fa.GlobDir); err != nil {
 				return err
@@ -400,7 +431,9 @@ func fillPathPairs(fa FileArg, src string, pathMappings *[]pathMapping,
 
 	var dest string
 
-	if fa.JunkPaths {
+	if fa.ExplicitPathInZip != "" {
+		dest = fa.ExplicitPathInZip
+	} else if fa.JunkPaths {
 		dest = filepath.Base(src)
 	} else {
 		var err error
@@ -427,6 +460,14 @@ func fillPathPairs(fa FileArg, src string, pathMappings *[]pathMapping,
 	return nil
 }
 
+// zipSort sorts the entries of a zip that is not a jar by name, so that the order of the entries
+// doesn't depend on the order of the arguments.  Entries with the same name keep their order.
+func zipSort(mappings []pathMapping) {
+	sort.SliceStable(mappings, func(i int, j int) bool {
+		return mappings[i].dest < mappings[j].dest
+	})
+}
+
 func jarSort(mappings []pathMapping) {
 	sort.SliceStable(mappings, func(i int, j int) bool {
 		return jar.EntryNamesLess(mappings[i].dest, mappings[j].dest)
@@ -468,6 +509,10 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 		pathMappings = append(pathMappings, pathMapping{jar.ManifestFile, manifest, zip.Deflate})
 
 		jarSort(pathMappings)
+	} else if !srcJar {
+		// The files in a srcjar are renamed to match their package, so they are kept in the order
+		// of the arguments.
+		zipSort(pathMappings)
 	}
 
 	go func() {
@@ -625,7 +670,7 @@ func (z *ZipWriter) addFile(dest, src string, method uint16, emulateJar, srcJar
 			return err
 		}
 
-		return z.writeSymlink(dest, src)
+		return z.writeSymlink(dest, src, z.modTime(s))
 	} else if s.Mode().IsRegular() {
 		r, err := z.fs.Open(src)
 		if err != nil {
@@ -661,6 +706,7 @@ func (z *ZipWriter) addFile(dest, src string, method uint16, emulateJar, srcJar
 			mode = 0755
 		}
 		header.SetMode(mode)
+		header.SetModTime(z.modTime(s))
 
 		err = createParentDirs(dest, src)
 		if err != nil {
@@ -706,12 +752,19 @@ func (z *ZipWriter) addManifest(dest string, src string, _ uint16) error {
 
 	reader := &byteReaderCloser{bytes.NewReader(buf), ioutil.NopCloser(nil)}
 
+	fh.SetModTime(z.time)
 	return z.writeFileContents(fh, reader)
 }
 
-func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.ReaderAtSeekerCloser) (err error) {
+// modTime returns the modification time to store in the zip for the file described by s.
+func (z *ZipWriter) modTime(s os.FileInfo) time.Time {
+	if z.preserveTimestamps {
+		return s.ModTime()
+	}
+	return z.time
+}
 
-	header.SetModTime(z.time)
+func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.ReaderAtSeekerCloser) (err error) {
 
 	compressChan := make(chan *zipEntry, 1)
 	z.writeOps <- compressChan
@@ -972,11 +1025,11 @@ func (z *ZipWriter) writeDirectory(dir string, src string, emulateJar bool) erro
 	return nil
 }
 
-func (z *ZipWriter) writeSymlink(rel, file string) error {
+func (z *ZipWriter) writeSymlink(rel, file string, modTime time.Time) error {
 	fileHeader := &zip.FileHeader{
 		Name: rel,
 	}
-	fileHeader.SetModTime(z.time)
+	fileHeader.SetModTime(modTime)
 	fileHeader.SetMode(0777 | os.ModeSymlink)
 
 	dest, err := z.fs.Readlink(file)
@@ -133,10 +133,10 @@ func TestZip(t *testing.T) {
 			compressionLevel: 9,
 
 			files: []zip.FileHeader{
+				fh("[", fileEmpty, zip.Store),
 				fh("a/a/a", fileA, zip.Deflate),
 				fh("a/a/b", fileB, zip.Deflate),
 				fh("c", fileC, zip.Deflate),
-				fh("[", fileEmpty, zip.Store),
 			},
 		},
 		{
@@ -235,10 +235,10 @@ func TestZip(t *testing.T) {
 			compressionLevel: 9,
 
 			files: []zip.FileHeader{
+				fh("[", fileEmpty, zip.Store),
 				fh("a/a/a", fileA, zip.Deflate),
 				fh("a/a/b", fileB, zip.Deflate),
 				fh("c", fileC, zip.Deflate),
-				fh("[", fileEmpty, zip.Store),
 			},
 		},
 		{
@@ -248,10 +248,10 @@ func TestZip(t *testing.T) {
 			compressionLevel: 9,
 
 			files: []zip.FileHeader{
+				fh("[", fileEmpty, zip.Store),
 				fh("a/a/a", fileA, zip.Deflate),
 				fh("a/a/b", fileB, zip.Deflate),
 				fh("c", fileC, zip.Deflate),
-				fh("[", fileEmpty, zip.Store),
 			},
 		},
 		{
@@ -261,11 +261,11 @@ func TestZip(t *testing.T) {
 			compressionLevel: 9,
 
 			files: []zip.FileHeader{
+				fh("@", fileC, zip.Deflate),
+				fh("[", fileEmpty, zip.Store),
 				fh("a/a/a", fileA, zip.Deflate),
 				fh("a/a/b", fileB, zip.Deflate),
-				fh("@", fileC, zip.Deflate),
 				fh("foo'bar", fileC, zip.Deflate),
-				fh("[", fileEmpty, zip.Store),
 			},
 		},
 		{
@@ -283,6 +283,20 @@ func TestZip(t *testing.T) {
 				fh("foo/c", fileC, zip.Deflate),
 			},
 		},
+		{
+			name: "explicit path in zip",
+			args: fileArgsBuilder().
+				PathPrefixInZip("foo").
+				ExplicitPathInZip("bin/b").
+				File("a/a/a").
+				File("c"),
+			compressionLevel: 9,
+
+			files: []zip.FileHeader{
+				fh("foo/bin/b", fileA, zip.Deflate),
+				fh("foo/c", fileC, zip.Deflate),
+			},
+		},
 		{
 			name: "relative root",
 			args: fileArgsBuilder().
@@ -535,6 +549,56 @@ func TestZip(t *testing.T) {
 	}
 }
 
+func TestExplicitPathInZipErrors(t *testing.T) {
+	if err := fileArgsBuilder().ExplicitPathInZip("b").Dir("a").Error(); err == nil {
+		t.Errorf("want an error for an explicit path in the zip followed by a directory")
+	}
+	if err := fileArgsBuilder().ExplicitPathInZip("b").List("l_nl").Error(); err == nil {
+		t.Errorf("want an error for an explicit path in the zip followed by a list")
+	}
+
+	args := ZipArgs{
+		FileArgs:   fileArgsBuilder().ExplicitPathInZip("b").File("a/a/*").FileArgs(),
+		Filesystem: mockFs,
+		Stderr:     &bytes.Buffer{},
+	}
+	if err := zipTo(args, &bytes.Buffer{}); err == nil {
+		t.Errorf("want an error for an explicit path in the zip followed by a glob of several files")
+	}
+}
+
+func TestZipDeterministic(t *testing.T) {
+	zipFiles := func(args *FileArgsBuilder) []byte {
+		t.Helper()
+		if args.Error() != nil {
+			t.Fatal(args.Error())
+		}
+		buf := &bytes.Buffer{}
+		err := zipTo(ZipArgs{
+			FileArgs:         args.FileArgs(),
+			CompressionLevel: 9,
+			NumParallelJobs:  4,
+			Filesystem:       mockFs,
+			Stderr:           &bytes.Buffer{},
+		}, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	first := zipFiles(fileArgsBuilder().File("a/a/a").File("a/a/b").File("c"))
+	second := zipFiles(fileArgsBuilder().File("a/a/a").File("a/a/b").File("c"))
+	if !bytes.Equal(first, second) {
+		t.Errorf("zipping the same files twice produced different zips")
+	}
+
+	reordered := zipFiles(fileArgsBuilder().File("c").File("a/a/b").File("a/a/a"))
+	if !bytes.Equal(first, reordered) {
+		t.Errorf("zipping the same files in a different order produced different zips")
+	}
+}
+
 func TestSrcJar(t *testing.T) {
 	mockFs := pathtools.MockFs(map[string][]byte{
 		"wrong_package.java":       []byte("package foo;"),